@@ -0,0 +1,327 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Geriye dönük testte kullanılabilecek en uzun zaman aralığı, varsayılan aralık ve
+// tüm servislerde okunabilecek en fazla kontrol kaydı
+const (
+	backtestMaxRange     = 31 * 24 * time.Hour
+	backtestDefaultRange = 24 * time.Hour
+	backtestMaxChecks    = 500000
+)
+
+// errBacktestTooLarge, kapsamdaki kontrol sayısı backtestMaxChecks'i aştığında döner
+var errBacktestTooLarge = fmt.Errorf("aralıkta %d'den fazla kontrol var; zaman aralığını veya kural kapsamını daraltın", backtestMaxChecks)
+
+// BacktestAlert, geriye dönük testte kuralın açacağı alarm
+type BacktestAlert struct {
+	ServiceID       int        `json:"service_id"`
+	ServiceName     string     `json:"service_name"`
+	Namespace       string     `json:"namespace"`
+	Cluster         string     `json:"cluster"`
+	Message         string     `json:"message"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Open            bool       `json:"open"` // aralık sonunda hâlâ açık
+}
+
+// BacktestSummary, geriye dönük testin özet sayıları
+type BacktestSummary struct {
+	ServicesEvaluated    int   `json:"services_evaluated"`
+	ServicesAffected     int   `json:"services_affected"`
+	ChecksReplayed       int   `json:"checks_replayed"`
+	Alerts               int   `json:"alerts"`
+	OpenAtEnd            int   `json:"open_at_end"`
+	TotalDurationSeconds int64 `json:"total_duration_seconds"`
+	MaxDurationSeconds   int64 `json:"max_duration_seconds"`
+}
+
+// ruleReplay, kuralı eskiden yeniye sıralı kontroller üzerinde artan anlarda değerlendirir.
+// Pencere kaydırılarak güncellenir; her kontrol pencereye bir kez girip bir kez çıkar.
+// Sonuçlar AlertRule.evaluate ile aynıdır.
+type ruleReplay struct {
+	rule       AlertRule
+	checks     []ruleCheck
+	start, end int // pencere: checks[start:end]
+
+	up, total int     // uptime_below: duraklatılmamış kontroller
+	samples   []int64 // latency_p95: sıralı yanıt süreleri
+	counted   []int   // failure_count: sayılan son kontrollerin indeksleri
+	failures  int
+	cert      int // cert_expiry: sertifika bilgisi olan son kontrolün indeksi (yoksa -1)
+}
+
+func newRuleReplay(rule AlertRule, checks []ruleCheck) *ruleReplay {
+	return &ruleReplay{rule: rule, checks: checks, cert: -1}
+}
+
+// add, i. kontrolü pencereye ekler
+func (p *ruleReplay) add(i int) {
+	c := p.checks[i]
+	switch p.rule.Type {
+	case RuleTypeLatencyP95:
+		if c.latencySample() {
+			n := sort.Search(len(p.samples), func(j int) bool { return p.samples[j] >= c.ResponseTime })
+			p.samples = append(p.samples, 0)
+			copy(p.samples[n+1:], p.samples[n:])
+			p.samples[n] = c.ResponseTime
+		}
+	case RuleTypeUptimeBelow:
+		if c.Status != CheckStatusPaused {
+			p.total++
+			if c.Status == "up" {
+				p.up++
+			}
+		}
+	case RuleTypeCertExpiry:
+		if c.CertExpiresAt != nil {
+			p.cert = i
+		}
+	case RuleTypeFailureCount:
+		if c.Status != CheckStatusPaused {
+			p.counted = append(p.counted, i)
+			if c.Status == "down" {
+				p.failures++
+			}
+			if len(p.counted) > p.rule.SampleSize {
+				p.dropCounted()
+			}
+		}
+	}
+}
+
+// remove, pencerenin başından düşen i. kontrolü çıkarır
+func (p *ruleReplay) remove(i int) {
+	c := p.checks[i]
+	switch p.rule.Type {
+	case RuleTypeLatencyP95:
+		if c.latencySample() {
+			n := sort.Search(len(p.samples), func(j int) bool { return p.samples[j] >= c.ResponseTime })
+			p.samples = append(p.samples[:n], p.samples[n+1:]...)
+		}
+	case RuleTypeUptimeBelow:
+		if c.Status != CheckStatusPaused {
+			p.total--
+			if c.Status == "up" {
+				p.up--
+			}
+		}
+	case RuleTypeCertExpiry:
+		if p.cert == i {
+			p.cert = -1
+		}
+	case RuleTypeFailureCount:
+		if len(p.counted) > 0 && p.counted[0] == i {
+			p.dropCounted()
+		}
+	}
+}
+
+// dropCounted, failure_count için sayılan en eski kontrolü bırakır
+func (p *ruleReplay) dropCounted() {
+	if p.checks[p.counted[0]].Status == "down" {
+		p.failures--
+	}
+	p.counted = p.counted[1:]
+}
+
+// evaluate, pencereyi at anına kaydırıp kuralı değerlendirir; at azalmamalıdır
+func (p *ruleReplay) evaluate(at time.Time) (bool, string) {
+	for p.end < len(p.checks) && !p.checks[p.end].Timestamp.After(at) {
+		p.add(p.end)
+		p.end++
+	}
+	if p.rule.WindowSeconds > 0 {
+		windowStart := at.Add(-p.rule.window())
+		for p.start < p.end && !p.checks[p.start].Timestamp.After(windowStart) {
+			p.remove(p.start)
+			p.start++
+		}
+	}
+
+	switch p.rule.Type {
+	case RuleTypeLatencyP95:
+		return p.rule.latencyFiring(p.samples)
+	case RuleTypeUptimeBelow:
+		return p.rule.uptimeFiring(p.up, p.total)
+	case RuleTypeCertExpiry:
+		if p.cert >= 0 {
+			return p.rule.certFiring(*p.checks[p.cert].CertExpiresAt, at)
+		}
+	case RuleTypeFailureCount:
+		return p.rule.failureFiring(len(p.counted), p.failures)
+	}
+	return false, ""
+}
+
+// replayRule, servisin kontrollerini from-to aralığında kural değerlendiricisinden geçirir.
+// Kural aralığın başında ve her kontrol kaydında değerlendirilir; sonuçtaki her firing
+// dönemi bir alarm olarak döndürülür. Hiçbir şey kaydedilmez.
+func replayRule(rule AlertRule, checks []ruleCheck, from, to time.Time) ([]BacktestAlert, int) {
+	points := []time.Time{from}
+	for _, c := range checks {
+		if c.Timestamp.After(from) && !c.Timestamp.After(to) {
+			points = append(points, c.Timestamp)
+		}
+	}
+	points = append(points, to)
+
+	replay := newRuleReplay(rule, checks)
+	var alerts []BacktestAlert
+	var current *BacktestAlert
+	for _, at := range points {
+		firing, message := replay.evaluate(at)
+		switch {
+		case firing && current == nil:
+			current = &BacktestAlert{Message: message, StartedAt: at}
+		case !firing && current != nil:
+			ended := at
+			current.EndedAt = &ended
+			current.DurationSeconds = int64(at.Sub(current.StartedAt).Seconds())
+			alerts = append(alerts, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		current.Open = true
+		current.DurationSeconds = int64(to.Sub(current.StartedAt).Seconds())
+		alerts = append(alerts, *current)
+	}
+	return alerts, len(points) - 2
+}
+
+// backtestAlertRule, kuralı kapsamındaki tüm servisler için geriye dönük çalıştırır.
+// Okunan kontrol sayısı backtestMaxChecks'i aşarsa errBacktestTooLarge döner.
+func backtestAlertRule(db *sql.DB, rule AlertRule, from, to time.Time) ([]BacktestAlert, BacktestSummary, error) {
+	var summary BacktestSummary
+	services, err := ruleServices(db, rule)
+	if err != nil {
+		return nil, summary, err
+	}
+	summary.ServicesEvaluated = len(services)
+
+	alerts := []BacktestAlert{}
+	loaded := 0
+	for _, serviceID := range services {
+		checks, err := loadRuleChecks(db, rule, serviceID, from, to)
+		if err != nil {
+			return nil, summary, err
+		}
+		if loaded += len(checks); loaded > backtestMaxChecks {
+			return nil, summary, errBacktestTooLarge
+		}
+		found, replayed := replayRule(rule, checks, from, to)
+		summary.ChecksReplayed += replayed
+		if len(found) == 0 {
+			continue
+		}
+
+		var name, namespace, cluster sql.NullString
+		db.QueryRow("SELECT name, namespace, cluster FROM services WHERE id = ?", serviceID).Scan(&name, &namespace, &cluster)
+		summary.ServicesAffected++
+		for _, a := range found {
+			a.ServiceID = serviceID
+			a.ServiceName, a.Namespace, a.Cluster = name.String, namespace.String, cluster.String
+			alerts = append(alerts, a)
+
+			summary.TotalDurationSeconds += a.DurationSeconds
+			if a.DurationSeconds > summary.MaxDurationSeconds {
+				summary.MaxDurationSeconds = a.DurationSeconds
+			}
+			if a.Open {
+				summary.OpenAtEnd++
+			}
+		}
+	}
+	summary.Alerts = len(alerts)
+	return alerts, summary, nil
+}
+
+// alertRulesBacktestHandler, bir kural tanımını (veya kayıtlı kuralı) verilen zaman aralığındaki
+// kontrol geçmişi üzerinde çalıştırır ve açılacak alarmları döndürür:
+//
+//	POST /api/v1/alert-rules/backtest {"rule": {...} | "rule_id": 1, "start": "...", "end": "..."}
+func alertRulesBacktestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Rule   *AlertRule `json:"rule"`
+		RuleID int64      `json:"rule_id"`
+		Start  *time.Time `json:"start"`
+		End    *time.Time `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+		return
+	}
+
+	var rule AlertRule
+	switch {
+	case req.Rule != nil:
+		rule = *req.Rule
+		if err := validateAlertRule(&rule); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+	case req.RuleID > 0:
+		var err error
+		rule, err = getAlertRule(db, req.RuleID)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Alarm kuralı bulunamadı"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralı alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, `{"error":"rule veya rule_id alanı gerekli"}`, http.StatusBadRequest)
+		return
+	}
+
+	end := time.Now()
+	if req.End != nil {
+		end = *req.End
+	}
+	start := end.Add(-backtestDefaultRange)
+	if req.Start != nil {
+		start = *req.Start
+	}
+	if !end.After(start) {
+		http.Error(w, `{"error":"end, start'tan sonra olmalı"}`, http.StatusBadRequest)
+		return
+	}
+	if end.Sub(start) > backtestMaxRange {
+		http.Error(w, fmt.Sprintf(`{"error":"Zaman aralığı en fazla %d gün olabilir"}`, int(backtestMaxRange.Hours()/24)), http.StatusBadRequest)
+		return
+	}
+
+	alerts, summary, err := backtestAlertRule(db, rule, start, end)
+	if errors.Is(err, errBacktestTooLarge) {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Geriye dönük test çalıştırılamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rule":    rule,
+		"start":   start,
+		"end":     end,
+		"alerts":  alerts,
+		"summary": summary,
+	})
+}
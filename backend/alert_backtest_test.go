@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBacktestAlertRuleReplaysFailureCount(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (7, 'checkout', 'shop', 'prod', 'service')`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (8, 'cart', 'shop', 'prod', 'service')`)
+
+	from := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(12 * time.Minute)
+	insert := func(serviceID int, offset time.Duration, status string) {
+		_, err := testDB.Exec(`INSERT INTO uptime_checks (service_id, status, response_time, timestamp) VALUES (?, ?, 100, ?)`,
+			serviceID, status, from.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Aralık öncesindeki son kontroller kuralın başlangıçta tetiklenmesini sağlar
+	insert(7, -3*time.Minute, "up")
+	insert(7, -2*time.Minute, "down")
+	insert(7, -1*time.Minute, "down")
+	for i, status := range []string{"up", "up", "up", "up", "down", "down", "up", "up", "down", "down"} {
+		insert(7, time.Duration(i+1)*time.Minute, status)
+		insert(8, time.Duration(i+1)*time.Minute, "up")
+	}
+	insert(7, 15*time.Minute, "down") // aralık sonrası, sayılmaz
+
+	rule := AlertRule{Name: "art arda hata", Type: RuleTypeFailureCount, Scope: RuleScopeNamespace, Namespace: "shop",
+		Threshold: 2, SampleSize: 3, Severity: SeverityCritical}
+	if err := rule.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, summary, err := backtestAlertRule(testDB, rule, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		start, end time.Duration
+		open       bool
+	}{
+		{0, 2 * time.Minute, false},               // aralık başında açık, iki başarılı kontrolle kapanır
+		{6 * time.Minute, 8 * time.Minute, false}, // 5. ve 6. dakikadaki hatalar
+		{10 * time.Minute, 12 * time.Minute, true},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("%d alarm bekleniyordu, alınan %d: %+v", len(want), len(alerts), alerts)
+	}
+	for i, w := range want {
+		a := alerts[i]
+		if a.ServiceID != 7 || a.ServiceName != "checkout" || a.Namespace != "shop" || a.Cluster != "prod" {
+			t.Errorf("alarm %d yanlış servise ait: %+v", i, a)
+		}
+		if !a.StartedAt.Equal(from.Add(w.start)) {
+			t.Errorf("alarm %d başlangıcı %s, beklenen %s", i, a.StartedAt, from.Add(w.start))
+		}
+		if a.Open != w.open {
+			t.Errorf("alarm %d açık olma durumu %v, beklenen %v", i, a.Open, w.open)
+		}
+		if w.open {
+			if a.EndedAt != nil {
+				t.Errorf("açık alarmın bitişi olmamalı: %v", a.EndedAt)
+			}
+		} else if a.EndedAt == nil || !a.EndedAt.Equal(from.Add(w.end)) {
+			t.Errorf("alarm %d bitişi %v, beklenen %s", i, a.EndedAt, from.Add(w.end))
+		}
+		if a.DurationSeconds != int64((w.end - w.start).Seconds()) {
+			t.Errorf("alarm %d süresi %d sn, beklenen %d sn", i, a.DurationSeconds, int64((w.end - w.start).Seconds()))
+		}
+	}
+
+	expected := BacktestSummary{
+		ServicesEvaluated:    2,
+		ServicesAffected:     1,
+		ChecksReplayed:       20, // servis başına aralıktaki 10 kontrol
+		Alerts:               3,
+		OpenAtEnd:            1,
+		TotalDurationSeconds: 360,
+		MaxDurationSeconds:   120,
+	}
+	if summary != expected {
+		t.Errorf("beklenen özet %+v, alınan %+v", expected, summary)
+	}
+
+	// Geriye dönük test hiçbir alarm kaydetmez
+	var count int
+	testDB.QueryRow(`SELECT COUNT(*) FROM alerts`).Scan(&count)
+	if count != 0 {
+		t.Errorf("alerts tablosuna %d kayıt yazıldı", count)
+	}
+}
+
+func TestReplayRuleCountsChecksInRange(t *testing.T) {
+	from := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	rule := AlertRule{Type: RuleTypeFailureCount, Threshold: 1, SampleSize: 1}
+	checks := checksEvery(from.Add(-time.Minute), time.Minute, []string{"up", "up", "down", "up"}, nil)
+
+	// from anındaki ve sonrasındaki kontroller: from+1m (down) ve from+2m (up); from'daki kayıt
+	// başlangıç değerlendirmesine dahildir, ayrıca sayılmaz
+	alerts, replayed := replayRule(rule, checks, from, from.Add(5*time.Minute))
+	if replayed != 2 {
+		t.Errorf("2 kontrol yeniden oynatılmalıydı, alınan %d", replayed)
+	}
+	if len(alerts) != 1 || alerts[0].DurationSeconds != 60 || alerts[0].Open {
+		t.Errorf("beklenmeyen alarmlar: %+v", alerts)
+	}
+
+	if alerts, replayed := replayRule(rule, nil, from, from.Add(time.Minute)); len(alerts) != 0 || replayed != 0 {
+		t.Errorf("kontrol yokken alarm veya yeniden oynatma beklenmiyordu: %+v %d", alerts, replayed)
+	}
+}
+
+func TestRuleReplayMatchesEvaluate(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	random := rand.New(rand.NewSource(1))
+	statuses := []string{"up", "up", "up", "warning", "down", CheckStatusPaused}
+
+	// Düzensiz aralıklı, aynı anda birden fazla kayıt içerebilen kontroller
+	var checks []ruleCheck
+	at := base
+	for i := 0; i < 2000; i++ {
+		at = at.Add(time.Duration(random.Intn(40)) * time.Second)
+		c := ruleCheck{Status: statuses[random.Intn(len(statuses))], Timestamp: at, ResponseTime: int64(random.Intn(3000))}
+		if random.Intn(10) == 0 {
+			expiresAt := at.Add(time.Duration(random.Intn(30*24)) * time.Hour)
+			c.CertExpiresAt = &expiresAt
+		}
+		checks = append(checks, c)
+	}
+
+	rules := []AlertRule{
+		{Type: RuleTypeLatencyP95, Threshold: 2000, WindowSeconds: 600},
+		{Type: RuleTypeUptimeBelow, Threshold: 60, WindowSeconds: 300},
+		{Type: RuleTypeCertExpiry, Threshold: 14},
+		{Type: RuleTypeFailureCount, Threshold: 3, SampleSize: 5},
+	}
+	for _, rule := range rules {
+		replay := newRuleReplay(rule, checks)
+		points := []time.Time{base.Add(-time.Minute)}
+		for _, c := range checks {
+			points = append(points, c.Timestamp, c.Timestamp.Add(time.Duration(random.Intn(90))*time.Second))
+		}
+		last := points[0]
+		for _, at := range points {
+			if at.Before(last) {
+				continue
+			}
+			last = at
+			wantFiring, wantMessage := rule.evaluate(checks, at)
+			firing, message := replay.evaluate(at)
+			if firing != wantFiring || message != wantMessage {
+				t.Fatalf("%s @%s: beklenen %v %q, alınan %v %q", rule.Type, at.Sub(base), wantFiring, wantMessage, firing, message)
+			}
+		}
+	}
+}
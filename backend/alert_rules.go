@@ -0,0 +1,687 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alarm kuralı türleri
+const (
+	RuleTypeLatencyP95   = "latency_p95"   // pencere içindeki p95 yanıt süresi eşiği (ms) aşarsa
+	RuleTypeUptimeBelow  = "uptime_below"  // pencere içindeki uptime yüzdesi eşiğin altına düşerse
+	RuleTypeCertExpiry   = "cert_expiry"   // sertifikanın bitmesine eşikten (gün) az kalırsa
+	RuleTypeFailureCount = "failure_count" // son sample_size kontrolün en az threshold tanesi başarısızsa
+)
+
+// Alarm kuralı kapsamları
+const (
+	RuleScopeService   = "service"
+	RuleScopeNamespace = "namespace"
+	RuleScopeCluster   = "cluster"
+)
+
+// alertRuleEvaluationInterval, kuralların değerlendirilme aralığı
+const alertRuleEvaluationInterval = time.Minute
+
+// maxRuleSampleSize, failure_count kuralında bakılabilecek en fazla kontrol sayısı
+const maxRuleSampleSize = 1000
+
+// AlertRule, uptime_checks üzerinden değerlendirilen alarm kuralı
+type AlertRule struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	Scope         string    `json:"scope"`
+	ServiceID     *int      `json:"service_id,omitempty"`
+	Namespace     string    `json:"namespace,omitempty"`
+	Cluster       string    `json:"cluster,omitempty"`
+	Threshold     float64   `json:"threshold"`
+	WindowSeconds int       `json:"window_seconds,omitempty"`
+	SampleSize    int       `json:"sample_size,omitempty"`
+	Severity      string    `json:"severity"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// window, pencere tabanlı kuralların değerlendirme penceresi
+func (r AlertRule) window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// Validate, kuralı doğrular ve kapsam dışı alanları temizler
+func (r *AlertRule) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name alanı gerekli")
+	}
+
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityCritical, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("severity critical, warning veya info olmalı")
+	}
+
+	switch r.Scope {
+	case RuleScopeService:
+		if r.ServiceID == nil || *r.ServiceID <= 0 {
+			return fmt.Errorf("service kapsamı için service_id gerekli")
+		}
+		r.Namespace, r.Cluster = "", ""
+	case RuleScopeNamespace:
+		if r.Namespace == "" {
+			return fmt.Errorf("namespace kapsamı için namespace gerekli")
+		}
+		r.ServiceID = nil
+	case RuleScopeCluster:
+		if r.Cluster == "" {
+			return fmt.Errorf("cluster kapsamı için cluster gerekli")
+		}
+		r.ServiceID, r.Namespace = nil, ""
+	default:
+		return fmt.Errorf("scope service, namespace veya cluster olmalı")
+	}
+
+	switch r.Type {
+	case RuleTypeLatencyP95:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("latency_p95 için threshold (ms) sıfırdan büyük olmalı")
+		}
+		if r.WindowSeconds < 60 {
+			return fmt.Errorf("window_seconds en az 60 olmalı")
+		}
+		r.SampleSize = 0
+	case RuleTypeUptimeBelow:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("uptime_below için threshold 0 ile 100 arasında olmalı")
+		}
+		if r.WindowSeconds < 60 {
+			return fmt.Errorf("window_seconds en az 60 olmalı")
+		}
+		r.SampleSize = 0
+	case RuleTypeCertExpiry:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("cert_expiry için threshold (gün) sıfırdan büyük olmalı")
+		}
+		r.WindowSeconds, r.SampleSize = 0, 0
+	case RuleTypeFailureCount:
+		if r.Threshold < 1 || r.Threshold != math.Trunc(r.Threshold) {
+			return fmt.Errorf("failure_count için threshold pozitif bir tam sayı olmalı")
+		}
+		if r.SampleSize < int(r.Threshold) || r.SampleSize > maxRuleSampleSize {
+			return fmt.Errorf("sample_size threshold ile %d arasında olmalı", maxRuleSampleSize)
+		}
+		r.WindowSeconds = 0
+	default:
+		return fmt.Errorf("type latency_p95, uptime_below, cert_expiry veya failure_count olmalı")
+	}
+	return nil
+}
+
+// ruleCheck, kural değerlendirmesinde kullanılan kontrol kaydı
+type ruleCheck struct {
+	Status        string
+	ResponseTime  int64
+	CertExpiresAt *time.Time
+	Timestamp     time.Time
+}
+
+// windowLabel, pencere süresini mesajlarda okunur biçimde yazar
+func windowLabel(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d saat", int(d/time.Hour))
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d dakika", int(d/time.Minute))
+	default:
+		return fmt.Sprintf("%d saniye", int(d/time.Second))
+	}
+}
+
+// evaluate, servisin eskiden yeniye sıralı kontrollerini at anına göre değerlendirir.
+// at anından sonraki kayıtlar dikkate alınmaz; duraklatılmış (paused) kontroller sayılmaz.
+func (r AlertRule) evaluate(checks []ruleCheck, at time.Time) (bool, string) {
+	// at anına kadarki kayıtlar; pencere kurallarında yalnızca pencere içindekiler
+	end := sort.Search(len(checks), func(i int) bool { return checks[i].Timestamp.After(at) })
+	checks = checks[:end]
+	if r.WindowSeconds > 0 {
+		start := sort.Search(len(checks), func(i int) bool { return checks[i].Timestamp.After(at.Add(-r.window())) })
+		checks = checks[start:]
+	}
+
+	switch r.Type {
+	case RuleTypeLatencyP95:
+		var samples []int64
+		for _, c := range checks {
+			if c.latencySample() {
+				samples = append(samples, c.ResponseTime)
+			}
+		}
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		return r.latencyFiring(samples)
+
+	case RuleTypeUptimeBelow:
+		var total, up int
+		for _, c := range checks {
+			if c.Status == CheckStatusPaused {
+				continue
+			}
+			total++
+			if c.Status == "up" {
+				up++
+			}
+		}
+		return r.uptimeFiring(up, total)
+
+	case RuleTypeCertExpiry:
+		for i := len(checks) - 1; i >= 0; i-- {
+			if checks[i].CertExpiresAt != nil {
+				return r.certFiring(*checks[i].CertExpiresAt, at)
+			}
+		}
+
+	case RuleTypeFailureCount:
+		var seen, failures int
+		for i := len(checks) - 1; i >= 0 && seen < r.SampleSize; i-- {
+			if checks[i].Status == CheckStatusPaused {
+				continue
+			}
+			seen++
+			if checks[i].Status == "down" {
+				failures++
+			}
+		}
+		return r.failureFiring(seen, failures)
+	}
+	return false, ""
+}
+
+// latencySample, kontrolün p95 hesabına katılıp katılmadığını döndürür
+func (c ruleCheck) latencySample() bool {
+	return (c.Status == "up" || c.Status == "warning") && c.ResponseTime > 0
+}
+
+// latencyFiring, küçükten büyüğe sıralı yanıt sürelerinin p95 değerini eşikle karşılaştırır
+func (r AlertRule) latencyFiring(sorted []int64) (bool, string) {
+	if len(sorted) == 0 {
+		return false, ""
+	}
+	p95 := sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
+	if float64(p95) > r.Threshold {
+		return true, fmt.Sprintf("Son %s içinde p95 yanıt süresi %d ms (eşik %.0f ms)", windowLabel(r.window()), p95, r.Threshold)
+	}
+	return false, ""
+}
+
+// uptimeFiring, duraklatılmamış kontrollerdeki uptime yüzdesini eşikle karşılaştırır
+func (r AlertRule) uptimeFiring(up, total int) (bool, string) {
+	if total == 0 {
+		return false, ""
+	}
+	percentage := float64(up) / float64(total) * 100
+	if percentage < r.Threshold {
+		return true, fmt.Sprintf("Son %s içinde uptime %%%.2f (eşik %%%.2f)", windowLabel(r.window()), percentage, r.Threshold)
+	}
+	return false, ""
+}
+
+// certFiring, en son görülen sertifikanın at anında kalan süresini eşikle karşılaştırır
+func (r AlertRule) certFiring(expiresAt, at time.Time) (bool, string) {
+	daysLeft := expiresAt.Sub(at).Hours() / 24
+	if daysLeft <= 0 {
+		return true, fmt.Sprintf("Sertifikanın süresi %s tarihinde doldu", expiresAt.Format("2006-01-02"))
+	}
+	if daysLeft < r.Threshold {
+		return true, fmt.Sprintf("Sertifikanın süresinin dolmasına %d gün kaldı (eşik %.0f gün)", int(daysLeft), r.Threshold)
+	}
+	return false, ""
+}
+
+// failureFiring, son kontrollerdeki başarısız kontrol sayısını eşikle karşılaştırır
+func (r AlertRule) failureFiring(seen, failures int) (bool, string) {
+	if failures >= int(r.Threshold) {
+		return true, fmt.Sprintf("Son %d kontrolün %d tanesi başarısız", seen, failures)
+	}
+	return false, ""
+}
+
+// ruleAlertKey, kural alarmlarının servis başına tekilleştirme anahtarı
+func ruleAlertKey(ruleID int64, serviceID int) string {
+	return fmt.Sprintf("rule:%d:%d", ruleID, serviceID)
+}
+
+// alertRuleColumns, alarm kuralı sorgularında kullanılan kolonlar
+const alertRuleColumns = `id, name, type, scope, service_id, namespace, cluster, threshold,
+	window_seconds, sample_size, severity, enabled, created_at, updated_at`
+
+// scanAlertRule, alertRuleColumns sırasıyla okunan satırı AlertRule'a dönüştürür
+func scanAlertRule(scanner interface{ Scan(...interface{}) error }) (AlertRule, error) {
+	var r AlertRule
+	var serviceID sql.NullInt64
+	var namespace, cluster sql.NullString
+	err := scanner.Scan(&r.ID, &r.Name, &r.Type, &r.Scope, &serviceID, &namespace, &cluster, &r.Threshold,
+		&r.WindowSeconds, &r.SampleSize, &r.Severity, &r.Enabled, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return r, err
+	}
+	if serviceID.Valid {
+		id := int(serviceID.Int64)
+		r.ServiceID = &id
+	}
+	r.Namespace = namespace.String
+	r.Cluster = cluster.String
+	return r, nil
+}
+
+// getAlertRule, kuralı ID ile döndürür
+func getAlertRule(db *sql.DB, id int64) (AlertRule, error) {
+	return scanAlertRule(db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`, id))
+}
+
+// listAlertRules, koşula uyan kuralları döndürür
+func listAlertRules(db *sql.DB, where string, args ...interface{}) ([]AlertRule, error) {
+	rows, err := db.Query(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []AlertRule{}
+	for rows.Next() {
+		r, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// saveAlertRule, kuralı ekler (ID sıfırsa) veya günceller
+func saveAlertRule(db *sql.DB, r *AlertRule) error {
+	var serviceID sql.NullInt64
+	if r.ServiceID != nil {
+		serviceID = sql.NullInt64{Int64: int64(*r.ServiceID), Valid: true}
+	}
+	now := time.Now()
+
+	if r.ID == 0 {
+		result, err := db.Exec(`
+			INSERT INTO alert_rules (name, type, scope, service_id, namespace, cluster, threshold,
+				window_seconds, sample_size, severity, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, r.Name, r.Type, r.Scope, serviceID, r.Namespace, r.Cluster, r.Threshold,
+			r.WindowSeconds, r.SampleSize, r.Severity, r.Enabled, now, now)
+		if err != nil {
+			return err
+		}
+		r.ID, _ = result.LastInsertId()
+		r.CreatedAt = now
+	} else {
+		_, err := db.Exec(`
+			UPDATE alert_rules SET name = ?, type = ?, scope = ?, service_id = ?, namespace = ?, cluster = ?,
+				threshold = ?, window_seconds = ?, sample_size = ?, severity = ?, enabled = ?, updated_at = ?
+			WHERE id = ?
+		`, r.Name, r.Type, r.Scope, serviceID, r.Namespace, r.Cluster, r.Threshold,
+			r.WindowSeconds, r.SampleSize, r.Severity, r.Enabled, now, r.ID)
+		if err != nil {
+			return err
+		}
+	}
+	r.UpdatedAt = now
+	return nil
+}
+
+// ruleServices, kuralın kapsamındaki izlenen servislerin ID'lerini döndürür
+func ruleServices(db *sql.DB, r AlertRule) ([]int, error) {
+	query := `SELECT id FROM services WHERE archived_at IS NULL AND COALESCE(monitoring_enabled, 1) = 1`
+	var args []interface{}
+	switch r.Scope {
+	case RuleScopeService:
+		query += " AND id = ?"
+		args = append(args, *r.ServiceID)
+	case RuleScopeNamespace:
+		// Cluster verilmemişse namespace tüm cluster'larda eşleşir
+		query += " AND namespace = ?"
+		args = append(args, r.Namespace)
+		if r.Cluster != "" {
+			query += " AND cluster = ?"
+			args = append(args, r.Cluster)
+		}
+	case RuleScopeCluster:
+		query += " AND cluster = ?"
+		args = append(args, r.Cluster)
+	}
+
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// queryRuleChecks, uptime_checks üzerinde verilen koşulla kontrol kayıtlarını okur
+func queryRuleChecks(db *sql.DB, where string, args ...interface{}) ([]ruleCheck, error) {
+	rows, err := db.Query(`SELECT status, COALESCE(response_time, 0), cert_expires_at, timestamp FROM uptime_checks WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []ruleCheck
+	for rows.Next() {
+		var c ruleCheck
+		var certExpiresAt sql.NullTime
+		if err := rows.Scan(&c.Status, &c.ResponseTime, &certExpiresAt, &c.Timestamp); err != nil {
+			return nil, err
+		}
+		if certExpiresAt.Valid {
+			c.CertExpiresAt = &certExpiresAt.Time
+		}
+		checks = append(checks, c)
+	}
+	return checks, rows.Err()
+}
+
+// loadRuleChecks, kuralı from ile to arasındaki her an için değerlendirmeye yetecek kontrolleri
+// eskiden yeniye sıralı döndürür: pencere kuralları için from'dan bir pencere öncesi,
+// failure_count için from'dan önceki son sample_size kontrol, cert_expiry için from'dan önceki son sertifika bilgisi
+func loadRuleChecks(db *sql.DB, r AlertRule, serviceID int, from, to time.Time) ([]ruleCheck, error) {
+	var before []ruleCheck
+	var err error
+	switch r.Type {
+	case RuleTypeLatencyP95, RuleTypeUptimeBelow:
+		return queryRuleChecks(db, `service_id = ? AND timestamp > ? AND timestamp <= ? ORDER BY timestamp`,
+			serviceID, from.Add(-r.window()), to)
+	case RuleTypeFailureCount:
+		before, err = queryRuleChecks(db, `service_id = ? AND timestamp <= ? AND status != ? ORDER BY timestamp DESC LIMIT ?`,
+			serviceID, from, CheckStatusPaused, r.SampleSize)
+	case RuleTypeCertExpiry:
+		before, err = queryRuleChecks(db, `service_id = ? AND timestamp <= ? AND cert_expires_at IS NOT NULL ORDER BY timestamp DESC LIMIT 1`,
+			serviceID, from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(before)-1; i < j; i, j = i+1, j-1 {
+		before[i], before[j] = before[j], before[i]
+	}
+	if !to.After(from) {
+		return before, nil
+	}
+
+	after, err := queryRuleChecks(db, `service_id = ? AND timestamp > ? AND timestamp <= ? ORDER BY timestamp`, serviceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(before, after...), nil
+}
+
+// AlertRuleEvaluator, etkin alarm kurallarını düzenli aralıklarla değerlendirir
+type AlertRuleEvaluator struct {
+	db      *sql.DB
+	manager *AlertManager
+}
+
+// NewAlertRuleEvaluator, yeni bir kural değerlendiricisi oluşturur
+func NewAlertRuleEvaluator(db *sql.DB, manager *AlertManager) *AlertRuleEvaluator {
+	return &AlertRuleEvaluator{db: db, manager: manager}
+}
+
+// Run, context iptal edilene kadar kuralları her dakika değerlendirir
+func (e *AlertRuleEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(alertRuleEvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.EvaluateAll(time.Now())
+		}
+	}
+}
+
+// EvaluateAll, tüm kuralları verilen ana göre değerlendirir. Devre dışı kuralların
+// ve kapsamdan çıkan servislerin açık alarmları kapatılır.
+func (e *AlertRuleEvaluator) EvaluateAll(at time.Time) {
+	rules, err := listAlertRules(e.db, "1 = 1")
+	if err != nil {
+		log.Printf("Alarm kuralları okunamadı: %v", err)
+		return
+	}
+
+	for _, rule := range rules {
+		active := map[string]bool{}
+		if rule.Enabled {
+			active, err = e.evaluateRule(rule, at)
+			if err != nil {
+				log.Printf("Alarm kuralı %d (%s) değerlendirilemedi: %v", rule.ID, rule.Name, err)
+				continue
+			}
+		}
+		e.clearInactive(rule.ID, active, at)
+	}
+}
+
+// suppressedByOutage, kural türünün servis kesintisiyle birlikte tetiklenip tetiklenmediğini döndürür.
+// Bu kurallar servisin durum alarmı açıkken aynı kesinti için ikinci bir alarm açmaz.
+func (r AlertRule) suppressedByOutage() bool {
+	return r.Type != RuleTypeCertExpiry
+}
+
+// openStatusAlertServices, açık durum (status:) alarmı olan servisleri döndürür
+func openStatusAlertServices(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query(`SELECT service_id FROM alerts WHERE rule_id IS NULL AND status = ?`, AlertStatusOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		services[id] = true
+	}
+	return services, rows.Err()
+}
+
+// evaluateRule, kuralı kapsamındaki her servis için değerlendirir ve alarmları açar ya da kapatır.
+// Durum alarmı açık olan servislerde kesintiye bağlı kurallar değerlendirilmez; varsa açık kural
+// alarmı olduğu gibi kalır ve kesinti bitince kural yeniden değerlendirilir.
+// Değerlendirilen servislerin anahtarlarını döndürür.
+func (e *AlertRuleEvaluator) evaluateRule(rule AlertRule, at time.Time) (map[string]bool, error) {
+	services, err := ruleServices(e.db, rule)
+	if err != nil {
+		return nil, err
+	}
+	outages := map[int]bool{}
+	if rule.suppressedByOutage() {
+		if outages, err = openStatusAlertServices(e.db); err != nil {
+			return nil, err
+		}
+	}
+
+	evaluated := make(map[string]bool, len(services))
+	for _, serviceID := range services {
+		key := ruleAlertKey(rule.ID, serviceID)
+		evaluated[key] = true
+		if outages[serviceID] {
+			continue
+		}
+
+		checks, err := loadRuleChecks(e.db, rule, serviceID, at, at)
+		if err != nil {
+			log.Printf("Servis %d kontrolleri okunamadı: %v", serviceID, err)
+			continue
+		}
+		if firing, message := rule.evaluate(checks, at); firing {
+			err = e.manager.Fire(key, serviceID, &rule.ID, rule.Severity, rule.Name+": "+message, at)
+		} else {
+			err = e.manager.Clear(key, at)
+		}
+		if err != nil {
+			log.Printf("Kural %d servis %d alarmı güncellenemedi: %v", rule.ID, serviceID, err)
+		}
+	}
+	return evaluated, nil
+}
+
+// clearInactive, kuralın artık değerlendirilmeyen servislerdeki açık alarmlarını kapatır
+func (e *AlertRuleEvaluator) clearInactive(ruleID int64, active map[string]bool, at time.Time) {
+	open, err := e.manager.List("a.rule_id = ? AND a.status = ?", ruleID, AlertStatusOpen)
+	if err != nil {
+		log.Printf("Kural %d alarmları okunamadı: %v", ruleID, err)
+		return
+	}
+	for _, alert := range open {
+		if active[alert.DedupKey] {
+			continue
+		}
+		if err := e.manager.Clear(alert.DedupKey, at); err != nil {
+			log.Printf("Kural %d alarmı kapatılamadı: %v", ruleID, err)
+		}
+	}
+}
+
+// decodeAlertRule, istek gövdesini mevcut kuralın üzerine okur ve doğrular
+func decodeAlertRule(r *http.Request, rule *AlertRule) error {
+	if err := json.NewDecoder(r.Body).Decode(rule); err != nil {
+		return fmt.Errorf("İstek gövdesi ayrıştırılamadı")
+	}
+	return validateAlertRule(rule)
+}
+
+// validateAlertRule, kuralı doğrular ve servis kapsamındaki servisin var olduğunu kontrol eder
+func validateAlertRule(rule *AlertRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if rule.Scope == RuleScopeService {
+		var exists int
+		if err := db.QueryRow("SELECT COUNT(*) FROM services WHERE id = ?", *rule.ServiceID).Scan(&exists); err != nil || exists == 0 {
+			return fmt.Errorf("servis %d bulunamadı", *rule.ServiceID)
+		}
+	}
+	return nil
+}
+
+// alertRulesHandler, /api/v1/alert-rules altındaki endpoint'leri yönetir:
+//
+//	GET    /api/v1/alert-rules       kurallar
+//	POST   /api/v1/alert-rules       kural oluşturma
+//	GET    /api/v1/alert-rules/{id}  kural detayı
+//	PUT    /api/v1/alert-rules/{id}  kural güncelleme (gönderilmeyen alanlar korunur)
+//	DELETE /api/v1/alert-rules/{id}  kural silme (açık alarmları kapatılır)
+func alertRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/alert-rules"), "/")
+	if path == "" {
+		switch r.Method {
+		case "GET":
+			rules, err := listAlertRules(db, "1 = 1")
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralları alınamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"rules": rules, "count": len(rules)})
+
+		case "POST":
+			rule := AlertRule{Enabled: true}
+			if err := decodeAlertRule(r, &rule); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			rule.ID = 0
+			if err := saveAlertRule(db, &rule); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Alarm kuralı oluşturuldu",
+				"rule":    rule,
+			})
+
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Geçersiz kural ID"}`, http.StatusBadRequest)
+		return
+	}
+	rule, err := getAlertRule(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Alarm kuralı bulunamadı"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralı alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(map[string]interface{}{"rule": rule})
+
+	case "PUT":
+		if err := decodeAlertRule(r, &rule); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		rule.ID = id
+		if err := saveAlertRule(db, &rule); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alarm kuralı güncellendi",
+			"rule":    rule,
+		})
+
+	case "DELETE":
+		if _, err := db.Exec("DELETE FROM alert_rules WHERE id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralı silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		if alertManager != nil {
+			NewAlertRuleEvaluator(db, alertManager).clearInactive(id, nil, time.Now())
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alarm kuralı silindi",
+			"id":      id,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
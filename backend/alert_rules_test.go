@@ -0,0 +1,326 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// checksEvery, base'den başlayarak aralıklı kontrol kayıtları üretir
+func checksEvery(base time.Time, interval time.Duration, statuses []string, responseTimes []int64) []ruleCheck {
+	checks := make([]ruleCheck, len(statuses))
+	for i, status := range statuses {
+		checks[i] = ruleCheck{Status: status, Timestamp: base.Add(time.Duration(i) * interval)}
+		if i < len(responseTimes) {
+			checks[i].ResponseTime = responseTimes[i]
+		}
+	}
+	return checks
+}
+
+func TestAlertRuleEvaluate(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	repeat := func(status string, n int) []string {
+		s := make([]string, n)
+		for i := range s {
+			s[i] = status
+		}
+		return s
+	}
+	expires := func(days float64) []ruleCheck {
+		at := base.Add(time.Duration(days * 24 * float64(time.Hour)))
+		return []ruleCheck{{Status: "up", Timestamp: base.Add(-time.Minute), CertExpiresAt: &at}}
+	}
+	latencies := make([]int64, 20)
+	for i := range latencies {
+		latencies[i] = int64(100 + i*10) // 100..290 ms; p95 = 19. örnek (280 ms)
+	}
+
+	cases := []struct {
+		name    string
+		rule    AlertRule
+		checks  []ruleCheck
+		at      time.Time
+		firing  bool
+		message string
+	}{
+		{
+			name:    "p95 eşiği aşıyor",
+			rule:    AlertRule{Type: RuleTypeLatencyP95, Threshold: 270, WindowSeconds: 3600},
+			checks:  checksEvery(base, time.Minute, repeat("up", 20), latencies),
+			at:      base.Add(20 * time.Minute),
+			firing:  true,
+			message: "p95 yanıt süresi 280 ms",
+		},
+		{
+			name:   "p95 eşiğe eşit",
+			rule:   AlertRule{Type: RuleTypeLatencyP95, Threshold: 280, WindowSeconds: 3600},
+			checks: checksEvery(base, time.Minute, repeat("up", 20), latencies),
+			at:     base.Add(20 * time.Minute),
+		},
+		{
+			name:   "p95 down ve paused kontrolleri saymaz",
+			rule:   AlertRule{Type: RuleTypeLatencyP95, Threshold: 150, WindowSeconds: 3600},
+			checks: checksEvery(base, time.Minute, []string{"up", "down", CheckStatusPaused, "up"}, []int64{100, 5000, 9000, 120}),
+			at:     base.Add(5 * time.Minute),
+		},
+		{
+			name:   "p95 pencere dışındaki yavaş kontrolleri saymaz",
+			rule:   AlertRule{Type: RuleTypeLatencyP95, Threshold: 500, WindowSeconds: 120},
+			checks: checksEvery(base, time.Minute, []string{"up", "up", "up", "up"}, []int64{900, 900, 100, 100}),
+			at:     base.Add(3 * time.Minute),
+		},
+		{
+			name:   "at anından sonraki kayıtlar sayılmaz",
+			rule:   AlertRule{Type: RuleTypeLatencyP95, Threshold: 500, WindowSeconds: 3600},
+			checks: checksEvery(base, time.Minute, []string{"up", "up", "up"}, []int64{100, 100, 900}),
+			at:     base.Add(90 * time.Second),
+		},
+		{
+			name:    "uptime eşiğin altında",
+			rule:    AlertRule{Type: RuleTypeUptimeBelow, Threshold: 90, WindowSeconds: 3600},
+			checks:  checksEvery(base, time.Minute, []string{"up", "up", "up", "down"}, nil),
+			at:      base.Add(10 * time.Minute),
+			firing:  true,
+			message: "uptime %75.00",
+		},
+		{
+			name:   "uptime paused kontrolleri saymaz",
+			rule:   AlertRule{Type: RuleTypeUptimeBelow, Threshold: 90, WindowSeconds: 3600},
+			checks: checksEvery(base, time.Minute, []string{"up", CheckStatusPaused, CheckStatusPaused, "up"}, nil),
+			at:     base.Add(10 * time.Minute),
+		},
+		{
+			name:   "uptime yalnızca paused kayıtlarla alarm üretmez",
+			rule:   AlertRule{Type: RuleTypeUptimeBelow, Threshold: 90, WindowSeconds: 3600},
+			checks: checksEvery(base, time.Minute, repeat(CheckStatusPaused, 5), nil),
+			at:     base.Add(10 * time.Minute),
+		},
+		{
+			name:   "uptime pencere dışındaki kesintiyi saymaz",
+			rule:   AlertRule{Type: RuleTypeUptimeBelow, Threshold: 90, WindowSeconds: 120},
+			checks: checksEvery(base, time.Minute, []string{"down", "down", "up", "up"}, nil),
+			at:     base.Add(3 * time.Minute),
+		},
+		{
+			name:    "sertifika eşikten az gün",
+			rule:    AlertRule{Type: RuleTypeCertExpiry, Threshold: 14},
+			checks:  expires(10.5),
+			at:      base,
+			firing:  true,
+			message: "10 gün kaldı",
+		},
+		{
+			name:   "sertifika eşikten fazla gün",
+			rule:   AlertRule{Type: RuleTypeCertExpiry, Threshold: 14},
+			checks: expires(20),
+			at:     base,
+		},
+		{
+			name:    "sertifika süresi dolmuş",
+			rule:    AlertRule{Type: RuleTypeCertExpiry, Threshold: 14},
+			checks:  expires(-1),
+			at:      base,
+			firing:  true,
+			message: "doldu",
+		},
+		{
+			name:   "sertifika bilgisi yok",
+			rule:   AlertRule{Type: RuleTypeCertExpiry, Threshold: 14},
+			checks: checksEvery(base, time.Minute, []string{"up"}, nil),
+			at:     base.Add(time.Minute),
+		},
+		{
+			name:    "failure_count son örneklerde eşiğe ulaşır",
+			rule:    AlertRule{Type: RuleTypeFailureCount, Threshold: 3, SampleSize: 5},
+			checks:  checksEvery(base, time.Minute, []string{"down", "down", "up", "down", "up", "down", "down"}, nil),
+			at:      base.Add(10 * time.Minute),
+			firing:  true,
+			message: "Son 5 kontrolün 3 tanesi",
+		},
+		{
+			name:   "failure_count örnek dışındaki eski hataları saymaz",
+			rule:   AlertRule{Type: RuleTypeFailureCount, Threshold: 3, SampleSize: 3},
+			checks: checksEvery(base, time.Minute, []string{"down", "down", "down", "up", "down", "up"}, nil),
+			at:     base.Add(10 * time.Minute),
+		},
+		{
+			name:    "failure_count paused kayıtları atlayarak geriye yürür",
+			rule:    AlertRule{Type: RuleTypeFailureCount, Threshold: 2, SampleSize: 2},
+			checks:  checksEvery(base, time.Minute, []string{"down", CheckStatusPaused, "down", CheckStatusPaused, CheckStatusPaused}, nil),
+			at:      base.Add(10 * time.Minute),
+			firing:  true,
+			message: "Son 2 kontrolün 2 tanesi",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			firing, message := c.rule.evaluate(c.checks, c.at)
+			if firing != c.firing {
+				t.Fatalf("beklenen %v, alınan %v (%s)", c.firing, firing, message)
+			}
+			if c.message != "" && !strings.Contains(message, c.message) {
+				t.Errorf("mesaj %q içermeli, alınan %q", c.message, message)
+			}
+		})
+	}
+}
+
+func TestAlertRuleValidate(t *testing.T) {
+	serviceID := 7
+	cases := []struct {
+		name  string
+		rule  AlertRule
+		err   string
+		check func(t *testing.T, r AlertRule)
+	}{
+		{
+			name: "ad gerekli",
+			rule: AlertRule{Name: "  ", Type: RuleTypeCertExpiry, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 14},
+			err:  "name",
+		},
+		{
+			name: "geçersiz önem derecesi",
+			rule: AlertRule{Name: "r", Type: RuleTypeCertExpiry, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 14, Severity: "fatal"},
+			err:  "severity",
+		},
+		{
+			name: "service kapsamı service_id ister",
+			rule: AlertRule{Name: "r", Type: RuleTypeCertExpiry, Scope: RuleScopeService, Threshold: 14},
+			err:  "service_id",
+		},
+		{
+			name: "namespace kapsamı namespace ister",
+			rule: AlertRule{Name: "r", Type: RuleTypeCertExpiry, Scope: RuleScopeNamespace, Threshold: 14},
+			err:  "namespace",
+		},
+		{
+			name: "geçersiz kapsam",
+			rule: AlertRule{Name: "r", Type: RuleTypeCertExpiry, Scope: "global", Threshold: 14},
+			err:  "scope",
+		},
+		{
+			name: "geçersiz tür",
+			rule: AlertRule{Name: "r", Type: "latency_p99", Scope: RuleScopeCluster, Cluster: "prod", Threshold: 14},
+			err:  "type",
+		},
+		{
+			name: "pencere en az 60 saniye",
+			rule: AlertRule{Name: "r", Type: RuleTypeLatencyP95, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 500, WindowSeconds: 30},
+			err:  "window_seconds",
+		},
+		{
+			name: "uptime eşiği 100'ü geçemez",
+			rule: AlertRule{Name: "r", Type: RuleTypeUptimeBelow, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 101, WindowSeconds: 300},
+			err:  "threshold",
+		},
+		{
+			name: "failure_count eşiği tam sayı olmalı",
+			rule: AlertRule{Name: "r", Type: RuleTypeFailureCount, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 2.5, SampleSize: 5},
+			err:  "tam sayı",
+		},
+		{
+			name: "sample_size eşikten küçük olamaz",
+			rule: AlertRule{Name: "r", Type: RuleTypeFailureCount, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 5, SampleSize: 3},
+			err:  "sample_size",
+		},
+		{
+			name: "sample_size üst sınırı",
+			rule: AlertRule{Name: "r", Type: RuleTypeFailureCount, Scope: RuleScopeCluster, Cluster: "prod", Threshold: 5, SampleSize: maxRuleSampleSize + 1},
+			err:  "sample_size",
+		},
+		{
+			name: "kapsam ve tür dışı alanlar temizlenir",
+			rule: AlertRule{Name: " kritik ", Type: RuleTypeFailureCount, Scope: RuleScopeService, ServiceID: &serviceID,
+				Namespace: "shop", Cluster: "prod", Threshold: 3, SampleSize: 5, WindowSeconds: 600},
+			check: func(t *testing.T, r AlertRule) {
+				if r.Name != "kritik" || r.Namespace != "" || r.Cluster != "" || r.WindowSeconds != 0 || r.Severity != SeverityWarning {
+					t.Errorf("beklenmeyen kural: %+v", r)
+				}
+			},
+		},
+		{
+			name: "cluster kapsamı servis ve namespace alanlarını temizler",
+			rule: AlertRule{Name: "r", Type: RuleTypeCertExpiry, Scope: RuleScopeCluster, ServiceID: &serviceID, Namespace: "shop",
+				Cluster: "prod", Threshold: 14, WindowSeconds: 600, SampleSize: 5, Severity: SeverityCritical},
+			check: func(t *testing.T, r AlertRule) {
+				if r.ServiceID != nil || r.Namespace != "" || r.WindowSeconds != 0 || r.SampleSize != 0 {
+					t.Errorf("beklenmeyen kural: %+v", r)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule := c.rule
+			err := rule.Validate()
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("%q içeren hata bekleniyordu, alınan %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("beklenmeyen hata: %v", err)
+			}
+			if c.check != nil {
+				c.check(t, rule)
+			}
+		})
+	}
+}
+
+func TestRuleAlertsAreSuppressedDuringOutage(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source) VALUES (1, 'api', 'shop', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source) VALUES (2, 'web', 'shop', 'prod', 'service', 'discovery')`)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	addChecks := func(serviceID int, status string, from time.Time) {
+		for i := 0; i < 3; i++ {
+			testDB.Exec(`INSERT INTO uptime_checks (service_id, status, response_time, timestamp) VALUES (?, ?, 10, ?)`,
+				serviceID, status, from.Add(time.Duration(i)*time.Minute))
+		}
+	}
+	addChecks(1, "down", base)
+	addChecks(2, "down", base)
+
+	rule := AlertRule{Name: "art arda hata", Type: RuleTypeFailureCount, Scope: RuleScopeCluster, Cluster: "prod",
+		Threshold: 2, SampleSize: 3, Severity: SeverityWarning, Enabled: true}
+	if err := saveAlertRule(testDB, &rule); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewAlertManager(testDB)
+	evaluator := NewAlertRuleEvaluator(testDB, manager)
+	ruleAlertOpen := func(serviceID int) bool {
+		alerts, _ := manager.List("a.dedup_key = ? AND a.status = ?", ruleAlertKey(rule.ID, serviceID), AlertStatusOpen)
+		return len(alerts) == 1
+	}
+
+	// Servis 1'in kesintisi durum alarmıyla bildirilmiş; aynı kesinti için kural alarmı açılmaz
+	manager.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "down", Timestamp: base.Add(2 * time.Minute)})
+	evaluator.EvaluateAll(base.Add(5 * time.Minute))
+	if ruleAlertOpen(1) {
+		t.Errorf("durum alarmı açıkken kural alarmı açılmamalıydı")
+	}
+	if !ruleAlertOpen(2) {
+		t.Errorf("durum alarmı olmayan servis için kural alarmı açılmalıydı")
+	}
+
+	// Önceden açılmış kural alarmı kesinti sırasında değişmeden kalır
+	manager.ProcessCheckResult(UptimeCheckResult{ServiceID: 2, Status: "down", Timestamp: base.Add(5 * time.Minute)})
+	addChecks(2, "up", base.Add(6*time.Minute))
+	evaluator.EvaluateAll(base.Add(10 * time.Minute))
+	if !ruleAlertOpen(2) {
+		t.Errorf("kesinti sürerken açık kural alarmı kapatılmamalıydı")
+	}
+
+	// Durum alarmı kapanınca kural yeniden değerlendirilir
+	manager.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "up", Timestamp: base.Add(10 * time.Minute)})
+	manager.ProcessCheckResult(UptimeCheckResult{ServiceID: 2, Status: "up", Timestamp: base.Add(10 * time.Minute)})
+	evaluator.EvaluateAll(base.Add(11 * time.Minute))
+	if !ruleAlertOpen(1) || ruleAlertOpen(2) {
+		t.Errorf("kesinti bitince kurallar yeniden değerlendirilmeliydi: servis 1 %v, servis 2 %v", ruleAlertOpen(1), ruleAlertOpen(2))
+	}
+}
@@ -0,0 +1,622 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alarm önem dereceleri
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Alarm durumları
+const (
+	AlertStatusOpen     = "open"
+	AlertStatusResolved = "resolved"
+)
+
+// Alarm olay türleri (bildirim kanallarına iletilir)
+const (
+	AlertEventFiring       = "firing"
+	AlertEventResolved     = "resolved"
+	AlertEventAcknowledged = "acknowledged"
+)
+
+// AlertEvent, alarmın açılması veya kapanması gibi bir durum değişikliği. Escalation,
+// olay bir eskalasyon seviyesi için gönderiliyorsa doludur.
+type AlertEvent struct {
+	Type       string
+	Alert      Alert
+	Escalation *AlertEscalation
+}
+
+// Alarm kapatma kaynakları
+const (
+	ResolvedByAuto   = "auto"
+	ResolvedByManual = "manual"
+)
+
+// Alert, bir servis için açılmış alarm
+type Alert struct {
+	ID          int64      `json:"id"`
+	ServiceID   int        `json:"service_id"`
+	ServiceName string     `json:"service_name"`
+	Namespace   string     `json:"namespace"`
+	Cluster     string     `json:"cluster"`
+	RuleID      *int64     `json:"rule_id,omitempty"`
+	DedupKey    string     `json:"dedup_key"`
+	Severity    string     `json:"severity"`
+	Status      string     `json:"status"`
+	Message     string     `json:"message"`
+	FirstSeen   time.Time  `json:"first_seen"`
+	LastSeen    time.Time  `json:"last_seen"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+	Occurrences int        `json:"occurrences"`
+
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	SnoozedUntil   *time.Time `json:"snoozed_until,omitempty"`
+	SnoozedBy      string     `json:"snoozed_by,omitempty"`
+	SilencedBy     *int64     `json:"silenced_by,omitempty"` // bildirimini bastıran sessize alma kuralı
+}
+
+// Duration, alarmın açık kaldığı süre (açıksa şu ana kadar)
+func (a Alert) Duration() time.Duration {
+	if a.ResolvedAt != nil {
+		return a.ResolvedAt.Sub(a.FirstSeen)
+	}
+	return time.Since(a.FirstSeen)
+}
+
+// MarshalJSON, alarmı süresiyle birlikte serileştirir
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alertJSON Alert
+	return json.Marshal(struct {
+		alertJSON
+		DurationSeconds int64 `json:"duration_seconds"`
+	}{alertJSON(a), int64(a.Duration().Seconds())})
+}
+
+// statusAlertKey, durum geçişi alarmlarının tekilleştirme anahtarı
+func statusAlertKey(serviceID int) string {
+	return fmt.Sprintf("status:%d", serviceID)
+}
+
+// alertColumns, alarm sorgularında kullanılan kolonlar (services ile birleştirilmiş)
+const alertColumns = `a.id, a.service_id, COALESCE(s.name, ''), COALESCE(s.namespace, ''), COALESCE(s.cluster, ''),
+	a.rule_id, a.dedup_key, a.severity, a.status, a.message, a.first_seen, a.last_seen,
+	a.resolved_at, a.resolved_by, a.occurrences, a.acknowledged_at, a.acknowledged_by,
+	a.snoozed_until, a.snoozed_by, a.silenced_by`
+
+// scanAlert, alertColumns sırasıyla okunan satırı Alert'e dönüştürür
+func scanAlert(scanner interface{ Scan(...interface{}) error }) (Alert, error) {
+	var a Alert
+	var ruleID, silencedBy sql.NullInt64
+	var message, resolvedBy, acknowledgedBy, snoozedBy sql.NullString
+	var resolvedAt, acknowledgedAt, snoozedUntil sql.NullTime
+	err := scanner.Scan(&a.ID, &a.ServiceID, &a.ServiceName, &a.Namespace, &a.Cluster,
+		&ruleID, &a.DedupKey, &a.Severity, &a.Status, &message, &a.FirstSeen, &a.LastSeen,
+		&resolvedAt, &resolvedBy, &a.Occurrences, &acknowledgedAt, &acknowledgedBy,
+		&snoozedUntil, &snoozedBy, &silencedBy)
+	if err != nil {
+		return a, err
+	}
+	if ruleID.Valid {
+		a.RuleID = &ruleID.Int64
+	}
+	if resolvedAt.Valid {
+		a.ResolvedAt = &resolvedAt.Time
+	}
+	if acknowledgedAt.Valid {
+		a.AcknowledgedAt = &acknowledgedAt.Time
+	}
+	if snoozedUntil.Valid {
+		a.SnoozedUntil = &snoozedUntil.Time
+	}
+	if silencedBy.Valid {
+		a.SilencedBy = &silencedBy.Int64
+	}
+	a.Message = message.String
+	a.ResolvedBy = resolvedBy.String
+	a.AcknowledgedBy = acknowledgedBy.String
+	a.SnoozedBy = snoozedBy.String
+	return a, nil
+}
+
+// AlertManager, kontrol sonuçlarından alarmları açar, günceller ve kapatır
+type AlertManager struct {
+	db *sql.DB
+
+	mu sync.Mutex
+	// manuallyResolved, kesinti sürerken elle kapatılan alarmların anahtarları;
+	// servis düzelene kadar yeni alarm açılmaz. alert_manual_resolutions tablosunun önbelleğidir.
+	manuallyResolved map[string]bool
+	// subscribers, alarm olaylarını alan dinleyiciler (ör. bildirim dağıtıcısı)
+	subscribers []func(AlertEvent)
+}
+
+// NewAlertManager, yeni bir alarm yöneticisi oluşturur ve elle kapatılmış alarm anahtarlarını yükler
+func NewAlertManager(db *sql.DB) *AlertManager {
+	m := &AlertManager{
+		db:               db,
+		manuallyResolved: make(map[string]bool),
+	}
+	if err := m.loadManualResolutions(); err != nil {
+		log.Printf("Elle kapatılan alarmlar okunamadı: %v", err)
+	}
+	return m
+}
+
+// loadManualResolutions, yeniden başlatma öncesinde elle kapatılmış alarm anahtarlarını okur
+func (m *AlertManager) loadManualResolutions() error {
+	rows, err := m.db.Query(`SELECT dedup_key FROM alert_manual_resolutions`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return err
+		}
+		m.manuallyResolved[key] = true
+	}
+	return rows.Err()
+}
+
+// alertManager, uptime monitor ve API tarafından kullanılan genel alarm yöneticisi
+var alertManager *AlertManager
+
+// Subscribe, alarm açıldığında ve kapandığında çağrılacak dinleyiciyi ekler.
+// Dinleyiciler kontrol döngüsünü bekletmemek için hızlı dönmelidir.
+func (m *AlertManager) Subscribe(fn func(AlertEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// publish, alarmın güncel halini okuyup olayı tüm dinleyicilere iletir
+func (m *AlertManager) publish(eventType string, id int64) {
+	m.mu.Lock()
+	subscribers := append([]func(AlertEvent){}, m.subscribers...)
+	m.mu.Unlock()
+	if len(subscribers) == 0 {
+		return
+	}
+
+	alert, err := m.Get(id)
+	if err != nil {
+		log.Printf("Alarm %d olayı için kayıt okunamadı: %v", id, err)
+		return
+	}
+	for _, fn := range subscribers {
+		fn(AlertEvent{Type: eventType, Alert: alert})
+	}
+}
+
+// ProcessCheckResult, tek bir kontrol sonucunu değerlendirir. Servis down olduğunda
+// açık alarm yoksa yenisi açılır, varsa güncellenir; servis düzeldiğinde alarm kendiliğinden kapanır.
+// Cluster erişilemezliği ve yapılandırma hataları servis alarmını değiştirmez.
+func (m *AlertManager) ProcessCheckResult(result UptimeCheckResult) {
+	key := statusAlertKey(result.ServiceID)
+
+	switch result.Status {
+	case "down":
+		if suppressed, marker := suppressedByRollout(m.db, result.ServiceID, result.Timestamp); suppressed {
+			log.Printf("Servis %d down, %s/%s rollout'u nedeniyle alarm bastırıldı: %s",
+				result.ServiceID, marker.Namespace, marker.Deployment, result.ErrorMessage)
+			return
+		}
+		message := result.ErrorMessage
+		if message == "" {
+			message = "Servis erişilemez durumda"
+		}
+		if err := m.Fire(key, result.ServiceID, nil, SeverityCritical, message, result.Timestamp); err != nil {
+			log.Printf("Servis %d için alarm kaydedilemedi: %v", result.ServiceID, err)
+		}
+	case "up", "warning", CheckStatusPaused:
+		if err := m.Clear(key, result.Timestamp); err != nil {
+			log.Printf("Servis %d alarmı kapatılamadı: %v", result.ServiceID, err)
+		}
+	}
+}
+
+// ClearUnmonitored, artık izlenmeyen (arşivlenen, izlemesi kapatılan veya endpoint'i kalmayan)
+// servislerin durum alarmlarını kapatır; bu servisler için kontrol sonucu gelmeyeceğinden
+// alarmlar kendiliğinden kapanmaz. Kural alarmları AlertRuleEvaluator.clearInactive ile kapanır.
+func (m *AlertManager) ClearUnmonitored(monitored map[int]bool, at time.Time) {
+	open, err := m.List("a.rule_id IS NULL AND a.status = ?", AlertStatusOpen)
+	if err != nil {
+		log.Printf("Açık durum alarmları okunamadı: %v", err)
+		return
+	}
+	keys := map[string]bool{}
+	for _, alert := range open {
+		if !monitored[alert.ServiceID] && alert.DedupKey == statusAlertKey(alert.ServiceID) {
+			keys[alert.DedupKey] = true
+		}
+	}
+	// Elle kapatılmış kesintiler de servis izlemeden çıkınca unutulur
+	m.mu.Lock()
+	for key := range m.manuallyResolved {
+		id, err := strconv.Atoi(strings.TrimPrefix(key, "status:"))
+		if err == nil && key == statusAlertKey(id) && !monitored[id] {
+			keys[key] = true
+		}
+	}
+	m.mu.Unlock()
+
+	for key := range keys {
+		if err := m.Clear(key, at); err != nil {
+			log.Printf("İzlenmeyen servis alarmı kapatılamadı (%s): %v", key, err)
+		}
+	}
+}
+
+// Fire, anahtar için açık alarm varsa son görülme zamanını ve mesajını günceller,
+// yoksa yeni alarm açar. Kesinti sırasında elle kapatılmış alarmlar yeniden açılmaz.
+func (m *AlertManager) Fire(key string, serviceID int, ruleID *int64, severity, message string, at time.Time) error {
+	result, err := m.db.Exec(`
+		UPDATE alerts SET last_seen = ?, message = ?, severity = ?, occurrences = occurrences + 1
+		WHERE dedup_key = ? AND status = ?
+	`, at, message, severity, key, AlertStatusOpen)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	skip := m.manuallyResolved[key]
+	m.mu.Unlock()
+	if skip {
+		return nil
+	}
+
+	var rule sql.NullInt64
+	if ruleID != nil {
+		rule = sql.NullInt64{Int64: *ruleID, Valid: true}
+	}
+	result, err = m.db.Exec(`
+		INSERT INTO alerts (service_id, rule_id, dedup_key, severity, status, message, first_seen, last_seen, occurrences)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1)
+	`, serviceID, rule, key, severity, AlertStatusOpen, message, at, at)
+	if err != nil {
+		return err
+	}
+	log.Printf("ALARM açıldı [%s] servis %d: %s", severity, serviceID, message)
+	if id, err := result.LastInsertId(); err == nil {
+		m.publish(AlertEventFiring, id)
+	}
+	return nil
+}
+
+// Clear, anahtarın açık alarmını kendiliğinden kapatır ve elle kapatma kaydını siler
+func (m *AlertManager) Clear(key string, at time.Time) error {
+	m.mu.Lock()
+	manual := m.manuallyResolved[key]
+	delete(m.manuallyResolved, key)
+	m.mu.Unlock()
+	if manual {
+		if _, err := m.db.Exec(`DELETE FROM alert_manual_resolutions WHERE dedup_key = ?`, key); err != nil {
+			return err
+		}
+	}
+
+	var id int64
+	err := m.db.QueryRow(`SELECT id FROM alerts WHERE dedup_key = ? AND status = ?`, key, AlertStatusOpen).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	result, err := m.db.Exec(`
+		UPDATE alerts SET status = ?, resolved_at = ?, resolved_by = ?
+		WHERE id = ? AND status = ?
+	`, AlertStatusResolved, at, ResolvedByAuto, id, AlertStatusOpen)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("ALARM kapandı: %s", key)
+		m.publish(AlertEventResolved, id)
+	}
+	return nil
+}
+
+// Resolve, alarmı elle kapatır. Kesinti sürüyorsa servis düzelene kadar yeni alarm açılmaz.
+func (m *AlertManager) Resolve(id int64) (Alert, error) {
+	alert, err := m.Get(id)
+	if err != nil {
+		return alert, err
+	}
+	if alert.Status != AlertStatusOpen {
+		return alert, fmt.Errorf("alarm zaten kapalı")
+	}
+
+	now := time.Now()
+	_, err = m.db.Exec(`
+		UPDATE alerts SET status = ?, resolved_at = ?, resolved_by = ? WHERE id = ? AND status = ?
+	`, AlertStatusResolved, now, ResolvedByManual, id, AlertStatusOpen)
+	if err != nil {
+		return alert, err
+	}
+
+	if _, err := m.db.Exec(`
+		INSERT INTO alert_manual_resolutions (dedup_key, resolved_at) VALUES (?, ?)
+		ON CONFLICT(dedup_key) DO UPDATE SET resolved_at = excluded.resolved_at
+	`, alert.DedupKey, now); err != nil {
+		log.Printf("Alarm %d elle kapatma kaydı yazılamadı: %v", id, err)
+	}
+	m.mu.Lock()
+	m.manuallyResolved[alert.DedupKey] = true
+	m.mu.Unlock()
+
+	log.Printf("ALARM elle kapatıldı: %d (%s)", id, alert.DedupKey)
+	m.publish(AlertEventResolved, id)
+	return m.Get(id)
+}
+
+// Acknowledge, açık alarmı kimin onayladığını kaydeder. Onaylanan alarmın eskalasyonu durur;
+// alarm servis düzelene veya elle kapatılana kadar açık kalır.
+func (m *AlertManager) Acknowledge(id int64, by string) (Alert, error) {
+	alert, err := m.Get(id)
+	if err != nil {
+		return alert, err
+	}
+	if alert.Status != AlertStatusOpen {
+		return alert, fmt.Errorf("alarm zaten kapalı")
+	}
+	if alert.AcknowledgedAt != nil {
+		return alert, fmt.Errorf("alarm zaten %s tarafından onaylandı", alert.AcknowledgedBy)
+	}
+
+	result, err := m.db.Exec(`
+		UPDATE alerts SET acknowledged_at = ?, acknowledged_by = ?
+		WHERE id = ? AND status = ? AND acknowledged_at IS NULL
+	`, time.Now(), by, id, AlertStatusOpen)
+	if err != nil {
+		return alert, err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("ALARM onaylandı: %d (%s) - %s", id, alert.DedupKey, by)
+		m.publish(AlertEventAcknowledged, id)
+	}
+	return m.Get(id)
+}
+
+// Snooze, açık alarmın eskalasyonunu until anına kadar erteler. Erteleme bittiğinde alarm
+// hâlâ açık ve onaylanmamışsa hatırlatma bildirimi gönderilir. Sıfır until ertelemeyi kaldırır.
+func (m *AlertManager) Snooze(id int64, until time.Time, by string) (Alert, error) {
+	alert, err := m.Get(id)
+	if err != nil {
+		return alert, err
+	}
+	if alert.Status != AlertStatusOpen {
+		return alert, fmt.Errorf("alarm zaten kapalı")
+	}
+
+	var snoozedUntil sql.NullTime
+	var snoozedBy sql.NullString
+	if !until.IsZero() {
+		if !until.After(time.Now()) {
+			return alert, fmt.Errorf("erteleme zamanı gelecekte olmalı")
+		}
+		snoozedUntil = sql.NullTime{Time: until.UTC(), Valid: true}
+		snoozedBy = sql.NullString{String: by, Valid: by != ""}
+	}
+	if _, err := m.db.Exec(`UPDATE alerts SET snoozed_until = ?, snoozed_by = ? WHERE id = ?`, snoozedUntil, snoozedBy, id); err != nil {
+		return alert, err
+	}
+	if until.IsZero() {
+		log.Printf("ALARM ertelemesi kaldırıldı: %d (%s)", id, alert.DedupKey)
+	} else {
+		log.Printf("ALARM ertelendi: %d (%s) %s tarihine kadar - %s", id, alert.DedupKey, until.Format(time.RFC3339), by)
+	}
+	return m.Get(id)
+}
+
+// Get, alarmı ID ile döndürür
+func (m *AlertManager) Get(id int64) (Alert, error) {
+	return getAlert(m.db, id)
+}
+
+// getAlert, alarmı servis bilgileriyle birlikte ID ile okur
+func getAlert(db *sql.DB, id int64) (Alert, error) {
+	row := db.QueryRow(`SELECT `+alertColumns+` FROM alerts a LEFT JOIN services s ON s.id = a.service_id WHERE a.id = ?`, id)
+	return scanAlert(row)
+}
+
+// List, koşula uyan alarmları en yeniden eskiye döndürür
+func (m *AlertManager) List(where string, args ...interface{}) ([]Alert, error) {
+	return listAlerts(m.db, where, args...)
+}
+
+// listAlerts, koşula uyan alarmları en yeniden eskiye döndürür
+func listAlerts(db *sql.DB, where string, args ...interface{}) ([]Alert, error) {
+	rows, err := db.Query(`SELECT `+alertColumns+` FROM alerts a LEFT JOIN services s ON s.id = a.service_id
+		WHERE `+where+` ORDER BY a.last_seen DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []Alert{}
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// alertsHandler, /api/v1/alerts altındaki endpoint'leri yönetir:
+//
+//	GET  /api/v1/alerts                                   açık alarmlar
+//	GET  /api/v1/alerts/service/{id}?includeResolved=true  servisin alarmları
+//	GET  /api/v1/alerts/{id}                              alarm detayı
+//	POST /api/v1/alerts/{id}/resolve                      alarmı elle kapatma
+//	POST /api/v1/alerts/{id}/acknowledge                  alarmı onaylama ({"by": "ayse"})
+//	POST   /api/v1/alerts/{id}/snooze                     alarmı erteleme ({"until": "...", "by": "ayse"} veya {"duration": "2h"})
+//	DELETE /api/v1/alerts/{id}/snooze                     ertelemeyi kaldırma
+func alertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/alerts"), "/")
+	segments := strings.Split(path, "/")
+
+	switch {
+	case path == "" && r.Method == "GET":
+		alerts, err := alertManager.List("a.status = ?", AlertStatusOpen)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarmlar alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"alerts": alerts, "count": len(alerts)})
+
+	case len(segments) == 2 && segments[0] == "service" && r.Method == "GET":
+		serviceID, err := strconv.Atoi(segments[1])
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz servis ID"}`, http.StatusBadRequest)
+			return
+		}
+		where, args := "a.service_id = ? AND a.status = ?", []interface{}{serviceID, AlertStatusOpen}
+		if r.URL.Query().Get("includeResolved") == "true" {
+			where, args = "a.service_id = ?", []interface{}{serviceID}
+		}
+		alerts, err := alertManager.List(where, args...)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarmlar alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"alerts": alerts, "count": len(alerts)})
+
+	case len(segments) == 1 && r.Method == "GET":
+		id, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz alarm ID"}`, http.StatusBadRequest)
+			return
+		}
+		alert, err := alertManager.Get(id)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Alarm bulunamadı"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"alert": alert})
+
+	case len(segments) == 2 && segments[1] == "resolve" && r.Method == "POST":
+		id, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz alarm ID"}`, http.StatusBadRequest)
+			return
+		}
+		alert, err := alertManager.Resolve(id)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Alarm bulunamadı"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alarm kapatıldı",
+			"alert":   alert,
+		})
+
+	case len(segments) == 2 && segments[1] == "acknowledge" && r.Method == "POST":
+		id, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz alarm ID"}`, http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			By string `json:"by"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.By) == "" {
+			http.Error(w, `{"error":"Onaylayan kişi (by) gerekli"}`, http.StatusBadRequest)
+			return
+		}
+		alert, err := alertManager.Acknowledge(id, strings.TrimSpace(req.By))
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Alarm bulunamadı"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Alarm onaylandı",
+			"alert":   alert,
+		})
+
+	case len(segments) == 2 && segments[1] == "snooze" && (r.Method == "POST" || r.Method == "DELETE"):
+		id, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz alarm ID"}`, http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Until    time.Time `json:"until"`
+			Duration string    `json:"duration"`
+			By       string    `json:"by"`
+		}
+		if r.Method == "POST" {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+				return
+			}
+			if req.Duration != "" {
+				d, err := time.ParseDuration(req.Duration)
+				if err != nil || d <= 0 {
+					http.Error(w, `{"error":"Geçersiz duration (ör. 30m, 2h)"}`, http.StatusBadRequest)
+					return
+				}
+				req.Until = time.Now().Add(d)
+			}
+			if req.Until.IsZero() {
+				http.Error(w, `{"error":"until veya duration gerekli"}`, http.StatusBadRequest)
+				return
+			}
+		}
+		alert, err := alertManager.Snooze(id, req.Until, strings.TrimSpace(req.By))
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Alarm bulunamadı"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusConflict)
+			return
+		}
+		message := "Alarm ertelendi"
+		if r.Method == "DELETE" {
+			message = "Alarm ertelemesi kaldırıldı"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": message,
+			"alert":   alert,
+		})
+
+	default:
+		http.Error(w, `{"error":"Bulunamadı"}`, http.StatusNotFound)
+	}
+}
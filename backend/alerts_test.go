@@ -0,0 +1,166 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// openStatusAlert, servisin açık durum alarmını döndürür; yoksa ok false olur
+func openStatusAlert(t *testing.T, m *AlertManager, serviceID int) (Alert, bool) {
+	t.Helper()
+	alerts, err := m.List("a.dedup_key = ? AND a.status = ?", statusAlertKey(serviceID), AlertStatusOpen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) == 0 {
+		return Alert{}, false
+	}
+	return alerts[0], true
+}
+
+func TestAlertLifecycleFireClearResolve(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source) VALUES (1, 'api', 'shop', 'prod', 'service', 'discovery')`)
+	m := NewAlertManager(testDB)
+
+	var events []string
+	m.Subscribe(func(e AlertEvent) { events = append(events, e.Type) })
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	down := func(at time.Time, message string) {
+		m.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "down", ErrorMessage: message, Timestamp: at})
+	}
+	up := func(at time.Time) {
+		m.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "up", Timestamp: at})
+	}
+
+	// Kesinti boyunca tek alarm açık kalır ve güncellenir
+	down(base, "bağlantı reddedildi")
+	down(base.Add(time.Minute), "zaman aşımı")
+	alert, ok := openStatusAlert(t, m, 1)
+	if !ok {
+		t.Fatalf("down sonucu alarm açmalıydı")
+	}
+	if alert.Occurrences != 2 || alert.Message != "zaman aşımı" || alert.Severity != SeverityCritical ||
+		!alert.FirstSeen.Equal(base) || !alert.LastSeen.Equal(base.Add(time.Minute)) || alert.ServiceName != "api" {
+		t.Errorf("beklenmeyen alarm: %+v", alert)
+	}
+
+	// Cluster erişilemezliği alarmı değiştirmez, servis düzelince alarm kendiliğinden kapanır
+	m.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "cluster_unreachable", Timestamp: base.Add(2 * time.Minute)})
+	if _, ok := openStatusAlert(t, m, 1); !ok {
+		t.Errorf("cluster erişilemezliği alarmı kapatmamalıydı")
+	}
+	up(base.Add(3 * time.Minute))
+	closed, _ := m.Get(alert.ID)
+	if closed.Status != AlertStatusResolved || closed.ResolvedBy != ResolvedByAuto || !closed.ResolvedAt.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("alarm kendiliğinden kapanmalıydı: %+v", closed)
+	}
+
+	// Elle kapatılan alarm kesinti sürdükçe yeniden açılmaz
+	down(base.Add(4*time.Minute), "")
+	alert, _ = openStatusAlert(t, m, 1)
+	if _, err := m.Resolve(alert.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resolve(alert.ID); err == nil {
+		t.Errorf("kapalı alarm yeniden kapatılamamalı")
+	}
+	down(base.Add(5*time.Minute), "")
+	if _, ok := openStatusAlert(t, m, 1); ok {
+		t.Errorf("elle kapatılan kesinti yeni alarm açmamalıydı")
+	}
+
+	// Servis düzeldikten sonraki yeni kesinti alarm açar
+	up(base.Add(6 * time.Minute))
+	down(base.Add(7*time.Minute), "")
+	if _, ok := openStatusAlert(t, m, 1); !ok {
+		t.Errorf("düzelmeden sonraki kesinti alarm açmalıydı")
+	}
+
+	want := []string{AlertEventFiring, AlertEventResolved, AlertEventFiring, AlertEventResolved, AlertEventFiring}
+	if len(events) != len(want) {
+		t.Fatalf("beklenen olaylar %v, alınan %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("beklenen olaylar %v, alınan %v", want, events)
+			break
+		}
+	}
+}
+
+func TestManualResolutionSurvivesRestart(t *testing.T) {
+	testDB := newTestDB(t)
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := NewAlertManager(testDB)
+	m.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "down", Timestamp: base})
+	alert, _ := openStatusAlert(t, m, 1)
+	if _, err := m.Resolve(alert.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Yeniden başlatılan yönetici elle kapatmayı veritabanından okur
+	restarted := NewAlertManager(testDB)
+	restarted.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "down", Timestamp: base.Add(time.Minute)})
+	if _, ok := openStatusAlert(t, restarted, 1); ok {
+		t.Errorf("yeniden başlatma sonrası elle kapatılan kesinti alarm açmamalıydı")
+	}
+
+	restarted.ProcessCheckResult(UptimeCheckResult{ServiceID: 1, Status: "up", Timestamp: base.Add(2 * time.Minute)})
+	var count int
+	testDB.QueryRow(`SELECT COUNT(*) FROM alert_manual_resolutions`).Scan(&count)
+	if count != 0 {
+		t.Errorf("servis düzelince elle kapatma kaydı silinmeliydi")
+	}
+	if again := NewAlertManager(testDB); len(again.manuallyResolved) != 0 {
+		t.Errorf("silinen kayıt yeniden yüklenmemeli: %v", again.manuallyResolved)
+	}
+}
+
+func TestUptimeReloadClearsAlertsOfUnmonitoredServices(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source, archived_at) VALUES (1, 'old', 'shop', 'prod', 'service', 'discovery', CURRENT_TIMESTAMP)`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source, endpoint, monitoring_enabled) VALUES (2, 'batch', 'shop', 'prod', 'service', 'discovery', 'http://batch', 0)`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source) VALUES (3, 'worker', 'shop', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, source, endpoint, check_interval) VALUES (4, 'api', 'shop', 'prod', 'service', 'discovery', 'http://127.0.0.1:1', 3600)`)
+
+	previous := alertManager
+	alertManager = NewAlertManager(testDB)
+	t.Cleanup(func() { alertManager = previous })
+
+	base := time.Now().Add(-time.Hour)
+	for id := 1; id <= 4; id++ {
+		alertManager.ProcessCheckResult(UptimeCheckResult{ServiceID: id, Status: "down", Timestamp: base})
+	}
+	// Kural alarmları kendi değerlendiricisine aittir
+	ruleID := int64(7)
+	alertManager.Fire(ruleAlertKey(ruleID, 1), 1, &ruleID, SeverityWarning, "kural", base)
+	// Elle kapatılmış kesinti de servis izlemeden çıkınca unutulur
+	alert, _ := openStatusAlert(t, alertManager, 3)
+	alertManager.Resolve(alert.ID)
+
+	monitor := NewUptimeMonitor(testDB)
+	if err := monitor.StartUptimeMonitoring(); err != nil {
+		t.Fatal(err)
+	}
+	monitor.StopUptimeMonitoring()
+
+	for id, open := range map[int]bool{1: false, 2: false, 3: false, 4: true} {
+		if _, ok := openStatusAlert(t, alertManager, id); ok != open {
+			t.Errorf("servis %d: açık alarm beklenen %v, alınan %v", id, open, ok)
+		}
+	}
+	if alerts, _ := alertManager.List("a.rule_id = ? AND a.status = ?", ruleID, AlertStatusOpen); len(alerts) != 1 {
+		t.Errorf("kural alarmı durum alarmlarıyla kapanmamalıydı")
+	}
+	var resolvedBy sql.NullString
+	testDB.QueryRow(`SELECT resolved_by FROM alerts WHERE dedup_key = ?`, statusAlertKey(1)).Scan(&resolvedBy)
+	if resolvedBy.String != ResolvedByAuto {
+		t.Errorf("izlemeden çıkan servisin alarmı kendiliğinden kapanmalıydı: %q", resolvedBy.String)
+	}
+	if alertManager.manuallyResolved[statusAlertKey(3)] {
+		t.Errorf("izlenmeyen servisin elle kapatma kaydı silinmeliydi")
+	}
+}
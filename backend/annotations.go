@@ -0,0 +1,423 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// monitoringAnnotationPrefix, izleme yapılandırması annotation'larının ön eki
+const monitoringAnnotationPrefix = "k8s-monitoring/"
+
+// Annotation ile yönetilebilen alanlar (API'de ve ui_overrides içinde bu adlarla geçer)
+const (
+	AnnotationFieldEnabled        = "enabled"
+	AnnotationFieldEndpoint       = "endpoint"
+	AnnotationFieldCheckInterval  = "check_interval"
+	AnnotationFieldExpectedStatus = "expected_status"
+	AnnotationFieldCheckType      = "check_type"
+	AnnotationFieldOwner          = "owner"
+)
+
+// MonitoringAnnotations, Service ve Ingress nesnelerindeki k8s-monitoring/* annotation'ları
+type MonitoringAnnotations struct {
+	Enabled        string `json:"enabled,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Port           string `json:"port,omitempty"`
+	Interval       string `json:"interval,omitempty"`
+	ExpectedStatus string `json:"expected_status,omitempty"`
+	CheckType      string `json:"check_type,omitempty"`
+	Owner          string `json:"owner,omitempty"`
+	Lock           bool   `json:"lock,omitempty"` // true ise annotation değerleri arayüzden yapılan değişikliklerin önüne geçer
+}
+
+// parseMonitoringAnnotations, nesne annotation'larından izleme yapılandırmasını okur
+func parseMonitoringAnnotations(annotations map[string]string) MonitoringAnnotations {
+	get := func(key string) string {
+		return strings.TrimSpace(annotations[monitoringAnnotationPrefix+key])
+	}
+	return MonitoringAnnotations{
+		Enabled:        get("enabled"),
+		Path:           get("path"),
+		Port:           get("port"),
+		Interval:       get("interval"),
+		ExpectedStatus: get("expected-status"),
+		CheckType:      strings.ToLower(get("check-type")),
+		Owner:          get("owner"),
+		Lock:           get("lock") == "true",
+	}
+}
+
+// merge, boş alanları verilen annotation'lardan doldurur (alıcıdaki değerler önceliklidir)
+func (a MonitoringAnnotations) merge(other MonitoringAnnotations) MonitoringAnnotations {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&a.Enabled, other.Enabled)
+	fill(&a.Path, other.Path)
+	fill(&a.Port, other.Port)
+	fill(&a.Interval, other.Interval)
+	fill(&a.ExpectedStatus, other.ExpectedStatus)
+	fill(&a.CheckType, other.CheckType)
+	fill(&a.Owner, other.Owner)
+	a.Lock = a.Lock || other.Lock
+	return a
+}
+
+// serviceMonitoringAnnotations, servisin kendi annotation'larını ve servise yönlendiren
+// Ingress'lerin annotation'larını birleştirir. Servis üzerindeki değerler önceliklidir.
+func serviceMonitoringAnnotations(svc *corev1.Service, ingresses []*networkingv1.Ingress) MonitoringAnnotations {
+	result := parseMonitoringAnnotations(svc.Annotations)
+
+	sorted := append([]*networkingv1.Ingress(nil), ingresses...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for _, ing := range sorted {
+		if ing.Namespace == svc.Namespace && containsString(ingressBackendServices(ing), svc.Name) {
+			result = result.merge(parseMonitoringAnnotations(ing.Annotations))
+		}
+	}
+	return result
+}
+
+// annotationEndpoint, path/port/check-type annotation'larından kontrol adresini oluşturur.
+// Annotation yoksa boş döner ve türetilmiş adaylar kullanılır.
+func annotationEndpoint(svc *corev1.Service, ann MonitoringAnnotations, candidates []DerivedEndpoint) string {
+	if ann.Path == "" && ann.Port == "" && ann.CheckType == "" {
+		return ""
+	}
+
+	port := ann.Port
+	if port == "" && len(svc.Spec.Ports) > 0 {
+		port = strconv.Itoa(int(svc.Spec.Ports[0].Port))
+	}
+	host := svc.Spec.ClusterIP
+	if host == "" || host == corev1.ClusterIPNone {
+		// Headless servisler için cluster içi DNS adı
+		host = fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+	}
+
+	switch ann.CheckType {
+	case "tcp":
+		if port == "" {
+			return ""
+		}
+		return host + ":" + port
+	case "dns":
+		return fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, svc.Namespace)
+	}
+
+	// HTTP(S): port belirtilmemişse dış adresi (Route/Ingress) yeni path ile kullan
+	if ann.Port == "" {
+		for _, c := range candidates {
+			if c.Source == EndpointSourceRoute || c.Source == EndpointSourceIngress {
+				host := strings.SplitN(strings.SplitN(c.Endpoint, "://", 2)[1], "/", 2)[0]
+				return endpointURL(host, ann.Path, c.TLS || ann.CheckType == "https" || ann.CheckType == "certificate")
+			}
+		}
+	}
+	if port == "" {
+		return ""
+	}
+	return endpointURL(host+":"+port, ann.Path, ann.CheckType == "https" || ann.CheckType == "certificate")
+}
+
+// annotationFields, annotation'lardan servis satırına yazılacak alan değerlerini döndürür
+func annotationFields(ann MonitoringAnnotations, endpoint string) map[string]string {
+	fields := map[string]string{}
+	if ann.Enabled == "true" || ann.Enabled == "false" {
+		fields[AnnotationFieldEnabled] = ann.Enabled
+	}
+	if endpoint != "" {
+		fields[AnnotationFieldEndpoint] = endpoint
+	}
+	if seconds, err := strconv.Atoi(ann.Interval); err == nil && seconds > 0 {
+		fields[AnnotationFieldCheckInterval] = ann.Interval
+	}
+	if code, err := strconv.Atoi(ann.ExpectedStatus); err == nil && code >= 100 && code <= 599 {
+		fields[AnnotationFieldExpectedStatus] = ann.ExpectedStatus
+	}
+	switch UptimeCheckType(ann.CheckType) {
+	case CheckTypeHTTP, CheckTypeTCP, CheckTypeDNS, CheckTypeCertificate:
+		fields[AnnotationFieldCheckType] = ann.CheckType
+	case "https":
+		fields[AnnotationFieldCheckType] = string(CheckTypeHTTP)
+	}
+	if ann.Owner != "" {
+		fields[AnnotationFieldOwner] = ann.Owner
+	}
+	return fields
+}
+
+// serviceAnnotationState, servis satırındaki annotation durumunu temsil eder
+type serviceAnnotationState struct {
+	Fields      map[string]string // annotation'dan gelen alanlar ve değerleri
+	Locked      bool
+	UIOverrides map[string]bool // arayüzden değiştirilmiş alanlar
+}
+
+// loadAnnotationState, servisin annotation durumunu okur
+func loadAnnotationState(db *sql.DB, where string, args ...interface{}) (serviceAnnotationState, error) {
+	var fields, overrides sql.NullString
+	var locked sql.NullBool
+	err := db.QueryRow("SELECT annotation_fields, annotation_lock, ui_overrides FROM services WHERE "+where, args...).
+		Scan(&fields, &locked, &overrides)
+	return decodeAnnotationState(fields, locked, overrides), err
+}
+
+// decodeAnnotationState, veritabanı kolonlarından annotation durumunu çözer
+func decodeAnnotationState(fields sql.NullString, locked sql.NullBool, overrides sql.NullString) serviceAnnotationState {
+	state := serviceAnnotationState{
+		Fields:      map[string]string{},
+		Locked:      locked.Bool,
+		UIOverrides: map[string]bool{},
+	}
+	if fields.Valid {
+		json.Unmarshal([]byte(fields.String), &state.Fields)
+	}
+	if overrides.Valid {
+		var list []string
+		json.Unmarshal([]byte(overrides.String), &list)
+		for _, f := range list {
+			state.UIOverrides[f] = true
+		}
+	}
+	return state
+}
+
+// applies, annotation değerinin alana yazılıp yazılmayacağını döndürür.
+// Kilitli servislerde annotation her zaman kazanır; değilse arayüz değişikliği korunur.
+func (s serviceAnnotationState) applies(field string) bool {
+	_, ok := s.Fields[field]
+	return ok && (s.Locked || !s.UIOverrides[field])
+}
+
+// autoEndpoints, namespace politikasını enabled annotation'ı ile birlikte değerlendirir
+func (s serviceAnnotationState) autoEndpoints(policy bool) bool {
+	switch s.Fields[AnnotationFieldEnabled] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return policy
+}
+
+// applyMonitoringAnnotations, annotation alanlarını servis satırına yazar.
+// Daha önce annotation'dan gelip artık tanımlı olmayan enabled değeri sıfırlanır.
+func applyMonitoringAnnotations(db *sql.DB, cluster, namespace, name string, ann MonitoringAnnotations, fields map[string]string) (serviceAnnotationState, error) {
+	previous, err := loadAnnotationState(db, "name = ? AND namespace = ? AND cluster = ?", name, namespace, cluster)
+	if err != nil {
+		return previous, err
+	}
+
+	state := serviceAnnotationState{Fields: fields, Locked: ann.Lock, UIOverrides: previous.UIOverrides}
+	data, _ := json.Marshal(fields)
+
+	sets := []string{"annotation_fields = ?", "annotation_lock = ?"}
+	args := []interface{}{string(data), ann.Lock}
+
+	if state.applies(AnnotationFieldEnabled) {
+		sets = append(sets, "monitoring_enabled = ?")
+		args = append(args, fields[AnnotationFieldEnabled] == "true")
+	} else if _, had := previous.Fields[AnnotationFieldEnabled]; had {
+		if _, has := fields[AnnotationFieldEnabled]; !has {
+			sets = append(sets, "monitoring_enabled = NULL")
+		}
+	}
+	if state.applies(AnnotationFieldCheckInterval) {
+		sets = append(sets, "check_interval = ?")
+		args = append(args, fields[AnnotationFieldCheckInterval])
+	}
+	if state.applies(AnnotationFieldExpectedStatus) {
+		sets = append(sets, "expected_status = ?")
+		args = append(args, fields[AnnotationFieldExpectedStatus])
+	}
+	if state.applies(AnnotationFieldCheckType) {
+		sets = append(sets, "check_type = ?")
+		args = append(args, fields[AnnotationFieldCheckType])
+	}
+	if state.applies(AnnotationFieldOwner) {
+		sets = append(sets, "owner = ?")
+		args = append(args, fields[AnnotationFieldOwner])
+	}
+
+	args = append(args, name, namespace, cluster)
+	_, err = db.Exec("UPDATE services SET "+strings.Join(sets, ", ")+" WHERE name = ? AND namespace = ? AND cluster = ?", args...)
+	return state, err
+}
+
+// recordUIOverrides, arayüzden değiştirilen alanları kaydeder; bu alanlar kilit yoksa
+// sonraki senkronizasyonlarda annotation değeriyle ezilmez
+func recordUIOverrides(db *sql.DB, serviceID int, state serviceAnnotationState, changed []string) error {
+	if len(changed) == 0 {
+		return nil
+	}
+	for _, field := range changed {
+		state.UIOverrides[field] = true
+	}
+	list := make([]string, 0, len(state.UIOverrides))
+	for field := range state.UIOverrides {
+		list = append(list, field)
+	}
+	sort.Strings(list)
+
+	data, _ := json.Marshal(list)
+	_, err := db.Exec("UPDATE services SET ui_overrides = ? WHERE id = ?", string(data), serviceID)
+	return err
+}
+
+// addAnnotationInfo, servis yanıtına annotation'dan gelen alanları ve kilit durumunu ekler
+func addAnnotationInfo(out map[string]interface{}, state serviceAnnotationState) {
+	sourced := []string{}
+	for field := range state.Fields {
+		if state.applies(field) {
+			sourced = append(sourced, field)
+		}
+	}
+	sort.Strings(sourced)
+
+	out["annotation_fields"] = state.Fields
+	out["annotation_sourced_fields"] = sourced
+	out["annotation_locked"] = state.Locked
+}
+
+// serviceMonitoringColumns, servis sorgularına eklenen izleme ve annotation kolonları
+const serviceMonitoringColumns = "owner, check_type, expected_status, monitoring_enabled, annotation_fields, annotation_lock, ui_overrides, monitor_ref"
+
+// serviceMonitoringRow, serviceMonitoringColumns kolonlarının okunduğu alanlar
+type serviceMonitoringRow struct {
+	Owner, CheckType  sql.NullString
+	ExpectedStatus    sql.NullInt64
+	MonitoringEnabled sql.NullBool
+	Fields            sql.NullString
+	Locked            sql.NullBool
+	Overrides         sql.NullString
+	MonitorRef        sql.NullString
+}
+
+// dest, Scan için hedef alanları kolon sırasıyla döndürür
+func (m *serviceMonitoringRow) dest() []interface{} {
+	return []interface{}{&m.Owner, &m.CheckType, &m.ExpectedStatus, &m.MonitoringEnabled, &m.Fields, &m.Locked, &m.Overrides, &m.MonitorRef}
+}
+
+// View, izleme alanlarını ve annotation bilgisini servis yanıtına ekler
+func (m serviceMonitoringRow) View(out map[string]interface{}) {
+	out["owner"] = m.Owner.String
+	out["check_type"] = m.CheckType.String
+	out["expected_status"] = m.ExpectedStatus.Int64
+	out["monitoring_enabled"] = !m.MonitoringEnabled.Valid || m.MonitoringEnabled.Bool
+	out["monitor_ref"] = m.MonitorRef.String
+	addAnnotationInfo(out, decodeAnnotationState(m.Fields, m.Locked, m.Overrides))
+}
+
+// MonitoringInput, servis oluşturma/güncelleme isteklerindeki isteğe bağlı izleme alanları.
+// nil alanlar değiştirilmez.
+type MonitoringInput struct {
+	MonitoringEnabled *bool   `json:"monitoring_enabled"`
+	ExpectedStatus    *int    `json:"expected_status"`
+	CheckType         *string `json:"check_type"`
+	Owner             *string `json:"owner"`
+}
+
+// Validate, izleme alanlarını doğrular
+func (in MonitoringInput) Validate() error {
+	if in.ExpectedStatus != nil && *in.ExpectedStatus != 0 && (*in.ExpectedStatus < 100 || *in.ExpectedStatus > 599) {
+		return fmt.Errorf("expected_status 100-599 aralığında olmalıdır")
+	}
+	if in.CheckType != nil {
+		switch UptimeCheckType(*in.CheckType) {
+		case "", CheckTypeHTTP, CheckTypeTCP, CheckTypeDNS, CheckTypeCertificate:
+		default:
+			return fmt.Errorf("geçersiz check_type: %s", *in.CheckType)
+		}
+	}
+	return nil
+}
+
+// saveMonitoringInput, gönderilen izleme alanlarını kaydeder
+func saveMonitoringInput(db *sql.DB, serviceID int64, in MonitoringInput) error {
+	var enabled, expected, checkType, owner interface{}
+	if in.MonitoringEnabled != nil {
+		enabled = *in.MonitoringEnabled
+	}
+	if in.ExpectedStatus != nil {
+		expected = *in.ExpectedStatus
+	}
+	if in.CheckType != nil {
+		checkType = *in.CheckType
+	}
+	if in.Owner != nil {
+		owner = *in.Owner
+	}
+	_, err := db.Exec(`
+		UPDATE services SET
+		monitoring_enabled = COALESCE(?, monitoring_enabled),
+		expected_status = COALESCE(?, expected_status),
+		check_type = COALESCE(?, check_type),
+		owner = COALESCE(?, owner)
+		WHERE id = ?
+	`, enabled, expected, checkType, owner, serviceID)
+	return err
+}
+
+// enforceAnnotationLocks, güncelleme isteğini annotation durumuna göre düzenler.
+// Kilitli servislerde annotation'dan gelen alanlar istekteki değerle değiştirilmez
+// (lockedFields); diğer alanlardaki değişiklikler ui_overrides olarak kaydedilmek
+// üzere changedFields içinde döner.
+func enforceAnnotationLocks(db *sql.DB, serviceID int, endpoint *string, checkInterval *int, in *MonitoringInput) (state serviceAnnotationState, changedFields, lockedFields []string, err error) {
+	var current serviceMonitoringRow
+	var currentEndpoint sql.NullString
+	var currentInterval int
+	dest := append([]interface{}{&currentEndpoint, &currentInterval}, current.dest()...)
+	err = db.QueryRow("SELECT endpoint, COALESCE(check_interval, 60), "+serviceMonitoringColumns+" FROM services WHERE id = ?", serviceID).Scan(dest...)
+	if err != nil {
+		return
+	}
+	state = decodeAnnotationState(current.Fields, current.Locked, current.Overrides)
+
+	// check, tek bir alan için kilidi uygular; istek değeri kilitliyse annotation değeriyle değiştirilir
+	check := func(field, requested, existing string, set func(string)) {
+		if requested == existing {
+			return
+		}
+		if state.Locked && state.applies(field) {
+			set(state.Fields[field])
+			lockedFields = append(lockedFields, field)
+			return
+		}
+		changedFields = append(changedFields, field)
+	}
+
+	check(AnnotationFieldEndpoint, *endpoint, currentEndpoint.String, func(v string) { *endpoint = v })
+	check(AnnotationFieldCheckInterval, strconv.Itoa(*checkInterval), strconv.Itoa(currentInterval), func(v string) {
+		*checkInterval, _ = strconv.Atoi(v)
+	})
+	if in.MonitoringEnabled != nil {
+		existing := strconv.FormatBool(!current.MonitoringEnabled.Valid || current.MonitoringEnabled.Bool)
+		check(AnnotationFieldEnabled, strconv.FormatBool(*in.MonitoringEnabled), existing, func(v string) {
+			enabled := v == "true"
+			in.MonitoringEnabled = &enabled
+		})
+	}
+	if in.ExpectedStatus != nil {
+		check(AnnotationFieldExpectedStatus, strconv.Itoa(*in.ExpectedStatus), strconv.FormatInt(current.ExpectedStatus.Int64, 10), func(v string) {
+			code, _ := strconv.Atoi(v)
+			in.ExpectedStatus = &code
+		})
+	}
+	if in.CheckType != nil {
+		check(AnnotationFieldCheckType, *in.CheckType, current.CheckType.String, func(v string) { in.CheckType = &v })
+	}
+	if in.Owner != nil {
+		check(AnnotationFieldOwner, *in.Owner, current.Owner.String, func(v string) { in.Owner = &v })
+	}
+	return
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseAndMergeMonitoringAnnotations(t *testing.T) {
+	ann := parseMonitoringAnnotations(map[string]string{
+		"k8s-monitoring/enabled":         " true ",
+		"k8s-monitoring/path":            "/healthz",
+		"k8s-monitoring/check-type":      "HTTPS",
+		"k8s-monitoring/expected-status": "204",
+		"k8s-monitoring/lock":            "yes",
+		"prometheus.io/port":             "9090",
+	})
+	want := MonitoringAnnotations{Enabled: "true", Path: "/healthz", CheckType: "https", ExpectedStatus: "204"}
+	if ann != want {
+		t.Errorf("beklenen %+v, alınan %+v", want, ann)
+	}
+	if parseMonitoringAnnotations(nil) != (MonitoringAnnotations{}) {
+		t.Errorf("annotation'sız nesne boş yapılandırma vermeli")
+	}
+
+	// Servis değerleri önceliklidir, boş alanlar ve kilit Ingress'ten gelir
+	svc := newService("shop", "api", nil)
+	svc.Annotations = map[string]string{"k8s-monitoring/path": "/ready", "k8s-monitoring/owner": "team-a"}
+	ingress := newIngress("shop", "public", nil, ingressRule("shop.example.com", map[string]string{"/": "api"}))
+	ingress.Annotations = map[string]string{
+		"k8s-monitoring/path":     "/ingress",
+		"k8s-monitoring/interval": "15",
+		"k8s-monitoring/lock":     "true",
+	}
+	other := newIngress("shop", "other", nil, ingressRule("web.example.com", map[string]string{"/": "web"}))
+	other.Annotations = map[string]string{"k8s-monitoring/owner": "team-web"}
+
+	merged := serviceMonitoringAnnotations(svc, []*networkingv1.Ingress{other, ingress})
+	want = MonitoringAnnotations{Path: "/ready", Owner: "team-a", Interval: "15", Lock: true}
+	if merged != want {
+		t.Errorf("beklenen %+v, alınan %+v", want, merged)
+	}
+}
+
+func TestAnnotationEndpoint(t *testing.T) {
+	svc := newService("shop", "api", nil)
+	headless := newService("shop", "db", nil)
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+	headless.Spec.Ports = []corev1.ServicePort{{Port: 5432}}
+	noPorts := newService("shop", "worker", nil)
+	noPorts.Spec.Ports = nil
+
+	external := []DerivedEndpoint{
+		{Endpoint: "https://api.example.com/", Source: EndpointSourceIngress, TLS: true},
+		{Endpoint: "10.0.0.1:80", Source: EndpointSourceClusterIP},
+	}
+
+	cases := []struct {
+		name       string
+		svc        *corev1.Service
+		ann        MonitoringAnnotations
+		candidates []DerivedEndpoint
+		want       string
+	}{
+		{"annotation yok", svc, MonitoringAnnotations{Owner: "team-a"}, external, ""},
+		{"path dış adresi kullanır", svc, MonitoringAnnotations{Path: "/healthz"}, external, "https://api.example.com/healthz"},
+		{"dış adres yoksa ClusterIP", svc, MonitoringAnnotations{Path: "healthz"}, external[1:], "http://10.0.0.1:80/healthz"},
+		{"port belirtilirse cluster içi", svc, MonitoringAnnotations{Path: "/metrics", Port: "9090"}, external, "http://10.0.0.1:9090/metrics"},
+		{"https", svc, MonitoringAnnotations{Port: "8443", CheckType: "https"}, nil, "https://10.0.0.1:8443/"},
+		{"tcp", svc, MonitoringAnnotations{CheckType: "tcp"}, external, "10.0.0.1:80"},
+		{"headless tcp", headless, MonitoringAnnotations{CheckType: "tcp"}, nil, "db.shop.svc:5432"},
+		{"portsuz tcp", noPorts, MonitoringAnnotations{CheckType: "tcp"}, nil, ""},
+		{"portsuz http", noPorts, MonitoringAnnotations{Path: "/healthz"}, nil, ""},
+		{"dns", svc, MonitoringAnnotations{CheckType: "dns"}, external, "api.shop.svc.cluster.local"},
+	}
+	for _, c := range cases {
+		if got := annotationEndpoint(c.svc, c.ann, c.candidates); got != c.want {
+			t.Errorf("%s: beklenen %q, alınan %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestAnnotationFields(t *testing.T) {
+	fields := annotationFields(MonitoringAnnotations{
+		Enabled:        "false",
+		Interval:       "30",
+		ExpectedStatus: "204",
+		CheckType:      "https",
+		Owner:          "team-a",
+	}, "https://api.example.com/healthz")
+	want := map[string]string{
+		AnnotationFieldEnabled:        "false",
+		AnnotationFieldEndpoint:       "https://api.example.com/healthz",
+		AnnotationFieldCheckInterval:  "30",
+		AnnotationFieldExpectedStatus: "204",
+		AnnotationFieldCheckType:      "http",
+		AnnotationFieldOwner:          "team-a",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("beklenen %v, alınan %v", want, fields)
+	}
+
+	// Geçersiz değerler alana dönüşmez
+	invalid := annotationFields(MonitoringAnnotations{
+		Enabled:        "evet",
+		Interval:       "-5",
+		ExpectedStatus: "700",
+		CheckType:      "grpc",
+	}, "")
+	if len(invalid) != 0 {
+		t.Errorf("geçersiz annotation'lar yok sayılmalıydı: %v", invalid)
+	}
+}
+
+func TestAnnotationStateApplies(t *testing.T) {
+	state := serviceAnnotationState{
+		Fields:      map[string]string{AnnotationFieldOwner: "team-a", AnnotationFieldCheckInterval: "30"},
+		UIOverrides: map[string]bool{AnnotationFieldOwner: true},
+	}
+	if state.applies(AnnotationFieldOwner) || !state.applies(AnnotationFieldCheckInterval) || state.applies(AnnotationFieldEndpoint) {
+		t.Errorf("kilitsiz serviste arayüz değişikliği korunmalıydı")
+	}
+	state.Locked = true
+	if !state.applies(AnnotationFieldOwner) || state.applies(AnnotationFieldEndpoint) {
+		t.Errorf("kilitli serviste annotation alanları her zaman uygulanmalıydı")
+	}
+
+	for policy, want := range map[string]bool{"true": true, "false": false, "": true} {
+		s := serviceAnnotationState{Fields: map[string]string{}}
+		if policy != "" {
+			s.Fields[AnnotationFieldEnabled] = policy
+		}
+		if s.autoEndpoints(true) != want {
+			t.Errorf("enabled=%q: beklenen %v", policy, want)
+		}
+	}
+}
+
+func TestEnforceAnnotationLocks(t *testing.T) {
+	testDB := newTestDB(t)
+	result, err := testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, endpoint, check_interval, owner, annotation_fields, annotation_lock)
+		VALUES ('api', 'shop', 'prod', 'service', 'discovery', 'https://api.example.com/healthz', 30, 'team-a', ?, ?)`,
+		`{"endpoint":"https://api.example.com/healthz","check_interval":"30","owner":"team-a"}`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+
+	endpoint, interval := "https://elle.example.com", 60
+	owner, checkType := "team-b", "tcp"
+	in := MonitoringInput{Owner: &owner, CheckType: &checkType}
+	_, changed, locked, err := enforceAnnotationLocks(testDB, int(id), &endpoint, &interval, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint != "https://api.example.com/healthz" || interval != 30 || *in.Owner != "team-a" {
+		t.Errorf("kilitli alanlar annotation değerine dönmeliydi: %s %d %s", endpoint, interval, *in.Owner)
+	}
+	if !reflect.DeepEqual(locked, []string{AnnotationFieldEndpoint, AnnotationFieldCheckInterval, AnnotationFieldOwner}) ||
+		!reflect.DeepEqual(changed, []string{AnnotationFieldCheckType}) || *in.CheckType != "tcp" {
+		t.Errorf("beklenmeyen alanlar: kilitli=%v değişen=%v", locked, changed)
+	}
+
+	// Kilit kalkınca aynı değişiklikler arayüz değişikliği olarak kaydedilir
+	testDB.Exec(`UPDATE services SET annotation_lock = 0 WHERE id = ?`, id)
+	endpoint, interval, owner = "https://elle.example.com", 60, "team-b"
+	in = MonitoringInput{Owner: &owner}
+	_, changed, locked, _ = enforceAnnotationLocks(testDB, int(id), &endpoint, &interval, &in)
+	if len(locked) != 0 || endpoint != "https://elle.example.com" ||
+		!reflect.DeepEqual(changed, []string{AnnotationFieldEndpoint, AnnotationFieldCheckInterval, AnnotationFieldOwner}) {
+		t.Errorf("kilitsiz serviste değişiklikler uygulanmalıydı: kilitli=%v değişen=%v", locked, changed)
+	}
+
+	if _, _, _, err := enforceAnnotationLocks(testDB, 999, &endpoint, &interval, &in); err != sql.ErrNoRows {
+		t.Errorf("olmayan servis için hata dönmeliydi: %v", err)
+	}
+}
+
+func TestUpdateEndpointsSkipsUnchangedServices(t *testing.T) {
+	testDB := newTestDB(t)
+	svc := newService("shop", "api", nil)
+	svc.Annotations = map[string]string{"k8s-monitoring/path": "/healthz", "k8s-monitoring/owner": "team-a"}
+	client := fake.NewSimpleClientset(svc)
+	var err error
+
+	factory := informers.NewSharedInformerFactory(client, 0)
+	d := NewServiceDiscovery(testDB, client, nil, 0, "prod")
+	d.services = factory.Core().V1().Services().Lister()
+	if d.rules, err = (DiscoveryRules{}).Compile(); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	factory.Start(stop)
+	factory.WaitForCacheSync(stop)
+	if services, _ := d.services.List(labels.Everything()); len(services) != 1 {
+		t.Fatalf("servis önbelleği dolmadı")
+	}
+
+	d.upsertService(svc)
+	d.updateEndpoints("shop", "api")
+	row := func() (endpoint, owner string) {
+		var e, o sql.NullString
+		testDB.QueryRow(`SELECT endpoint, owner FROM services WHERE name = 'api'`).Scan(&e, &o)
+		return e.String, o.String
+	}
+	if endpoint, owner := row(); endpoint != "http://10.0.0.1:80/healthz" || owner != "team-a" {
+		t.Fatalf("annotation'lar uygulanmalıydı: %s %s", endpoint, owner)
+	}
+
+	// Değişiklik yoksa (ör. Ingress resync'i) satıra yazılmaz
+	testDB.Exec(`UPDATE services SET endpoint = 'yazılmadı', owner = 'yazılmadı' WHERE name = 'api'`)
+	d.updateEndpoints("shop", "api")
+	if endpoint, owner := row(); endpoint != "yazılmadı" || owner != "yazılmadı" {
+		t.Errorf("değişmeyen servis için UPDATE çalışmamalıydı: %s %s", endpoint, owner)
+	}
+
+	// Servis nesnesi değiştiğinde değerler yeniden yazılır
+	d.syncService(svc)
+	if endpoint, owner := row(); endpoint != "http://10.0.0.1:80/healthz" || owner != "team-a" {
+		t.Errorf("servis senkronizasyonu değerleri yeniden yazmalıydı: %s %s", endpoint, owner)
+	}
+}
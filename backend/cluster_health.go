@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Cluster sağlık durumları
+const (
+	ClusterStatusUp           = "up"
+	ClusterStatusDegraded     = "degraded"     // API sunucusu canlı ama hazır değil
+	ClusterStatusUnauthorized = "unauthorized" // API sunucusuna ulaşıldı ama kimlik bilgileri reddedildi
+	ClusterStatusUnreachable  = "unreachable"  // API sunucusuna hiç ulaşılamadı
+	ClusterStatusConfigError  = "config_error" // Kimlik bilgisi referansı çözülemedi
+	ClusterStatusUnknown      = "unknown"
+)
+
+// ClusterHealthResult, tek bir cluster sağlık kontrolünün sonucunu temsil eder
+type ClusterHealthResult struct {
+	ClusterID      int       `json:"cluster_id"`
+	Cluster        string    `json:"cluster"`
+	Status         string    `json:"status"`
+	ResponseTime   int64     `json:"response_time"` // milisaniye
+	ReadyzOK       bool      `json:"readyz_ok"`
+	LivezOK        bool      `json:"livez_ok"`
+	ReadyzFailures []string  `json:"readyz_failures,omitempty"`
+	ServerVersion  string    `json:"server_version,omitempty"`
+	NodeCount      int       `json:"node_count"` // -1: yetki yok veya alınamadı
+	ErrorMessage   string    `json:"error_message,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// ClusterHealthRegistry, her cluster'ın son sağlık sonucunu bellekte tutar.
+// Uptime monitor, bir servisin kesintisini cluster kesintisinden ayırt etmek için kullanır.
+type ClusterHealthRegistry struct {
+	mu      sync.RWMutex
+	results map[string]ClusterHealthResult
+}
+
+// NewClusterHealthRegistry, yeni bir sağlık kayıt defteri oluşturur
+func NewClusterHealthRegistry() *ClusterHealthRegistry {
+	return &ClusterHealthRegistry{results: make(map[string]ClusterHealthResult)}
+}
+
+// Set, cluster'ın son sağlık sonucunu kaydeder
+func (r *ClusterHealthRegistry) Set(result ClusterHealthResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[result.Cluster] = result
+}
+
+// Get, cluster'ın son sağlık sonucunu döndürür
+func (r *ClusterHealthRegistry) Get(cluster string) (ClusterHealthResult, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result, ok := r.results[cluster]
+	return result, ok
+}
+
+// Delete, cluster'ın sağlık kaydını siler
+func (r *ClusterHealthRegistry) Delete(cluster string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.results, cluster)
+}
+
+// Unreachable, cluster'ın son kontrolde erişilemez olup olmadığını döndürür. Kimlik doğrulama
+// hatası API sunucusunun erişilebilir olduğunu gösterir ve servis kontrolleri token kullanmaz;
+// bu durum yalnızca cluster durumunda (unauthorized) raporlanır, servis kesintileri alarm üretir.
+func (r *ClusterHealthRegistry) Unreachable(cluster string) bool {
+	result, ok := r.Get(cluster)
+	return ok && result.Status == ClusterStatusUnreachable
+}
+
+// clusterHealth, tüm cluster'ların son sağlık durumları
+var clusterHealth = NewClusterHealthRegistry()
+
+// clusterStatus, cluster'ın bellekteki son sağlık durumunu döndürür (veritabanına erişmez)
+func clusterStatus(cluster string) string {
+	if result, ok := clusterHealth.Get(cluster); ok {
+		return result.Status
+	}
+	return ClusterStatusUnknown
+}
+
+// clusterHealthInterval, sağlık kontrolleri arasındaki süre
+func clusterHealthInterval() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("CLUSTER_HEALTH_INTERVAL")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// ClusterHealthMonitor, bir cluster'ın API sunucusunu periyodik olarak kontrol eder
+type ClusterHealthMonitor struct {
+	db        *sql.DB
+	client    kubernetes.Interface
+	clusterID int
+	cluster   string
+	interval  time.Duration
+}
+
+// NewClusterHealthMonitor, yeni bir cluster sağlık izleyicisi oluşturur
+func NewClusterHealthMonitor(db *sql.DB, client kubernetes.Interface, clusterID int, cluster string) *ClusterHealthMonitor {
+	return &ClusterHealthMonitor{
+		db:        db,
+		client:    client,
+		clusterID: clusterID,
+		cluster:   cluster,
+		interval:  clusterHealthInterval(),
+	}
+}
+
+// Run, context iptal edilene kadar cluster sağlığını kontrol eder
+func (m *ClusterHealthMonitor) Run(ctx context.Context) error {
+	defer clusterHealth.Delete(m.cluster)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		result := m.check(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		m.record(result)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check, API sunucusunun /livez ve /readyz uç noktalarını, sürümünü ve node sayısını kontrol eder
+func (m *ClusterHealthMonitor) check(ctx context.Context) ClusterHealthResult {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result := ClusterHealthResult{
+		ClusterID: m.clusterID,
+		Cluster:   m.cluster,
+		Status:    ClusterStatusUnreachable,
+		NodeCount: -1,
+		Timestamp: time.Now(),
+	}
+
+	restClient := m.client.Discovery().RESTClient()
+	if restClient == nil {
+		result.Status = ClusterStatusUnknown
+		result.ErrorMessage = "Kubernetes REST istemcisi kullanılamıyor"
+		return result
+	}
+
+	// /readyz?verbose, başarısız alt kontrolleri "[-]" ile listeler
+	start := time.Now()
+	body, readyErr := restClient.Get().AbsPath("/readyz").Param("verbose", "").DoRaw(ctx)
+	result.ResponseTime = time.Since(start).Milliseconds()
+	result.ReadyzOK = readyErr == nil
+	result.ReadyzFailures = parseHealthzFailures(string(body))
+
+	_, liveErr := restClient.Get().AbsPath("/livez").DoRaw(ctx)
+	result.LivezOK = liveErr == nil
+
+	var refErr *SecretRefError
+	switch {
+	case result.ReadyzOK && result.LivezOK:
+		result.Status = ClusterStatusUp
+	case errors.As(readyErr, &refErr) || errors.As(liveErr, &refErr):
+		// Hedefe hiç istek gönderilmedi, bu bir cluster kesintisi değil
+		result.Status = ClusterStatusConfigError
+		result.ErrorMessage = refErr.Error()
+		return result
+	case isAuthError(readyErr) || isAuthError(liveErr):
+		result.Status = ClusterStatusUnauthorized
+		result.ErrorMessage = fmt.Sprintf("Kimlik doğrulama hatası: %v", firstError(readyErr, liveErr))
+	case isAPIResponse(readyErr) || isAPIResponse(liveErr):
+		// Sunucu yanıt veriyor ama sağlık kontrolleri başarısız
+		result.Status = ClusterStatusDegraded
+		result.ErrorMessage = fmt.Sprintf("API sunucusu sağlıksız: %v", firstError(readyErr, liveErr))
+	default:
+		result.ErrorMessage = fmt.Sprintf("API sunucusuna ulaşılamadı: %v", firstError(readyErr, liveErr))
+		return result
+	}
+
+	if version, err := m.client.Discovery().ServerVersion(); err == nil {
+		result.ServerVersion = version.GitVersion
+	}
+
+	// Watch önbelleğinden okunarak etcd'ye yük bindirilmez
+	nodes, err := m.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err == nil {
+		result.NodeCount = len(nodes.Items)
+	}
+
+	return result
+}
+
+// record, sonucu bellekte ve veritabanında saklar, durum değişimlerini loglar
+func (m *ClusterHealthMonitor) record(result ClusterHealthResult) {
+	previous, hadPrevious := clusterHealth.Get(m.cluster)
+	clusterHealth.Set(result)
+
+	if hadPrevious && previous.Status != result.Status {
+		log.Printf("%s cluster'ının durumu değişti: %s -> %s %s",
+			m.cluster, previous.Status, result.Status, result.ErrorMessage)
+	}
+
+	failures, _ := json.Marshal(result.ReadyzFailures)
+	clusterID := sql.NullInt64{Int64: int64(m.clusterID), Valid: m.clusterID > 0}
+
+	_, err := m.db.Exec(`
+		INSERT INTO cluster_checks
+		(cluster_id, cluster, status, response_time, readyz_ok, livez_ok, readyz_failures,
+		 server_version, node_count, error_message, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, clusterID, m.cluster, result.Status, result.ResponseTime, result.ReadyzOK, result.LivezOK,
+		string(failures), result.ServerVersion, result.NodeCount, result.ErrorMessage, result.Timestamp)
+	if err != nil {
+		log.Printf("%s cluster'ı için sağlık sonucu kaydedilemedi: %v", m.cluster, err)
+	}
+}
+
+// parseHealthzFailures, verbose healthz çıktısındaki başarısız kontrolleri döndürür
+func parseHealthzFailures(body string) []string {
+	var failures []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[-]") {
+			failures = append(failures, strings.TrimPrefix(line, "[-]"))
+		}
+	}
+	return failures
+}
+
+// isAPIResponse, hatanın API sunucusundan gelen bir HTTP yanıtı olup olmadığını döndürür
+func isAPIResponse(err error) bool {
+	_, ok := err.(apierrors.APIStatus)
+	return ok
+}
+
+// isAuthError, hatanın kimlik doğrulama veya yetki hatası olup olmadığını döndürür
+func isAuthError(err error) bool {
+	return apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err)
+}
+
+// firstError, ilk nil olmayan hatayı döndürür
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterStatusSummary, cluster'ın son sağlık durumunu ve son başarılı iletişim zamanını döndürür.
+// Bellekte sonuç yoksa (ör. yeniden başlatma sonrası) veritabanındaki son kayıt kullanılır.
+func clusterStatusSummary(db *sql.DB, cluster string) map[string]interface{} {
+	summary := map[string]interface{}{
+		"status": ClusterStatusUnknown,
+	}
+
+	result, ok := clusterHealth.Get(cluster)
+	if !ok {
+		var failures sql.NullString
+		var serverVersion, errorMessage sql.NullString
+		err := db.QueryRow(`
+			SELECT status, response_time, readyz_ok, livez_ok, readyz_failures,
+			       server_version, node_count, error_message, timestamp
+			FROM cluster_checks WHERE cluster = ?
+			ORDER BY timestamp DESC LIMIT 1
+		`, cluster).Scan(&result.Status, &result.ResponseTime, &result.ReadyzOK, &result.LivezOK,
+			&failures, &serverVersion, &result.NodeCount, &errorMessage, &result.Timestamp)
+		if err != nil {
+			return summary
+		}
+		if failures.Valid {
+			json.Unmarshal([]byte(failures.String), &result.ReadyzFailures)
+		}
+		result.ServerVersion = serverVersion.String
+		result.ErrorMessage = errorMessage.String
+	}
+
+	summary["status"] = result.Status
+	summary["last_check"] = result.Timestamp
+	summary["response_time"] = result.ResponseTime
+	summary["readyz_ok"] = result.ReadyzOK
+	summary["livez_ok"] = result.LivezOK
+	summary["readyz_failures"] = result.ReadyzFailures
+	summary["server_version"] = result.ServerVersion
+	summary["node_count"] = result.NodeCount
+	if result.ErrorMessage != "" {
+		summary["error_message"] = result.ErrorMessage
+	}
+
+	// MAX() kolon türünü taşımadığından sürücü değeri zamana çeviremez; son satır okunur
+	var lastSuccess sql.NullTime
+	db.QueryRow(`
+		SELECT timestamp FROM cluster_checks WHERE cluster = ? AND status = ?
+		ORDER BY timestamp DESC LIMIT 1
+	`, cluster, ClusterStatusUp).Scan(&lastSuccess)
+	if lastSuccess.Valid {
+		summary["last_success"] = lastSuccess.Time
+	}
+
+	return summary
+}
+
+// clusterHealthHistoryHandler, bir cluster'ın sağlık kontrolü geçmişini döndürür
+func clusterHealthHistoryHandler(w http.ResponseWriter, r *http.Request, clusterID int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var clusterName string
+	if err := db.QueryRow("SELECT name FROM clusters WHERE id = ?", clusterID).Scan(&clusterName); err != nil {
+		http.Error(w, `{"error":"Cluster bulunamadı"}`, http.StatusNotFound)
+		return
+	}
+
+	// Varsayılan olarak son 24 saat
+	endDate := time.Now()
+	startDate := endDate.Add(-24 * time.Hour)
+	if v := r.URL.Query().Get("startDate"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz startDate formatı"}`, http.StatusBadRequest)
+			return
+		}
+		startDate = parsed
+	}
+	if v := r.URL.Query().Get("endDate"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz endDate formatı"}`, http.StatusBadRequest)
+			return
+		}
+		endDate = parsed
+	}
+
+	rows, err := db.Query(`
+		SELECT status, response_time, readyz_ok, livez_ok, server_version, node_count,
+		       COALESCE(error_message, ''), timestamp
+		FROM cluster_checks
+		WHERE cluster = ? AND timestamp BETWEEN ? AND ?
+		ORDER BY timestamp ASC
+	`, clusterName, startDate, endDate)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Veriler alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	history := []map[string]interface{}{}
+	for rows.Next() {
+		var status, errorMessage string
+		var serverVersion sql.NullString
+		var responseTime int64
+		var readyzOK, livezOK bool
+		var nodeCount int
+		var timestamp time.Time
+
+		if err := rows.Scan(&status, &responseTime, &readyzOK, &livezOK, &serverVersion,
+			&nodeCount, &errorMessage, &timestamp); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veri okunamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		record := map[string]interface{}{
+			"timestamp":     timestamp,
+			"status":        status,
+			"responseTime":  responseTime,
+			"readyzOk":      readyzOK,
+			"livezOk":       livezOK,
+			"serverVersion": serverVersion.String,
+			"nodeCount":     nodeCount,
+		}
+		if errorMessage != "" {
+			record["errorMessage"] = errorMessage
+		}
+		history = append(history, record)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cluster": clusterName,
+		"history": history,
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeAPIServer, sağlık uç noktalarının yanıtları değiştirilebilen sahte bir API sunucusu
+type fakeAPIServer struct {
+	mu     sync.Mutex
+	readyz int
+	livez  int
+	body   string
+}
+
+func (s *fakeAPIServer) set(readyz, livez int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readyz, s.livez, s.body = readyz, livez, body
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	readyz, livez, body := s.readyz, s.livez, s.body
+	s.mu.Unlock()
+
+	status := func(code int) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Failure", "code": code})
+	}
+	switch r.URL.Path {
+	case "/readyz":
+		if readyz == http.StatusUnauthorized {
+			status(readyz)
+			return
+		}
+		w.WriteHeader(readyz)
+		w.Write([]byte(body))
+	case "/livez":
+		w.WriteHeader(livez)
+	case "/version":
+		json.NewEncoder(w).Encode(map[string]string{"gitVersion": "v1.28.4"})
+	case "/api/v1/nodes":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"kind": "NodeList", "apiVersion": "v1",
+			"items": []interface{}{map[string]interface{}{"metadata": map[string]string{"name": "n1"}}}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestClusterHealthMonitorStateTransitions(t *testing.T) {
+	testDB := newTestDB(t)
+	api := &fakeAPIServer{}
+	server := httptest.NewServer(api)
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const cluster = "health-test"
+	monitor := NewClusterHealthMonitor(testDB, client, 4, cluster)
+	t.Cleanup(func() { clusterHealth.Delete(cluster) })
+
+	steps := []struct {
+		name        string
+		setup       func()
+		status      string
+		unreachable bool
+		failures    []string
+		nodes       int
+	}{
+		{
+			name:   "sağlıklı",
+			setup:  func() { api.set(200, 200, "ok") },
+			status: ClusterStatusUp,
+			nodes:  1,
+		},
+		{
+			name: "readyz başarısız",
+			setup: func() {
+				api.set(500, 200, "[+]ping ok\n[-]etcd failed: reason withheld\n[-]informer-sync failed\nreadyz check failed")
+			},
+			status:   ClusterStatusDegraded,
+			failures: []string{"etcd failed: reason withheld", "informer-sync failed"},
+			nodes:    1,
+		},
+		{
+			name:   "kimlik bilgisi reddedildi",
+			setup:  func() { api.set(http.StatusUnauthorized, 200, "") },
+			status: ClusterStatusUnauthorized,
+			nodes:  1,
+		},
+		{
+			name:        "sunucu kapalı",
+			setup:       server.Close,
+			status:      ClusterStatusUnreachable,
+			unreachable: true,
+			nodes:       -1,
+		},
+	}
+
+	for _, step := range steps {
+		step.setup()
+		result := monitor.check(context.Background())
+		monitor.record(result)
+
+		if result.Status != step.status {
+			t.Fatalf("%s: beklenen durum %s, alınan %s (%s)", step.name, step.status, result.Status, result.ErrorMessage)
+		}
+		if got := clusterHealth.Unreachable(cluster); got != step.unreachable {
+			t.Errorf("%s: Unreachable %v döndü", step.name, got)
+		}
+		if clusterStatus(cluster) != step.status {
+			t.Errorf("%s: bellekteki durum güncellenmedi: %s", step.name, clusterStatus(cluster))
+		}
+		if !reflect.DeepEqual(result.ReadyzFailures, step.failures) {
+			t.Errorf("%s: beklenen başarısız kontroller %v, alınan %v", step.name, step.failures, result.ReadyzFailures)
+		}
+		if result.NodeCount != step.nodes {
+			t.Errorf("%s: beklenen node sayısı %d, alınan %d", step.name, step.nodes, result.NodeCount)
+		}
+		if step.status != ClusterStatusUp && result.ErrorMessage == "" {
+			t.Errorf("%s: hata mesajı boş", step.name)
+		}
+	}
+
+	var count int
+	testDB.QueryRow(`SELECT COUNT(*) FROM cluster_checks WHERE cluster = ? AND cluster_id = 4`, cluster).Scan(&count)
+	if count != len(steps) {
+		t.Errorf("her kontrol kaydedilmeliydi: %d", count)
+	}
+
+	// Bellekte sonuç yokken özet veritabanındaki son kayıttan ve son başarılı kontrolden oluşur
+	clusterHealth.Delete(cluster)
+	summary := clusterStatusSummary(testDB, cluster)
+	if summary["status"] != ClusterStatusUnreachable || summary["last_success"] == nil || summary["server_version"] != "" {
+		t.Errorf("beklenmeyen özet: %+v", summary)
+	}
+	if summary := clusterStatusSummary(testDB, "olmayan"); summary["status"] != ClusterStatusUnknown {
+		t.Errorf("kaydı olmayan cluster'ın durumu bilinmiyor olmalı: %+v", summary)
+	}
+}
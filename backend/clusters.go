@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultClusterName, ortam değişkenleri / in-cluster ile bağlanılan cluster'ın adı
+const defaultClusterName = "default"
+
+// ClusterRecord, clusters tablosundaki bir kaydı temsil eder
+type ClusterRecord struct {
+	ID            int
+	Name          string
+	APIURL        string
+	AuthType      string
+	Token         string
+	CACert        string
+	SkipTLSVerify bool
+	Kubeconfig    string // auth_type = "kubeconfig" için küçültülmüş kubeconfig
+	KubeContext   string // kubeconfig içinde kullanılacak context
+}
+
+// loadCluster, veritabanından tek bir cluster kaydını okur
+func loadCluster(db *sql.DB, id int) (ClusterRecord, error) {
+	var c ClusterRecord
+	var token, caCert, kubeconfig, kubeContext sql.NullString
+	var skipTLSVerify int
+
+	err := db.QueryRow(`
+		SELECT id, name, api_url, auth_type, token, ca_cert, skip_tls_verify, kubeconfig, kube_context
+		FROM clusters WHERE id = ?
+	`, id).Scan(&c.ID, &c.Name, &c.APIURL, &c.AuthType, &token, &caCert, &skipTLSVerify, &kubeconfig, &kubeContext)
+	if err != nil {
+		return c, err
+	}
+
+	c.SkipTLSVerify = skipTLSVerify == 1
+	c.KubeContext = kubeContext.String
+
+	// Kimlik bilgileri veritabanında şifreli saklanır
+	if c.Token, err = decryptSecret(token.String); err != nil {
+		return c, err
+	}
+	if c.CACert, err = decryptSecret(caCert.String); err != nil {
+		return c, err
+	}
+	if c.Kubeconfig, err = decryptSecret(kubeconfig.String); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// validateClusterRecord, yeni bir cluster kaydının alanlarını doğrular ve tamamlar.
+// Kubeconfig kayıtlarında yalnızca seçilen context saklanır ve API adresi ondan alınır.
+func validateClusterRecord(c *ClusterRecord) error {
+	if c.Name == "" || c.AuthType == "" {
+		return fmt.Errorf("Name ve auth_type alanları gerekli")
+	}
+
+	switch c.AuthType {
+	case "token":
+		if c.APIURL == "" {
+			return fmt.Errorf("Token auth type için api_url gerekli")
+		}
+		if c.Token == "" {
+			return fmt.Errorf("Token auth type için token gerekli")
+		}
+	case "kubeconfig":
+		minified, server, err := extractKubeconfigContext(c.Kubeconfig, c.KubeContext)
+		if err != nil {
+			return err
+		}
+		if c.KubeContext == "" {
+			config, _ := parseKubeconfig(c.Kubeconfig)
+			c.KubeContext = config.CurrentContext
+		}
+		c.Kubeconfig = minified
+		if c.APIURL == "" {
+			c.APIURL = server
+		}
+	default:
+		return fmt.Errorf("Desteklenmeyen kimlik doğrulama türü: %s", c.AuthType)
+	}
+	return nil
+}
+
+// insertCluster, cluster kaydını doğrulayıp veritabanına ekler ve yeni ID'yi döndürür
+func insertCluster(db *sql.DB, c *ClusterRecord) (int64, error) {
+	if err := validateClusterRecord(c); err != nil {
+		return 0, err
+	}
+
+	skipTLSVerify := 0
+	if c.SkipTLSVerify {
+		skipTLSVerify = 1
+	}
+
+	secrets, err := encryptClusterSecrets(*c)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO clusters (name, api_url, auth_type, token, ca_cert, skip_tls_verify, kubeconfig, kube_context)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.Name, c.APIURL, c.AuthType, secrets[0], secrets[1], skipTLSVerify, secrets[2], c.KubeContext)
+	if err != nil {
+		return 0, fmt.Errorf("Cluster eklenemedi: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	c.ID = int(id)
+	return id, nil
+}
+
+// encryptClusterSecrets, token, CA sertifikası ve kubeconfig alanlarını
+// veritabanına yazılacak biçimde (şifreli) döndürür
+func encryptClusterSecrets(c ClusterRecord) ([3]string, error) {
+	var out [3]string
+	for i, value := range []string{c.Token, c.CACert, c.Kubeconfig} {
+		encrypted, err := encryptSecret(value)
+		if err != nil {
+			return out, fmt.Errorf("Kimlik bilgisi şifrelenemedi: %v", err)
+		}
+		out[i] = encrypted
+	}
+	return out, nil
+}
+
+// loadClusterIDs, kayıtlı tüm cluster'ların ID'lerini döndürür
+func loadClusterIDs(db *sql.DB) ([]int, error) {
+	rows, err := db.Query("SELECT id FROM clusters ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// clusterRuntime, çalışan bir cluster'ın istemcisini ve işçilerini tutar
+type clusterRuntime struct {
+	id      int
+	name    string
+	client  kubernetes.Interface
+	dynamic dynamic.Interface // CRD'ler ve OpenShift Route'ları için
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+
+	// Keşif, kurallar değiştiğinde diğer işçilerden bağımsız yeniden başlatılabilir
+	discoveryMu     sync.Mutex
+	discoveryCancel context.CancelFunc
+	discoveryDone   chan struct{}
+}
+
+// ClusterManager, her kayıtlı cluster için istemci ve arka plan işçilerini yönetir.
+// ID'si 0 olan çalışma zamanı, ortam değişkenleriyle yapılandırılan varsayılan cluster'dır.
+type ClusterManager struct {
+	db       *sql.DB
+	parent   context.Context
+	mu       sync.RWMutex
+	clusters map[int]*clusterRuntime
+}
+
+// NewClusterManager, yeni bir cluster yöneticisi oluşturur
+func NewClusterManager(parent context.Context, db *sql.DB) *ClusterManager {
+	return &ClusterManager{
+		db:       db,
+		parent:   parent,
+		clusters: make(map[int]*clusterRuntime),
+	}
+}
+
+// StartAll, veritabanındaki tüm cluster'lar için işçileri başlatır
+func (m *ClusterManager) StartAll() error {
+	ids, err := loadClusterIDs(m.db)
+	if err != nil {
+		return fmt.Errorf("cluster listesi alınamadı: %v", err)
+	}
+
+	for _, id := range ids {
+		if err := m.StartCluster(id); err != nil {
+			log.Printf("Cluster %d başlatılamadı: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// StartCluster, veritabanındaki cluster kaydından istemci oluşturur ve işçilerini başlatır.
+// Cluster zaten çalışıyorsa yeni ayarlarla yeniden başlatılır.
+func (m *ClusterManager) StartCluster(id int) error {
+	record, err := loadCluster(m.db, id)
+	if err != nil {
+		return fmt.Errorf("cluster bilgileri alınamadı: %v", err)
+	}
+
+	client, err := newClusterClient(record)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := newClusterDynamicClient(record)
+	if err != nil {
+		return err
+	}
+
+	m.start(id, record.Name, client, dynamicClient)
+	return nil
+}
+
+// SetDefaultClient, ortam değişkenleriyle oluşturulan varsayılan istemciyi (yeniden) başlatır
+func (m *ClusterManager) SetDefaultClient(client kubernetes.Interface, dynamicClient dynamic.Interface) {
+	m.start(0, defaultClusterName, client, dynamicClient)
+}
+
+// start, verilen istemci için cluster işçilerini başlatır
+func (m *ClusterManager) start(id int, name string, client kubernetes.Interface, dynamicClient dynamic.Interface) {
+	m.StopCluster(id)
+
+	ctx, cancel := context.WithCancel(m.parent)
+	rt := &clusterRuntime{
+		id:      id,
+		name:    name,
+		client:  client,
+		dynamic: dynamicClient,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	m.mu.Lock()
+	m.clusters[id] = rt
+	m.mu.Unlock()
+
+	m.runWorkers(ctx, rt)
+	rt.discoveryMu.Lock()
+	m.runDiscovery(rt)
+	rt.discoveryMu.Unlock()
+	log.Printf("%s cluster'ı için işçiler başlatıldı", name)
+}
+
+// runWorkers, bir cluster'a ait tüm arka plan işçilerini başlatır
+func (m *ClusterManager) runWorkers(ctx context.Context, rt *clusterRuntime) {
+	workers := map[string]func(context.Context) error{
+		"olay izleyicisi":    NewEventWatcher(m.db, rt.client, rt.name).Run,
+		"rollout izleyicisi": NewRolloutWatcher(m.db, rt.client, rt.name).Run,
+		"sağlık izleyicisi":  NewClusterHealthMonitor(m.db, rt.client, rt.id, rt.name).Run,
+	}
+	// Kimlik bilgisi süreleri yalnızca veritabanında saklanan cluster'lar için izlenir
+	if rt.id > 0 {
+		workers["kimlik bilgisi izleyicisi"] = NewCredentialExpiryMonitor(m.db, rt.id, rt.name).Run
+	}
+	if rt.dynamic != nil {
+		workers["Monitor denetleyicisi"] = NewMonitorController(m.db, rt.dynamic, rt.id, rt.name).Run
+	}
+
+	for name, run := range workers {
+		rt.done.Add(1)
+		go func(name string, run func(context.Context) error) {
+			defer rt.done.Done()
+			if err := run(ctx); err != nil {
+				log.Printf("%s cluster'ı için %s çalıştırılamadı: %v", rt.name, name, err)
+			}
+		}(name, run)
+	}
+}
+
+// runDiscovery, cluster'ın servis keşfini kendi context'iyle başlatır; rt.discoveryMu tutulmalıdır
+func (m *ClusterManager) runDiscovery(rt *clusterRuntime) {
+	if rt.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(rt.ctx)
+	done := make(chan struct{})
+	rt.discoveryCancel, rt.discoveryDone = cancel, done
+
+	rt.done.Add(1)
+	go func() {
+		defer rt.done.Done()
+		defer close(done)
+		if err := NewServiceDiscovery(m.db, rt.client, rt.dynamic, rt.id, rt.name).Run(ctx); err != nil {
+			log.Printf("%s cluster'ı için servis keşfi çalıştırılamadı: %v", rt.name, err)
+		}
+	}()
+}
+
+// StopCluster, cluster'ın işçilerini durdurur ve bitmelerini bekler
+func (m *ClusterManager) StopCluster(id int) {
+	m.mu.Lock()
+	rt, ok := m.clusters[id]
+	delete(m.clusters, id)
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	rt.cancel()
+	rt.done.Wait()
+	log.Printf("%s cluster'ı için işçiler durduruldu", rt.name)
+}
+
+// StopAll, tüm cluster işçilerini durdurur
+func (m *ClusterManager) StopAll() {
+	m.mu.RLock()
+	ids := make([]int, 0, len(m.clusters))
+	for id := range m.clusters {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+
+	for _, id := range ids {
+		m.StopCluster(id)
+	}
+}
+
+// Client, ID'ye veya ada göre cluster istemcisini döndürür.
+// Boş değer varsayılan cluster'ı seçer.
+func (m *ClusterManager) Client(cluster string) (kubernetes.Interface, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if cluster == "" {
+		cluster = defaultClusterName
+	}
+
+	if id, err := strconv.Atoi(cluster); err == nil {
+		if rt, ok := m.clusters[id]; ok {
+			return rt.client, rt.name, nil
+		}
+	}
+	for _, rt := range m.clusters {
+		if rt.name == cluster {
+			return rt.client, rt.name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%s cluster'ı için Kubernetes bağlantısı bulunamadı", cluster)
+}
+
+// Restart, adı veya ID'si verilen çalışan cluster'ın işçilerini aynı istemciyle yeniden başlatır
+func (m *ClusterManager) Restart(cluster string) bool {
+	target := m.runtime(cluster)
+	if target == nil {
+		return false
+	}
+	m.start(target.id, target.name, target.client, target.dynamic)
+	return true
+}
+
+// RestartDiscovery, çalışan cluster'ın yalnızca servis keşfini yeniden başlatır;
+// olay, rollout ve sağlık izleyicileri kesintisiz çalışmaya devam eder
+func (m *ClusterManager) RestartDiscovery(cluster string) bool {
+	target := m.runtime(cluster)
+	if target == nil {
+		return false
+	}
+
+	target.discoveryMu.Lock()
+	defer target.discoveryMu.Unlock()
+	if target.discoveryCancel != nil {
+		target.discoveryCancel()
+		<-target.discoveryDone
+	}
+	m.runDiscovery(target)
+	return true
+}
+
+// runtime, adı veya ID'si verilen çalışan cluster'ı döndürür
+func (m *ClusterManager) runtime(cluster string) *clusterRuntime {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, rt := range m.clusters {
+		if rt.name == cluster || strconv.Itoa(rt.id) == cluster {
+			return rt
+		}
+	}
+	return nil
+}
+
+// Running, cluster'ın işçilerinin çalışıp çalışmadığını döndürür
+func (m *ClusterManager) Running(id int) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.clusters[id]
+	return ok
+}
+
+// Count, çalışan cluster sayısını döndürür
+func (m *ClusterManager) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.clusters)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// stopWithin, fn'in verilen sürede dönmesini bekler
+func stopWithin(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("zaman aşımı: %s", what)
+	}
+}
+
+func TestClusterManagerStartsRestartsAndStopsWorkers(t *testing.T) {
+	testDB := newTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := NewClusterManager(ctx, testDB)
+	defer stopWithin(t, "tüm işçilerin durması", manager.StopAll)
+
+	defaultClient := fake.NewSimpleClientset(newService("shop", "api", nil))
+	manager.SetDefaultClient(defaultClient, nil)
+	prodClient := fake.NewSimpleClientset(newService("billing", "invoices", nil))
+	manager.start(3, "prod", prodClient, nil)
+
+	if manager.Count() != 2 || !manager.Running(0) || !manager.Running(3) {
+		t.Fatalf("iki cluster çalışıyor olmalıydı: %d", manager.Count())
+	}
+	for _, ref := range []string{"", "default", "0"} {
+		if client, name, err := manager.Client(ref); err != nil || client != defaultClient || name != defaultClusterName {
+			t.Errorf("%q varsayılan cluster'ı seçmeliydi: %s %v", ref, name, err)
+		}
+	}
+	for _, ref := range []string{"prod", "3"} {
+		if client, name, err := manager.Client(ref); err != nil || client != prodClient || name != "prod" {
+			t.Errorf("%q prod cluster'ını seçmeliydi: %s %v", ref, name, err)
+		}
+	}
+
+	// Her cluster'ın keşif işçisi kendi servislerini kendi adıyla kaydeder
+	eventually(t, "iki cluster'ın servislerinin keşfedilmesi", func() bool {
+		var count int
+		testDB.QueryRow(`SELECT COUNT(*) FROM services WHERE (name = 'api' AND cluster = 'default')
+			OR (name = 'invoices' AND cluster = 'prod' AND cluster_id = 3)`).Scan(&count)
+		return count == 2
+	})
+
+	// Yeniden başlatma aynı istemciyle yeni bir çalışma zamanı oluşturur
+	manager.mu.RLock()
+	before := manager.clusters[3]
+	manager.mu.RUnlock()
+	var restarted bool
+	stopWithin(t, "prod işçilerinin yeniden başlaması", func() { restarted = manager.Restart("prod") })
+	manager.mu.RLock()
+	after := manager.clusters[3]
+	manager.mu.RUnlock()
+	if !restarted || after == before || after.client != prodClient {
+		t.Errorf("prod cluster'ı aynı istemciyle yeniden başlatılmalıydı")
+	}
+	if manager.Restart("staging") {
+		t.Errorf("çalışmayan cluster yeniden başlatılmamalıydı")
+	}
+
+	stopWithin(t, "prod işçilerinin durması", func() { manager.StopCluster(3) })
+	if manager.Running(3) || manager.Count() != 1 {
+		t.Errorf("prod cluster'ı durdurulmalıydı")
+	}
+	if _, _, err := manager.Client("prod"); err == nil {
+		t.Errorf("durdurulan cluster'ın istemcisi dönmemeliydi")
+	}
+	// Çalışmayan cluster'ı durdurmak sorun çıkarmaz
+	manager.StopCluster(3)
+}
+
+func TestClusterManagerStartClusterFromDatabase(t *testing.T) {
+	testDB := newTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := NewClusterManager(ctx, testDB)
+	defer stopWithin(t, "tüm işçilerin durması", manager.StopAll)
+
+	if err := manager.StartCluster(42); err == nil {
+		t.Errorf("kayıtlı olmayan cluster başlatılmamalıydı")
+	}
+
+	id, err := insertCluster(testDB, &ClusterRecord{Name: "stage", APIURL: "https://127.0.0.1:1", AuthType: "token", Token: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDB.Exec(`INSERT INTO clusters (name, api_url, auth_type, kubeconfig) VALUES ('bozuk', 'https://x', 'kubeconfig', 'yaml değil')`)
+
+	if err := manager.StartAll(); err != nil {
+		t.Fatal(err)
+	}
+	if !manager.Running(int(id)) || manager.Count() != 1 {
+		t.Errorf("geçerli cluster çalışmalı, geçersiz kubeconfig'li cluster atlanmalıydı: %d", manager.Count())
+	}
+	if _, name, err := manager.Client("stage"); err != nil || name != "stage" {
+		t.Errorf("veritabanından başlatılan cluster'ın istemcisi dönmeliydi: %v", err)
+	}
+}
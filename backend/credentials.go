@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kimlik bilgisi süre durumları
+const (
+	CredentialStatusValid    = "valid"
+	CredentialStatusExpiring = "expiring"
+	CredentialStatusExpired  = "expired"
+	CredentialStatusNoExpiry = "no_expiry"
+	CredentialStatusUnknown  = "unknown"
+)
+
+// credentialCheckInterval, kimlik bilgisi sürelerinin yeniden kontrol edilme aralığı
+const credentialCheckInterval = 12 * time.Hour
+
+// CredentialExpiry, bir cluster kimlik bilgisinin (token veya sertifika) süre bilgisi
+type CredentialExpiry struct {
+	Kind      string     `json:"kind"` // token, client_certificate, ca_certificate
+	Subject   string     `json:"subject,omitempty"`
+	Issuer    string     `json:"issuer,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	DaysLeft  *int       `json:"days_left,omitempty"`
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Source    string     `json:"source,omitempty"` // değer bir referanstan okunduysa referansın kendisi
+}
+
+// CredentialReport, bir cluster'ın tüm kimlik bilgilerinin süre özetidir
+type CredentialReport struct {
+	Credentials []CredentialExpiry `json:"credentials"`
+	NextExpiry  *time.Time         `json:"next_expiry,omitempty"`
+	WarningDays int                `json:"warning_days"`
+	Warnings    []string           `json:"warnings"`
+}
+
+// credentialWarningDays, süre dolmadan kaç gün önce uyarı verileceği
+func credentialWarningDays() int {
+	if days, err := strconv.Atoi(os.Getenv("CREDENTIAL_EXPIRY_WARNING_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 14
+}
+
+// jwtClaims, süre takibi için gereken JWT alanları
+type jwtClaims struct {
+	Exp     int64  `json:"exp"`
+	Subject string `json:"sub"`
+	Issuer  string `json:"iss"`
+}
+
+// parseJWTClaims, bearer token bir JWT ise imzayı doğrulamadan payload alanlarını okur
+func parseJWTClaims(token string) (*jwtClaims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token JWT biçiminde değil")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("JWT payload çözülemedi: %v", err)
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("JWT payload ayrıştırılamadı: %v", err)
+	}
+	return &claims, nil
+}
+
+// parseCertificates, PEM (veya base64 kodlu PEM) içindeki tüm sertifikaları döndürür
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	if !strings.Contains(string(data), "-----BEGIN") {
+		if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil {
+			data = decoded
+		}
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("sertifika ayrıştırılamadı: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("PEM sertifikası bulunamadı")
+	}
+	return certs, nil
+}
+
+// tokenExpiry, bearer token'ın süre bilgisini döndürür.
+// JWT olmayan token'lar (ör. OpenShift sha256~ token'ları) için süre bilinemez.
+func tokenExpiry(token string, now time.Time, warningDays int) CredentialExpiry {
+	item := CredentialExpiry{Kind: "token"}
+
+	claims, err := parseJWTClaims(token)
+	if err != nil {
+		item.Status = CredentialStatusUnknown
+		item.Message = fmt.Sprintf("Token süresi okunamadı: %v", err)
+		return item
+	}
+
+	item.Subject = claims.Subject
+	item.Issuer = claims.Issuer
+	if claims.Exp == 0 {
+		item.Status = CredentialStatusNoExpiry
+		item.Message = "Token içinde exp alanı yok"
+		return item
+	}
+
+	setExpiry(&item, time.Unix(claims.Exp, 0).UTC(), now, warningDays)
+	return item
+}
+
+// certificateExpiry, PEM içindeki her sertifika için süre bilgisini döndürür
+func certificateExpiry(kind string, data []byte, now time.Time, warningDays int) []CredentialExpiry {
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return []CredentialExpiry{{
+			Kind:    kind,
+			Status:  CredentialStatusUnknown,
+			Message: fmt.Sprintf("Sertifika süresi okunamadı: %v", err),
+		}}
+	}
+
+	items := make([]CredentialExpiry, 0, len(certs))
+	for _, cert := range certs {
+		item := CredentialExpiry{
+			Kind:    kind,
+			Subject: cert.Subject.String(),
+			Issuer:  cert.Issuer.String(),
+		}
+		setExpiry(&item, cert.NotAfter.UTC(), now, warningDays)
+		items = append(items, item)
+	}
+	return items
+}
+
+// setExpiry, bitiş zamanına göre kalan gün sayısını ve durumu doldurur
+func setExpiry(item *CredentialExpiry, expiresAt, now time.Time, warningDays int) {
+	daysLeft := int(expiresAt.Sub(now).Hours() / 24)
+	item.ExpiresAt = &expiresAt
+	item.DaysLeft = &daysLeft
+
+	switch {
+	case !expiresAt.After(now):
+		item.Status = CredentialStatusExpired
+	case expiresAt.Before(now.AddDate(0, 0, warningDays)):
+		item.Status = CredentialStatusExpiring
+	default:
+		item.Status = CredentialStatusValid
+	}
+}
+
+// clusterCredentialReport, kayıtlı cluster'ın token ve sertifikalarının sürelerini çıkarır
+func clusterCredentialReport(c ClusterRecord, now time.Time) CredentialReport {
+	warningDays := credentialWarningDays()
+	report := CredentialReport{
+		Credentials: []CredentialExpiry{},
+		WarningDays: warningDays,
+		Warnings:    []string{},
+	}
+
+	switch c.AuthType {
+	case "token":
+		if c.Token != "" {
+			// Referanslı token'ların süresi çözülen değerden okunur
+			token, err := secretResolver.Resolve(c.Token, defaultClusterName)
+			if err != nil {
+				report.Warnings = append(report.Warnings, err.Error())
+			} else {
+				item := tokenExpiry(token, now, warningDays)
+				if isSecretReference(c.Token) {
+					item.Source = c.Token
+				}
+				report.Credentials = append(report.Credentials, item)
+			}
+		}
+		if c.CACert != "" {
+			report.Credentials = append(report.Credentials, certificateExpiry("ca_certificate", []byte(c.CACert), now, warningDays)...)
+		}
+
+	case "kubeconfig":
+		config, err := parseKubeconfig(c.Kubeconfig)
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+			break
+		}
+		kctx, ok := config.Contexts[c.KubeContext]
+		if !ok {
+			kctx, ok = config.Contexts[config.CurrentContext]
+		}
+		if !ok {
+			break
+		}
+		if user, ok := config.AuthInfos[kctx.AuthInfo]; ok {
+			if user.Token != "" {
+				report.Credentials = append(report.Credentials, tokenExpiry(user.Token, now, warningDays))
+			}
+			if len(user.ClientCertificateData) > 0 {
+				report.Credentials = append(report.Credentials, certificateExpiry("client_certificate", user.ClientCertificateData, now, warningDays)...)
+			}
+		}
+		if cluster, ok := config.Clusters[kctx.Cluster]; ok && len(cluster.CertificateAuthorityData) > 0 {
+			report.Credentials = append(report.Credentials, certificateExpiry("ca_certificate", cluster.CertificateAuthorityData, now, warningDays)...)
+		}
+	}
+
+	sort.SliceStable(report.Credentials, func(i, j int) bool {
+		a, b := report.Credentials[i].ExpiresAt, report.Credentials[j].ExpiresAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Before(*b)
+	})
+
+	for _, item := range report.Credentials {
+		if item.ExpiresAt != nil && report.NextExpiry == nil {
+			report.NextExpiry = item.ExpiresAt
+		}
+		switch item.Status {
+		case CredentialStatusExpired:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s süresi %s tarihinde doldu", credentialLabel(item), item.ExpiresAt.Format("2006-01-02")))
+		case CredentialStatusExpiring:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s süresi %d gün içinde (%s) dolacak", credentialLabel(item), *item.DaysLeft, item.ExpiresAt.Format("2006-01-02")))
+		}
+	}
+
+	return report
+}
+
+// credentialLabel, uyarı mesajlarında kullanılacak kimlik bilgisi adı
+func credentialLabel(item CredentialExpiry) string {
+	switch item.Kind {
+	case "token":
+		return "Token"
+	case "client_certificate":
+		return "İstemci sertifikası"
+	case "ca_certificate":
+		return "CA sertifikası"
+	}
+	return item.Kind
+}
+
+// CredentialExpiryMonitor, kayıtlı bir cluster'ın kimlik bilgisi sürelerini periyodik olarak kontrol eder
+type CredentialExpiryMonitor struct {
+	db        *sql.DB
+	clusterID int
+	cluster   string
+}
+
+// NewCredentialExpiryMonitor, yeni bir kimlik bilgisi süre izleyicisi oluşturur
+func NewCredentialExpiryMonitor(db *sql.DB, clusterID int, cluster string) *CredentialExpiryMonitor {
+	return &CredentialExpiryMonitor{db: db, clusterID: clusterID, cluster: cluster}
+}
+
+// Run, context iptal edilene kadar süresi yaklaşan kimlik bilgileri için uyarı loglar
+func (m *CredentialExpiryMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(credentialCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check, kimlik bilgilerini okur ve her uyarıyı loglar
+func (m *CredentialExpiryMonitor) check() {
+	record, err := loadCluster(m.db, m.clusterID)
+	if err != nil {
+		log.Printf("%s cluster'ı kimlik bilgileri okunamadı: %v", m.cluster, err)
+		return
+	}
+
+	report := clusterCredentialReport(record, time.Now())
+	for _, warning := range report.Warnings {
+		log.Printf("UYARI: %s cluster'ı: %s", m.cluster, warning)
+	}
+}
+
+// clusterCredentialsHandler, cluster kimlik bilgilerinin sürelerini döndürür (GET)
+// veya kimlik bilgisini yeniden test ederek yerinde değiştirir (PUT)
+func clusterCredentialsHandler(w http.ResponseWriter, r *http.Request, clusterID int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	record, err := loadCluster(db, clusterID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Cluster bulunamadı"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(clusterCredentialReport(record, time.Now()))
+
+	case "PUT":
+		var req struct {
+			AuthType      string `json:"auth_type"`
+			ApiURL        string `json:"api_url"`
+			Token         string `json:"token"`
+			CACert        string `json:"ca_cert"`
+			Kubeconfig    string `json:"kubeconfig"`
+			Context       string `json:"context"`
+			SkipTLSVerify *bool  `json:"skip_tls_verify"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+
+		// Yalnızca gönderilen alanlar değiştirilir
+		updated := record
+		if req.AuthType != "" {
+			updated.AuthType = req.AuthType
+		}
+		if req.ApiURL != "" {
+			updated.APIURL = req.ApiURL
+		}
+		if req.Token != "" {
+			updated.Token = req.Token
+		}
+		if req.CACert != "" {
+			updated.CACert = req.CACert
+		}
+		if req.Kubeconfig != "" {
+			updated.Kubeconfig = req.Kubeconfig
+			updated.KubeContext = req.Context
+		}
+		if req.SkipTLSVerify != nil {
+			updated.SkipTLSVerify = *req.SkipTLSVerify
+		}
+
+		if err := validateClusterRecord(&updated); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		// Yeni kimlik bilgisi çalışmıyorsa eskisini koru
+		if connected, message := TestClusterConnection(updated); !connected {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":     "Yeni kimlik bilgisiyle bağlantı kurulamadı, değişiklik kaydedilmedi",
+				"connected": false,
+				"message":   message,
+			})
+			return
+		}
+
+		if err := updateClusterCredentials(db, updated); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		// Referanslar yeni değerlerle yeniden çözülsün
+		secretResolver.Invalidate()
+
+		// İşçileri yeni kimlik bilgisiyle yeniden başlat
+		if err := clusterManager.StartCluster(clusterID); err != nil {
+			log.Printf("Cluster %d işçileri yeniden başlatılamadı: %v", clusterID, err)
+		}
+		log.Printf("%s cluster'ının kimlik bilgisi yenilendi", updated.Name)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     "Kimlik bilgisi güncellendi",
+			"connected":   true,
+			"credentials": clusterCredentialReport(updated, time.Now()),
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// updateClusterCredentials, cluster'ın kimlik doğrulama alanlarını günceller
+func updateClusterCredentials(db *sql.DB, c ClusterRecord) error {
+	skipTLSVerify := 0
+	if c.SkipTLSVerify {
+		skipTLSVerify = 1
+	}
+
+	secrets, err := encryptClusterSecrets(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE clusters SET api_url = ?, auth_type = ?, token = ?, ca_cert = ?, skip_tls_verify = ?,
+		kubeconfig = ?, kube_context = ?, credentials_updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, c.APIURL, c.AuthType, secrets[0], secrets[1], skipTLSVerify, secrets[2], c.KubeContext, c.ID)
+	if err != nil {
+		return fmt.Errorf("Kimlik bilgisi kaydedilemedi: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJWT, imzasız bir JWT üretir; payload olduğu gibi kodlanır
+func testJWT(payload string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".imza"
+}
+
+// testCertificatePEM, verilen tarihte süresi dolan kendinden imzalı bir sertifika üretir
+func testCertificatePEM(t *testing.T, commonName string, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		token   string
+		status  string
+		days    int
+		message string
+	}{
+		{"geçerli", testJWT(fmt.Sprintf(`{"exp":%d,"sub":"system:serviceaccount:monitoring:k8s-monitor","iss":"kubernetes"}`, now.AddDate(0, 2, 0).Unix())), CredentialStatusValid, 61, ""},
+		{"dolmak üzere", testJWT(fmt.Sprintf(`{"exp":%d}`, now.AddDate(0, 0, 3).Unix())), CredentialStatusExpiring, 3, ""},
+		{"dolmuş", testJWT(fmt.Sprintf(`{"exp":%d}`, now.Add(-time.Hour).Unix())), CredentialStatusExpired, 0, ""},
+		{"exp yok", testJWT(`{"sub":"admin"}`), CredentialStatusNoExpiry, -1, "exp alanı yok"},
+		{"JWT değil", "sha256~opaque-token", CredentialStatusUnknown, -1, "JWT biçiminde değil"},
+		{"bozuk payload", "a.%%%.c", CredentialStatusUnknown, -1, "payload çözülemedi"},
+		{"JSON olmayan payload", "a." + base64.RawURLEncoding.EncodeToString([]byte("json değil")) + ".c", CredentialStatusUnknown, -1, "payload ayrıştırılamadı"},
+		{"yanlış tipte exp", testJWT(`{"exp":"yarın"}`), CredentialStatusUnknown, -1, "payload ayrıştırılamadı"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			item := tokenExpiry(c.token, now, 14)
+			if item.Kind != "token" || item.Status != c.status {
+				t.Fatalf("beklenen durum %s, alınan %+v", c.status, item)
+			}
+			if c.days < 0 {
+				if item.ExpiresAt != nil || item.DaysLeft != nil {
+					t.Errorf("süre bilinmeyen token'ın bitiş zamanı olmamalı: %+v", item)
+				}
+			} else if item.DaysLeft == nil || *item.DaysLeft != c.days {
+				t.Errorf("beklenen kalan gün %d, alınan %v", c.days, item.DaysLeft)
+			}
+			if !strings.Contains(item.Message, c.message) {
+				t.Errorf("beklenen mesaj %q, alınan %q", c.message, item.Message)
+			}
+		})
+	}
+
+	item := tokenExpiry(cases[0].token, now, 14)
+	if item.Subject != "system:serviceaccount:monitoring:k8s-monitor" || item.Issuer != "kubernetes" {
+		t.Errorf("token sahibi ve yayıncısı okunmalıydı: %+v", item)
+	}
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	leaf := testCertificatePEM(t, "admin", now.AddDate(0, 0, 10))
+	ca := testCertificatePEM(t, "kubernetes-ca", now.AddDate(5, 0, 0))
+
+	// Zincirdeki her sertifika ayrı raporlanır, sertifika olmayan bloklar atlanır
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("anahtar")})
+	items := certificateExpiry("client_certificate", append(append(key, leaf...), ca...), now, 14)
+	if len(items) != 2 {
+		t.Fatalf("iki sertifika raporlanmalıydı: %+v", items)
+	}
+	if items[0].Subject != "CN=admin" || items[0].Status != CredentialStatusExpiring || *items[0].DaysLeft != 10 {
+		t.Errorf("beklenmeyen yaprak sertifika: %+v", items[0])
+	}
+	if items[1].Subject != "CN=kubernetes-ca" || items[1].Status != CredentialStatusValid {
+		t.Errorf("beklenmeyen CA sertifikası: %+v", items[1])
+	}
+
+	// Kubeconfig'deki gibi base64 kodlu PEM de okunur
+	encoded := []byte(base64.StdEncoding.EncodeToString(leaf))
+	if items := certificateExpiry("ca_certificate", encoded, now.AddDate(0, 1, 0), 14); len(items) != 1 || items[0].Status != CredentialStatusExpired {
+		t.Errorf("base64 kodlu sertifika okunmalıydı: %+v", items)
+	}
+
+	malformed := map[string]string{
+		"boş":              "",
+		"PEM değil":        "sertifika değil",
+		"yalnızca anahtar": string(key),
+		"bozuk sertifika":  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("bozuk")})),
+	}
+	for name, data := range malformed {
+		items := certificateExpiry("ca_certificate", []byte(data), now, 14)
+		if len(items) != 1 || items[0].Status != CredentialStatusUnknown || items[0].ExpiresAt != nil ||
+			!strings.Contains(items[0].Message, "Sertifika süresi okunamadı") {
+			t.Errorf("%s: okunamayan sertifika bilinmiyor olarak raporlanmalıydı: %+v", name, items)
+		}
+	}
+}
+
+func TestClusterCredentialReportFromKubeconfig(t *testing.T) {
+	t.Setenv("CREDENTIAL_EXPIRY_WARNING_DAYS", "30")
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clientCert := testCertificatePEM(t, "admin", now.AddDate(0, 0, 20))
+	caCert := testCertificatePEM(t, "kubernetes-ca", now.AddDate(0, 0, -1))
+	token := testJWT(fmt.Sprintf(`{"exp":%d}`, now.AddDate(1, 0, 0).Unix()))
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+    certificate-authority-data: %s
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: %s
+    client-certificate-data: %s
+`, base64.StdEncoding.EncodeToString(caCert), token, base64.StdEncoding.EncodeToString(clientCert))
+
+	report := clusterCredentialReport(ClusterRecord{AuthType: "kubeconfig", Kubeconfig: kubeconfig}, now)
+	if report.WarningDays != 30 || len(report.Credentials) != 3 {
+		t.Fatalf("beklenmeyen rapor: %+v", report)
+	}
+	kinds := []string{report.Credentials[0].Kind, report.Credentials[1].Kind, report.Credentials[2].Kind}
+	if strings.Join(kinds, ",") != "ca_certificate,client_certificate,token" {
+		t.Errorf("kimlik bilgileri bitiş zamanına göre sıralanmalıydı: %v", kinds)
+	}
+	if report.NextExpiry == nil || !report.NextExpiry.Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("en yakın bitiş CA sertifikasının olmalıydı: %v", report.NextExpiry)
+	}
+	if len(report.Warnings) != 2 || !strings.Contains(report.Warnings[0], "doldu") || !strings.Contains(report.Warnings[1], "20 gün") {
+		t.Errorf("dolmuş ve dolmak üzere olan sertifikalar için uyarı verilmeliydi: %v", report.Warnings)
+	}
+
+	// Bozuk kubeconfig uyarı olarak raporlanır
+	report = clusterCredentialReport(ClusterRecord{AuthType: "kubeconfig", Kubeconfig: "yaml değil"}, now)
+	if len(report.Credentials) != 0 || len(report.Warnings) != 1 {
+		t.Errorf("bozuk kubeconfig uyarıya dönüşmeliydi: %+v", report)
+	}
+
+	// JWT olmayan token süresi bilinmiyor olarak listelenir, uyarı üretmez
+	report = clusterCredentialReport(ClusterRecord{AuthType: "token", Token: "sha256~opaque"}, now)
+	if len(report.Credentials) != 1 || report.Credentials[0].Status != CredentialStatusUnknown || len(report.Warnings) != 0 || report.NextExpiry != nil {
+		t.Errorf("beklenmeyen token raporu: %+v", report)
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// discoveryResyncPeriod, informer önbelleğinin handler'lara yeniden gönderilme aralığı.
+// Değişmeyen nesneler ResourceVersion karşılaştırmasıyla atlandığından resync ucuzdur.
+const discoveryResyncPeriod = 30 * time.Minute
+
+// ServiceDiscovery, bir cluster'daki Service nesnelerini informer ile izler
+// ve services tablosunu güncel tutar. Ingress ve OpenShift Route nesnelerinden
+// her servis için aday endpoint'ler türetir.
+type ServiceDiscovery struct {
+	db        *sql.DB
+	client    kubernetes.Interface
+	dynamic   dynamic.Interface
+	clusterID int
+	cluster   string
+
+	services  corelisters.ServiceLister
+	ingresses networkinglisters.IngressLister // yetki yoksa nil
+	routes    cache.GenericLister             // OpenShift değilse nil
+
+	rules *compiledDiscoveryRules // cluster'ın keşif kuralları
+
+	mu         sync.Mutex
+	candidates map[string]string // ns/ad -> son yazılan aday listesi (JSON)
+}
+
+// NewServiceDiscovery, yeni bir servis keşif örneği oluşturur.
+// clusterID 0 ise (varsayılan cluster) services.cluster_id boş bırakılır.
+// dynamicClient nil ise Route'lar izlenmez.
+func NewServiceDiscovery(db *sql.DB, client kubernetes.Interface, dynamicClient dynamic.Interface, clusterID int, cluster string) *ServiceDiscovery {
+	return &ServiceDiscovery{
+		db:         db,
+		client:     client,
+		dynamic:    dynamicClient,
+		clusterID:  clusterID,
+		cluster:    cluster,
+		candidates: make(map[string]string),
+	}
+}
+
+// Run, context iptal edilene kadar servis değişikliklerini izler
+func (d *ServiceDiscovery) Run(ctx context.Context) error {
+	// Kurallar değiştiğinde keşif yeniden başlatılır, bu yüzden başlangıçta bir kez okunur
+	rules, err := loadDiscoveryRules(d.db, d.cluster)
+	if err == nil {
+		d.rules, err = rules.Compile()
+	}
+	if err != nil {
+		return fmt.Errorf("keşif kuralları okunamadı: %v", err)
+	}
+
+	factory := informers.NewSharedInformerFactory(d.client, discoveryResyncPeriod)
+	informer := factory.Core().V1().Services().Informer()
+	d.services = factory.Core().V1().Services().Lister()
+	synced := []cache.InformerSynced{informer.HasSynced}
+
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				d.syncService(svc)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSvc, ok1 := oldObj.(*corev1.Service)
+			newSvc, ok2 := newObj.(*corev1.Service)
+			if !ok1 || !ok2 {
+				return
+			}
+			// Periyodik resync'te nesne değişmemişse veritabanına dokunma
+			if oldSvc.ResourceVersion == newSvc.ResourceVersion {
+				return
+			}
+			d.syncService(newSvc)
+		},
+		DeleteFunc: func(obj interface{}) {
+			// Silme olayı kaçırıldıysa nesne tombstone içinde gelir
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if svc, ok := obj.(*corev1.Service); ok {
+				d.archiveService(svc.Namespace, svc.Name, "cluster'dan silindiği için")
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("servis keşif handler'ı kaydedilemedi: %v", err)
+	}
+
+	// Ingress'ler; yetki yoksa informer sonsuza kadar senkronize olmaya çalışmasın diye önce denenir
+	if _, err := d.client.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{Limit: 1}); err == nil {
+		ingressInformer := factory.Networking().V1().Ingresses().Informer()
+		d.ingresses = factory.Networking().V1().Ingresses().Lister()
+		synced = append(synced, ingressInformer.HasSynced)
+		if _, err := ingressInformer.AddEventHandler(d.backendHandler(func(obj interface{}) (string, []string) {
+			if ing, ok := obj.(*networkingv1.Ingress); ok {
+				return ing.Namespace, ingressBackendServices(ing)
+			}
+			return "", nil
+		})); err != nil {
+			return fmt.Errorf("ingress handler'ı kaydedilemedi: %v", err)
+		}
+	} else {
+		log.Printf("%s cluster'ında Ingress'ler okunamadı, Ingress endpoint'leri türetilmeyecek: %v", d.cluster, err)
+	}
+
+	// OpenShift Route'ları; API yoksa (OpenShift olmayan cluster) atlanır
+	var dynamicFactory dynamicinformer.DynamicSharedInformerFactory
+	if d.dynamic != nil {
+		if _, err := d.dynamic.Resource(routeGVR).List(ctx, metav1.ListOptions{Limit: 1}); err == nil {
+			dynamicFactory = dynamicinformer.NewDynamicSharedInformerFactory(d.dynamic, discoveryResyncPeriod)
+			routeInformer := dynamicFactory.ForResource(routeGVR)
+			d.routes = routeInformer.Lister()
+			synced = append(synced, routeInformer.Informer().HasSynced)
+			if _, err := routeInformer.Informer().AddEventHandler(d.backendHandler(func(obj interface{}) (string, []string) {
+				if route, ok := obj.(*unstructured.Unstructured); ok {
+					return route.GetNamespace(), routeBackendServices(route)
+				}
+				return "", nil
+			})); err != nil {
+				return fmt.Errorf("route handler'ı kaydedilemedi: %v", err)
+			}
+		}
+	}
+
+	factory.Start(ctx.Done())
+	if dynamicFactory != nil {
+		dynamicFactory.Start(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("%s cluster'ı için servis önbelleği senkronize edilemedi", d.cluster)
+	}
+
+	// Uygulama kapalıyken silinen servisleri de arşivle
+	services, err := factory.Core().V1().Services().Lister().List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("önbellekteki servisler listelenemedi: %v", err)
+	}
+	if err := d.archiveMissing(services); err != nil {
+		log.Printf("%s cluster'ında silinmiş servisler arşivlenemedi: %v", d.cluster, err)
+	}
+
+	log.Printf("%s cluster'ı için servis keşfi başlatıldı (%d servis)", d.cluster, len(services))
+
+	<-ctx.Done()
+	factory.Shutdown()
+	if dynamicFactory != nil {
+		dynamicFactory.Shutdown()
+	}
+	log.Printf("%s cluster'ı için servis keşfi durduruldu", d.cluster)
+	return nil
+}
+
+// syncService, keşif kurallarına uyan servisi kaydeder; uymayanı (ör. label'ı değişen) arşivler
+func (d *ServiceDiscovery) syncService(svc *corev1.Service) {
+	if ok, reason := d.rules.Match(svc); !ok {
+		d.archiveService(svc.Namespace, svc.Name, "keşif kurallarına uymadığı için ("+reason+")")
+		return
+	}
+	d.upsertService(svc)
+	// Servis nesnesi değişti (veya satır yeni eklendi); endpoint'ler önbellekten bağımsız yeniden yazılır
+	d.forgetEndpoints(svc.Namespace, svc.Name)
+	d.updateEndpoints(svc.Namespace, svc.Name)
+}
+
+// forgetEndpoints, servisin son yazılan aday listesini unutur; sonraki updateEndpoints veritabanına yazar
+func (d *ServiceDiscovery) forgetEndpoints(namespace, name string) {
+	d.mu.Lock()
+	delete(d.candidates, namespace+"/"+name)
+	d.mu.Unlock()
+}
+
+// upsertService, servisi veritabanına ekler veya günceller.
+// Daha önce arşivlenmiş bir servis yeniden oluşturulduysa arşivden çıkarılır.
+func (d *ServiceDiscovery) upsertService(svc *corev1.Service) {
+	// Olay ilişkilendirmesi için seçiciyi sakla
+	var selector sql.NullString
+	if len(svc.Spec.Selector) > 0 {
+		if data, err := json.Marshal(svc.Spec.Selector); err == nil {
+			selector = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+
+	// Bildirim yönlendirmesi için label'ları sakla
+	var serviceLabels sql.NullString
+	if len(svc.Labels) > 0 {
+		if data, err := json.Marshal(svc.Labels); err == nil {
+			serviceLabels = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+
+	clusterID := sql.NullInt64{Int64: int64(d.clusterID), Valid: d.clusterID > 0}
+
+	// Eski keşif döngüsünün eklediği satırlar source kolonu eklenirken 'manual' değerini aldı;
+	// endpoint'i hiç girilmemiş veya keşiften türetilmiş olanlar keşif kaydı sayılır ki
+	// cluster'dan silindiklerinde arşivlenebilsinler
+	_, err := d.db.Exec(`
+		INSERT INTO services (name, namespace, cluster, cluster_id, type, selector, labels, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'discovery')
+		ON CONFLICT(name, namespace, cluster) DO UPDATE SET
+		cluster_id = excluded.cluster_id, type = excluded.type, selector = excluded.selector,
+		labels = excluded.labels, archived_at = NULL, updated_at = CURRENT_TIMESTAMP,
+		source = CASE
+			WHEN source = 'manual' AND (endpoint IS NULL OR endpoint_source IN ('auto', 'annotation')) THEN 'discovery'
+			ELSE source
+		END
+	`, svc.Name, svc.Namespace, d.cluster, clusterID, "service", selector, serviceLabels)
+	if err != nil {
+		log.Printf("Servis %s/%s veritabanına eklenemedi: %v", svc.Namespace, svc.Name, err)
+	}
+}
+
+// backendHandler, Ingress veya Route değiştiğinde yönlendirdiği servislerin
+// aday endpoint'lerini yeniden hesaplayan bir handler döndürür
+func (d *ServiceDiscovery) backendHandler(backends func(obj interface{}) (string, []string)) cache.ResourceEventHandler {
+	refresh := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		namespace, names := backends(obj)
+		for _, name := range names {
+			d.updateEndpoints(namespace, name)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: refresh,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// Eski ve yeni backend'lerin ikisi de güncellenmeli
+			refresh(oldObj)
+			refresh(newObj)
+		},
+		DeleteFunc: refresh,
+	}
+}
+
+// updateEndpoints, servisin aday endpoint'lerini önbellekteki Service, Ingress ve Route
+// nesnelerinden yeniden hesaplar ve değiştiyse veritabanına yazar
+func (d *ServiceDiscovery) updateEndpoints(namespace, name string) {
+	svc, err := d.services.Services(namespace).Get(name)
+	if err != nil {
+		return
+	}
+
+	var ingresses []*networkingv1.Ingress
+	if d.ingresses != nil {
+		ingresses, _ = d.ingresses.Ingresses(namespace).List(labels.Everything())
+	}
+	var routes []*unstructured.Unstructured
+	if d.routes != nil {
+		objs, _ := d.routes.ByNamespace(namespace).List(labels.Everything())
+		for _, obj := range objs {
+			if route, ok := obj.(*unstructured.Unstructured); ok {
+				routes = append(routes, route)
+			}
+		}
+	}
+
+	candidates := deriveEndpoints(svc, ingresses, routes)
+
+	// k8s-monitoring/* annotation'ları: açık bir kontrol adresi tanımlıyorsa ilk aday olur
+	ann := serviceMonitoringAnnotations(svc, ingresses)
+	endpoint := annotationEndpoint(svc, ann, candidates)
+	if endpoint != "" {
+		candidates = append([]DerivedEndpoint{{Endpoint: endpoint, Source: EndpointSourceAnnotation, Object: svc.Name}}, candidates...)
+	}
+	fields := annotationFields(ann, endpoint)
+
+	data, _ := json.Marshal(candidates)
+	annData, _ := json.Marshal(ann)
+
+	// Ingress/Route resync'lerinde aday listesi ve annotation'lar değişmediyse veritabanına
+	// dokunulmaz; kilitli alanlar arayüzden zaten değiştirilemez (enforceAnnotationLocks)
+	key := namespace + "/" + name
+	written := string(data) + string(annData)
+	d.mu.Lock()
+	unchanged := d.candidates[key] == written
+	d.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	state, err := applyMonitoringAnnotations(d.db, d.cluster, namespace, name, ann, fields)
+	if err != nil {
+		log.Printf("Servis %s/%s annotation'ları uygulanamadı: %v", namespace, name, err)
+		return
+	}
+
+	enabled := state.autoEndpoints(autoEndpointsEnabled(d.db, d.cluster, namespace)) || endpoint != ""
+	if err := applyDerivedEndpoints(d.db, d.cluster, namespace, name, candidates, enabled, endpoint != "" && ann.Lock); err != nil {
+		log.Printf("Servis %s/%s endpoint'leri güncellenemedi: %v", namespace, name, err)
+		return
+	}
+
+	d.mu.Lock()
+	d.candidates[key] = written
+	d.mu.Unlock()
+
+	// Yeni endpoint'lerin izlenmeye başlaması için uptime monitor'ü yeniden yükle
+	if uptimeMonitor != nil {
+		uptimeMonitor.RequestReload()
+	}
+}
+
+// archiveService, cluster'dan silinen veya keşif kurallarına artık uymayan servisi geçmişiyle
+// birlikte arşivler. reason arşivleme nedenini loglar.
+func (d *ServiceDiscovery) archiveService(namespace, name, reason string) {
+	result, err := d.db.Exec(`
+		UPDATE services SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND namespace = ? AND cluster = ? AND source = 'discovery' AND archived_at IS NULL
+	`, name, namespace, d.cluster)
+	if err != nil {
+		log.Printf("Servis %s/%s arşivlenemedi: %v", namespace, name, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("Servis %s/%s %s arşivlendi", namespace, name, reason)
+		// Arşivlenen servisin kontrolleri durur ve açık alarmı kapanır
+		if uptimeMonitor != nil {
+			uptimeMonitor.RequestReload()
+		}
+	}
+}
+
+// archiveMissing, veritabanında olup cluster'da artık bulunmayan keşfedilmiş servisleri arşivler
+func (d *ServiceDiscovery) archiveMissing(services []*corev1.Service) error {
+	// Kurallara uymayan servisler de cluster'da yokmuş gibi arşivlenir
+	existing := make(map[string]bool, len(services))
+	excluded := map[string]string{}
+	for _, svc := range services {
+		key := svc.Namespace + "/" + svc.Name
+		if ok, reason := d.rules.Match(svc); ok {
+			existing[key] = true
+		} else {
+			excluded[key] = reason
+		}
+	}
+
+	missing, err := d.unknownServices(existing)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range missing {
+		reason := "cluster'da bulunmadığı için"
+		if r, ok := excluded[m[0]+"/"+m[1]]; ok {
+			reason = "keşif kurallarına uymadığı için (" + r + ")"
+		}
+		d.archiveService(m[0], m[1], reason)
+	}
+	return nil
+}
+
+// unknownServices, bu cluster'a ait olup verilen kümede bulunmayan keşfedilmiş servisleri döndürür
+func (d *ServiceDiscovery) unknownServices(existing map[string]bool) ([][2]string, error) {
+	rows, err := d.db.Query(`
+		SELECT name, namespace FROM services
+		WHERE cluster = ? AND source = 'discovery' AND archived_at IS NULL
+	`, d.cluster)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing [][2]string
+	for rows.Next() {
+		var name, namespace string
+		if err := rows.Scan(&name, &namespace); err != nil {
+			return nil, err
+		}
+		if !existing[namespace+"/"+name] {
+			missing = append(missing, [2]string{namespace, name})
+		}
+	}
+	return missing, rows.Err()
+}
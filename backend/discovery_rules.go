@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// serviceTypeHeadless, ClusterIP'si olmayan servisler için kullanılan tür filtresi
+const serviceTypeHeadless = "Headless"
+
+// DiscoveryRules, bir cluster için hangi servislerin keşif ile içe aktarılacağını belirler.
+// Include listeleri boşsa her şey dahildir; exclude listeleri include'dan önceliklidir.
+type DiscoveryRules struct {
+	IncludeNamespaces []string `json:"include_namespaces"` // regex
+	ExcludeNamespaces []string `json:"exclude_namespaces"` // regex
+	IncludeNames      []string `json:"include_names"`      // regex
+	ExcludeNames      []string `json:"exclude_names"`      // regex
+	LabelSelector     string   `json:"label_selector"`     // ör. "app.kubernetes.io/part-of=shop,tier!=internal"
+	ExcludeTypes      []string `json:"exclude_types"`      // ClusterIP, NodePort, LoadBalancer, ExternalName, Headless
+}
+
+// compiledDiscoveryRules, derlenmiş keşif kuralları
+type compiledDiscoveryRules struct {
+	includeNamespaces, excludeNamespaces []*regexp.Regexp
+	includeNames, excludeNames           []*regexp.Regexp
+	selector                             labels.Selector
+	excludeTypes                         map[string]bool
+}
+
+// compileRegexps, regex listesini derler
+func compileRegexps(field string, patterns []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile("^(?:" + p + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%s içinde geçersiz regex %q: %v", field, p, err)
+		}
+		result = append(result, re)
+	}
+	return result, nil
+}
+
+// Compile, kuralları doğrular ve derler
+func (r DiscoveryRules) Compile() (*compiledDiscoveryRules, error) {
+	c := &compiledDiscoveryRules{selector: labels.Everything(), excludeTypes: map[string]bool{}}
+
+	var err error
+	if c.includeNamespaces, err = compileRegexps("include_namespaces", r.IncludeNamespaces); err != nil {
+		return nil, err
+	}
+	if c.excludeNamespaces, err = compileRegexps("exclude_namespaces", r.ExcludeNamespaces); err != nil {
+		return nil, err
+	}
+	if c.includeNames, err = compileRegexps("include_names", r.IncludeNames); err != nil {
+		return nil, err
+	}
+	if c.excludeNames, err = compileRegexps("exclude_names", r.ExcludeNames); err != nil {
+		return nil, err
+	}
+	if r.LabelSelector != "" {
+		if c.selector, err = labels.Parse(r.LabelSelector); err != nil {
+			return nil, fmt.Errorf("geçersiz label_selector: %v", err)
+		}
+	}
+	for _, t := range r.ExcludeTypes {
+		switch t {
+		case string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort),
+			string(corev1.ServiceTypeLoadBalancer), string(corev1.ServiceTypeExternalName), serviceTypeHeadless:
+			c.excludeTypes[t] = true
+		default:
+			return nil, fmt.Errorf("geçersiz servis türü: %s", t)
+		}
+	}
+	return c, nil
+}
+
+// matchAny, değerin listedeki bir regex ile eşleşip eşleşmediğini döndürür
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, re := range patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match, servisin içe aktarılıp aktarılmayacağını ve aktarılmıyorsa nedenini döndürür
+func (c *compiledDiscoveryRules) Match(svc *corev1.Service) (bool, string) {
+	if len(c.includeNamespaces) > 0 && !matchAny(c.includeNamespaces, svc.Namespace) {
+		return false, "namespace include listesinde değil"
+	}
+	if matchAny(c.excludeNamespaces, svc.Namespace) {
+		return false, "namespace exclude listesinde"
+	}
+	if len(c.includeNames) > 0 && !matchAny(c.includeNames, svc.Name) {
+		return false, "ad include listesinde değil"
+	}
+	if matchAny(c.excludeNames, svc.Name) {
+		return false, "ad exclude listesinde"
+	}
+	if !c.selector.Matches(labels.Set(svc.Labels)) {
+		return false, "label seçicisiyle eşleşmiyor"
+	}
+
+	serviceType := string(svc.Spec.Type)
+	if serviceType == "" {
+		serviceType = string(corev1.ServiceTypeClusterIP)
+	}
+	if c.excludeTypes[serviceType] {
+		return false, serviceType + " türü hariç tutuluyor"
+	}
+	if svc.Spec.ClusterIP == corev1.ClusterIPNone && c.excludeTypes[serviceTypeHeadless] {
+		return false, "headless servisler hariç tutuluyor"
+	}
+	return true, ""
+}
+
+// loadDiscoveryRules, cluster'ın kayıtlı keşif kurallarını okur. Kural yoksa tüm servisler dahildir.
+func loadDiscoveryRules(db *sql.DB, cluster string) (DiscoveryRules, error) {
+	var rules DiscoveryRules
+	var data string
+	err := db.QueryRow("SELECT rules FROM discovery_rules WHERE cluster = ?", cluster).Scan(&data)
+	if err == sql.ErrNoRows {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, err
+	}
+	return rules, json.Unmarshal([]byte(data), &rules)
+}
+
+// DiscoveryPreview, bir kural kümesinin cluster'daki servislere etkisi
+type DiscoveryPreview struct {
+	Import   []string          `json:"import"`   // yeni eklenecek veya arşivden çıkacak servisler
+	Keep     []string          `json:"keep"`     // zaten izlenen ve kalacak servisler
+	Archive  []string          `json:"archive"`  // kurala uymadığı için arşivlenecek servisler
+	Excluded map[string]string `json:"excluded"` // ns/ad -> hariç tutulma nedeni
+}
+
+// previewDiscoveryRules, kuralları kaydetmeden hangi servislerin içe aktarılacağını veya arşivleneceğini hesaplar
+func previewDiscoveryRules(db *sql.DB, cluster string, services []corev1.Service, rules *compiledDiscoveryRules) (DiscoveryPreview, error) {
+	preview := DiscoveryPreview{Import: []string{}, Keep: []string{}, Archive: []string{}, Excluded: map[string]string{}}
+
+	rows, err := db.Query(`
+		SELECT name, namespace FROM services
+		WHERE cluster = ? AND source = 'discovery' AND archived_at IS NULL
+	`, cluster)
+	if err != nil {
+		return preview, err
+	}
+	active := map[string]bool{}
+	for rows.Next() {
+		var name, namespace string
+		if err := rows.Scan(&name, &namespace); err == nil {
+			active[namespace+"/"+name] = true
+		}
+	}
+	rows.Close()
+
+	for i := range services {
+		svc := &services[i]
+		key := svc.Namespace + "/" + svc.Name
+		ok, reason := rules.Match(svc)
+		switch {
+		case !ok:
+			preview.Excluded[key] = reason
+			if active[key] {
+				preview.Archive = append(preview.Archive, key)
+			}
+		case active[key]:
+			preview.Keep = append(preview.Keep, key)
+		default:
+			preview.Import = append(preview.Import, key)
+		}
+	}
+
+	sort.Strings(preview.Import)
+	sort.Strings(preview.Keep)
+	sort.Strings(preview.Archive)
+	return preview, nil
+}
+
+// discoveryRulesHandler, cluster bazlı keşif kurallarını listeler (GET), kaydeder (PUT) ve siler (DELETE)
+func discoveryRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	cluster := r.URL.Query().Get("cluster")
+	if cluster == "" {
+		cluster = defaultClusterName
+	}
+	// Kurallar cluster adıyla saklanır; ID verildiyse ada çevrilir
+	if clusterManager != nil {
+		if _, name, err := clusterManager.Client(cluster); err == nil {
+			cluster = name
+		}
+	}
+
+	switch r.Method {
+	case "GET":
+		rows, err := db.Query("SELECT cluster, rules, updated_at FROM discovery_rules ORDER BY cluster")
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		result := []map[string]interface{}{}
+		for rows.Next() {
+			var name, data string
+			var updatedAt sql.NullTime
+			if err := rows.Scan(&name, &data, &updatedAt); err != nil {
+				continue
+			}
+			// ?cluster= ID olarak da verilebilir, karşılaştırma çözülmüş adla yapılır
+			if r.URL.Query().Get("cluster") != "" && name != cluster {
+				continue
+			}
+			var rules DiscoveryRules
+			json.Unmarshal([]byte(data), &rules)
+			result = append(result, map[string]interface{}{
+				"cluster":    name,
+				"rules":      rules,
+				"updated_at": updatedAt.Time,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"rules": result})
+
+	case "PUT":
+		var rules DiscoveryRules
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		if _, err := rules.Compile(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		data, _ := json.Marshal(rules)
+		_, err := db.Exec(`
+			INSERT INTO discovery_rules (cluster, rules) VALUES (?, ?)
+			ON CONFLICT(cluster) DO UPDATE SET rules = excluded.rules, updated_at = CURRENT_TIMESTAMP
+		`, cluster, string(data))
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Keşif kuralları kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		restartDiscovery(cluster)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Keşif kuralları güncellendi",
+			"cluster": cluster,
+			"rules":   rules,
+		})
+
+	case "DELETE":
+		if _, err := db.Exec("DELETE FROM discovery_rules WHERE cluster = ?", cluster); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Keşif kuralları silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		restartDiscovery(cluster)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Keşif kuralları silindi, tüm servisler içe aktarılacak",
+			"cluster": cluster,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// discoveryRulesDryRunHandler, gönderilen kuralların etkisini kaydetmeden gösterir (POST)
+func discoveryRulesDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var rules DiscoveryRules
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+		return
+	}
+	compiled, err := rules.Compile()
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	client, cluster, err := clusterManager.Client(r.URL.Query().Get("cluster"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	list, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Servisler listelenemedi: %v"}`, err), http.StatusBadGateway)
+		return
+	}
+
+	preview, err := previewDiscoveryRules(db, cluster, list.Items, compiled)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cluster":        cluster,
+		"total":          len(list.Items),
+		"import":         preview.Import,
+		"keep":           preview.Keep,
+		"archive":        preview.Archive,
+		"excluded":       preview.Excluded,
+		"excluded_count": len(preview.Excluded),
+	})
+}
+
+// restartDiscovery, yeni kuralların uygulanması için cluster'ın servis keşfini yeniden başlatır.
+// Keşif yeniden senkronize olurken kurala uymayan servisler arşivlenir.
+func restartDiscovery(cluster string) {
+	if clusterManager == nil {
+		return
+	}
+	if !clusterManager.RestartDiscovery(cluster) {
+		log.Printf("%s cluster'ı çalışmıyor, keşif kuralları bir sonraki başlatmada uygulanacak", cluster)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiscoveryRulesCompileRejectsInvalidRules(t *testing.T) {
+	cases := map[string]DiscoveryRules{
+		"namespace regex": {IncludeNamespaces: []string{"shop-("}},
+		"ad regex":        {ExcludeNames: []string{"["}},
+		"label seçici":    {LabelSelector: "tier in (web"},
+		"servis türü":     {ExcludeTypes: []string{"Ingress"}},
+	}
+	for name, rules := range cases {
+		if _, err := rules.Compile(); err == nil {
+			t.Errorf("%s: geçersiz kural kabul edildi", name)
+		}
+	}
+	if _, err := (DiscoveryRules{ExcludeTypes: []string{"NodePort", "Headless"}, LabelSelector: "tier!=internal"}).Compile(); err != nil {
+		t.Errorf("geçerli kural reddedildi: %v", err)
+	}
+}
+
+func TestDiscoveryRulesMatch(t *testing.T) {
+	rules, err := DiscoveryRules{
+		IncludeNamespaces: []string{"shop-.*", "billing"},
+		ExcludeNamespaces: []string{"shop-dev"},
+		ExcludeNames:      []string{".*-canary"},
+		LabelSelector:     "tier!=internal",
+		ExcludeTypes:      []string{"NodePort", "Headless"},
+	}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodePort := newService("shop-prod", "admin", nil)
+	nodePort.Spec.Type = corev1.ServiceTypeNodePort
+	headless := newService("billing", "db", nil)
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+
+	cases := []struct {
+		svc    *corev1.Service
+		ok     bool
+		reason string
+	}{
+		{newService("shop-prod", "api", nil), true, ""},
+		{newService("billing", "invoices", map[string]string{"tier": "web"}), true, ""},
+		// Regex'ler tam eşleşmelidir
+		{newService("billing-archive", "api", nil), false, "namespace include listesinde değil"},
+		{newService("kube-system", "dns", nil), false, "namespace include listesinde değil"},
+		{newService("shop-dev", "api", nil), false, "namespace exclude listesinde"},
+		{newService("shop-prod", "api-canary", nil), false, "ad exclude listesinde"},
+		{newService("shop-prod", "metrics", map[string]string{"tier": "internal"}), false, "label seçicisiyle eşleşmiyor"},
+		{nodePort, false, "NodePort türü hariç tutuluyor"},
+		{headless, false, "headless servisler hariç tutuluyor"},
+	}
+	for _, c := range cases {
+		ok, reason := rules.Match(c.svc)
+		if ok != c.ok || reason != c.reason {
+			t.Errorf("%s/%s: beklenen %v %q, alınan %v %q", c.svc.Namespace, c.svc.Name, c.ok, c.reason, ok, reason)
+		}
+	}
+
+	// Boş kurallar her şeyi içe aktarır
+	all, _ := DiscoveryRules{}.Compile()
+	if ok, _ := all.Match(headless); !ok {
+		t.Errorf("kural yokken tüm servisler dahil olmalıydı")
+	}
+}
+
+func TestPreviewDiscoveryRules(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('api', 'shop', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('worker', 'batch', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, archived_at) VALUES ('old', 'shop', 'prod', 'service', 'discovery', CURRENT_TIMESTAMP)`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('cron', 'batch', 'staging', 'service', 'discovery')`)
+
+	rules, _ := DiscoveryRules{ExcludeNamespaces: []string{"batch"}}.Compile()
+	services := []corev1.Service{
+		*newService("shop", "api", nil),
+		*newService("shop", "web", nil),
+		*newService("shop", "old", nil),
+		*newService("batch", "worker", nil),
+		*newService("batch", "cron", nil),
+	}
+	preview, err := previewDiscoveryRules(testDB, "prod", services, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DiscoveryPreview{
+		Import:  []string{"shop/old", "shop/web"},
+		Keep:    []string{"shop/api"},
+		Archive: []string{"batch/worker"},
+		Excluded: map[string]string{
+			"batch/worker": "namespace exclude listesinde",
+			"batch/cron":   "namespace exclude listesinde",
+		},
+	}
+	if !reflect.DeepEqual(preview, want) {
+		t.Errorf("beklenen %+v, alınan %+v", want, preview)
+	}
+}
+
+func TestDiscoveryRulesHandlerRestartsOnlyDiscovery(t *testing.T) {
+	testDB := newTestDB(t)
+	previousDB, previousManager := db, clusterManager
+	db = testDB
+	ctx, cancel := context.WithCancel(context.Background())
+	clusterManager = NewClusterManager(ctx, testDB)
+	t.Cleanup(func() {
+		cancel()
+		clusterManager.StopAll()
+		db, clusterManager = previousDB, previousManager
+	})
+
+	clusterManager.start(3, "prod", fake.NewSimpleClientset(newService("shop", "api", nil), newService("billing", "invoices", nil)), nil)
+	archived := func(name string) bool {
+		var archived bool
+		testDB.QueryRow(`SELECT archived_at IS NOT NULL FROM services WHERE name = ? AND cluster = 'prod'`, name).Scan(&archived)
+		return archived
+	}
+	eventually(t, "servislerin keşfedilmesi", func() bool {
+		var count int
+		testDB.QueryRow(`SELECT COUNT(*) FROM services WHERE cluster = 'prod' AND archived_at IS NULL`).Scan(&count)
+		return count == 2
+	})
+
+	clusterManager.mu.RLock()
+	before := clusterManager.clusters[3]
+	clusterManager.mu.RUnlock()
+
+	// Kurallar cluster ID'si ile kaydedilir, adla saklanır
+	rec := httptest.NewRecorder()
+	discoveryRulesHandler(rec, httptest.NewRequest("PUT", "/api/v1/discovery-rules?cluster=3",
+		strings.NewReader(`{"exclude_namespaces":["billing"]}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("kurallar kaydedilemedi: %d %s", rec.Code, rec.Body)
+	}
+	eventually(t, "kurala uymayan servisin arşivlenmesi", func() bool { return archived("invoices") })
+	if archived("api") {
+		t.Errorf("kurala uyan servis arşivlenmemeliydi")
+	}
+
+	// Yalnızca keşif yeniden başlatılır, cluster'ın diğer işçileri aynı çalışma zamanında kalır
+	clusterManager.mu.RLock()
+	after := clusterManager.clusters[3]
+	clusterManager.mu.RUnlock()
+	if after != before {
+		t.Errorf("kural değişikliği tüm cluster işçilerini yeniden başlatmamalıydı")
+	}
+
+	for _, query := range []string{"3", "prod"} {
+		rec = httptest.NewRecorder()
+		discoveryRulesHandler(rec, httptest.NewRequest("GET", "/api/v1/discovery-rules?cluster="+query, nil))
+		var resp struct {
+			Rules []struct {
+				Cluster string         `json:"cluster"`
+				Rules   DiscoveryRules `json:"rules"`
+			} `json:"rules"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if len(resp.Rules) != 1 || resp.Rules[0].Cluster != "prod" || !reflect.DeepEqual(resp.Rules[0].Rules.ExcludeNamespaces, []string{"billing"}) {
+			t.Errorf("?cluster=%s kuralları döndürmeliydi: %s", query, rec.Body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	discoveryRulesHandler(rec, httptest.NewRequest("DELETE", "/api/v1/discovery-rules?cluster=prod", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("kurallar silinemedi: %d %s", rec.Code, rec.Body)
+	}
+	eventually(t, "kurallar silinince servisin arşivden çıkması", func() bool { return !archived("invoices") })
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// eventually, koşul sağlanana kadar en fazla 5 saniye bekler
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("zaman aşımı: %s", what)
+}
+
+func newService(namespace, name string, labels map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: corev1.ServiceSpec{
+			Selector:  map[string]string{"app": name},
+			ClusterIP: "10.0.0.1",
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+}
+
+// serviceState, servisin kaynak ve arşiv durumunu okur
+func serviceState(t *testing.T, testDB *sql.DB, namespace, name string) (source string, archived bool, found bool) {
+	t.Helper()
+	var archivedAt sql.NullTime
+	err := testDB.QueryRow(`SELECT source, archived_at FROM services WHERE name = ? AND namespace = ? AND cluster = 'prod'`,
+		name, namespace).Scan(&source, &archivedAt)
+	if err == sql.ErrNoRows {
+		return "", false, false
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return source, archivedAt.Valid, true
+}
+
+func TestServiceDiscoveryImportsAndArchivesServices(t *testing.T) {
+	testDB := newTestDB(t)
+	// Eski keşif döngüsünden kalan satırlar 'manual' olarak işaretlenmişti
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('legacy', 'shop', 'prod', 'service', 'manual')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('gone', 'shop', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, endpoint, endpoint_source)
+		VALUES ('external', 'shop', 'prod', 'service', 'manual', 'https://example.com/health', 'manual')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, endpoint, endpoint_source)
+		VALUES ('custom', 'shop', 'prod', 'service', 'manual', 'https://custom.example.com', 'manual')`)
+
+	client := fake.NewSimpleClientset(
+		newService("shop", "api", map[string]string{"tier": "web"}),
+		newService("shop", "legacy", nil),
+		newService("shop", "custom", nil),
+	)
+	discovery := NewServiceDiscovery(testDB, client, nil, 0, "prod")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- discovery.Run(ctx) }()
+
+	eventually(t, "cluster'da olmayan keşif kaydının arşivlenmesi", func() bool {
+		_, archived, _ := serviceState(t, testDB, "shop", "gone")
+		return archived
+	})
+	eventually(t, "cluster'daki servislerin kaydedilmesi", func() bool {
+		legacy, _, _ := serviceState(t, testDB, "shop", "legacy")
+		_, _, found := serviceState(t, testDB, "shop", "api")
+		return legacy == "discovery" && found
+	})
+
+	if source, archived, found := serviceState(t, testDB, "shop", "api"); !found || source != "discovery" || archived {
+		t.Errorf("yeni servis keşif kaydı olarak eklenmeliydi: %s %v %v", source, archived, found)
+	}
+	var labels sql.NullString
+	testDB.QueryRow(`SELECT labels FROM services WHERE name = 'api'`).Scan(&labels)
+	if labels.String != `{"tier":"web"}` {
+		t.Errorf("servis label'ları saklanmalıydı: %q", labels.String)
+	}
+	if source, _, _ := serviceState(t, testDB, "shop", "legacy"); source != "discovery" {
+		t.Errorf("endpoint'i girilmemiş eski kayıt keşif kaydına dönüşmeliydi: %s", source)
+	}
+	if source, _, _ := serviceState(t, testDB, "shop", "custom"); source != "manual" {
+		t.Errorf("elle endpoint girilmiş kayıt elle eklenmiş kalmalıydı: %s", source)
+	}
+	if _, archived, _ := serviceState(t, testDB, "shop", "external"); archived {
+		t.Errorf("elle eklenen servis arşivlenmemeliydi")
+	}
+
+	// Silinen servis geçmişiyle arşivlenir, yeniden oluşturulunca arşivden çıkar
+	if err := client.CoreV1().Services("shop").Delete(ctx, "api", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "silinen servisin arşivlenmesi", func() bool {
+		_, archived, _ := serviceState(t, testDB, "shop", "api")
+		return archived
+	})
+	if _, err := client.CoreV1().Services("shop").Create(ctx, newService("shop", "api", nil), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "yeniden oluşturulan servisin arşivden çıkması", func() bool {
+		_, archived, _ := serviceState(t, testDB, "shop", "api")
+		return !archived
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("keşif hatayla durdu: %v", err)
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// routeGVR, OpenShift Route kaynağının grup/sürüm/kaynak bilgisi
+var routeGVR = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// Türetilmiş endpoint kaynakları
+const (
+	EndpointSourceRoute      = "route"
+	EndpointSourceIngress    = "ingress"
+	EndpointSourceClusterIP  = "cluster_ip"
+	EndpointSourceAnnotation = "annotation"
+)
+
+// DerivedEndpoint, keşif sırasında Kubernetes nesnelerinden türetilen aday endpoint
+type DerivedEndpoint struct {
+	Endpoint string `json:"endpoint"`
+	Source   string `json:"source"` // route, ingress, cluster_ip, annotation
+	Object   string `json:"object"` // endpoint'in türetildiği nesnenin adı
+	TLS      bool   `json:"tls"`
+}
+
+// deriveEndpoints, bir servis için aday endpoint'leri döndürür. Dışarıdan erişilebilen
+// HTTP(S) adresleri (Route, Ingress) önce, cluster içi ClusterIP:port (TCP) adresleri sonra gelir;
+// varsayılan endpoint listedeki ilk adaydır.
+func deriveEndpoints(svc *corev1.Service, ingresses []*networkingv1.Ingress, routes []*unstructured.Unstructured) []DerivedEndpoint {
+	candidates := []DerivedEndpoint{}
+	seen := map[string]bool{}
+	add := func(e DerivedEndpoint) {
+		if !seen[e.Endpoint] {
+			seen[e.Endpoint] = true
+			candidates = append(candidates, e)
+		}
+	}
+
+	// OpenShift Route'ları
+	sortedRoutes := append([]*unstructured.Unstructured(nil), routes...)
+	sort.Slice(sortedRoutes, func(i, j int) bool { return sortedRoutes[i].GetName() < sortedRoutes[j].GetName() })
+	for _, route := range sortedRoutes {
+		if route.GetNamespace() != svc.Namespace || !containsString(routeBackendServices(route), svc.Name) {
+			continue
+		}
+		host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+		if host == "" {
+			continue
+		}
+		path, _, _ := unstructured.NestedString(route.Object, "spec", "path")
+		_, tls, _ := unstructured.NestedMap(route.Object, "spec", "tls")
+		add(DerivedEndpoint{
+			Endpoint: endpointURL(host, path, tls),
+			Source:   EndpointSourceRoute,
+			Object:   route.GetName(),
+			TLS:      tls,
+		})
+	}
+
+	// Ingress kuralları
+	sortedIngresses := append([]*networkingv1.Ingress(nil), ingresses...)
+	sort.Slice(sortedIngresses, func(i, j int) bool { return sortedIngresses[i].Name < sortedIngresses[j].Name })
+	for _, ing := range sortedIngresses {
+		if ing.Namespace != svc.Namespace {
+			continue
+		}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == "" || rule.HTTP == nil {
+				continue
+			}
+			for _, p := range rule.HTTP.Paths {
+				if p.Backend.Service == nil || p.Backend.Service.Name != svc.Name {
+					continue
+				}
+				tls := ingressHostHasTLS(ing, rule.Host)
+				add(DerivedEndpoint{
+					Endpoint: endpointURL(rule.Host, p.Path, tls),
+					Source:   EndpointSourceIngress,
+					Object:   ing.Name,
+					TLS:      tls,
+				})
+			}
+		}
+	}
+
+	// ClusterIP:port (yalnızca TCP portları)
+	if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		for _, port := range svc.Spec.Ports {
+			if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+				continue
+			}
+			add(DerivedEndpoint{
+				Endpoint: fmt.Sprintf("%s:%d", svc.Spec.ClusterIP, port.Port),
+				Source:   EndpointSourceClusterIP,
+				Object:   svc.Name,
+			})
+		}
+	}
+
+	return candidates
+}
+
+// endpointURL, host ve path'ten HTTP(S) adresi oluşturur
+func endpointURL(host, path string, tls bool) string {
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// ingressHostHasTLS, Ingress'in verilen host için TLS tanımı olup olmadığını döndürür
+func ingressHostHasTLS(ing *networkingv1.Ingress, host string) bool {
+	for _, tls := range ing.Spec.TLS {
+		// Host listesi boş TLS tanımı tüm host'lar için geçerlidir
+		if len(tls.Hosts) == 0 || containsString(tls.Hosts, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressBackendServices, Ingress'in yönlendirdiği servis adlarını döndürür
+func ingressBackendServices(ing *networkingv1.Ingress) []string {
+	var names []string
+	if ing.Spec.DefaultBackend != nil && ing.Spec.DefaultBackend.Service != nil {
+		names = append(names, ing.Spec.DefaultBackend.Service.Name)
+	}
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, p := range rule.HTTP.Paths {
+			if p.Backend.Service != nil {
+				names = append(names, p.Backend.Service.Name)
+			}
+		}
+	}
+	return names
+}
+
+// routeBackendServices, Route'un yönlendirdiği servis adlarını döndürür (spec.to ve alternateBackends)
+func routeBackendServices(route *unstructured.Unstructured) []string {
+	var names []string
+	if kind, _, _ := unstructured.NestedString(route.Object, "spec", "to", "kind"); kind == "" || kind == "Service" {
+		if name, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name"); name != "" {
+			names = append(names, name)
+		}
+	}
+	backends, _, _ := unstructured.NestedSlice(route.Object, "spec", "alternateBackends")
+	for _, b := range backends {
+		if backend, ok := b.(map[string]interface{}); ok {
+			if name, ok := backend["name"].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// containsString, dilimde değerin olup olmadığını döndürür
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDerivedEndpoints, aday endpoint'leri servise yazar. Endpoint boşsa veya daha önce
+// otomatik türetildiyse (endpoint_source = 'auto') ilk aday varsayılan olur; kullanıcının
+// elle girdiği endpoint'lere dokunulmaz. Politika kapalıysa otomatik endpoint temizlenir.
+// force true ise (kilitli annotation endpoint'i) elle girilmiş endpoint de ezilir.
+func applyDerivedEndpoints(db *sql.DB, cluster, namespace, name string, candidates []DerivedEndpoint, enabled, force bool) error {
+	data, err := json.Marshal(candidates)
+	if err != nil {
+		return err
+	}
+
+	var defaultEndpoint, endpointSource sql.NullString
+	if enabled && len(candidates) > 0 {
+		defaultEndpoint = sql.NullString{String: candidates[0].Endpoint, Valid: true}
+		endpointSource = sql.NullString{String: "auto", Valid: true}
+		if candidates[0].Source == EndpointSourceAnnotation {
+			endpointSource.String = "annotation"
+		}
+	}
+
+	_, err = db.Exec(`
+		UPDATE services SET
+		candidate_endpoints = ?,
+		endpoint = CASE WHEN ? OR COALESCE(endpoint, '') = '' OR endpoint_source IN ('auto', 'annotation') THEN ? ELSE endpoint END,
+		endpoint_source = CASE WHEN ? OR COALESCE(endpoint, '') = '' OR endpoint_source IN ('auto', 'annotation') THEN ? ELSE endpoint_source END,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE name = ? AND namespace = ? AND cluster = ?
+	`, string(data), force, defaultEndpoint, force, endpointSource, name, namespace, cluster)
+	return err
+}
+
+// addEndpointCandidates, servis yanıtına aday endpoint'leri ve varsayılan endpoint'in kaynağını ekler
+func addEndpointCandidates(out map[string]interface{}, candidates, source sql.NullString) {
+	list := []DerivedEndpoint{}
+	if candidates.Valid {
+		json.Unmarshal([]byte(candidates.String), &list)
+	}
+	out["candidate_endpoints"] = list
+
+	endpointSource := source.String
+	if endpointSource == "" && out["endpoint"] != "" {
+		endpointSource = "manual"
+	}
+	out["endpoint_source"] = endpointSource
+}
+
+// autoEndpointsDefault, politikası tanımlanmamış namespace'ler için varsayılan değer
+func autoEndpointsDefault() bool {
+	return os.Getenv("AUTO_ENDPOINTS_DEFAULT") != "false"
+}
+
+// autoEndpointsEnabled, namespace için otomatik türetilen kontrollerin açık olup olmadığını döndürür
+func autoEndpointsEnabled(db *sql.DB, cluster, namespace string) bool {
+	var enabled bool
+	err := db.QueryRow(`
+		SELECT auto_endpoints FROM namespace_policies WHERE cluster = ? AND namespace = ?
+	`, cluster, namespace).Scan(&enabled)
+	if err != nil {
+		return autoEndpointsDefault()
+	}
+	return enabled
+}
+
+// applyNamespacePolicy, namespace politikası değiştiğinde kayıtlı aday endpoint'leri yeniden uygular
+func applyNamespacePolicy(db *sql.DB, cluster, namespace string, enabled bool) error {
+	rows, err := db.Query(`
+		SELECT name, candidate_endpoints, annotation_fields, annotation_lock FROM services
+		WHERE cluster = ? AND namespace = ? AND candidate_endpoints IS NOT NULL
+	`, cluster, namespace)
+	if err != nil {
+		return err
+	}
+
+	// Tek bağlantılı havuzda satırlar açıkken güncelleme yapılamaz, önce topla
+	candidates := map[string][]DerivedEndpoint{}
+	states := map[string]serviceAnnotationState{}
+	for rows.Next() {
+		var name, data string
+		var fields sql.NullString
+		var locked sql.NullBool
+		if err := rows.Scan(&name, &data, &fields, &locked); err != nil {
+			rows.Close()
+			return err
+		}
+		var list []DerivedEndpoint
+		if err := json.Unmarshal([]byte(data), &list); err == nil {
+			candidates[name] = list
+			states[name] = decodeAnnotationState(fields, locked, sql.NullString{})
+		}
+	}
+	rows.Close()
+
+	for name, list := range candidates {
+		// Annotation ile verilen enabled değeri namespace politikasından önceliklidir
+		state := states[name]
+		_, annotated := state.Fields[AnnotationFieldEndpoint]
+		if err := applyDerivedEndpoints(db, cluster, namespace, name, list, state.autoEndpoints(enabled) || annotated, annotated && state.Locked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namespacePoliciesHandler, namespace bazlı otomatik endpoint politikalarını listeler (GET) ve günceller (PUT)
+func namespacePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		query := "SELECT cluster, namespace, auto_endpoints, updated_at FROM namespace_policies"
+		args := []interface{}{}
+		if cluster := r.URL.Query().Get("cluster"); cluster != "" {
+			query += " WHERE cluster = ?"
+			args = append(args, cluster)
+		}
+		query += " ORDER BY cluster, namespace"
+
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		policies := []map[string]interface{}{}
+		for rows.Next() {
+			var cluster, namespace string
+			var enabled bool
+			var updatedAt sql.NullTime
+			if err := rows.Scan(&cluster, &namespace, &enabled, &updatedAt); err != nil {
+				continue
+			}
+			policies = append(policies, map[string]interface{}{
+				"cluster":        cluster,
+				"namespace":      namespace,
+				"auto_endpoints": enabled,
+				"updated_at":     updatedAt.Time,
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"policies": policies,
+			"default":  autoEndpointsDefault(),
+		})
+
+	case "PUT":
+		var req struct {
+			Cluster       string `json:"cluster"`
+			Namespace     string `json:"namespace"`
+			AutoEndpoints *bool  `json:"auto_endpoints"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Namespace == "" || req.AutoEndpoints == nil {
+			http.Error(w, `{"error":"namespace ve auto_endpoints alanları gerekli"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Cluster == "" {
+			req.Cluster = defaultClusterName
+		}
+
+		_, err := db.Exec(`
+			INSERT INTO namespace_policies (cluster, namespace, auto_endpoints)
+			VALUES (?, ?, ?)
+			ON CONFLICT(cluster, namespace) DO UPDATE SET
+			auto_endpoints = excluded.auto_endpoints, updated_at = CURRENT_TIMESTAMP
+		`, req.Cluster, req.Namespace, *req.AutoEndpoints)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Politika kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		if err := applyNamespacePolicy(db, req.Cluster, req.Namespace, *req.AutoEndpoints); err != nil {
+			log.Printf("%s/%s namespace politikası uygulanamadı: %v", req.Cluster, req.Namespace, err)
+		}
+		if uptimeMonitor != nil {
+			uptimeMonitor.RequestReload()
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":        "Namespace politikası güncellendi",
+			"cluster":        req.Cluster,
+			"namespace":      req.Namespace,
+			"auto_endpoints": *req.AutoEndpoints,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newRoute(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "route.openshift.io/v1",
+		"kind":       "Route",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":       spec,
+	}}
+}
+
+func newIngress(namespace, name string, tls []networkingv1.IngressTLS, rules ...networkingv1.IngressRule) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       networkingv1.IngressSpec{TLS: tls, Rules: rules},
+	}
+}
+
+func ingressRule(host string, paths map[string]string) networkingv1.IngressRule {
+	rule := networkingv1.IngressRule{Host: host, IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{}}}
+	for path, service := range paths {
+		rule.HTTP.Paths = append(rule.HTTP.Paths, networkingv1.HTTPIngressPath{
+			Path:    path,
+			Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: service}},
+		})
+	}
+	return rule
+}
+
+func TestDeriveEndpoints(t *testing.T) {
+	svc := newService("shop", "api", nil)
+	svc.Spec.Ports = []corev1.ServicePort{
+		{Name: "http", Port: 80},
+		{Name: "metrics", Port: 9090, Protocol: corev1.ProtocolTCP},
+		{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+	}
+
+	headless := newService("shop", "api", nil)
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+
+	routes := []*unstructured.Unstructured{
+		newRoute("shop", "z-canary", map[string]interface{}{
+			"host":              "canary.shop.example.com",
+			"to":                map[string]interface{}{"kind": "Service", "name": "api-v2"},
+			"alternateBackends": []interface{}{map[string]interface{}{"kind": "Service", "name": "api"}},
+		}),
+		newRoute("shop", "a-public", map[string]interface{}{
+			"host": "api.shop.example.com",
+			"path": "v1",
+			"to":   map[string]interface{}{"name": "api"},
+			"tls":  map[string]interface{}{"termination": "edge"},
+		}),
+		newRoute("shop", "no-host", map[string]interface{}{"to": map[string]interface{}{"name": "api"}}),
+		newRoute("billing", "other-ns", map[string]interface{}{"host": "billing.example.com", "to": map[string]interface{}{"name": "api"}}),
+		newRoute("shop", "other-service", map[string]interface{}{"host": "web.example.com", "to": map[string]interface{}{"name": "web"}}),
+	}
+	ingresses := []*networkingv1.Ingress{
+		newIngress("shop", "public", []networkingv1.IngressTLS{{Hosts: []string{"shop.example.com"}}},
+			ingressRule("shop.example.com", map[string]string{"/api": "api"}),
+			ingressRule("internal.example.com", map[string]string{"": "api"}),
+			ingressRule("", map[string]string{"/": "api"}),
+		),
+		// Route ile aynı adres tekrar aday olmaz
+		newIngress("shop", "wildcard-tls", []networkingv1.IngressTLS{{}},
+			ingressRule("api.shop.example.com", map[string]string{"/v1": "api", "/web": "web"}),
+		),
+		newIngress("billing", "other-ns", nil, ingressRule("billing.example.com", map[string]string{"/": "api"})),
+	}
+
+	cases := []struct {
+		name      string
+		svc       *corev1.Service
+		ingresses []*networkingv1.Ingress
+		routes    []*unstructured.Unstructured
+		want      []DerivedEndpoint
+	}{
+		{
+			name: "route, ingress ve ClusterIP",
+			svc:  svc, ingresses: ingresses, routes: routes,
+			want: []DerivedEndpoint{
+				{Endpoint: "https://api.shop.example.com/v1", Source: EndpointSourceRoute, Object: "a-public", TLS: true},
+				{Endpoint: "http://canary.shop.example.com/", Source: EndpointSourceRoute, Object: "z-canary"},
+				{Endpoint: "https://shop.example.com/api", Source: EndpointSourceIngress, Object: "public", TLS: true},
+				{Endpoint: "http://internal.example.com/", Source: EndpointSourceIngress, Object: "public"},
+				{Endpoint: "10.0.0.1:80", Source: EndpointSourceClusterIP, Object: "api"},
+				{Endpoint: "10.0.0.1:9090", Source: EndpointSourceClusterIP, Object: "api"},
+			},
+		},
+		{
+			name: "headless servis",
+			svc:  headless, ingresses: ingresses,
+			want: []DerivedEndpoint{
+				{Endpoint: "https://shop.example.com/api", Source: EndpointSourceIngress, Object: "public", TLS: true},
+				{Endpoint: "http://internal.example.com/", Source: EndpointSourceIngress, Object: "public"},
+				{Endpoint: "https://api.shop.example.com/v1", Source: EndpointSourceIngress, Object: "wildcard-tls", TLS: true},
+			},
+		},
+		{
+			name: "yalnızca ClusterIP",
+			svc:  svc,
+			want: []DerivedEndpoint{
+				{Endpoint: "10.0.0.1:80", Source: EndpointSourceClusterIP, Object: "api"},
+				{Endpoint: "10.0.0.1:9090", Source: EndpointSourceClusterIP, Object: "api"},
+			},
+		},
+		{
+			name: "aday yok",
+			svc:  headless,
+			want: []DerivedEndpoint{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := deriveEndpoints(c.svc, c.ingresses, c.routes)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("beklenen\n%+v\nalınan\n%+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestEndpointURL(t *testing.T) {
+	cases := []struct {
+		host, path string
+		tls        bool
+		want       string
+	}{
+		{"api.example.com", "", false, "http://api.example.com/"},
+		{"api.example.com", "/healthz", true, "https://api.example.com/healthz"},
+		{"api.example.com", "v1/healthz", false, "http://api.example.com/v1/healthz"},
+	}
+	for _, c := range cases {
+		if got := endpointURL(c.host, c.path, c.tls); got != c.want {
+			t.Errorf("beklenen %s, alınan %s", c.want, got)
+		}
+	}
+}
+
+func TestApplyDerivedEndpointsKeepsManualEndpoints(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('api', 'shop', 'prod', 'service', 'discovery')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, endpoint, endpoint_source)
+		VALUES ('web', 'shop', 'prod', 'service', 'discovery', 'https://manual.example.com', 'manual')`)
+
+	endpoint := func(name string) (string, string) {
+		var endpoint, source sql.NullString
+		testDB.QueryRow(`SELECT endpoint, endpoint_source FROM services WHERE name = ?`, name).Scan(&endpoint, &source)
+		return endpoint.String, source.String
+	}
+	candidates := []DerivedEndpoint{
+		{Endpoint: "https://api.example.com/", Source: EndpointSourceIngress, Object: "public", TLS: true},
+		{Endpoint: "10.0.0.1:80", Source: EndpointSourceClusterIP, Object: "api"},
+	}
+
+	for _, name := range []string{"api", "web"} {
+		if err := applyDerivedEndpoints(testDB, "prod", "shop", name, candidates, true, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, source := endpoint("api"); got != "https://api.example.com/" || source != "auto" {
+		t.Errorf("boş endpoint ilk adayla doldurulmalıydı: %s (%s)", got, source)
+	}
+	if got, source := endpoint("web"); got != "https://manual.example.com" || source != "manual" {
+		t.Errorf("elle girilen endpoint korunmalıydı: %s (%s)", got, source)
+	}
+
+	// Otomatik endpoint yeni aday listesine göre güncellenir
+	applyDerivedEndpoints(testDB, "prod", "shop", "api", candidates[1:], true, false)
+	if got, _ := endpoint("api"); got != "10.0.0.1:80" {
+		t.Errorf("otomatik endpoint güncellenmeliydi: %s", got)
+	}
+
+	// Politika kapanınca otomatik endpoint temizlenir, elle girilen kalır
+	applyDerivedEndpoints(testDB, "prod", "shop", "api", candidates, false, false)
+	applyDerivedEndpoints(testDB, "prod", "shop", "web", candidates, false, false)
+	if got, source := endpoint("api"); got != "" || source != "" {
+		t.Errorf("otomatik endpoint temizlenmeliydi: %s (%s)", got, source)
+	}
+	if got, _ := endpoint("web"); got != "https://manual.example.com" {
+		t.Errorf("elle girilen endpoint politika kapanınca silinmemeliydi: %s", got)
+	}
+
+	// Kilitli annotation endpoint'i elle girileni ezer
+	annotated := []DerivedEndpoint{{Endpoint: "https://web.example.com/healthz", Source: EndpointSourceAnnotation, Object: "web"}}
+	applyDerivedEndpoints(testDB, "prod", "shop", "web", annotated, true, true)
+	if got, source := endpoint("web"); got != "https://web.example.com/healthz" || source != "annotation" {
+		t.Errorf("kilitli annotation endpoint'i uygulanmalıydı: %s (%s)", got, source)
+	}
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Eskalasyon durumları
+const (
+	EscalationActive    = "active"    // sonraki seviye bekleniyor
+	EscalationStopped   = "stopped"   // alarm onaylandı, kapandı veya politika silindi
+	EscalationCompleted = "completed" // tüm seviyeler bildirildi
+)
+
+// EscalationLevel, politikadaki bir seviye. Seviye, alarm açıldıktan AfterMinutes dakika
+// sonra hâlâ onaylanmamışsa kanallarına bildirilir; bildirimde rotasyonlardaki o anki
+// nöbetçiler yer alır.
+type EscalationLevel struct {
+	AfterMinutes int      `json:"after_minutes"`
+	Channels     []string `json:"channels"`            // kanal ID'si ("3") veya ortam değişkeni kanalı ("env:slack")
+	Schedules    []int64  `json:"schedules,omitempty"` // nöbet rotasyonu ID'leri
+}
+
+// EscalationPolicy, onaylanmayan alarmların sırayla bildirildiği seviyeler
+type EscalationPolicy struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	Levels    []EscalationLevel `json:"levels"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// AlertEscalation, eskalasyon seviyesi için gönderilen olayın bilgileri
+type AlertEscalation struct {
+	PolicyID int64    `json:"policy_id"`
+	Policy   string   `json:"policy"`
+	Level    int      `json:"level"` // 1'den başlar
+	OnCall   []OnCall `json:"on_call"`
+}
+
+// OnCallNames, nöbetçilerin adlarını döndürür
+func (e AlertEscalation) OnCallNames() []string {
+	names := make([]string, 0, len(e.OnCall))
+	for _, o := range e.OnCall {
+		names = append(names, o.Person)
+	}
+	return names
+}
+
+// Validate, politikayı doğrular
+func (p *EscalationPolicy) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("name gerekli")
+	}
+	if len(p.Levels) == 0 {
+		return fmt.Errorf("en az bir seviye gerekli")
+	}
+	previous := 0
+	for i, level := range p.Levels {
+		switch {
+		case level.AfterMinutes < 0:
+			return fmt.Errorf("levels[%d].after_minutes negatif olamaz", i)
+		case level.AfterMinutes < previous:
+			return fmt.Errorf("levels[%d].after_minutes önceki seviyeden küçük olamaz", i)
+		case len(level.Channels) == 0:
+			return fmt.Errorf("levels[%d] için en az bir kanal gerekli", i)
+		}
+		previous = level.AfterMinutes
+	}
+	return nil
+}
+
+// validateEscalationReferences, seviyelerdeki kanal ve rotasyonların var olduğunu doğrular
+func validateEscalationReferences(db *sql.DB, p EscalationPolicy) error {
+	known, err := knownChannelKeys(db)
+	if err != nil {
+		return err
+	}
+	for i, level := range p.Levels {
+		for _, ref := range level.Channels {
+			if !known[routeChannelKey(ref)] {
+				return fmt.Errorf("levels[%d]: kanal bulunamadı: %s", i, ref)
+			}
+		}
+		for _, id := range level.Schedules {
+			if _, err := getOnCallSchedule(db, id); err != nil {
+				return fmt.Errorf("levels[%d]: nöbet rotasyonu %d bulunamadı", i, id)
+			}
+		}
+	}
+	return nil
+}
+
+// knownChannelKeys, veritabanı ve ortam değişkeni kanallarının anahtarlarını döndürür
+func knownChannelKeys(db *sql.DB) (map[string]bool, error) {
+	channels, err := listNotificationChannels(db, "1 = 1")
+	if err != nil {
+		return nil, err
+	}
+	if notificationDispatcher != nil {
+		channels = append(channels, notificationDispatcher.envChannels...)
+	}
+	known := make(map[string]bool, len(channels))
+	for _, c := range channels {
+		known[c.key()] = true
+	}
+	return known, nil
+}
+
+// scanEscalationPolicy, satırı EscalationPolicy'ye dönüştürür
+func scanEscalationPolicy(scanner interface{ Scan(...interface{}) error }) (EscalationPolicy, error) {
+	var p EscalationPolicy
+	var levels string
+	if err := scanner.Scan(&p.ID, &p.Name, &levels, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	return p, json.Unmarshal([]byte(levels), &p.Levels)
+}
+
+// getEscalationPolicy, politikayı ID ile döndürür
+func getEscalationPolicy(db *sql.DB, id int64) (EscalationPolicy, error) {
+	return scanEscalationPolicy(db.QueryRow(`SELECT id, name, levels, created_at, updated_at FROM escalation_policies WHERE id = ?`, id))
+}
+
+// listEscalationPolicies, tüm politikaları döndürür
+func listEscalationPolicies(db *sql.DB) ([]EscalationPolicy, error) {
+	rows, err := db.Query(`SELECT id, name, levels, created_at, updated_at FROM escalation_policies ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []EscalationPolicy{}
+	for rows.Next() {
+		p, err := scanEscalationPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// saveEscalationPolicy, politikayı ekler (ID 0 ise) veya günceller
+func saveEscalationPolicy(db *sql.DB, p *EscalationPolicy) error {
+	levels, err := json.Marshal(p.Levels)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if p.ID == 0 {
+		result, err := db.Exec(`INSERT INTO escalation_policies (name, levels, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+			p.Name, string(levels), now, now)
+		if err != nil {
+			return err
+		}
+		p.ID, _ = result.LastInsertId()
+		p.CreatedAt = now
+	} else {
+		if _, err := db.Exec(`UPDATE escalation_policies SET name = ?, levels = ?, updated_at = ? WHERE id = ?`,
+			p.Name, string(levels), now, p.ID); err != nil {
+			return err
+		}
+	}
+	p.UpdatedAt = now
+	return nil
+}
+
+// escalationPolicyUsingSchedule, rotasyonu kullanan ilk politikanın adını döndürür
+func escalationPolicyUsingSchedule(db *sql.DB, scheduleID int64) (string, bool) {
+	policies, err := listEscalationPolicies(db)
+	if err != nil {
+		return "", false
+	}
+	for _, p := range policies {
+		for _, level := range p.Levels {
+			for _, id := range level.Schedules {
+				if id == scheduleID {
+					return p.Name, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// routeUsesPolicy, yönlendirme ağacında politikayı kullanan bir düğüm olup olmadığını döndürür
+func routeUsesPolicy(route NotificationRoute, policyID int64) bool {
+	if route.EscalationPolicy != nil && *route.EscalationPolicy == policyID {
+		return true
+	}
+	for _, child := range route.Routes {
+		if routeUsesPolicy(child, policyID) {
+			return true
+		}
+	}
+	return false
+}
+
+// startEscalations, açılan alarm için yönlendirmenin seçtiği politikaları başlatır ve
+// zamanı gelmiş ilk seviyeleri hemen bildirir
+func (d *NotificationDispatcher) startEscalations(ctx context.Context, alert Alert, policies []int64) {
+	if len(policies) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, policyID := range policies {
+		policy, err := getEscalationPolicy(d.db, policyID)
+		if err != nil {
+			log.Printf("Eskalasyon politikası %d okunamadı: %v", policyID, err)
+			continue
+		}
+		nextAt := alert.FirstSeen.Add(time.Duration(policy.Levels[0].AfterMinutes) * time.Minute)
+		_, err = d.db.Exec(`
+			INSERT INTO alert_escalations (alert_id, policy_id, next_level, next_at, status, created_at, updated_at)
+			VALUES (?, ?, 0, ?, ?, ?, ?)
+			ON CONFLICT(alert_id, policy_id) DO NOTHING
+		`, alert.ID, policyID, nextAt.UTC(), EscalationActive, now, now)
+		if err != nil {
+			log.Printf("Alarm %d için eskalasyon başlatılamadı: %v", alert.ID, err)
+		}
+	}
+	d.Escalate(ctx, now)
+}
+
+// stopEscalations, alarm onaylandığında veya kapandığında bekleyen eskalasyonları durdurur
+func (d *NotificationDispatcher) stopEscalations(alertID int64) {
+	_, err := d.db.Exec(`UPDATE alert_escalations SET status = ?, next_at = NULL, updated_at = ? WHERE alert_id = ? AND status = ?`,
+		EscalationStopped, time.Now(), alertID, EscalationActive)
+	if err != nil {
+		log.Printf("Alarm %d eskalasyonu durdurulamadı: %v", alertID, err)
+	}
+}
+
+// Escalate, zamanı gelen eskalasyon seviyelerini bildirir. Onaylanmış veya kapanmış
+// alarmların eskalasyonu bildirim yapılmadan durdurulur; ertelenmiş veya sessize alınmış
+// alarmlarınki erteleme ya da kural bitene kadar bekletilir.
+func (d *NotificationDispatcher) Escalate(ctx context.Context, now time.Time) {
+	type due struct {
+		id, alertID, policyID int64
+		level                 int
+	}
+
+	// Tek bağlantılı havuzda satırlar açıkken başka sorgu yapılamaz, önce topla
+	rows, err := d.db.Query(`
+		SELECT id, alert_id, policy_id, next_level FROM alert_escalations
+		WHERE status = ? AND next_at <= ? ORDER BY next_at
+	`, EscalationActive, now.UTC())
+	if err != nil {
+		log.Printf("Bekleyen eskalasyonlar okunamadı: %v", err)
+		return
+	}
+	var pending []due
+	for rows.Next() {
+		var item due
+		if err := rows.Scan(&item.id, &item.alertID, &item.policyID, &item.level); err == nil {
+			pending = append(pending, item)
+		}
+	}
+	rows.Close()
+	if len(pending) == 0 {
+		return
+	}
+
+	channels, err := d.channels()
+	if err != nil {
+		log.Printf("Bildirim kanalları okunamadı: %v", err)
+		return
+	}
+	byKey := make(map[string]NotificationChannel, len(channels))
+	for _, c := range channels {
+		byKey[c.key()] = c
+	}
+
+	for _, item := range pending {
+		alert, err := getAlert(d.db, item.alertID)
+		if err != nil || alert.Status != AlertStatusOpen || alert.AcknowledgedAt != nil {
+			d.stopEscalations(item.alertID)
+			continue
+		}
+		if until := d.suppressedUntil(alert, now); until.After(now) {
+			d.db.Exec(`UPDATE alert_escalations SET next_at = ?, updated_at = ? WHERE id = ?`, until.UTC(), now, item.id)
+			continue
+		}
+		policy, err := getEscalationPolicy(d.db, item.policyID)
+		if err == sql.ErrNoRows {
+			// Politika eskalasyon sürerken silindi; kalan seviyeler bildirilmez
+			log.Printf("Alarm %d eskalasyonu durduruldu: politika %d silinmiş", alert.ID, item.policyID)
+			d.db.Exec(`UPDATE alert_escalations SET status = ?, next_at = NULL, updated_at = ? WHERE id = ?`,
+				EscalationStopped, now, item.id)
+			continue
+		} else if err != nil {
+			log.Printf("Eskalasyon politikası %d okunamadı: %v", item.policyID, err)
+			continue
+		}
+		if item.level >= len(policy.Levels) {
+			d.db.Exec(`UPDATE alert_escalations SET status = ?, next_at = NULL, updated_at = ? WHERE id = ?`,
+				EscalationCompleted, now, item.id)
+			continue
+		}
+
+		level := policy.Levels[item.level]
+		onCall, err := onCallFor(d.db, level.Schedules, now)
+		if err != nil {
+			log.Printf("Nöbetçiler okunamadı: %v", err)
+		}
+		event := AlertEvent{Type: AlertEventFiring, Alert: alert, Escalation: &AlertEscalation{
+			PolicyID: policy.ID, Policy: policy.Name, Level: item.level + 1, OnCall: onCall,
+		}}
+		log.Printf("ALARM eskalasyonu: %d (%s) seviye %d", alert.ID, policy.Name, item.level+1)
+		for _, ref := range level.Channels {
+			channel, ok := byKey[routeChannelKey(ref)]
+			if !ok {
+				log.Printf("Eskalasyondaki %s kanalı bulunamadı veya devre dışı, atlandı", ref)
+				continue
+			}
+			if err := d.send(ctx, channel, event); err != nil {
+				log.Printf("Alarm %d eskalasyonu %s kanalına gönderilemedi: %v", alert.ID, channel.Name, err)
+			}
+		}
+
+		next := item.level + 1
+		if next < len(policy.Levels) {
+			nextAt := alert.FirstSeen.Add(time.Duration(policy.Levels[next].AfterMinutes) * time.Minute)
+			d.db.Exec(`UPDATE alert_escalations SET next_level = ?, next_at = ?, updated_at = ? WHERE id = ?`,
+				next, nextAt.UTC(), now, item.id)
+		} else {
+			d.db.Exec(`UPDATE alert_escalations SET next_level = ?, status = ?, next_at = NULL, updated_at = ? WHERE id = ?`,
+				next, EscalationCompleted, now, item.id)
+		}
+	}
+}
+
+// escalationPoliciesHandler, /api/v1/escalation-policies altındaki endpoint'leri yönetir:
+//
+//	GET    /api/v1/escalation-policies       politikalar
+//	POST   /api/v1/escalation-policies       politika oluşturma
+//	GET    /api/v1/escalation-policies/{id}  politika detayı
+//	PUT    /api/v1/escalation-policies/{id}  politika güncelleme
+//	DELETE /api/v1/escalation-policies/{id}  politika silme (yönlendirmede kullanılıyorsa 409)
+func escalationPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	decode := func(policy *EscalationPolicy) error {
+		if err := json.NewDecoder(r.Body).Decode(policy); err != nil {
+			return fmt.Errorf("İstek gövdesi ayrıştırılamadı")
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		return validateEscalationReferences(db, *policy)
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/escalation-policies"), "/")
+	if path == "" {
+		switch r.Method {
+		case "GET":
+			policies, err := listEscalationPolicies(db)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikaları alınamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"policies": policies, "count": len(policies)})
+
+		case "POST":
+			var policy EscalationPolicy
+			if err := decode(&policy); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			policy.ID = 0
+			if err := saveEscalationPolicy(db, &policy); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikası kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Eskalasyon politikası oluşturuldu",
+				"policy":  policy,
+			})
+
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Geçersiz politika ID"}`, http.StatusBadRequest)
+		return
+	}
+	policy, err := getEscalationPolicy(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Eskalasyon politikası bulunamadı"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikası alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(map[string]interface{}{"policy": policy})
+
+	case "PUT":
+		if err := decode(&policy); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		policy.ID = id
+		if err := saveEscalationPolicy(db, &policy); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikası kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Eskalasyon politikası güncellendi",
+			"policy":  policy,
+		})
+
+	case "DELETE":
+		if tree, err := loadNotificationRoutes(db); err == nil && tree != nil && routeUsesPolicy(*tree, id) {
+			http.Error(w, `{"error":"Politika bildirim yönlendirmesinde kullanılıyor"}`, http.StatusConflict)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM alert_escalations WHERE policy_id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon kayıtları silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM escalation_policies WHERE id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikası silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Eskalasyon politikası silindi",
+			"id":      id,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// onCallHandler, bir yönlendirmenin eskalasyon politikasında şu an (veya at anında) kimlerin
+// nöbetçi olduğunu gösterir:
+//
+//	GET /api/v1/oncall?route=payments[&at=2026-01-05T10:00:00Z]
+//
+// route verilmezse kök (varsayılan) yönlendirme kullanılır.
+func onCallHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	at := time.Now()
+	if s := r.URL.Query().Get("at"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			http.Error(w, `{"error":"at RFC3339 biçiminde olmalı"}`, http.StatusBadRequest)
+			return
+		}
+		at = t
+	}
+
+	tree, err := loadNotificationRoutes(db)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+	if tree == nil {
+		http.Error(w, `{"error":"Bildirim yönlendirmesi tanımlı değil"}`, http.StatusNotFound)
+		return
+	}
+	root, err := tree.Compile("route")
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	name := r.URL.Query().Get("route")
+	if name == "" {
+		name = root.name
+	}
+	route := root.find(name)
+	if route == nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Yönlendirme bulunamadı: %s"}`, name), http.StatusNotFound)
+		return
+	}
+	if route.policy == 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{"route": name, "at": at, "escalation_policy": nil, "levels": []interface{}{}})
+		return
+	}
+
+	policy, err := getEscalationPolicy(db, route.policy)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon politikası %d alınamadı: %v"}`, route.policy, err), http.StatusInternalServerError)
+		return
+	}
+	levels := []map[string]interface{}{}
+	for i, level := range policy.Levels {
+		onCall, err := onCallFor(db, level.Schedules, at)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbetçiler alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		levels = append(levels, map[string]interface{}{
+			"level":         i + 1,
+			"after_minutes": level.AfterMinutes,
+			"channels":      level.Channels,
+			"on_call":       onCall,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"route":             name,
+		"at":                at,
+		"escalation_policy": map[string]interface{}{"id": policy.ID, "name": policy.Name},
+		"levels":            levels,
+	})
+}
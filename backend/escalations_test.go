@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnCallScheduleRotationAndOverrides(t *testing.T) {
+	istanbul, _ := time.LoadLocation("Europe/Istanbul")
+	weekly := OnCallSchedule{
+		ID: 1, Name: "platform", Rotation: RotationWeekly, Participants: []string{"ayse", "mehmet", "zeynep"},
+		StartAt: time.Date(2026, 1, 5, 9, 0, 0, 0, istanbul), TimeZone: "Europe/Istanbul",
+	}
+	cases := []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Date(2026, 1, 5, 9, 0, 0, 0, istanbul), "ayse"},
+		{time.Date(2026, 1, 12, 8, 59, 0, 0, istanbul), "ayse"},
+		{time.Date(2026, 1, 12, 9, 0, 0, 0, istanbul), "mehmet"},
+		{time.Date(2026, 1, 20, 12, 0, 0, 0, istanbul), "zeynep"},
+		{time.Date(2026, 1, 27, 12, 0, 0, 0, istanbul), "ayse"},
+		{time.Date(2026, 1, 1, 12, 0, 0, 0, istanbul), "zeynep"}, // başlangıçtan önceki nöbet
+	}
+	for _, c := range cases {
+		if got := weekly.At(c.at, nil); got.Person != c.want {
+			t.Errorf("%s: beklenen %s, alınan %s", c.at, c.want, got.Person)
+		}
+	}
+
+	overrides := []OnCallOverride{{
+		Person:  "ali",
+		StartAt: time.Date(2026, 1, 13, 0, 0, 0, 0, istanbul),
+		EndAt:   time.Date(2026, 1, 14, 0, 0, 0, 0, istanbul),
+	}}
+	if got := weekly.At(time.Date(2026, 1, 13, 10, 0, 0, 0, istanbul), overrides); got.Person != "ali" || !got.Override {
+		t.Errorf("değişiklik uygulanmadı: %+v", got)
+	}
+	if got := weekly.At(time.Date(2026, 1, 14, 10, 0, 0, 0, istanbul), overrides); got.Person != "mehmet" || got.Override {
+		t.Errorf("değişiklik bitince rotasyona dönülmeliydi: %+v", got)
+	}
+
+	// Günlük devirler yaz saati geçişinde de aynı duvar saatinde yapılır
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	daily := OnCallSchedule{Name: "gece", Rotation: RotationDaily, Participants: []string{"a", "b"},
+		StartAt: time.Date(2026, 3, 27, 9, 0, 0, 0, berlin), TimeZone: "Europe/Berlin"}
+	got := daily.At(time.Date(2026, 3, 30, 9, 30, 0, 0, berlin), nil)
+	if got.Person != "b" || !got.ShiftStart.Equal(time.Date(2026, 3, 30, 9, 0, 0, 0, berlin)) {
+		t.Errorf("yaz saati sonrası devir yanlış: %+v", got)
+	}
+}
+
+func TestOnCallShiftAcrossDaylightSavingTransitions(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	daily := OnCallSchedule{Name: "gece", Rotation: RotationDaily, Participants: []string{"a", "b", "c"},
+		StartAt: time.Date(2026, 3, 27, 9, 0, 0, 0, berlin), TimeZone: "Europe/Berlin"}
+	weekly := OnCallSchedule{Name: "hafta", Rotation: RotationWeekly, Participants: []string{"a", "b"},
+		StartAt: time.Date(2026, 10, 19, 9, 0, 0, 0, berlin), TimeZone: "Europe/Berlin"}
+
+	// 2026'da Berlin'de yaz saati 29 Mart'ta başlar (23 saatlik gün) ve 25 Ekim'de biter (25 saatlik gün)
+	cases := []struct {
+		name     string
+		schedule OnCallSchedule
+		at       time.Time
+		n        int
+		start    time.Time
+		length   time.Duration
+	}{
+		{"ileri geçiş öncesi", daily, time.Date(2026, 3, 28, 9, 0, 0, 0, berlin), 1,
+			time.Date(2026, 3, 28, 9, 0, 0, 0, berlin), 23 * time.Hour},
+		{"ileri geçişli nöbetin sonu (UTC)", daily, time.Date(2026, 3, 29, 6, 59, 59, 0, time.UTC), 1,
+			time.Date(2026, 3, 28, 9, 0, 0, 0, berlin), 23 * time.Hour},
+		{"ileri geçiş sonrası devir (UTC)", daily, time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC), 2,
+			time.Date(2026, 3, 29, 9, 0, 0, 0, berlin), 24 * time.Hour},
+		{"geri geçişli gün", daily, time.Date(2026, 10, 25, 12, 0, 0, 0, berlin), 212,
+			time.Date(2026, 10, 25, 9, 0, 0, 0, berlin), 24 * time.Hour},
+		{"geri geçişi içeren nöbet", daily, time.Date(2026, 10, 25, 2, 0, 0, 0, time.UTC), 211,
+			time.Date(2026, 10, 24, 9, 0, 0, 0, berlin), 25 * time.Hour},
+		{"başlangıçtan önce, geçiş öncesi", daily, time.Date(2026, 3, 20, 8, 59, 0, 0, berlin), -8,
+			time.Date(2026, 3, 19, 9, 0, 0, 0, berlin), 24 * time.Hour},
+		{"haftalık nöbet geri geçişi içerir", weekly, time.Date(2026, 10, 26, 7, 59, 0, 0, time.UTC), 0,
+			time.Date(2026, 10, 19, 9, 0, 0, 0, berlin), 7*24*time.Hour + time.Hour},
+		{"haftalık devir kış saatinde 09:00", weekly, time.Date(2026, 10, 26, 8, 0, 0, 0, time.UTC), 1,
+			time.Date(2026, 10, 26, 9, 0, 0, 0, berlin), 7 * 24 * time.Hour},
+		{"yıllar sonra duvar saati korunur", weekly, time.Date(2030, 7, 1, 9, 0, 0, 0, berlin), 193,
+			time.Date(2030, 7, 1, 9, 0, 0, 0, berlin), 7 * 24 * time.Hour},
+	}
+	for _, c := range cases {
+		n, start, end := c.schedule.shift(c.at)
+		if n != c.n || !start.Equal(c.start) || end.Sub(start) != c.length {
+			t.Errorf("%s: beklenen %d %s (%s), alınan %d %s (%s)", c.name, c.n, c.start, c.length, n, start, end.Sub(start))
+		}
+		if start.After(c.at) || !end.After(c.at) {
+			t.Errorf("%s: nöbet %s anını kapsamıyor: %s - %s", c.name, c.at, start, end)
+		}
+		if local := start.In(berlin); local.Hour() != 9 || local.Minute() != 0 {
+			t.Errorf("%s: devir 09:00'da olmalıydı: %s", c.name, local)
+		}
+
+		// At, aynı nöbetin katılımcısını ve sınırlarını döndürür
+		want := c.schedule.Participants[((c.n%len(c.schedule.Participants))+len(c.schedule.Participants))%len(c.schedule.Participants)]
+		got := c.schedule.At(c.at, nil)
+		if got.Person != want || !got.ShiftStart.Equal(start) || !got.ShiftEnd.Equal(end) {
+			t.Errorf("%s: beklenen %s, alınan %+v", c.name, want, got)
+		}
+	}
+}
+
+func TestEscalationNotifiesLevelsUntilAcknowledged(t *testing.T) {
+	first, firstRequests := newRecordingServer(t, func(string) string { return "ok" })
+	second, secondRequests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "seviye-1", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": first.URL}},
+		NotificationChannel{Name: "seviye-2", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": second.URL}},
+	)
+	previous := alertManager
+	alertManager = manager
+	t.Cleanup(func() { alertManager = previous })
+
+	schedule := OnCallSchedule{Name: "platform", Rotation: RotationDaily, Participants: []string{"ayse"},
+		StartAt: time.Now().Add(-time.Hour), TimeZone: "UTC"}
+	if err := saveOnCallSchedule(db, &schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"name":"kritik","levels":[{"after_minutes":0,"channels":["1"],"schedules":[%d]},{"after_minutes":15,"channels":["2"]}]}`, schedule.ID)
+	escalationPoliciesHandler(rec, httptest.NewRequest("POST", "/api/v1/escalation-policies", strings.NewReader(body)))
+	if rec.Code != 201 {
+		t.Fatalf("politika oluşturulamadı: %d %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	notificationRoutesHandler(rec, httptest.NewRequest("PUT", "/api/v1/notification-routes",
+		strings.NewReader(`{"channels":[],"routes":[{"name":"kritik","match":{"severities":["critical"]},"channels":[],"escalation_policy":1}]}`)))
+	if rec.Code != 200 {
+		t.Fatalf("yönlendirme kaydedilemedi: %d %s", rec.Code, rec.Body.String())
+	}
+
+	start := time.Now()
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", start)
+	got := firstRequests()
+	if len(got) != 1 {
+		t.Fatalf("ilk seviye hemen bildirilmeliydi, alınan %d", len(got))
+	}
+	message, _ := json.Marshal(got[0].Body)
+	if !strings.Contains(string(message), "ESKALASYON 1") || !strings.Contains(string(message), "ayse") {
+		t.Errorf("bildirimde seviye veya nöbetçi yok: %s", message)
+	}
+
+	dispatcher.Escalate(context.Background(), start.Add(10*time.Minute))
+	if got := len(secondRequests()); got != 0 {
+		t.Fatalf("ikinci seviye süresinden önce bildirildi")
+	}
+	dispatcher.Escalate(context.Background(), start.Add(16*time.Minute))
+	if got := len(secondRequests()); got != 1 {
+		t.Fatalf("ikinci seviye bildirilmeliydi, alınan %d", got)
+	}
+
+	// Onaylanan alarmın eskalasyonu durur
+	dispatcher.db.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (8, 'cart', 'shop', 'prod', 'service')`)
+	manager.Fire(statusAlertKey(8), 8, nil, SeverityCritical, "down", start)
+	var alertID int64
+	dispatcher.db.QueryRow(`SELECT id FROM alerts WHERE service_id = 8`).Scan(&alertID)
+	rec = httptest.NewRecorder()
+	alertsHandler(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v1/alerts/%d/acknowledge", alertID), strings.NewReader(`{"by":"ayse"}`)))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"acknowledged_by":"ayse"`) {
+		t.Fatalf("alarm onaylanamadı: %d %s", rec.Code, rec.Body.String())
+	}
+	dispatcher.Escalate(context.Background(), start.Add(16*time.Minute))
+	if got := len(secondRequests()); got != 1 {
+		t.Errorf("onaylanan alarm eskale edilmemeliydi, ikinci seviyeye %d bildirim", got)
+	}
+	var status string
+	dispatcher.db.QueryRow(`SELECT status FROM alert_escalations WHERE alert_id = ?`, alertID).Scan(&status)
+	if status != EscalationStopped {
+		t.Errorf("eskalasyon durdurulmalıydı: %s", status)
+	}
+
+	rec = httptest.NewRecorder()
+	onCallHandler(rec, httptest.NewRequest("GET", "/api/v1/oncall?route=kritik", nil))
+	var onCall struct {
+		Levels []struct {
+			OnCall []OnCall `json:"on_call"`
+		} `json:"levels"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &onCall); err != nil || len(onCall.Levels) != 2 ||
+		len(onCall.Levels[0].OnCall) != 1 || onCall.Levels[0].OnCall[0].Person != "ayse" {
+		t.Errorf("beklenmeyen nöbetçi yanıtı: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	escalationPoliciesHandler(rec, httptest.NewRequest("DELETE", "/api/v1/escalation-policies/1", nil))
+	if rec.Code != 409 {
+		t.Errorf("yönlendirmede kullanılan politika silinmemeli: %d", rec.Code)
+	}
+}
+
+func TestEscalationStopsWhenPolicyIsDeleted(t *testing.T) {
+	server, requests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "seviye", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": server.URL}},
+	)
+	start := time.Now().Add(-time.Hour)
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", start)
+	alertID := mustOpenAlertID(t, dispatcher)
+
+	policy := EscalationPolicy{Name: "kritik", Levels: []EscalationLevel{{Channels: []string{"1"}}, {AfterMinutes: 15, Channels: []string{"1"}}}}
+	if err := saveEscalationPolicy(dispatcher.db, &policy); err != nil {
+		t.Fatal(err)
+	}
+	escalation := func(level int) {
+		dispatcher.db.Exec(`DELETE FROM alert_escalations`)
+		dispatcher.db.Exec(`INSERT INTO alert_escalations (alert_id, policy_id, next_level, next_at, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, alertID, policy.ID, level, start.UTC(), EscalationActive, start, start)
+	}
+	status := func() string {
+		var status string
+		dispatcher.db.QueryRow(`SELECT status FROM alert_escalations WHERE alert_id = ?`, alertID).Scan(&status)
+		return status
+	}
+
+	// Tüm seviyeleri bildirilen eskalasyon tamamlanır
+	escalation(2)
+	dispatcher.Escalate(context.Background(), time.Now())
+	if got := status(); got != EscalationCompleted {
+		t.Errorf("tüm seviyeler bildirilince eskalasyon tamamlanmalıydı: %s", got)
+	}
+
+	// Eskalasyon sürerken silinen politika kalan seviyeleri bildirmez ve eskalasyonu durdurur
+	escalation(1)
+	dispatcher.db.Exec(`DELETE FROM escalation_policies WHERE id = ?`, policy.ID)
+	sent := len(requests())
+	dispatcher.Escalate(context.Background(), time.Now())
+	if got := status(); got != EscalationStopped {
+		t.Errorf("silinen politikanın eskalasyonu durdurulmalıydı: %s", got)
+	}
+	if got := len(requests()); got != sent {
+		t.Errorf("silinen politika bildirim göndermemeliydi: %d", got-sent)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// eventCorrelationLead, kesinti penceresinden ne kadar önceki olayların da
+// ilişkilendirileceğini belirler (ör. OOMKilled genellikle kesintiden hemen önce gelir)
+const eventCorrelationLead = 5 * time.Minute
+
+// K8sEvent, veritabanında saklanan bir Kubernetes Warning olayını temsil eder
+type K8sEvent struct {
+	ID             int               `json:"id"`
+	Cluster        string            `json:"cluster"`
+	Namespace      string            `json:"namespace"`
+	Reason         string            `json:"reason"`
+	Message        string            `json:"message"`
+	Type           string            `json:"type"`
+	InvolvedKind   string            `json:"involved_kind"`
+	InvolvedName   string            `json:"involved_name"`
+	InvolvedLabels map[string]string `json:"involved_labels,omitempty"`
+	Count          int               `json:"count"`
+	FirstTimestamp time.Time         `json:"first_timestamp"`
+	LastTimestamp  time.Time         `json:"last_timestamp"`
+}
+
+// DowntimeWindow, servis geçmişindeki kesintisiz bir "up olmayan" dönemi temsil eder
+type DowntimeWindow struct {
+	Start  time.Time  `json:"start"`
+	End    time.Time  `json:"end"`
+	Events []K8sEvent `json:"events"`
+}
+
+// EventWatcher, bir cluster'daki Warning olaylarını izler ve veritabanına kaydeder
+type EventWatcher struct {
+	db      *sql.DB
+	client  kubernetes.Interface
+	cluster string
+}
+
+// NewEventWatcher, yeni bir olay izleyici oluşturur
+func NewEventWatcher(db *sql.DB, client kubernetes.Interface, cluster string) *EventWatcher {
+	return &EventWatcher{
+		db:      db,
+		client:  client,
+		cluster: cluster,
+	}
+}
+
+// Run, context iptal edilene kadar olayları izler
+func (w *EventWatcher) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(w.client, 0,
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = "type=" + corev1.EventTypeWarning
+		}))
+
+	informer := factory.Core().V1().Events().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ev, ok := obj.(*corev1.Event); ok {
+				w.handleEvent(ctx, ev)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if ev, ok := newObj.(*corev1.Event); ok {
+				w.handleEvent(ctx, ev)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("olay izleyicisi kaydedilemedi: %v", err)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("%s cluster'ı için olay önbelleği senkronize edilemedi", w.cluster)
+	}
+	log.Printf("%s cluster'ı için Kubernetes olay izleyicisi başlatıldı", w.cluster)
+
+	<-ctx.Done()
+	factory.Shutdown()
+	return nil
+}
+
+// handleEvent, tek bir olayı işler ve Warning ise kaydeder
+func (w *EventWatcher) handleEvent(ctx context.Context, ev *corev1.Event) {
+	// Field selector her istemcide uygulanmayabilir, tekrar kontrol et
+	if ev.Type != corev1.EventTypeWarning {
+		return
+	}
+
+	first, last := eventTimes(ev)
+	count := int(ev.Count)
+	if ev.Series != nil && int(ev.Series.Count) > count {
+		count = int(ev.Series.Count)
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	// İlgili nesnenin etiketlerini servis seçicileriyle eşleştirmek için sakla
+	var labelsJSON sql.NullString
+	if objLabels := w.involvedObjectLabels(ctx, ev); len(objLabels) > 0 {
+		data, err := json.Marshal(objLabels)
+		if err == nil {
+			labelsJSON = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+
+	namespace := ev.InvolvedObject.Namespace
+	if namespace == "" {
+		namespace = ev.Namespace
+	}
+
+	_, err := w.db.Exec(`
+		INSERT INTO k8s_events
+		(cluster, uid, namespace, reason, message, type, involved_kind, involved_name,
+		 involved_labels, count, first_timestamp, last_timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(cluster, uid) DO UPDATE SET
+		message = excluded.message, count = excluded.count,
+		last_timestamp = excluded.last_timestamp,
+		involved_labels = COALESCE(excluded.involved_labels, k8s_events.involved_labels)
+	`, w.cluster, string(ev.UID), namespace, ev.Reason, ev.Message, ev.Type,
+		ev.InvolvedObject.Kind, ev.InvolvedObject.Name, labelsJSON, count, first, last)
+	if err != nil {
+		log.Printf("Olay %s/%s kaydedilemedi: %v", namespace, ev.Name, err)
+	}
+}
+
+// involvedObjectLabels, olayın ilgili olduğu Pod'un etiketlerini döndürür
+func (w *EventWatcher) involvedObjectLabels(ctx context.Context, ev *corev1.Event) map[string]string {
+	if ev.InvolvedObject.Kind != "Pod" {
+		return nil
+	}
+
+	pod, err := w.client.CoreV1().Pods(ev.InvolvedObject.Namespace).Get(ctx, ev.InvolvedObject.Name, metav1.GetOptions{})
+	if err != nil {
+		// Pod silinmiş olabilir (ör. tahliye), etiketsiz kaydet
+		return nil
+	}
+	return pod.Labels
+}
+
+// eventTimes, olayın ilk ve son görülme zamanlarını döndürür.
+// Yeni events.k8s.io API'si ile oluşturulan olaylarda eski alanlar boş olabilir.
+func eventTimes(ev *corev1.Event) (time.Time, time.Time) {
+	first := ev.FirstTimestamp.Time
+	if first.IsZero() {
+		first = ev.EventTime.Time
+	}
+	if first.IsZero() {
+		first = ev.CreationTimestamp.Time
+	}
+
+	last := ev.LastTimestamp.Time
+	if ev.Series != nil && ev.Series.LastObservedTime.After(last) {
+		last = ev.Series.LastObservedTime.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+
+	return first.UTC(), last.UTC()
+}
+
+// downtimeWindows, sıralı kontrol geçmişinden kesinti pencerelerini çıkarır.
+// Pencere, ilk "up olmayan" kontrol ile ardından gelen ilk "up" (veya bilerek
+// duraklatılmış) kontrol arasıdır.
+func downtimeWindows(statuses []string, timestamps []time.Time) []DowntimeWindow {
+	windows := []DowntimeWindow{}
+	var current *DowntimeWindow
+
+	for i, status := range statuses {
+		if status != "up" && status != CheckStatusPaused {
+			if current == nil {
+				current = &DowntimeWindow{Start: timestamps[i], Events: []K8sEvent{}}
+			}
+			current.End = timestamps[i]
+			continue
+		}
+		if current != nil {
+			current.End = timestamps[i]
+			windows = append(windows, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		windows = append(windows, *current)
+	}
+
+	return windows
+}
+
+// serviceSelector, servisin saklanan etiket seçicisini döndürür
+func serviceSelector(db *sql.DB, serviceID int) (string, string, string, map[string]string, error) {
+	var name, namespace, cluster string
+	var selectorJSON sql.NullString
+
+	err := db.QueryRow(`
+		SELECT name, namespace, cluster, selector FROM services WHERE id = ?
+	`, serviceID).Scan(&name, &namespace, &cluster, &selectorJSON)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	selector := map[string]string{}
+	if selectorJSON.Valid && selectorJSON.String != "" {
+		if err := json.Unmarshal([]byte(selectorJSON.String), &selector); err != nil {
+			return "", "", "", nil, fmt.Errorf("servis seçicisi ayrıştırılamadı: %v", err)
+		}
+	}
+	return name, namespace, cluster, selector, nil
+}
+
+// attachServiceEvents, servise ait olayları ilgili kesinti pencerelerine ekler.
+// Olay, servisin namespace'inde olmalı ve ya doğrudan Service nesnesine ya da
+// etiketleri servis seçicisiyle eşleşen bir Pod'a ait olmalıdır.
+func attachServiceEvents(db *sql.DB, serviceID int, windows []DowntimeWindow) error {
+	if len(windows) == 0 {
+		return nil
+	}
+
+	name, namespace, cluster, selector, err := serviceSelector(db, serviceID)
+	if err != nil {
+		return err
+	}
+
+	rangeStart := windows[0].Start.Add(-eventCorrelationLead)
+	rangeEnd := windows[len(windows)-1].End
+
+	rows, err := db.Query(`
+		SELECT id, cluster, namespace, reason, COALESCE(message, ''), type,
+		       COALESCE(involved_kind, ''), COALESCE(involved_name, ''), involved_labels,
+		       count, first_timestamp, last_timestamp
+		FROM k8s_events
+		WHERE cluster = ? AND namespace = ? AND last_timestamp >= ? AND first_timestamp <= ?
+		ORDER BY first_timestamp ASC
+	`, cluster, namespace, rangeStart.UTC(), rangeEnd.UTC())
+	if err != nil {
+		return fmt.Errorf("olaylar alınamadı: %v", err)
+	}
+	defer rows.Close()
+
+	var matcher labels.Selector
+	if len(selector) > 0 {
+		matcher = labels.SelectorFromSet(selector)
+	}
+
+	for rows.Next() {
+		var ev K8sEvent
+		var labelsJSON sql.NullString
+		if err := rows.Scan(&ev.ID, &ev.Cluster, &ev.Namespace, &ev.Reason, &ev.Message, &ev.Type,
+			&ev.InvolvedKind, &ev.InvolvedName, &labelsJSON, &ev.Count,
+			&ev.FirstTimestamp, &ev.LastTimestamp); err != nil {
+			return fmt.Errorf("olay okunamadı: %v", err)
+		}
+		if labelsJSON.Valid {
+			json.Unmarshal([]byte(labelsJSON.String), &ev.InvolvedLabels)
+		}
+
+		related := ev.InvolvedKind == "Service" && ev.InvolvedName == name
+		if !related && matcher != nil && len(ev.InvolvedLabels) > 0 {
+			related = matcher.Matches(labels.Set(ev.InvolvedLabels))
+		}
+		if !related {
+			continue
+		}
+
+		for i := range windows {
+			start := windows[i].Start.Add(-eventCorrelationLead)
+			if !ev.LastTimestamp.Before(start) && !ev.FirstTimestamp.After(windows[i].End) {
+				windows[i].Events = append(windows[i].Events, ev)
+			}
+		}
+	}
+
+	return rows.Err()
+}
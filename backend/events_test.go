@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestDB, testler için geçici bir SQLite veritabanı oluşturur
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	testDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("test veritabanı açılamadı: %v", err)
+	}
+	testDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { testDB.Close() })
+
+	if err := createTables(testDB); err != nil {
+		t.Fatalf("tablolar oluşturulamadı: %v", err)
+	}
+	return testDB
+}
+
+func warningEvent(name, reason string, involved corev1.ObjectReference, at time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: involved.Namespace,
+			UID:       types.UID("uid-" + name),
+		},
+		InvolvedObject: involved,
+		Reason:         reason,
+		Message:        reason + " mesajı",
+		Type:           corev1.EventTypeWarning,
+		Count:          1,
+		FirstTimestamp: metav1.NewTime(at),
+		LastTimestamp:  metav1.NewTime(at),
+	}
+}
+
+func TestEventWatcherStoresWarningEvents(t *testing.T) {
+	testDB := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-7d9f",
+			Namespace: "shop",
+			Labels:    map[string]string{"app": "api", "pod-template-hash": "7d9f"},
+		},
+	}
+	podRef := corev1.ObjectReference{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace}
+
+	normal := warningEvent("api-7d9f.normal", "Pulled", podRef, now)
+	normal.Type = corev1.EventTypeNormal
+
+	client := fake.NewSimpleClientset(
+		pod,
+		warningEvent("api-7d9f.oom", "OOMKilling", podRef, now),
+		normal,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewEventWatcher(testDB, client, "default").Run(ctx)
+
+	var count int
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		testDB.QueryRow(`SELECT COUNT(*) FROM k8s_events`).Scan(&count)
+		if count > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if count != 1 {
+		t.Fatalf("1 Warning olayı bekleniyordu, %d bulundu", count)
+	}
+
+	var reason string
+	var labelsJSON sql.NullString
+	testDB.QueryRow(`SELECT reason, involved_labels FROM k8s_events`).Scan(&reason, &labelsJSON)
+	if reason != "OOMKilling" {
+		t.Errorf("beklenmeyen olay nedeni: %s", reason)
+	}
+	if !labelsJSON.Valid {
+		t.Errorf("Pod etiketleri olayla birlikte saklanmalıydı")
+	}
+
+	// Aynı olayın tekrar etmesi yeni satır eklememeli, sayacı güncellemeli
+	repeated := warningEvent("api-7d9f.oom", "OOMKilling", podRef, now)
+	repeated.Count = 3
+	repeated.LastTimestamp = metav1.NewTime(now.Add(time.Minute))
+	w := NewEventWatcher(testDB, client, "default")
+	w.handleEvent(ctx, repeated)
+
+	var storedCount int
+	testDB.QueryRow(`SELECT COUNT(*), MAX(count) FROM k8s_events`).Scan(&count, &storedCount)
+	if count != 1 || storedCount != 3 {
+		t.Errorf("tekrarlanan olay güncellenmeliydi: satır=%d, count=%d", count, storedCount)
+	}
+}
+
+func TestAttachServiceEventsCorrelatesBySelector(t *testing.T) {
+	testDB := newTestDB(t)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	res, err := testDB.Exec(`
+		INSERT INTO services (name, namespace, cluster, type, selector)
+		VALUES ('api', 'shop', 'default', 'service', '{"app":"api"}')
+	`)
+	if err != nil {
+		t.Fatalf("servis eklenemedi: %v", err)
+	}
+	serviceID, _ := res.LastInsertId()
+
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-1", Namespace: "shop", Labels: map[string]string{"app": "api"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}}},
+	)
+	w := NewEventWatcher(testDB, client, "default")
+	ctx := context.Background()
+
+	apiRef := corev1.ObjectReference{Kind: "Pod", Name: "api-1", Namespace: "shop"}
+	webRef := corev1.ObjectReference{Kind: "Pod", Name: "web-1", Namespace: "shop"}
+	svcRef := corev1.ObjectReference{Kind: "Service", Name: "api", Namespace: "shop"}
+
+	w.handleEvent(ctx, warningEvent("api-1.backoff", "BackOff", apiRef, base.Add(2*time.Minute)))
+	w.handleEvent(ctx, warningEvent("svc.sync", "FailedToUpdateEndpoint", svcRef, base.Add(3*time.Minute)))
+	w.handleEvent(ctx, warningEvent("web-1.unhealthy", "Unhealthy", webRef, base.Add(2*time.Minute)))
+	w.handleEvent(ctx, warningEvent("api-1.old", "FailedScheduling", apiRef, base.Add(-time.Hour)))
+
+	statuses := []string{"up", "down", "down", "up"}
+	timestamps := []time.Time{base, base.Add(time.Minute), base.Add(2 * time.Minute), base.Add(4 * time.Minute)}
+	windows := downtimeWindows(statuses, timestamps)
+	if len(windows) != 1 {
+		t.Fatalf("1 kesinti penceresi bekleniyordu, %d bulundu", len(windows))
+	}
+
+	if err := attachServiceEvents(testDB, int(serviceID), windows); err != nil {
+		t.Fatalf("olaylar ilişkilendirilemedi: %v", err)
+	}
+
+	reasons := map[string]bool{}
+	for _, ev := range windows[0].Events {
+		reasons[ev.Reason] = true
+	}
+	if len(windows[0].Events) != 2 || !reasons["BackOff"] || !reasons["FailedToUpdateEndpoint"] {
+		t.Errorf("beklenmeyen ilişkilendirilmiş olaylar: %+v", windows[0].Events)
+	}
+}
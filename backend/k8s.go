@@ -5,19 +5,22 @@ import (
 	"crypto/x509"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// InitKubernetes, Kubernetes API'sine bağlanır
-func InitKubernetes() (*kubernetes.Clientset, error) {
+// InitKubernetes, Kubernetes API'sine bağlanır. CRD ve OpenShift kaynakları için
+// aynı konfigürasyonla oluşturulan dinamik istemci de döner.
+func InitKubernetes() (*kubernetes.Clientset, dynamic.Interface, error) {
 	var config *rest.Config
 	var err error
 	// 1. Önce token tabanlı kimlik doğrulamayı dene
@@ -49,13 +52,13 @@ func InitKubernetes() (*kubernetes.Clientset, error) {
 				if home := os.Getenv("HOME"); home != "" {
 					kubeconfig = filepath.Join(home, ".kube", "config")
 				} else {
-					return nil, fmt.Errorf("kubeconfig dosyası bulunamadı, $HOME ortam değişkeni ayarlanmamış")
+					return nil, nil, fmt.Errorf("kubeconfig dosyası bulunamadı, $HOME ortam değişkeni ayarlanmamış")
 				}
 			}
 			// Kubeconfig dosyasından konfigürasyon oluştur
 			config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
 			if err != nil {
-				return nil, fmt.Errorf("kubeconfig dosyasından konfigürasyon oluşturulamadı: %v", err)
+				return nil, nil, fmt.Errorf("kubeconfig dosyasından konfigürasyon oluşturulamadı: %v", err)
 			}
 
 			log.Println("Kubeconfig tabanlı Kubernetes kimlik doğrulama kullanılıyor")
@@ -66,18 +69,22 @@ func InitKubernetes() (*kubernetes.Clientset, error) {
 	// Clientset oluştur
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("Kubernetes clientset oluşturulamadı: %v", err)
+		return nil, nil, fmt.Errorf("Kubernetes clientset oluşturulamadı: %v", err)
 	}
-	return clientset, nil
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Kubernetes dinamik istemcisi oluşturulamadı: %v", err)
+	}
+	return clientset, dynamicClient, nil
 }
 
 // ListNamespaces, mevcut tüm namespace'leri listeler
-func ListNamespaces() ([]string, error) {
-	if clientset == nil {
+func ListNamespaces(client kubernetes.Interface) ([]string, error) {
+	if client == nil {
 		return nil, fmt.Errorf("Kubernetes istemcisi başlatılmadı")
 	}
 	// Namespace'leri getir
-	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	namespaces, err := client.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("namespace'ler getirilemedi: %v", err)
 	}
@@ -89,12 +96,12 @@ func ListNamespaces() ([]string, error) {
 }
 
 // ListServices, belirli bir namespace'deki tüm servisleri listeler
-func ListServices(namespace string) ([]string, error) {
-	if clientset == nil {
+func ListServices(client kubernetes.Interface, namespace string) ([]string, error) {
+	if client == nil {
 		return nil, fmt.Errorf("Kubernetes istemcisi başlatılmadı")
 	}
 	// Servisleri getir
-	services, err := clientset.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
+	services, err := client.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("servisler getirilemedi: %v", err)
 	}
@@ -105,55 +112,94 @@ func ListServices(namespace string) ([]string, error) {
 	return serviceNames, nil
 }
 
-// TestClusterConnection, bir cluster bağlantısını test eder
-func TestClusterConnection(name, apiURL, authType, token, caCert string, skipTLSVerify int) (bool, string) {
-	// Zaman aşımı ayarla
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// Konfigürasyon oluştur
-	var config *rest.Config
-	var configErr error
-
-	switch authType {
+// buildClusterConfig, saklanan cluster kaydından REST konfigürasyonu oluşturur
+func buildClusterConfig(c ClusterRecord) (*rest.Config, error) {
+	switch c.AuthType {
 	case "token":
 		// Token tabanlı kimlik doğrulama
-		config = &rest.Config{
-			Host:        apiURL,
-			BearerToken: token,
+		config := &rest.Config{
+			Host:        c.APIURL,
+			BearerToken: c.Token,
 			TLSClientConfig: rest.TLSClientConfig{
-				Insecure: skipTLSVerify == 1,
+				Insecure: c.SkipTLSVerify,
 			},
 		}
 
+		// Token bir referanssa (env:, file:, secretRef:) her istekte önbellekten çözülür
+		if isSecretReference(c.Token) {
+			if _, err := secretResolver.Resolve(c.Token, defaultClusterName); err != nil {
+				return nil, err
+			}
+			ref := c.Token
+			config.BearerToken = ""
+			config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+				return &secretRefTransport{ref: ref, cluster: defaultClusterName, next: rt}
+			}
+		}
+
 		// CA sertifikası varsa ekle
-		if caCert != "" && skipTLSVerify == 0 {
-			// CA sertifikasını yükle
+		if c.CACert != "" && !c.SkipTLSVerify {
+			// CA sertifikasını doğrula
 			caCertPool := x509.NewCertPool()
-			if ok := caCertPool.AppendCertsFromPEM([]byte(caCert)); !ok {
-				return false, "Geçersiz CA sertifikası"
+			if ok := caCertPool.AppendCertsFromPEM([]byte(c.CACert)); !ok {
+				return nil, fmt.Errorf("Geçersiz CA sertifikası")
 			}
 
-			config.TLSClientConfig.CAData = []byte(caCert)
+			config.TLSClientConfig.CAData = []byte(c.CACert)
 		}
+		return config, nil
 
 	case "kubeconfig":
-		// Kubeconfig dosyasından konfigürasyon oluştur
-		configErr = fmt.Errorf("Kubeconfig dosyası henüz desteklenmiyor")
-		return false, configErr.Error()
+		// Saklanan kubeconfig'in seçilen context'inden konfigürasyon oluştur
+		return kubeconfigRESTConfig(c.Kubeconfig, c.KubeContext, c.SkipTLSVerify)
 
 	default:
-		return false, fmt.Sprintf("Desteklenmeyen kimlik doğrulama türü: %s", authType)
+		return nil, fmt.Errorf("Desteklenmeyen kimlik doğrulama türü: %s", c.AuthType)
+	}
+}
+
+// newClusterClient, saklanan cluster kaydı için Kubernetes istemcisi oluşturur
+func newClusterClient(c ClusterRecord) (kubernetes.Interface, error) {
+	config, err := buildClusterConfig(c)
+	if err != nil {
+		return nil, err
 	}
 
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Kubernetes istemcisi oluşturulamadı: %v", err)
+	}
+	return client, nil
+}
+
+// newClusterDynamicClient, saklanan cluster kaydı için dinamik istemci oluşturur (CRD'ler, Route'lar)
+func newClusterDynamicClient(c ClusterRecord) (dynamic.Interface, error) {
+	config, err := buildClusterConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Kubernetes dinamik istemcisi oluşturulamadı: %v", err)
+	}
+	return client, nil
+}
+
+// TestClusterConnection, bir cluster bağlantısını test eder
+func TestClusterConnection(c ClusterRecord) (bool, string) {
+	// Zaman aşımı ayarla
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
 	// Kubernetes istemcisini oluştur
-	clientset, err := kubernetes.NewForConfig(config)
+	client, err := newClusterClient(c)
 	if err != nil {
-		return false, fmt.Sprintf("Kubernetes istemcisi oluşturulamadı: %v", err)
+		return false, err.Error()
 	}
 
 	// Temel bir Kubernetes API çağrısı yaparak bağlantıyı test et
-	_, err = clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	_, err = client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		// Hata detaylarını düzenle
 		errMsg := err.Error()
@@ -174,5 +220,5 @@ func TestClusterConnection(name, apiURL, authType, token, caCert string, skipTLS
 	}
 
 	// Bağlantı başarılı
-	return true, fmt.Sprintf("%s kümesine bağlantı başarılı", name)
+	return true, fmt.Sprintf("%s kümesine bağlantı başarılı", c.Name)
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigContext, bir kubeconfig dosyasındaki context özetini temsil eder
+type KubeconfigContext struct {
+	Name       string `json:"name"`
+	Cluster    string `json:"cluster"`
+	Server     string `json:"server"`
+	User       string `json:"user"`
+	Namespace  string `json:"namespace,omitempty"`
+	AuthMethod string `json:"auth_method"`
+	Current    bool   `json:"current"`
+}
+
+// parseKubeconfig, yüklenen kubeconfig içeriğini ayrıştırır
+func parseKubeconfig(data string) (*clientcmdapi.Config, error) {
+	if data == "" {
+		return nil, fmt.Errorf("kubeconfig içeriği boş")
+	}
+
+	config, err := clientcmd.Load([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig ayrıştırılamadı: %v", err)
+	}
+	if len(config.Contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig içinde context bulunamadı")
+	}
+	return config, nil
+}
+
+// kubeconfigAuthMethod, kullanıcı kaydının hangi kimlik doğrulama yöntemini kullandığını döndürür
+func kubeconfigAuthMethod(user *clientcmdapi.AuthInfo) string {
+	switch {
+	case user == nil:
+		return "none"
+	case user.Exec != nil:
+		return "exec"
+	case user.AuthProvider != nil:
+		return "auth-provider"
+	case len(user.ClientCertificateData) > 0 || user.ClientCertificate != "":
+		return "client-certificate"
+	case user.Token != "" || user.TokenFile != "":
+		return "token"
+	case user.Username != "":
+		return "basic"
+	default:
+		return "none"
+	}
+}
+
+// listKubeconfigContexts, kubeconfig içindeki tüm context'leri ada göre sıralı döndürür
+func listKubeconfigContexts(config *clientcmdapi.Config) []KubeconfigContext {
+	contexts := []KubeconfigContext{}
+	for name, kctx := range config.Contexts {
+		item := KubeconfigContext{
+			Name:       name,
+			Cluster:    kctx.Cluster,
+			User:       kctx.AuthInfo,
+			Namespace:  kctx.Namespace,
+			AuthMethod: kubeconfigAuthMethod(config.AuthInfos[kctx.AuthInfo]),
+			Current:    name == config.CurrentContext,
+		}
+		if cluster, ok := config.Clusters[kctx.Cluster]; ok {
+			item.Server = cluster.Server
+		}
+		contexts = append(contexts, item)
+	}
+
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+	return contexts
+}
+
+// extractKubeconfigContext, kubeconfig'i yalnızca seçilen context'in cluster ve
+// kullanıcı bilgilerini içerecek şekilde küçültür. Böylece veritabanında diğer
+// cluster'ların kimlik bilgileri saklanmaz. Küçültülmüş içerik ve API adresi döner.
+func extractKubeconfigContext(data, contextName string) (string, string, error) {
+	config, err := parseKubeconfig(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	if contextName == "" {
+		contextName = config.CurrentContext
+	}
+	kctx, ok := config.Contexts[contextName]
+	if !ok {
+		return "", "", fmt.Errorf("kubeconfig içinde %q context'i bulunamadı", contextName)
+	}
+	cluster, ok := config.Clusters[kctx.Cluster]
+	if !ok {
+		return "", "", fmt.Errorf("%q context'inin cluster tanımı bulunamadı", contextName)
+	}
+
+	config.CurrentContext = contextName
+	if err := clientcmdapi.MinifyConfig(config); err != nil {
+		return "", "", fmt.Errorf("kubeconfig küçültülemedi: %v", err)
+	}
+
+	minified, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", "", fmt.Errorf("kubeconfig yazılamadı: %v", err)
+	}
+	return string(minified), cluster.Server, nil
+}
+
+// kubeconfigRESTConfig, saklanan kubeconfig ve context için REST konfigürasyonu oluşturur.
+// Exec eklentileri ve istemci sertifikaları client-go tarafından desteklenir.
+func kubeconfigRESTConfig(data, contextName string, skipTLSVerify bool) (*rest.Config, error) {
+	config, err := parseKubeconfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: contextName}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, overrides, nil).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig konfigürasyonu oluşturulamadı: %v", err)
+	}
+
+	if skipTLSVerify {
+		// CA verisi ile insecure birlikte kullanılamaz
+		restConfig.TLSClientConfig.Insecure = true
+		restConfig.TLSClientConfig.CAData = nil
+		restConfig.TLSClientConfig.CAFile = ""
+	}
+	return restConfig, nil
+}
+
+// kubeconfigContextsHandler, yüklenen kubeconfig içindeki context'leri listeler
+func kubeconfigContextsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Kubeconfig string `json:"kubeconfig"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+		return
+	}
+
+	config, err := parseKubeconfig(req.Kubeconfig)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"current_context": config.CurrentContext,
+		"contexts":        listKubeconfigContexts(config),
+	})
+}
+
+// importClustersHandler, tek bir kubeconfig dosyasından seçilen context'leri ayrı cluster'lar olarak ekler
+func importClustersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Kubeconfig    string   `json:"kubeconfig"`
+		Contexts      []string `json:"contexts"`
+		NamePrefix    string   `json:"name_prefix"`
+		SkipTLSVerify bool     `json:"skip_tls_verify"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+		return
+	}
+
+	config, err := parseKubeconfig(req.Kubeconfig)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Context belirtilmemişse tümünü içe aktar
+	if len(req.Contexts) == 0 {
+		for _, kctx := range listKubeconfigContexts(config) {
+			req.Contexts = append(req.Contexts, kctx.Name)
+		}
+	}
+
+	imported := []map[string]interface{}{}
+	failed := []map[string]interface{}{}
+
+	for _, contextName := range req.Contexts {
+		record := ClusterRecord{
+			Name:          req.NamePrefix + contextName,
+			AuthType:      "kubeconfig",
+			Kubeconfig:    req.Kubeconfig,
+			KubeContext:   contextName,
+			SkipTLSVerify: req.SkipTLSVerify,
+		}
+
+		id, err := insertCluster(db, &record)
+		if err != nil {
+			failed = append(failed, map[string]interface{}{
+				"context": contextName,
+				"error":   err.Error(),
+			})
+			continue
+		}
+
+		imported = append(imported, map[string]interface{}{
+			"id":      id,
+			"name":    record.Name,
+			"context": contextName,
+			"api_url": record.APIURL,
+		})
+
+		go func(id int) {
+			if err := clusterManager.StartCluster(id); err != nil {
+				log.Printf("Cluster %d işçileri başlatılamadı: %v", id, err)
+			}
+		}(int(id))
+	}
+
+	status := http.StatusCreated
+	if len(imported) == 0 {
+		status = http.StatusBadRequest
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": imported,
+		"failed":   failed,
+	})
+}
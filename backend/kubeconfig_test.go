@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// testKubeconfig, prod (token), stage (exec) ve cluster tanımı eksik bir context içeren kubeconfig üretir
+func testKubeconfig(prodServer string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: %s
+- name: stage
+  cluster:
+    server: https://stage.example.com:6443
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: prod-admin
+    namespace: shop
+- name: stage
+  context:
+    cluster: stage
+    user: stage-sso
+- name: orphan
+  context:
+    cluster: missing
+    user: prod-admin
+users:
+- name: prod-admin
+  user:
+    token: prod-token
+- name: stage-sso
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: stage-login
+`, prodServer)
+}
+
+func TestParseKubeconfigRejectsInvalidInput(t *testing.T) {
+	cases := map[string]string{
+		"boş":              "",
+		"YAML değil":       "::: bu bir kubeconfig değil",
+		"context yok":      "apiVersion: v1\nkind: Config\nclusters: []\n",
+		"yanlış tür alanı": "apiVersion: v1\nkind: Config\ncontexts: 3\n",
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseKubeconfig(data); err == nil {
+				t.Errorf("geçersiz kubeconfig kabul edildi")
+			}
+		})
+	}
+
+	config, err := parseKubeconfig(testKubeconfig("https://prod.example.com:6443"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contexts := listKubeconfigContexts(config)
+	if len(contexts) != 3 || contexts[0].Name != "orphan" || contexts[1].Name != "prod" {
+		t.Fatalf("context'ler ada göre sıralı listelenmeliydi: %+v", contexts)
+	}
+	prod, stage := contexts[1], contexts[2]
+	if !prod.Current || prod.Server != "https://prod.example.com:6443" || prod.AuthMethod != "token" || prod.Namespace != "shop" {
+		t.Errorf("beklenmeyen prod context'i: %+v", prod)
+	}
+	if stage.Current || stage.AuthMethod != "exec" {
+		t.Errorf("beklenmeyen stage context'i: %+v", stage)
+	}
+	if contexts[0].Server != "" {
+		t.Errorf("cluster tanımı olmayan context'in adresi boş olmalıydı: %+v", contexts[0])
+	}
+}
+
+func TestKubeconfigAuthMethod(t *testing.T) {
+	cases := []struct {
+		user *clientcmdapi.AuthInfo
+		want string
+	}{
+		{nil, "none"},
+		{&clientcmdapi.AuthInfo{}, "none"},
+		{&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "login"}, Token: "t"}, "exec"},
+		{&clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc"}}, "auth-provider"},
+		{&clientcmdapi.AuthInfo{ClientCertificateData: []byte("pem")}, "client-certificate"},
+		{&clientcmdapi.AuthInfo{ClientCertificate: "/etc/cert.pem"}, "client-certificate"},
+		{&clientcmdapi.AuthInfo{TokenFile: "/var/run/token"}, "token"},
+		{&clientcmdapi.AuthInfo{Username: "admin", Password: "x"}, "basic"},
+	}
+	for i, c := range cases {
+		if got := kubeconfigAuthMethod(c.user); got != c.want {
+			t.Errorf("%d: beklenen %s, alınan %s", i, c.want, got)
+		}
+	}
+}
+
+func TestExtractKubeconfigContextKeepsOnlySelectedCredentials(t *testing.T) {
+	data := testKubeconfig("https://prod.example.com:6443")
+
+	minified, server, err := extractKubeconfigContext(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server != "https://prod.example.com:6443" {
+		t.Errorf("varsayılan context'in adresi dönmeliydi: %s", server)
+	}
+	if !strings.Contains(minified, "prod-token") || strings.Contains(minified, "stage-login") || strings.Contains(minified, "stage.example.com") {
+		t.Errorf("küçültülmüş kubeconfig yalnızca prod bilgilerini içermeli:\n%s", minified)
+	}
+
+	if _, server, err := extractKubeconfigContext(data, "stage"); err != nil || server != "https://stage.example.com:6443" {
+		t.Errorf("stage context'i çıkarılamadı: %s %v", server, err)
+	}
+	if _, _, err := extractKubeconfigContext(data, "dev"); err == nil || !strings.Contains(err.Error(), "dev") {
+		t.Errorf("olmayan context reddedilmeliydi: %v", err)
+	}
+	if _, _, err := extractKubeconfigContext(data, "orphan"); err == nil || !strings.Contains(err.Error(), "cluster tanımı") {
+		t.Errorf("cluster tanımı olmayan context reddedilmeliydi: %v", err)
+	}
+
+	record := ClusterRecord{Name: "bozuk", AuthType: "kubeconfig", Kubeconfig: data, KubeContext: "orphan"}
+	if err := validateClusterRecord(&record); err == nil {
+		t.Errorf("cluster tanımı olmayan context ile kayıt doğrulanmamalıydı")
+	}
+	record = ClusterRecord{Name: "prod", AuthType: "kubeconfig", Kubeconfig: data}
+	if err := validateClusterRecord(&record); err != nil || record.KubeContext != "prod" || record.APIURL != "https://prod.example.com:6443" {
+		t.Errorf("kayıt varsayılan context ile tamamlanmalıydı: %+v %v", record, err)
+	}
+}
+
+func TestClusterConnectionWithKubeconfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Authorization") != "Bearer prod-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "Unauthorized", "code": 401})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kind": "NamespaceList", "apiVersion": "v1", "items": []interface{}{}})
+	}))
+	defer server.Close()
+
+	record := ClusterRecord{Name: "prod", AuthType: "kubeconfig", Kubeconfig: testKubeconfig(server.URL), SkipTLSVerify: true}
+	if ok, message := TestClusterConnection(record); !ok {
+		t.Errorf("bağlantı başarılı olmalıydı: %s", message)
+	}
+
+	// Sunucu sertifikası doğrulanamayan cluster reddedilir
+	record.SkipTLSVerify = false
+	if ok, message := TestClusterConnection(record); ok || !strings.Contains(message, "sertifika") {
+		t.Errorf("doğrulanmayan sertifika reddedilmeliydi: %s", message)
+	}
+
+	// Yanlış kimlik bilgisi
+	record = ClusterRecord{Name: "prod", AuthType: "kubeconfig", SkipTLSVerify: true,
+		Kubeconfig: strings.Replace(testKubeconfig(server.URL), "token: prod-token", "token: eski-token", 1)}
+	if ok, message := TestClusterConnection(record); ok {
+		t.Errorf("geçersiz token reddedilmeliydi: %s", message)
+	}
+
+	// Erişilemeyen API sunucusu
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "https://" + listener.Addr().String()
+	listener.Close()
+	record = ClusterRecord{Name: "prod", AuthType: "kubeconfig", Kubeconfig: testKubeconfig(closed), SkipTLSVerify: true}
+	if ok, message := TestClusterConnection(record); ok || !strings.Contains(message, "reddedildi") {
+		t.Errorf("erişilemeyen cluster reddedilmeliydi: %s", message)
+	}
+
+	// Geçersiz kubeconfig istemci oluşturulmadan reddedilir
+	record = ClusterRecord{Name: "prod", AuthType: "kubeconfig", Kubeconfig: "yaml değil"}
+	if ok, _ := TestClusterConnection(record); ok {
+		t.Errorf("geçersiz kubeconfig ile bağlantı kurulmamalıydı")
+	}
+}
+
+func TestImportClustersHandlerReportsFailedContexts(t *testing.T) {
+	testDB := newTestDB(t)
+	previousDB, previousManager := db, clusterManager
+	db = testDB
+	ctx, cancel := context.WithCancel(context.Background())
+	clusterManager = NewClusterManager(ctx, testDB)
+	t.Cleanup(func() {
+		cancel()
+		clusterManager.StopAll()
+		db, clusterManager = previousDB, previousManager
+	})
+
+	rec := httptest.NewRecorder()
+	importClustersHandler(rec, httptest.NewRequest("POST", "/api/v1/clusters/import",
+		strings.NewReader(`{"kubeconfig":"yaml değil"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("geçersiz kubeconfig reddedilmeliydi: %d %s", rec.Code, rec.Body)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"kubeconfig":  testKubeconfig("https://127.0.0.1:1"),
+		"contexts":    []string{"prod", "orphan", "dev"},
+		"name_prefix": "acme-",
+	})
+	rec = httptest.NewRecorder()
+	importClustersHandler(rec, httptest.NewRequest("POST", "/api/v1/clusters/import", strings.NewReader(string(body))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("geçerli context içe aktarılmalıydı: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Imported []map[string]interface{} `json:"imported"`
+		Failed   []map[string]interface{} `json:"failed"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Imported) != 1 || resp.Imported[0]["name"] != "acme-prod" || len(resp.Failed) != 2 {
+		t.Fatalf("beklenmeyen içe aktarma sonucu: %s", rec.Body)
+	}
+	// İşçiler arka planda başlatılır
+	eventually(t, "içe aktarılan cluster'ın başlatılması", func() bool {
+		return clusterManager.Running(int(resp.Imported[0]["id"].(float64)))
+	})
+
+	var stored string
+	testDB.QueryRow(`SELECT kubeconfig FROM clusters WHERE name = 'acme-prod'`).Scan(&stored)
+	if strings.Contains(stored, "stage-login") {
+		t.Errorf("diğer context'lerin kimlik bilgileri saklanmamalıydı")
+	}
+}
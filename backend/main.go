@@ -8,13 +8,15 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
 	"backend/api/handlers" // Bu handler'ları içeri aktarır
@@ -22,10 +24,12 @@ import (
 
 // Küresel değişkenler
 var (
-	db            *sql.DB
-	clientset     *kubernetes.Clientset
-	ctx           = context.Background()
-	uptimeMonitor *UptimeMonitor // Global uptime monitor
+	db             *sql.DB
+	clientset      *kubernetes.Clientset
+	dynamicClient  dynamic.Interface
+	ctx            = context.Background()
+	uptimeMonitor  *UptimeMonitor  // Global uptime monitor
+	clusterManager *ClusterManager // Cluster başına istemci ve işçi yöneticisi
 )
 
 // Veritabanı bağlantısını başlat
@@ -105,6 +109,9 @@ func createTables(db *sql.DB) error {
 		return fmt.Errorf("uptime_checks tablosu oluşturulamadı: %w", err)
 	}
 
+	// Sertifika kontrollerinde okunan son kullanma tarihi (cert_expiry alarm kuralları için)
+	db.Exec(`ALTER TABLE uptime_checks ADD COLUMN cert_expires_at TIMESTAMP`)
+
 	// settings tablosu
 	_, err = db.Exec(`
 	CREATE TABLE IF NOT EXISTS settings (
@@ -139,6 +146,358 @@ func createTables(db *sql.DB) error {
     `)
 	// Hata oluşursa (zaten eklenmiş olabilir), görmezden gel
 
+	// services tablosuna etiket seçicisi ekle (olay ilişkilendirmesi için)
+	db.Exec(`ALTER TABLE services ADD COLUMN selector TEXT`)
+
+	// Service label'ları (bildirim yönlendirmesi için, JSON)
+	db.Exec(`ALTER TABLE services ADD COLUMN labels TEXT`)
+
+	// Keşif kaynağı ve arşiv bilgisi (cluster'dan silinen servisler geçmişiyle saklanır)
+	db.Exec(`ALTER TABLE services ADD COLUMN source TEXT DEFAULT 'manual'`)
+	db.Exec(`ALTER TABLE services ADD COLUMN archived_at TIMESTAMP`)
+
+	// Keşif sırasında türetilen aday endpoint'ler; endpoint_source 'auto' ise endpoint ilk adaydan gelir
+	db.Exec(`ALTER TABLE services ADD COLUMN candidate_endpoints TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN endpoint_source TEXT`)
+
+	// Uptime kontrolü kimlik bilgileri (düz metin şifreli saklanır veya env:/file:/secretRef: referansı)
+	db.Exec(`ALTER TABLE services ADD COLUMN check_username TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN check_password TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN check_headers TEXT`)
+
+	// İzleme ayarları ve k8s-monitoring/* annotation durumu. annotation_fields annotation'dan
+	// gelen alanları, ui_overrides arayüzden değiştirilen alanları tutar (JSON)
+	db.Exec(`ALTER TABLE services ADD COLUMN monitoring_enabled BOOLEAN`)
+	db.Exec(`ALTER TABLE services ADD COLUMN expected_status INTEGER`)
+	db.Exec(`ALTER TABLE services ADD COLUMN check_type TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN owner TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN annotation_fields TEXT`)
+	db.Exec(`ALTER TABLE services ADD COLUMN annotation_lock BOOLEAN DEFAULT 0`)
+	db.Exec(`ALTER TABLE services ADD COLUMN ui_overrides TEXT`)
+
+	// Servisi yöneten Monitor/ClusterMonitor kaynağı (ör. Monitor/ns/ad)
+	db.Exec(`ALTER TABLE services ADD COLUMN monitor_ref TEXT`)
+
+	// k8s_events tablosu
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS k8s_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cluster TEXT NOT NULL,
+		uid TEXT NOT NULL,
+		namespace TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		message TEXT,
+		type TEXT NOT NULL,
+		involved_kind TEXT,
+		involved_name TEXT,
+		involved_labels TEXT,
+		count INTEGER DEFAULT 1,
+		first_timestamp TIMESTAMP,
+		last_timestamp TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(cluster, uid)
+	)`)
+	if err != nil {
+		return fmt.Errorf("k8s_events tablosu oluşturulamadı: %w", err)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_k8s_events_ns_time ON k8s_events(cluster, namespace, last_timestamp)`)
+
+	// clusters tablosuna kubeconfig kimlik doğrulaması için alanlar ekle
+	db.Exec(`ALTER TABLE clusters ADD COLUMN kubeconfig TEXT`)
+	db.Exec(`ALTER TABLE clusters ADD COLUMN kube_context TEXT`)
+
+	// Kimlik bilgisi yenileme zamanı
+	db.Exec(`ALTER TABLE clusters ADD COLUMN credentials_updated_at TIMESTAMP`)
+
+	// cluster_checks tablosu (API sunucusu sağlık geçmişi)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS cluster_checks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cluster_id INTEGER,
+		cluster TEXT NOT NULL,
+		status TEXT NOT NULL,
+		response_time INTEGER,
+		readyz_ok INTEGER,
+		livez_ok INTEGER,
+		readyz_failures TEXT,
+		server_version TEXT,
+		node_count INTEGER,
+		error_message TEXT,
+		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("cluster_checks tablosu oluşturulamadı: %w", err)
+	}
+
+	// namespace_policies tablosu (otomatik türetilen kontrollerin namespace bazında açılıp kapatılması)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS namespace_policies (
+		cluster TEXT NOT NULL,
+		namespace TEXT NOT NULL,
+		auto_endpoints INTEGER NOT NULL DEFAULT 1,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY(cluster, namespace)
+	)`)
+	if err != nil {
+		return fmt.Errorf("namespace_policies tablosu oluşturulamadı: %w", err)
+	}
+
+	// discovery_rules tablosu (cluster bazlı keşif include/exclude kuralları, JSON)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS discovery_rules (
+		cluster TEXT PRIMARY KEY,
+		rules TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("discovery_rules tablosu oluşturulamadı: %w", err)
+	}
+
+	// rollout_markers tablosu (Deployment rollout başlangıç/bitiş işaretleri, servis başına)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS rollout_markers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id INTEGER,
+		cluster TEXT NOT NULL,
+		namespace TEXT NOT NULL,
+		deployment TEXT NOT NULL,
+		revision TEXT,
+		status TEXT NOT NULL,
+		message TEXT,
+		started_at TIMESTAMP NOT NULL,
+		ended_at TIMESTAMP,
+		FOREIGN KEY(service_id) REFERENCES services(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("rollout_markers tablosu oluşturulamadı: %w", err)
+	}
+
+	// alerts tablosu (dedup_key ile aynı sorun için tek açık alarm tutulur)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id INTEGER NOT NULL,
+		rule_id INTEGER,
+		dedup_key TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		status TEXT NOT NULL,
+		message TEXT,
+		first_seen TIMESTAMP NOT NULL,
+		last_seen TIMESTAMP NOT NULL,
+		resolved_at TIMESTAMP,
+		resolved_by TEXT,
+		occurrences INTEGER NOT NULL DEFAULT 1,
+		FOREIGN KEY(service_id) REFERENCES services(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("alerts tablosu oluşturulamadı: %w", err)
+	}
+	// alert_rules tablosu (uptime_checks üzerinden değerlendirilen alarm kuralları)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		service_id INTEGER,
+		namespace TEXT,
+		cluster TEXT,
+		threshold REAL NOT NULL,
+		window_seconds INTEGER NOT NULL DEFAULT 0,
+		sample_size INTEGER NOT NULL DEFAULT 0,
+		severity TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("alert_rules tablosu oluşturulamadı: %w", err)
+	}
+	// notification_channels tablosu (config, gizli ayarlar içerdiğinden şifreli saklanır)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_channels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		config TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_channels tablosu oluşturulamadı: %w", err)
+	}
+
+	// notification_messages tablosu (kapanışta güncellenecek kanal iletilerinin referansları)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_messages (
+		alert_id INTEGER NOT NULL,
+		channel_key TEXT NOT NULL,
+		ref TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (alert_id, channel_key)
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_messages tablosu oluşturulamadı: %w", err)
+	}
+	// notification_digest tablosu (özet modundaki kanallar için biriken olaylar)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_digest (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		channel_key TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		alert TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_digest tablosu oluşturulamadı: %w", err)
+	}
+	// Alarm onayı (onaylanan alarmların eskalasyonu durur)
+	db.Exec(`ALTER TABLE alerts ADD COLUMN acknowledged_at TIMESTAMP`)
+	db.Exec(`ALTER TABLE alerts ADD COLUMN acknowledged_by TEXT`)
+	// Alarm erteleme ve sessize alma (bildirimi bastıran kural)
+	db.Exec(`ALTER TABLE alerts ADD COLUMN snoozed_until TIMESTAMP`)
+	db.Exec(`ALTER TABLE alerts ADD COLUMN snoozed_by TEXT`)
+	db.Exec(`ALTER TABLE alerts ADD COLUMN silenced_by INTEGER`)
+
+	// silences tablosu (eşleştiriciler JSON, süre dolunca kayıt geçmiş için kalır)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS silences (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		matchers TEXT NOT NULL,
+		starts_at TIMESTAMP NOT NULL,
+		ends_at TIMESTAMP NOT NULL,
+		comment TEXT NOT NULL,
+		created_by TEXT,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("silences tablosu oluşturulamadı: %w", err)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_silences_ends_at ON silences(ends_at)`)
+
+	// oncall_schedules ve oncall_overrides tabloları (nöbet rotasyonları ve geçici değişiklikler)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS oncall_schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		rotation TEXT NOT NULL,
+		participants TEXT NOT NULL,
+		start_at TIMESTAMP NOT NULL,
+		timezone TEXT NOT NULL DEFAULT 'UTC',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("oncall_schedules tablosu oluşturulamadı: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS oncall_overrides (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		schedule_id INTEGER NOT NULL,
+		person TEXT NOT NULL,
+		start_at TIMESTAMP NOT NULL,
+		end_at TIMESTAMP NOT NULL,
+		comment TEXT,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY(schedule_id) REFERENCES oncall_schedules(id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("oncall_overrides tablosu oluşturulamadı: %w", err)
+	}
+
+	// escalation_policies ve alert_escalations tabloları (seviyeler JSON, alarm başına ilerleme)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		levels TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("escalation_policies tablosu oluşturulamadı: %w", err)
+	}
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS alert_escalations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		alert_id INTEGER NOT NULL,
+		policy_id INTEGER NOT NULL,
+		next_level INTEGER NOT NULL DEFAULT 0,
+		next_at TIMESTAMP,
+		status TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		UNIQUE(alert_id, policy_id)
+	)`)
+	if err != nil {
+		return fmt.Errorf("alert_escalations tablosu oluşturulamadı: %w", err)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_alert_escalations_due ON alert_escalations(status, next_at)`)
+
+	// notification_routes tablosu (bildirim yönlendirme ağacı, tek satır JSON)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_routes (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		tree TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_routes tablosu oluşturulamadı: %w", err)
+	}
+
+	// notification_deliveries tablosu (kanal başına gönderim kaydı ve yeniden denemeler)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		channel_key TEXT NOT NULL,
+		channel_name TEXT NOT NULL,
+		alert_id INTEGER NOT NULL,
+		event_type TEXT NOT NULL,
+		alert TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_deliveries tablosu oluşturulamadı: %w", err)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_notification_deliveries_pending ON notification_deliveries(status, next_attempt_at)`)
+
+	// notification_backlog tablosu (bellek kuyruğuna sığmayan, gönderilmeyi bekleyen alarm olayları)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS notification_backlog (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		event TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("notification_backlog tablosu oluşturulamadı: %w", err)
+	}
+	// alert_manual_resolutions tablosu (kesinti sürerken elle kapatılan alarm anahtarları;
+	// servis düzelene kadar aynı anahtarla yeni alarm açılmaz, yeniden başlatmada korunur)
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS alert_manual_resolutions (
+		dedup_key TEXT PRIMARY KEY,
+		resolved_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("alert_manual_resolutions tablosu oluşturulamadı: %w", err)
+	}
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_alerts_rule ON alerts(rule_id, status)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_uptime_checks_service_time ON uptime_checks(service_id, timestamp)`)
+	db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open_key ON alerts(dedup_key) WHERE status = 'open'`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_alerts_service ON alerts(service_id, last_seen)`)
+	// Açık işaretlerin alarm bastırmasının sınırı (başlangıç + progressDeadlineSeconds)
+	db.Exec(`ALTER TABLE rollout_markers ADD COLUMN deadline_at TIMESTAMP`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_rollout_markers_service ON rollout_markers(service_id, started_at)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_rollout_markers_deployment ON rollout_markers(cluster, namespace, deployment, ended_at)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_cluster_checks_time ON cluster_checks(cluster, timestamp)`)
+
 	return nil
 }
 
@@ -149,7 +508,11 @@ func clustersHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		// Tüm cluster'ları getir
-		rows, err := db.Query("SELECT id, name, api_url, auth_type, skip_tls_verify FROM clusters")
+		rows, err := db.Query(`
+			SELECT id, name, api_url, auth_type, skip_tls_verify, COALESCE(kube_context, ''),
+			COALESCE(token, '') != '', COALESCE(ca_cert, '') != '', COALESCE(kubeconfig, '') != ''
+			FROM clusters
+		`)
 		if err != nil {
 			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı sorgusu başarısız: %v"}`, err), http.StatusInternalServerError)
 			return
@@ -160,9 +523,10 @@ func clustersHandler(w http.ResponseWriter, r *http.Request) {
 
 		for rows.Next() {
 			var id int
-			var name, apiURL, authType string
+			var name, apiURL, authType, kubeContext string
 			var skipTLSVerify int
-			if err := rows.Scan(&id, &name, &apiURL, &authType, &skipTLSVerify); err != nil {
+			var tokenSet, caCertSet, kubeconfigSet bool
+			if err := rows.Scan(&id, &name, &apiURL, &authType, &skipTLSVerify, &kubeContext, &tokenSet, &caCertSet, &kubeconfigSet); err != nil {
 				http.Error(w, fmt.Sprintf(`{"error":"Veri okunamadı: %v"}`, err), http.StatusInternalServerError)
 				return
 			}
@@ -173,8 +537,26 @@ func clustersHandler(w http.ResponseWriter, r *http.Request) {
 				"api_url":         apiURL,
 				"auth_type":       authType,
 				"skip_tls_verify": skipTLSVerify == 1,
+				"context":         kubeContext,
+				// Sırlar hiçbir zaman geri döndürülmez, yalnızca tanımlı olup olmadıkları
+				"token_set":      tokenSet,
+				"ca_cert_set":    caCertSet,
+				"kubeconfig_set": kubeconfigSet,
 			})
 		}
+		rows.Close()
+
+		// Durum bilgisi sağlık izleyicisinin son sonucundan gelir
+		for _, cluster := range clusters {
+			health := clusterStatusSummary(db, cluster["name"].(string))
+			cluster["status"] = health["status"]
+			cluster["health"] = health
+
+			// Token ve sertifika bitiş tarihleri
+			if record, err := loadCluster(db, cluster["id"].(int)); err == nil {
+				cluster["credentials"] = clusterCredentialReport(record, time.Now())
+			}
+		}
 
 		response := map[string]interface{}{
 			"clusters": clusters,
@@ -190,6 +572,8 @@ func clustersHandler(w http.ResponseWriter, r *http.Request) {
 			Token         string `json:"token"`
 			CACert        string `json:"ca_cert"`
 			SkipTLSVerify bool   `json:"skip_tls_verify"`
+			Kubeconfig    string `json:"kubeconfig"`
+			Context       string `json:"context"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&cluster); err != nil {
@@ -197,47 +581,53 @@ func clustersHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Gerekli alanları kontrol et
-		if cluster.Name == "" || cluster.ApiURL == "" || cluster.AuthType == "" {
-			http.Error(w, `{"error":"Name, api_url ve auth_type alanları gerekli"}`, http.StatusBadRequest)
-			return
+		record := ClusterRecord{
+			Name:          cluster.Name,
+			APIURL:        cluster.ApiURL,
+			AuthType:      cluster.AuthType,
+			Token:         cluster.Token,
+			CACert:        cluster.CACert,
+			SkipTLSVerify: cluster.SkipTLSVerify,
+			Kubeconfig:    cluster.Kubeconfig,
+			KubeContext:   cluster.Context,
 		}
 
-		// Token auth type için token kontrolü
-		if cluster.AuthType == "token" && cluster.Token == "" {
-			http.Error(w, `{"error":"Token auth type için token gerekli"}`, http.StatusBadRequest)
+		// Alanları doğrula ve veritabanına kaydet
+		if err := validateClusterRecord(&record); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
 			return
 		}
-
-		// Veritabanına kaydet
-		skipTLSVerify := 0
-		if cluster.SkipTLSVerify {
-			skipTLSVerify = 1
-		}
-
-		result, err := db.Exec(`
-			INSERT INTO clusters (name, api_url, auth_type, token, ca_cert, skip_tls_verify)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, cluster.Name, cluster.ApiURL, cluster.AuthType, cluster.Token, cluster.CACert, skipTLSVerify)
+		id, err := insertCluster(db, &record)
 		if err != nil {
-			http.Error(w, fmt.Sprintf(`{"error":"Cluster eklenemedi: %v"}`, err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusInternalServerError)
 			return
 		}
 
-		// Yeni cluster ID'sini al
-		id, _ := result.LastInsertId()
+		// Bağlantıyı test et ve hangi özelliklerin çalışacağını yanıtta raporla
+		// (yetki kontrolü preflightCluster içinde zaman aşımıyla sınırlıdır)
+		connected, message := testConnection(int(id))
+		response := map[string]interface{}{
+			"id":        id,
+			"message":   "Cluster başarıyla eklendi",
+			"connected": connected,
+		}
+		if !connected {
+			log.Printf("Cluster %d bağlantı testi başarısız: %s", id, message)
+			response["connection_message"] = message
+		} else if report, err := preflightCluster(int(id)); err != nil {
+			log.Printf("Cluster %d yetki kontrolü yapılamadı: %v", id, err)
+			response["permissions_error"] = err.Error()
+		} else {
+			response["permissions"] = report
+		}
 
-		// Test bağlantısı
+		// Cluster işçilerini başlat
 		go func() {
-			// Test cluster bağlantısı
-			testConnection(int(id))
+			if err := clusterManager.StartCluster(int(id)); err != nil {
+				log.Printf("Cluster %d işçileri başlatılamadı: %v", id, err)
+			}
 		}()
 
-		// Başarılı yanıt
-		response := map[string]interface{}{
-			"id":      id,
-			"message": "Cluster başarıyla eklendi",
-		}
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(response)
 
@@ -260,18 +650,26 @@ func clusterHandler(w http.ResponseWriter, r *http.Request) {
 	case "GET":
 		// Cluster detaylarını getir
 		var cluster struct {
-			ID            int    `json:"id"`
-			Name          string `json:"name"`
-			ApiURL        string `json:"api_url"`
-			AuthType      string `json:"auth_type"`
-			SkipTLSVerify bool   `json:"skip_tls_verify"`
-			Status        string `json:"status"`
+			ID            int                    `json:"id"`
+			Name          string                 `json:"name"`
+			ApiURL        string                 `json:"api_url"`
+			AuthType      string                 `json:"auth_type"`
+			SkipTLSVerify bool                   `json:"skip_tls_verify"`
+			Status        string                 `json:"status"`
+			Running       bool                   `json:"running"`
+			Health        map[string]interface{} `json:"health"`
+			Credentials   *CredentialReport      `json:"credentials,omitempty"`
+			TokenSet      bool                   `json:"token_set"`
+			CACertSet     bool                   `json:"ca_cert_set"`
+			KubeconfigSet bool                   `json:"kubeconfig_set"`
 		}
 
 		err := db.QueryRow(`
-			SELECT id, name, api_url, auth_type, skip_tls_verify
+			SELECT id, name, api_url, auth_type, skip_tls_verify,
+			COALESCE(token, '') != '', COALESCE(ca_cert, '') != '', COALESCE(kubeconfig, '') != ''
 			FROM clusters WHERE id = ?
-		`, id).Scan(&cluster.ID, &cluster.Name, &cluster.ApiURL, &cluster.AuthType, &cluster.SkipTLSVerify)
+		`, id).Scan(&cluster.ID, &cluster.Name, &cluster.ApiURL, &cluster.AuthType, &cluster.SkipTLSVerify,
+			&cluster.TokenSet, &cluster.CACertSet, &cluster.KubeconfigSet)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -282,13 +680,24 @@ func clusterHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// TODO: Cluster bağlantı durumunu kontrol et
-		cluster.Status = "unknown"
+		// Bağlantı durumu periyodik sağlık kontrollerinden gelir
+		cluster.Health = clusterStatusSummary(db, cluster.Name)
+		cluster.Status = cluster.Health["status"].(string)
+		cluster.Running = clusterManager.Running(cluster.ID)
+
+		// Token ve sertifika bitiş tarihleri
+		if record, err := loadCluster(db, cluster.ID); err == nil {
+			report := clusterCredentialReport(record, time.Now())
+			cluster.Credentials = &report
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(cluster)
 
 	case "DELETE":
+		// Önce cluster işçilerini durdur
+		clusterManager.StopCluster(id)
+
 		// Cluster'ı sil
 		_, err := db.Exec("DELETE FROM clusters WHERE id = ?", id)
 		if err != nil {
@@ -296,6 +705,15 @@ func clusterHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Bu cluster'dan keşfedilen servisleri geçmişleriyle birlikte arşivle
+		_, err = db.Exec(`
+			UPDATE services SET archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE cluster_id = ? AND source = 'discovery' AND archived_at IS NULL
+		`, id)
+		if err != nil {
+			log.Printf("Cluster %d servisleri arşivlenemedi: %v", id, err)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"message": "Cluster silindi"})
 
@@ -317,32 +735,36 @@ func testClusterHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Test bağlantısı
 	connected, message := testConnection(id)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	response := map[string]interface{}{
 		"connected": connected,
 		"message":   message,
-	})
+	}
+
+	// Bağlantı başarılıysa hangi özelliklerin çalışacağını da raporla
+	if connected {
+		report, err := preflightCluster(id)
+		if err != nil {
+			response["permissions_error"] = err.Error()
+		} else {
+			response["permissions"] = report
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // testConnection, bir cluster bağlantısını test eder
 func testConnection(clusterID int) (bool, string) {
 	// Cluster bilgilerini al
-	var name, apiURL, authType, token, caCert string
-	var skipTLSVerify int
-
-	err := db.QueryRow(`
-		SELECT name, api_url, auth_type, token, ca_cert, skip_tls_verify
-		FROM clusters WHERE id = ?
-	`, clusterID).Scan(&name, &apiURL, &authType, &token, &caCert, &skipTLSVerify)
-
+	record, err := loadCluster(db, clusterID)
 	if err != nil {
 		log.Printf("Cluster bilgileri alınamadı: %v", err)
 		return false, fmt.Sprintf("Cluster bilgileri alınamadı: %v", err)
 	}
 
 	// K8s.go'dan test bağlantısını çağır
-	return TestClusterConnection(name, apiURL, authType, token, caCert, skipTLSVerify)
+	return TestClusterConnection(record)
 }
 
 // Ana sayfa handler'ı
@@ -357,12 +779,13 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 // Sağlık kontrolü endpoint'i
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	k8sConnected := clientset != nil
+	k8sConnected := clusterManager.Count() > 0
 
 	response := map[string]interface{}{
 		"status":               "ok",
 		"database_connected":   true,
 		"kubernetes_connected": k8sConnected,
+		"clusters":             clusterManager.Count(),
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -384,8 +807,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 	case "GET":
 		log.Printf("[DEBUG] GET isteği işleniyor")
 
+		// Arşivlenmiş (cluster'dan silinmiş) servisler yalnızca istenirse döner
+		query := "SELECT id, name, namespace, cluster, type, endpoint, check_interval, archived_at, check_username, check_password, check_headers, candidate_endpoints, endpoint_source, " + serviceMonitoringColumns + " FROM services"
+		if r.URL.Query().Get("includeArchived") != "true" {
+			query += " WHERE archived_at IS NULL"
+		}
+
 		// Veritabanı sorgusu
-		rows, err := db.Query("SELECT id, name, namespace, cluster, type, endpoint, check_interval FROM services")
+		rows, err := db.Query(query)
 		if err != nil {
 			log.Printf("[ERROR] Veritabanı sorgusu hatası: %v", err)
 			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v","success":false}`, err), http.StatusInternalServerError)
@@ -399,8 +828,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			var name, namespace, cluster, sType string
 			var endpoint sql.NullString
 			var checkInterval int
-
-			if err := rows.Scan(&id, &name, &namespace, &cluster, &sType, &endpoint, &checkInterval); err != nil {
+			var archivedAt sql.NullTime
+			var checkUsername, checkPassword, checkHeaders sql.NullString
+			var candidateEndpoints, endpointSource sql.NullString
+			var monitoring serviceMonitoringRow
+
+			dest := []interface{}{&id, &name, &namespace, &cluster, &sType, &endpoint, &checkInterval, &archivedAt,
+				&checkUsername, &checkPassword, &checkHeaders, &candidateEndpoints, &endpointSource}
+			if err := rows.Scan(append(dest, monitoring.dest()...)...); err != nil {
 				log.Printf("[ERROR] Veri okuma hatası: %v", err)
 				continue
 			}
@@ -413,7 +848,17 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 				"type":           sType,
 				"endpoint":       endpoint.String,
 				"check_interval": checkInterval,
+				"archived":       archivedAt.Valid,
+				"cluster_status": clusterStatus(cluster),
 			}
+			if archivedAt.Valid {
+				serviceInfo["archived_at"] = archivedAt.Time
+			}
+			if auth, err := decodeCheckAuth(checkUsername, checkPassword, checkHeaders); err == nil {
+				auth.View(serviceInfo)
+			}
+			addEndpointCandidates(serviceInfo, candidateEndpoints, endpointSource)
+			monitoring.View(serviceInfo)
 
 			services = append(services, serviceInfo)
 		}
@@ -440,6 +885,8 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			Type          string `json:"type"`
 			Endpoint      string `json:"endpoint"`
 			CheckInterval int    `json:"check_interval"`
+			CheckAuthInput
+			MonitoringInput
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
@@ -453,6 +900,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, `{"error":"Name ve namespace alanları zorunludur","success":false}`, http.StatusBadRequest)
 			return
 		}
+		if err := service.CheckAuthInput.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q,"success":false}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		if err := service.MonitoringInput.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q,"success":false}`, err.Error()), http.StatusBadRequest)
+			return
+		}
 
 		// Varsayılan değerleri ayarla
 		if service.Cluster == "" {
@@ -465,12 +920,18 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			service.CheckInterval = 60
 		}
 
-		log.Printf("[DEBUG] Yeni servis ekleniyor: %+v", service)
+		log.Printf("[DEBUG] Yeni servis ekleniyor: %s/%s (%s)", service.Namespace, service.Name, service.Cluster)
 
+		// cluster_id, aynı adlı kayıtlı cluster'dan doldurulur (varsayılan cluster için boş kalır)
+		// Elle girilen endpoint keşif tarafından değiştirilmez
+		var endpointSource sql.NullString
+		if service.Endpoint != "" {
+			endpointSource = sql.NullString{String: "manual", Valid: true}
+		}
 		result, err := db.Exec(`
-			INSERT INTO services (name, namespace, cluster, type, endpoint, check_interval)
-			VALUES (?, ?, ?, ?, ?, ?)
-		`, service.Name, service.Namespace, service.Cluster, service.Type, service.Endpoint, service.CheckInterval)
+			INSERT INTO services (name, namespace, cluster, cluster_id, type, endpoint, endpoint_source, check_interval)
+			VALUES (?, ?, ?, (SELECT id FROM clusters WHERE name = ?), ?, ?, ?, ?)
+		`, service.Name, service.Namespace, service.Cluster, service.Cluster, service.Type, service.Endpoint, endpointSource, service.CheckInterval)
 
 		if err != nil {
 			log.Printf("[DEBUG] Servis ekleme hatası: %v", err)
@@ -481,6 +942,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 		id, _ := result.LastInsertId()
 		log.Printf("[DEBUG] Yeni servis başarıyla eklendi. ID: %d", id)
 
+		if err := saveCheckAuth(db, id, service.CheckAuthInput); err != nil {
+			log.Printf("[ERROR] Servis %d kimlik bilgileri kaydedilemedi: %v", id, err)
+		}
+		if err := saveMonitoringInput(db, id, service.MonitoringInput); err != nil {
+			log.Printf("[ERROR] Servis %d izleme ayarları kaydedilemedi: %v", id, err)
+		}
+		uptimeMonitor.RequestReload()
+
 		response := map[string]interface{}{
 			"id":      id,
 			"message": "Servis başarıyla eklendi",
@@ -507,6 +976,8 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			Type          string `json:"type"`
 			Endpoint      string `json:"endpoint"`
 			CheckInterval int    `json:"check_interval"`
+			CheckAuthInput
+			MonitoringInput
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
@@ -520,6 +991,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, `{"error":"Name ve namespace alanları zorunludur","success":false}`, http.StatusBadRequest)
 			return
 		}
+		if err := service.CheckAuthInput.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q,"success":false}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		if err := service.MonitoringInput.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q,"success":false}`, err.Error()), http.StatusBadRequest)
+			return
+		}
 
 		// Varsayılan değerleri ayarla
 		if service.Cluster == "" {
@@ -532,7 +1011,7 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			service.CheckInterval = 60
 		}
 
-		log.Printf("[DEBUG] Servis güncelleniyor. ID: %d, Yeni değerler: %+v", id, service)
+		log.Printf("[DEBUG] Servis güncelleniyor. ID: %d, Yeni değerler: %s/%s (%s) %s", id, service.Namespace, service.Name, service.Cluster, service.Endpoint)
 
 		// Önce servisi kontrol et
 		var existingService struct {
@@ -550,6 +1029,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// k8s-monitoring/lock annotation'ı olan servislerde annotation'dan gelen alanlar değiştirilemez
+		annotationState, changedFields, lockedFields, err := enforceAnnotationLocks(db, id, &service.Endpoint, &service.CheckInterval, &service.MonitoringInput)
+		if err != nil {
+			log.Printf("[ERROR] Servis %d annotation durumu okunamadı: %v", id, err)
+			http.Error(w, fmt.Sprintf(`{"error":"Servis kontrol edilemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+
 		// Transaction başlat
 		tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 		if err != nil {
@@ -604,8 +1091,14 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			SET name = ?, 
 				namespace = ?, 
 				cluster = ?, 
+				cluster_id = (SELECT id FROM clusters WHERE name = ?),
 				type = ?, 
 				endpoint = ?, 
+				endpoint_source = CASE
+					WHEN COALESCE(endpoint, '') = ? THEN endpoint_source
+					WHEN ? = '' THEN NULL
+					ELSE 'manual'
+				END,
 				check_interval = ?, 
 				updated_at = CURRENT_TIMESTAMP
 			WHERE id = ?
@@ -613,8 +1106,11 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			service.Name,
 			service.Namespace,
 			service.Cluster,
+			service.Cluster,
 			service.Type,
 			sql.NullString{String: service.Endpoint, Valid: true},
+			service.Endpoint,
+			service.Endpoint,
 			service.CheckInterval,
 			id)
 
@@ -676,6 +1172,18 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Gönderilen kimlik bilgisi alanlarını kaydet
+		if err := saveCheckAuth(db, int64(id), service.CheckAuthInput); err != nil {
+			log.Printf("[ERROR] Servis %d kimlik bilgileri kaydedilemedi: %v", id, err)
+		}
+		if err := saveMonitoringInput(db, int64(id), service.MonitoringInput); err != nil {
+			log.Printf("[ERROR] Servis %d izleme ayarları kaydedilemedi: %v", id, err)
+		}
+		if err := recordUIOverrides(db, id, annotationState, changedFields); err != nil {
+			log.Printf("[ERROR] Servis %d arayüz değişiklikleri kaydedilemedi: %v", id, err)
+		}
+		uptimeMonitor.RequestReload()
+
 		// Başarılı yanıt
 		response := map[string]interface{}{
 			"message": "Servis başarıyla güncellendi",
@@ -690,6 +1198,11 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 				"check_interval": updatedService.CheckInterval,
 			},
 		}
+		if len(lockedFields) > 0 {
+			// Kilitli alanlar annotation değerinde kaldı
+			response["locked_fields"] = lockedFields
+			response["message"] = "Servis güncellendi; annotation ile kilitli alanlar değiştirilmedi"
+		}
 		json.NewEncoder(w).Encode(response)
 
 	case "DELETE":
@@ -753,6 +1266,42 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Silinen uptime kayıt sayısı: %d", deletedChecks)
 		}
 
+		// Servisin alarmlarını ve bildirim iletisi referanslarını sil
+		if _, err = tx.ExecContext(ctx, "DELETE FROM notification_messages WHERE alert_id IN (SELECT id FROM alerts WHERE service_id = ?)", id); err != nil {
+			log.Printf("Bildirim kayıtları silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Bildirim kayıtları silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM alert_escalations WHERE alert_id IN (SELECT id FROM alerts WHERE service_id = ?)", id); err != nil {
+			log.Printf("Eskalasyon kayıtları silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Eskalasyon kayıtları silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM notification_deliveries WHERE alert_id IN (SELECT id FROM alerts WHERE service_id = ?)", id); err != nil {
+			log.Printf("Bildirim kayıtları silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Bildirim kayıtları silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+		if _, err = tx.ExecContext(ctx, "DELETE FROM alerts WHERE service_id = ?", id); err != nil {
+			log.Printf("Alarm kayıtları silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm kayıtları silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		// Yalnızca bu servise bağlı alarm kurallarını sil
+		if _, err = tx.ExecContext(ctx, "DELETE FROM alert_rules WHERE scope = ? AND service_id = ?", RuleScopeService, id); err != nil {
+			log.Printf("Alarm kuralı silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Alarm kuralları silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+
+		// Servisin rollout işaretlerini sil
+		if _, err = tx.ExecContext(ctx, "DELETE FROM rollout_markers WHERE service_id = ?", id); err != nil {
+			log.Printf("Rollout işareti silme hatası: %v", err)
+			http.Error(w, fmt.Sprintf(`{"error":"Rollout işaretleri silinemedi: %v","success":false}`, err), http.StatusInternalServerError)
+			return
+		}
+
 		// Sonra servisi sil
 		result, err = tx.ExecContext(ctx, "DELETE FROM services WHERE id = ?", id)
 		if err != nil {
@@ -782,6 +1331,7 @@ func servicesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		log.Printf("'%s' isimli servis başarıyla silindi (ID: %d)", serviceName, id)
+		uptimeMonitor.RequestReload()
 
 		// Başarılı yanıt
 		w.WriteHeader(http.StatusOK)
@@ -804,15 +1354,22 @@ func mergedNamespacesHandler(w http.ResponseWriter, r *http.Request) {
 
 	// İlk olarak Kubernetes'ten namespace'leri çekelim (varsa)
 	mergedNamespaces := []string{}
-	if clientset != nil {
-		nsList, err := ListNamespaces()
+	client, clusterName, err := clusterManager.Client(r.URL.Query().Get("cluster"))
+	if err == nil {
+		nsList, err := ListNamespaces(client)
 		if err == nil {
 			mergedNamespaces = append(mergedNamespaces, nsList...)
 		}
 	}
 
 	// Ardından, veritabanındaki servislerden distinct namespace değerlerini ekleyelim
-	rows, err := db.Query("SELECT DISTINCT namespace FROM services")
+	query := "SELECT DISTINCT namespace FROM services"
+	args := []interface{}{}
+	if r.URL.Query().Get("cluster") != "" && clusterName != "" {
+		query += " WHERE cluster = ?"
+		args = append(args, clusterName)
+	}
+	rows, err := db.Query(query, args...)
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
@@ -843,12 +1400,14 @@ func mergedNamespacesHandler(w http.ResponseWriter, r *http.Request) {
 func namespacesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if clientset == nil {
-		http.Error(w, `{"error":"Kubernetes bağlantısı kurulmadı"}`, http.StatusServiceUnavailable)
+	// ?cluster= parametresi cluster adı veya ID'si olabilir
+	client, clusterName, err := clusterManager.Client(r.URL.Query().Get("cluster"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Kubernetes bağlantısı kurulmadı: %v"}`, err), http.StatusServiceUnavailable)
 		return
 	}
 
-	namespaces, err := ListNamespaces()
+	namespaces, err := ListNamespaces(client)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"error":"Namespace'ler listelenemedi: %v"}`, err), http.StatusInternalServerError)
 		return
@@ -856,6 +1415,7 @@ func namespacesHandler(w http.ResponseWriter, r *http.Request) {
 
 	// JSON yanıtı oluştur
 	response := map[string]interface{}{
+		"cluster":    clusterName,
 		"namespaces": namespaces,
 	}
 
@@ -866,8 +1426,10 @@ func namespacesHandler(w http.ResponseWriter, r *http.Request) {
 func k8sServicesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if clientset == nil {
-		http.Error(w, `{"error":"Kubernetes bağlantısı kurulmadı"}`, http.StatusServiceUnavailable)
+	// ?cluster= parametresi cluster adı veya ID'si olabilir
+	client, clusterName, err := clusterManager.Client(r.URL.Query().Get("cluster"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Kubernetes bağlantısı kurulmadı: %v"}`, err), http.StatusServiceUnavailable)
 		return
 	}
 
@@ -877,7 +1439,7 @@ func k8sServicesHandler(w http.ResponseWriter, r *http.Request) {
 		namespace = "default" // Varsayılan namespace
 	}
 
-	services, err := ListServices(namespace)
+	services, err := ListServices(client, namespace)
 	if err != nil {
 		http.Error(w, fmt.Sprintf(`{"error":"Servisler listelenemedi: %v"}`, err), http.StatusInternalServerError)
 		return
@@ -885,6 +1447,7 @@ func k8sServicesHandler(w http.ResponseWriter, r *http.Request) {
 
 	// JSON yanıtı oluştur
 	response := map[string]interface{}{
+		"cluster":   clusterName,
 		"namespace": namespace,
 		"services":  services,
 	}
@@ -892,10 +1455,38 @@ func k8sServicesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// settingsGetHandler, kayıtlı API ayarlarını döndürür. Token yalnızca tanımlı olup olmadığıyla gösterilir.
+func settingsGetHandler(w http.ResponseWriter) {
+	values := map[string]string{}
+	rows, err := db.Query("SELECT key, value FROM settings")
+	if err != nil {
+		http.Error(w, `{"error":"Ayarlar okunamadı"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var value sql.NullString
+		if err := rows.Scan(&key, &value); err == nil {
+			values[key] = value.String
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"k8s_api_url":         values["k8s_api_url"],
+		"k8s_api_token_set":   values["k8s_api_token"] != "",
+		"k8s_skip_tls_verify": values["k8s_skip_tls_verify"] == "true",
+	})
+}
+
 // settingsHandler, API ayarlarını kaydeder
 func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	if r.Method == "GET" {
+		settingsGetHandler(w)
+		return
+	}
 	if r.Method != "POST" {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		return
@@ -924,11 +1515,17 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Token veritabanına şifreli yazılır
+	encryptedToken, err := encryptSecret(settings.K8sApiToken)
+	if err != nil {
+		http.Error(w, `{"error":"Token şifrelenemedi"}`, http.StatusInternalServerError)
+		return
+	}
 	_, err = db.Exec(`
 		INSERT INTO settings (key, value)
 		VALUES ('k8s_api_token', ?)
 		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
-	`, settings.K8sApiToken)
+	`, encryptedToken)
 	if err != nil {
 		http.Error(w, `{"error":"Ayarlar kaydedilemedi"}`, http.StatusInternalServerError)
 		return
@@ -956,14 +1553,16 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Kubernetes bağlantısını yeniden başlat
-		newClientset, err := InitKubernetes()
+		newClientset, newDynamicClient, err := InitKubernetes()
 		if err != nil {
 			log.Printf("Kubernetes bağlantısı yeniden başlatılamadı: %v", err)
 			return
 		}
 
-		// Global clientset'i güncelle
+		// Global clientset'i güncelle ve varsayılan cluster işçilerini yeniden başlat
 		clientset = newClientset
+		dynamicClient = newDynamicClient
+		clusterManager.SetDefaultClient(newClientset, newDynamicClient)
 		log.Println("Kubernetes bağlantısı başarıyla yeniden başlatıldı")
 	}()
 
@@ -974,51 +1573,6 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// serviceDiscoveryWorker, arka planda çalışarak K8s servislerini keşfeder ve veritabanına kaydeder
-func serviceDiscoveryWorker() {
-	for {
-		log.Println("Servis keşif işlemi başlatılıyor...")
-
-		// Tüm namespace'leri tara
-		namespaces, err := ListNamespaces()
-		if err != nil {
-			log.Printf("Namespace'ler listelenemedi: %v", err)
-			time.Sleep(5 * time.Minute)
-			continue
-		}
-
-		for _, namespace := range namespaces {
-			// Her namespace'deki servisleri tara
-			services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
-			if err != nil {
-				log.Printf("Namespace %s içindeki servisler listelenemedi: %v", namespace, err)
-				continue
-			}
-
-			for _, service := range services.Items {
-				// Servisi veritabanına ekle veya güncelle
-				_, err := db.Exec(`
-					INSERT INTO services (name, namespace, cluster, type)
-					VALUES (?, ?, ?, ?)
-					ON CONFLICT(name, namespace, cluster) DO UPDATE SET
-					type = excluded.type, updated_at = CURRENT_TIMESTAMP
-				`, service.Name, service.Namespace, "default", "service")
-
-				if err != nil {
-					log.Printf("Servis %s/%s veritabanına eklenemedi: %v",
-						service.Namespace, service.Name, err)
-				} else {
-					log.Printf("Servis %s/%s veritabanına eklendi veya güncellendi",
-						service.Namespace, service.Name)
-				}
-			}
-		}
-
-		log.Println("Servis keşif işlemi tamamlandı, 5 dakika sonra tekrar çalışacak.")
-		time.Sleep(5 * time.Minute)
-	}
-}
-
 // uptimeHistoryHandler örneği:
 func uptimeHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1067,6 +1621,11 @@ func uptimeHistoryHandler(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	history := []map[string]interface{}{}
+	var statuses []string
+	var timestamps []time.Time
+	// SLA hesabı: iş yükü bilerek durdurulduğunda (paused) yapılan kontroller sayılmaz
+	var counted, up, paused int
+	var totalResponseTime int64
 	for rows.Next() {
 		var status string
 		var responseTime int64
@@ -1086,10 +1645,50 @@ func uptimeHistoryHandler(w http.ResponseWriter, r *http.Request) {
 			record["errorMessage"] = errorMessage.String
 		}
 		history = append(history, record)
+		statuses = append(statuses, status)
+		timestamps = append(timestamps, timestamp)
+
+		if status == CheckStatusPaused {
+			paused++
+			continue
+		}
+		counted++
+		totalResponseTime += responseTime
+		if status == "up" {
+			up++
+		}
+	}
+
+	summary := map[string]interface{}{
+		"total_checks":      counted,
+		"up_checks":         up,
+		"paused_checks":     paused,
+		"uptime_percentage": 0.0,
+		"avg_response_time": int64(0),
+	}
+	if counted > 0 {
+		summary["uptime_percentage"] = float64(up) * 100 / float64(counted)
+		summary["avg_response_time"] = totalResponseTime / int64(counted)
+	}
+
+	// Kesinti pencerelerini çıkar ve ilgili Kubernetes olaylarını ekle
+	downtimes := downtimeWindows(statuses, timestamps)
+	if err := attachServiceEvents(db, serviceId, downtimes); err != nil {
+		log.Printf("Servis %d için olaylar ilişkilendirilemedi: %v", serviceId, err)
+	}
+
+	// Grafiklerde gösterilmek üzere aralıktaki rollout işaretleri
+	markers, err := rolloutMarkers(db, serviceId, startDate, endDate)
+	if err != nil {
+		log.Printf("Servis %d için rollout işaretleri alınamadı: %v", serviceId, err)
+		markers = []RolloutMarker{}
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"history": history,
+		"history":   history,
+		"summary":   summary,
+		"downtimes": downtimes,
+		"rollouts":  markers,
 	})
 }
 
@@ -1132,9 +1731,9 @@ func serviceDetailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// DELETE isteği için özel işlem
-	if r.Method == "DELETE" {
-		// Silme işlemi için servicesHandler'a yönlendir
+	// DELETE ve PUT istekleri için özel işlem
+	if r.Method == "DELETE" || r.Method == "PUT" {
+		// Silme ve güncelleme işlemleri için servicesHandler'a yönlendir
 		servicesHandler(w, r)
 		return
 	}
@@ -1148,14 +1747,11 @@ func serviceDetailHandler(w http.ResponseWriter, r *http.Request) {
 		Endpoint      sql.NullString
 		CheckInterval int
 	}
+	var checkUsername, checkPassword, checkHeaders sql.NullString
+	var candidateEndpoints, endpointSource sql.NullString
+	var monitoring serviceMonitoringRow
 
-	err = db.QueryRow(`
-		SELECT id, name, namespace, cluster, type, 
-			   COALESCE(endpoint, '') as endpoint, 
-			   COALESCE(check_interval, 60) as check_interval 
-		FROM services 
-		WHERE id = ?
-	`, id).Scan(
+	dest := []interface{}{
 		&service.ID,
 		&service.Name,
 		&service.Namespace,
@@ -1163,7 +1759,21 @@ func serviceDetailHandler(w http.ResponseWriter, r *http.Request) {
 		&service.Type,
 		&service.Endpoint,
 		&service.CheckInterval,
-	)
+		&checkUsername,
+		&checkPassword,
+		&checkHeaders,
+		&candidateEndpoints,
+		&endpointSource,
+	}
+	err = db.QueryRow(`
+		SELECT id, name, namespace, cluster, type, 
+			   COALESCE(endpoint, '') as endpoint, 
+			   COALESCE(check_interval, 60) as check_interval,
+			   check_username, check_password, check_headers,
+			   candidate_endpoints, endpoint_source, `+serviceMonitoringColumns+`
+		FROM services 
+		WHERE id = ?
+	`, id).Scan(append(dest, monitoring.dest()...)...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -1175,16 +1785,23 @@ func serviceDetailHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Yanıtı hazırla
+	serviceInfo := map[string]interface{}{
+		"id":             service.ID,
+		"name":           service.Name,
+		"namespace":      service.Namespace,
+		"cluster":        service.Cluster,
+		"type":           service.Type,
+		"check_interval": service.CheckInterval,
+		"endpoint":       service.Endpoint.String,
+	}
+	// Sırlar geri döndürülmez; referanslar ve tanımlı olup olmadıkları gösterilir
+	if auth, err := decodeCheckAuth(checkUsername, checkPassword, checkHeaders); err == nil {
+		auth.View(serviceInfo)
+	}
+	addEndpointCandidates(serviceInfo, candidateEndpoints, endpointSource)
+	monitoring.View(serviceInfo)
 	response := map[string]interface{}{
-		"service": map[string]interface{}{
-			"id":             service.ID,
-			"name":           service.Name,
-			"namespace":      service.Namespace,
-			"cluster":        service.Cluster,
-			"type":           service.Type,
-			"check_interval": service.CheckInterval,
-			"endpoint":       service.Endpoint.String,
-		},
+		"service": serviceInfo,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1192,6 +1809,11 @@ func serviceDetailHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
+	// Arka plan işlemleri kapanışta bu context ile durdurulur
+	var stopWorkers context.CancelFunc
+	ctx, stopWorkers = context.WithCancel(context.Background())
+	defer stopWorkers()
+
 	// Veritabanı bağlantısını başlat
 	var err error
 	db, err = initDB()
@@ -1206,14 +1828,41 @@ func main() {
 	}
 	log.Println("Veritabanı tabloları başarıyla oluşturuldu")
 
+	// Sırları şifrelemek için ana anahtarı yükle
+	secretKeyring, err = LoadSecretKeyring()
+	if err != nil {
+		log.Fatalf("Ana anahtar yüklenemedi: %v", err)
+	}
+	if secretKeyring == nil {
+		log.Println("UYARI: SECRETS_MASTER_KEY tanımlı değil, sırlar veritabanında düz metin saklanacak")
+	}
+
+	// "secrets migrate|rotate" komutu sunucuyu başlatmadan çalışır ve çıkar
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		if err := runSecretsCommand(db, os.Args[2:]); err != nil {
+			log.Fatalf("Sır taşıma başarısız: %v", err)
+		}
+		return
+	}
+
 	// Kubernetes API'sine bağlan
-	clientset, err = InitKubernetes()
+	clientset, dynamicClient, err = InitKubernetes()
 	if err != nil {
 		log.Printf("Kubernetes bağlantısı başlatılamadı (bu opsiyonel): %v", err)
 	} else {
 		log.Println("Kubernetes bağlantısı başarıyla kuruldu")
 	}
 
+	// Cluster yöneticisini oluştur
+	clusterManager = NewClusterManager(ctx, db)
+
+	// Kontrol sonuçlarından alarm üreten yönetici uptime monitor'den önce hazır olmalı
+	alertManager = NewAlertManager(db)
+	notificationDispatcher = NewNotificationDispatcher(db)
+	alertManager.Subscribe(notificationDispatcher.Enqueue)
+	go notificationDispatcher.Run(ctx)
+	go NewAlertRuleEvaluator(db, alertManager).Run(ctx)
+
 	// Uptime monitor'ü global değişkene ata ve başlat
 	uptimeMonitor = NewUptimeMonitor(db)
 	err = uptimeMonitor.StartUptimeMonitoring()
@@ -1232,15 +1881,77 @@ func main() {
 	http.HandleFunc("/api/v1/settings", settingsHandler)
 	http.HandleFunc("/api/v1/test-uptime", handlers.HandleUptimeTest)
 	http.HandleFunc("/api/v1/mergedNamespaces", mergedNamespacesHandler)
+	http.HandleFunc("/api/v1/namespace-policies", namespacePoliciesHandler)
+	http.HandleFunc("/api/v1/discovery-rules", discoveryRulesHandler)
+	http.HandleFunc("/api/v1/discovery-rules/dry-run", discoveryRulesDryRunHandler)
+	http.HandleFunc("/api/v1/alerts", alertsHandler)
+	http.HandleFunc("/api/v1/alerts/", alertsHandler)
+	http.HandleFunc("/api/v1/alert-rules", alertRulesHandler)
+	http.HandleFunc("/api/v1/alert-rules/", alertRulesHandler)
+	http.HandleFunc("/api/v1/alert-rules/backtest", alertRulesBacktestHandler)
+	http.HandleFunc("/api/v1/notification-channels", notificationChannelsHandler)
+	http.HandleFunc("/api/v1/notification-channels/", notificationChannelsHandler)
+	http.HandleFunc("/api/v1/notification-deliveries", notificationDeliveriesHandler)
+	http.HandleFunc("/api/v1/notification-routes", notificationRoutesHandler)
+	http.HandleFunc("/api/v1/notification-routes/preview", notificationRoutesPreviewHandler)
+	http.HandleFunc("/api/v1/escalation-policies", escalationPoliciesHandler)
+	http.HandleFunc("/api/v1/escalation-policies/", escalationPoliciesHandler)
+	http.HandleFunc("/api/v1/oncall-schedules", onCallSchedulesHandler)
+	http.HandleFunc("/api/v1/oncall-schedules/", onCallSchedulesHandler)
+	http.HandleFunc("/api/v1/oncall", onCallHandler)
+	http.HandleFunc("/api/v1/silences", silencesHandler)
+	http.HandleFunc("/api/v1/silences/", silencesHandler)
 
 	// Cluster API endpoint'lerini ekle
 	http.HandleFunc("/api/v1/clusters", clustersHandler)
 	http.HandleFunc("/api/v1/clusters/", func(w http.ResponseWriter, r *http.Request) {
+		// Kubeconfig yükleme endpoint'leri
+		switch r.URL.Path {
+		case "/api/v1/clusters/kubeconfig/contexts":
+			kubeconfigContextsHandler(w, r)
+			return
+		case "/api/v1/clusters/import":
+			importClustersHandler(w, r)
+			return
+		}
 		// /api/v1/clusters/{id}/test endpoint'i için
 		if strings.HasSuffix(r.URL.Path, "/test") {
 			testClusterHandler(w, r)
 			return
 		}
+		// /api/v1/clusters/{id}/permissions endpoint'i için
+		if strings.HasSuffix(r.URL.Path, "/permissions") {
+			idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/clusters/"), "/permissions")
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				http.Error(w, `{"error":"Geçersiz cluster ID"}`, http.StatusBadRequest)
+				return
+			}
+			clusterPermissionsHandler(w, r, id)
+			return
+		}
+		// /api/v1/clusters/{id}/credentials endpoint'i için
+		if strings.HasSuffix(r.URL.Path, "/credentials") {
+			idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/clusters/"), "/credentials")
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				http.Error(w, `{"error":"Geçersiz cluster ID"}`, http.StatusBadRequest)
+				return
+			}
+			clusterCredentialsHandler(w, r, id)
+			return
+		}
+		// /api/v1/clusters/{id}/health endpoint'i için
+		if strings.HasSuffix(r.URL.Path, "/health") {
+			idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/clusters/"), "/health")
+			id, err := strconv.Atoi(idStr)
+			if err != nil {
+				http.Error(w, `{"error":"Geçersiz cluster ID"}`, http.StatusBadRequest)
+				return
+			}
+			clusterHealthHistoryHandler(w, r, id)
+			return
+		}
 		// /api/v1/clusters/{id} endpoint'i için
 		clusterHandler(w, r)
 	})
@@ -1264,9 +1975,12 @@ func main() {
 		})
 	}
 
-	// Servis keşfi arka plan işlemi
+	// Her cluster için servis keşfi ve Kubernetes olay izleyicisi arka plan işlemleri
 	if clientset != nil {
-		go serviceDiscoveryWorker()
+		clusterManager.SetDefaultClient(clientset, dynamicClient)
+	}
+	if err := clusterManager.StartAll(); err != nil {
+		log.Printf("Kayıtlı cluster'lar başlatılamadı: %v", err)
 	}
 
 	// Sunucuyu başlat
@@ -1274,7 +1988,36 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
-	httpHandler := corsMiddleware(http.DefaultServeMux)
-	log.Printf("Server %s portunda çalışıyor...", port)
-	log.Fatal(http.ListenAndServe(":"+port, httpHandler))
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: corsMiddleware(http.DefaultServeMux),
+	}
+
+	// Sunucuyu arka planda başlat
+	go func() {
+		log.Printf("Server %s portunda çalışıyor...", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("HTTP sunucusu hatası: %v", err)
+		}
+	}()
+
+	// Graceful shutdown için sinyal dinle
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Sunucu kapatılıyor...")
+
+	// Arka plan işlemlerini (keşif, olay izleyici) durdur
+	stopWorkers()
+	clusterManager.StopAll()
+	uptimeMonitor.StopUptimeMonitoring()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Sunucu zorla kapatıldı: %v", err)
+	}
+
+	log.Println("Sunucu başarıyla kapatıldı")
 }
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Monitor (namespace kapsamlı) ve ClusterMonitor (cluster kapsamlı) özel kaynakları.
+// Şema deploy/crds altındaki CRD tanımlarındadır.
+var (
+	monitorGVR        = schema.GroupVersionResource{Group: "k8s-monitoring.io", Version: "v1alpha1", Resource: "monitors"}
+	clusterMonitorGVR = schema.GroupVersionResource{Group: "k8s-monitoring.io", Version: "v1alpha1", Resource: "clustermonitors"}
+)
+
+// monitorStatusInterval, CR .status alanlarının son kontrol sonuçlarıyla güncellenme aralığı
+const monitorStatusInterval = time.Minute
+
+// clusterMonitorDefaultNamespace, spec.namespace verilmeyen ClusterMonitor'ların kaydedildiği namespace
+const clusterMonitorDefaultNamespace = "default"
+
+// MonitorSpec, Monitor/ClusterMonitor spec alanı
+type MonitorSpec struct {
+	Service        string // izlenecek Service adı; boşsa kaynağın adı kullanılır
+	Namespace      string // yalnızca ClusterMonitor
+	Endpoint       string
+	CheckType      string
+	Interval       int
+	ExpectedStatus int
+	Owner          string
+	Enabled        bool
+	Auth           CheckAuthInput
+}
+
+// parseMonitorSpec, unstructured nesneden spec'i okur ve doğrular
+func parseMonitorSpec(obj *unstructured.Unstructured) (MonitorSpec, error) {
+	spec := MonitorSpec{Enabled: true, Interval: 60}
+
+	spec.Service, _, _ = unstructured.NestedString(obj.Object, "spec", "service")
+	spec.Namespace, _, _ = unstructured.NestedString(obj.Object, "spec", "namespace")
+	spec.Endpoint, _, _ = unstructured.NestedString(obj.Object, "spec", "endpoint")
+	spec.CheckType, _, _ = unstructured.NestedString(obj.Object, "spec", "checkType")
+	spec.Owner, _, _ = unstructured.NestedString(obj.Object, "spec", "owner")
+	if v, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "intervalSeconds"); ok {
+		spec.Interval = int(v)
+	}
+	if v, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "expectedStatus"); ok {
+		spec.ExpectedStatus = int(v)
+	}
+	if v, ok, _ := unstructured.NestedBool(obj.Object, "spec", "enabled"); ok {
+		spec.Enabled = v
+	}
+
+	if username, ok, _ := unstructured.NestedString(obj.Object, "spec", "auth", "username"); ok {
+		spec.Auth.Username = &username
+	}
+	if password, ok, _ := unstructured.NestedString(obj.Object, "spec", "auth", "passwordRef"); ok {
+		spec.Auth.Password = &password
+	}
+	if headers, ok, _ := unstructured.NestedStringMap(obj.Object, "spec", "auth", "headers"); ok {
+		spec.Auth.Headers = &headers
+	}
+
+	if spec.Endpoint == "" {
+		return spec, fmt.Errorf("spec.endpoint zorunludur")
+	}
+	if spec.Interval < 10 {
+		return spec, fmt.Errorf("spec.intervalSeconds en az 10 olmalıdır")
+	}
+	input := MonitoringInput{CheckType: &spec.CheckType, ExpectedStatus: &spec.ExpectedStatus}
+	if err := input.Validate(); err != nil {
+		return spec, err
+	}
+	// CR'lar Git'te tutulduğundan şifre düz metin olamaz, yalnızca referans kabul edilir
+	if spec.Auth.Password != nil && *spec.Auth.Password != "" {
+		if err := validateMonitorSecretRef(obj, spec, "spec.auth.passwordRef", *spec.Auth.Password); err != nil {
+			return spec, err
+		}
+	}
+	if spec.Auth.Headers != nil {
+		for name, value := range *spec.Auth.Headers {
+			if !isSecretReference(value) {
+				continue
+			}
+			if err := validateMonitorSecretRef(obj, spec, "spec.auth.headers."+name, value); err != nil {
+				return spec, err
+			}
+		}
+	}
+	if err := spec.Auth.Validate(); err != nil {
+		return spec, err
+	}
+	return spec, nil
+}
+
+// validateMonitorSecretRef, CR'daki sır referansının izlenen servisin namespace'indeki bir Secret'ı
+// gösterdiğini doğrular. Değer CR'ın seçtiği adrese gönderildiğinden namespace'e CR yazabilen biri
+// env:, file: veya başka namespace'lerdeki Secret'ları okuyamamalıdır.
+func validateMonitorSecretRef(obj *unstructured.Unstructured, spec MonitorSpec, field, value string) error {
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = spec.Namespace
+		if namespace == "" {
+			namespace = clusterMonitorDefaultNamespace
+		}
+	}
+
+	ref, ok, err := parseSecretReference(value)
+	if !ok || ref.Kind != SecretRefKubernetes {
+		return fmt.Errorf("%s secretRef: %s/ad#anahtar referansı olmalıdır", field, namespace)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", field, err)
+	}
+	if ref.Cluster != "" || ref.Namespace != namespace {
+		return fmt.Errorf("%s yalnızca %s namespace'indeki Secret'ları gösterebilir", field, namespace)
+	}
+	return nil
+}
+
+// monitorRef, CR'ı services.monitor_ref kolonunda tanımlayan anahtar
+func monitorRef(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return "ClusterMonitor/" + obj.GetName()
+	}
+	return "Monitor/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// MonitorController, bir cluster'daki Monitor ve ClusterMonitor kaynaklarını services
+// tablosuna uygular ve kontrol sonuçlarını kaynakların .status alanına geri yazar
+type MonitorController struct {
+	db        *sql.DB
+	dynamic   dynamic.Interface
+	clusterID int
+	cluster   string
+
+	listers map[schema.GroupVersionResource]cache.GenericLister
+
+	// Monitor ve ClusterMonitor olayları ayrı goroutine'lerde işlenir
+	mu     sync.Mutex
+	errors map[string]string // monitor_ref -> doğrulama hatası
+}
+
+// NewMonitorController, yeni bir Monitor denetleyicisi oluşturur
+func NewMonitorController(db *sql.DB, dynamicClient dynamic.Interface, clusterID int, cluster string) *MonitorController {
+	return &MonitorController{
+		db:        db,
+		dynamic:   dynamicClient,
+		clusterID: clusterID,
+		cluster:   cluster,
+		listers:   make(map[schema.GroupVersionResource]cache.GenericLister),
+		errors:    make(map[string]string),
+	}
+}
+
+// Run, context iptal edilene kadar Monitor kaynaklarını izler.
+// CRD'ler cluster'a yüklenmemişse denetleyici çalışmaz.
+func (c *MonitorController) Run(ctx context.Context) error {
+	if _, err := c.dynamic.Resource(monitorGVR).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		log.Printf("%s cluster'ında Monitor CRD'si bulunamadı, Monitor kaynakları izlenmeyecek: %v", c.cluster, err)
+		return nil
+	}
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.dynamic, discoveryResyncPeriod)
+	synced := []cache.InformerSynced{}
+	for _, gvr := range []schema.GroupVersionResource{monitorGVR, clusterMonitorGVR} {
+		informer := factory.ForResource(gvr)
+		c.listers[gvr] = informer.Lister()
+		synced = append(synced, informer.Informer().HasSynced)
+
+		if _, err := informer.Informer().AddEventHandler(c.eventHandler(ctx, gvr)); err != nil {
+			return fmt.Errorf("monitor handler'ı kaydedilemedi: %v", err)
+		}
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("%s cluster'ı için monitor önbelleği senkronize edilemedi", c.cluster)
+	}
+
+	if err := c.releaseMissing(); err != nil {
+		log.Printf("%s cluster'ında silinmiş monitor'ler kaldırılamadı: %v", c.cluster, err)
+	}
+	log.Printf("%s cluster'ı için Monitor denetleyicisi başlatıldı", c.cluster)
+
+	ticker := time.NewTicker(monitorStatusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			factory.Shutdown()
+			log.Printf("%s cluster'ı için Monitor denetleyicisi durduruldu", c.cluster)
+			return nil
+		case <-ticker.C:
+			c.SyncStatus(ctx)
+		}
+	}
+}
+
+// eventHandler, Monitor olaylarını uzlaştırma adımlarına bağlar
+func (c *MonitorController) eventHandler(ctx context.Context, gvr schema.GroupVersionResource) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				c.Reconcile(ctx, gvr, u)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU, ok1 := oldObj.(*unstructured.Unstructured)
+			newU, ok2 := newObj.(*unstructured.Unstructured)
+			if !ok1 || !ok2 {
+				return
+			}
+			// Yalnızca .status değiştiyse (kendi yazdığımız) yeniden uygulama.
+			// Periyodik resync'te (aynı ResourceVersion) arayüzden yapılan sapmalar düzeltilir.
+			if oldU.GetResourceVersion() != newU.GetResourceVersion() && oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+			c.Reconcile(ctx, gvr, newU)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				c.release(monitorRef(u))
+			}
+		},
+	}
+}
+
+// Reconcile, Monitor kaynağını services tablosuna uygular ve durumunu yazar
+func (c *MonitorController) Reconcile(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	ref := monitorRef(obj)
+	spec, err := parseMonitorSpec(obj)
+	if err != nil {
+		log.Printf("%s cluster'ında %s geçersiz: %v", c.cluster, ref, err)
+		c.setError(ref, err)
+		c.writeStatus(ctx, gvr, obj)
+		return
+	}
+
+	err = c.apply(ref, obj, spec)
+	if err != nil {
+		log.Printf("%s cluster'ında %s uygulanamadı: %v", c.cluster, ref, err)
+	}
+	c.setError(ref, err)
+	c.writeStatus(ctx, gvr, obj)
+
+	if uptimeMonitor != nil {
+		uptimeMonitor.RequestReload()
+	}
+}
+
+// setError, CR'ın son uzlaştırma hatasını kaydeder (nil ise temizler)
+func (c *MonitorController) setError(ref string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.errors[ref] = err.Error()
+	} else {
+		delete(c.errors, ref)
+	}
+}
+
+// apply, spec'i hedef servis satırına yazar. Servis yoksa 'monitor' kaynaklı olarak oluşturulur;
+// keşfedilmiş bir servisi hedefliyorsa o satırın kontrol ayarları CR'dan gelir. Annotation ile
+// kilitlenmiş (k8s-monitoring/lock) servislerde annotation'lar önceliklidir: CR uygulanmaz,
+// servisle bağı kaldırılır ve durumu Invalid olur.
+func (c *MonitorController) apply(ref string, obj *unstructured.Unstructured, spec MonitorSpec) error {
+	name := spec.Service
+	if name == "" {
+		name = obj.GetName()
+	}
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = spec.Namespace
+		if namespace == "" {
+			namespace = clusterMonitorDefaultNamespace
+		}
+	}
+
+	var locked sql.NullBool
+	err := c.db.QueryRow("SELECT annotation_lock FROM services WHERE name = ? AND namespace = ? AND cluster = ?",
+		name, namespace, c.cluster).Scan(&locked)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if locked.Bool {
+		if err := c.releaseWhere(`monitor_ref = ? AND cluster = ?`, ref, c.cluster); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s/%s servisi annotation ile kilitli, ayarları annotation'lardan gelir", namespace, name)
+	}
+
+	// Spec'te hedef servis değiştiyse eski satır bırakılır
+	if err := c.releaseExcept(ref, name, namespace); err != nil {
+		return err
+	}
+
+	clusterID := sql.NullInt64{Int64: int64(c.clusterID), Valid: c.clusterID > 0}
+	_, err = c.db.Exec(`
+		INSERT INTO services (name, namespace, cluster, cluster_id, type, source, monitor_ref)
+		VALUES (?, ?, ?, ?, 'monitor', 'monitor', ?)
+		ON CONFLICT(name, namespace, cluster) DO UPDATE SET
+		monitor_ref = excluded.monitor_ref, archived_at = NULL, updated_at = CURRENT_TIMESTAMP
+	`, name, namespace, c.cluster, clusterID, ref)
+	if err != nil {
+		return err
+	}
+
+	var checkType interface{}
+	if spec.CheckType != "" {
+		checkType = spec.CheckType
+	}
+	var expectedStatus interface{}
+	if spec.ExpectedStatus > 0 {
+		expectedStatus = spec.ExpectedStatus
+	}
+	// 'monitor' kaynaklı endpoint'ler keşif tarafından ezilmez
+	_, err = c.db.Exec(`
+		UPDATE services SET
+		endpoint = ?, endpoint_source = 'monitor', check_interval = ?, expected_status = ?,
+		check_type = ?, owner = ?, monitoring_enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE monitor_ref = ? AND cluster = ?
+	`, spec.Endpoint, spec.Interval, expectedStatus, checkType, spec.Owner, spec.Enabled, ref, c.cluster)
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	if err := c.db.QueryRow("SELECT id FROM services WHERE monitor_ref = ? AND cluster = ?", ref, c.cluster).Scan(&id); err != nil {
+		return err
+	}
+	return saveCheckAuth(c.db, id, spec.Auth)
+}
+
+// releaseExcept, CR'a bağlı olup artık hedeflenmeyen servis satırlarını bırakır
+func (c *MonitorController) releaseExcept(ref, name, namespace string) error {
+	return c.releaseWhere(`monitor_ref = ? AND cluster = ? AND NOT (name = ? AND namespace = ?)`, ref, c.cluster, name, namespace)
+}
+
+// release, silinen CR'ın servis satırını bırakır
+func (c *MonitorController) release(ref string) {
+	c.setError(ref, nil)
+	if err := c.releaseWhere(`monitor_ref = ? AND cluster = ?`, ref, c.cluster); err != nil {
+		log.Printf("%s cluster'ında %s kaldırılamadı: %v", c.cluster, ref, err)
+		return
+	}
+	log.Printf("%s cluster'ında %s silindi", c.cluster, ref)
+	if uptimeMonitor != nil {
+		uptimeMonitor.RequestReload()
+	}
+}
+
+// releaseWhere, koşula uyan satırlardan CR bağını kaldırır. CR tarafından oluşturulan
+// satırlar geçmişiyle birlikte arşivlenir; keşfedilmiş servisler yerinde kalır.
+func (c *MonitorController) releaseWhere(where string, args ...interface{}) error {
+	_, err := c.db.Exec(`
+		UPDATE services SET
+		archived_at = CASE WHEN source = 'monitor' THEN CURRENT_TIMESTAMP ELSE archived_at END,
+		endpoint_source = CASE WHEN endpoint_source = 'monitor' THEN 'manual' ELSE endpoint_source END,
+		monitor_ref = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE `+where, args...)
+	return err
+}
+
+// releaseMissing, uygulama kapalıyken silinen CR'ların satırlarını bırakır
+func (c *MonitorController) releaseMissing() error {
+	existing := map[string]bool{}
+	for _, lister := range c.listers {
+		objs, err := lister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				existing[monitorRef(u)] = true
+			}
+		}
+	}
+
+	rows, err := c.db.Query("SELECT DISTINCT monitor_ref FROM services WHERE monitor_ref IS NOT NULL AND cluster = ?", c.cluster)
+	if err != nil {
+		return err
+	}
+	// Tek bağlantılı havuzda satırlar açıkken güncelleme yapılamaz, önce topla
+	var missing []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err == nil && !existing[ref] {
+			missing = append(missing, ref)
+		}
+	}
+	rows.Close()
+
+	for _, ref := range missing {
+		c.release(ref)
+	}
+	return nil
+}
+
+// SyncStatus, tüm Monitor kaynaklarının durumunu son kontrol sonuçlarıyla günceller
+func (c *MonitorController) SyncStatus(ctx context.Context) {
+	for gvr, lister := range c.listers {
+		objs, err := lister.List(labels.Everything())
+		if err != nil {
+			continue
+		}
+		for _, obj := range objs {
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				c.writeStatus(ctx, gvr, u)
+			}
+		}
+	}
+}
+
+// monitorStatus, CR'a yazılacak .status alanını oluşturur
+func (c *MonitorController) monitorStatus(obj *unstructured.Unstructured) map[string]interface{} {
+	ref := monitorRef(obj)
+	status := map[string]interface{}{
+		"observedGeneration": obj.GetGeneration(),
+	}
+	c.mu.Lock()
+	msg, failed := c.errors[ref]
+	c.mu.Unlock()
+	if failed {
+		status["state"] = "Invalid"
+		status["message"] = msg
+		return status
+	}
+
+	var id int64
+	var enabled sql.NullBool
+	err := c.db.QueryRow("SELECT id, monitoring_enabled FROM services WHERE monitor_ref = ? AND cluster = ?", ref, c.cluster).Scan(&id, &enabled)
+	if err != nil {
+		status["state"] = "Pending"
+		status["message"] = "Servis kaydı henüz oluşturulmadı"
+		return status
+	}
+	status["serviceId"] = id
+	if enabled.Valid && !enabled.Bool {
+		status["state"] = "Disabled"
+		return status
+	}
+
+	var checkStatus string
+	var responseTime sql.NullInt64
+	var errorMessage sql.NullString
+	var timestamp time.Time
+	err = c.db.QueryRow(`
+		SELECT status, response_time, error_message, timestamp FROM uptime_checks
+		WHERE service_id = ? ORDER BY timestamp DESC LIMIT 1
+	`, id).Scan(&checkStatus, &responseTime, &errorMessage, &timestamp)
+	if err != nil {
+		status["state"] = "Pending"
+		status["message"] = "Henüz kontrol yapılmadı"
+		return status
+	}
+
+	lastResult := map[string]interface{}{
+		"status":         checkStatus,
+		"responseTimeMs": responseTime.Int64,
+	}
+	if errorMessage.String != "" {
+		lastResult["error"] = errorMessage.String
+	}
+	status["state"] = checkStatus
+	status["lastCheckTime"] = timestamp.UTC().Format(time.RFC3339)
+	status["lastResult"] = lastResult
+
+	var total, up int64
+	c.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = 'up' THEN 1 ELSE 0 END), 0)
+		FROM uptime_checks WHERE service_id = ? AND timestamp >= ? AND status != ?
+	`, id, time.Now().Add(-24*time.Hour), CheckStatusPaused).Scan(&total, &up)
+	if total > 0 {
+		status["uptime24h"] = fmt.Sprintf("%.2f", float64(up)*100/float64(total))
+	}
+	return status
+}
+
+// writeStatus, durum değiştiyse CR'ın .status alt kaynağını günceller
+func (c *MonitorController) writeStatus(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) {
+	status := c.monitorStatus(obj)
+	current, _, _ := unstructured.NestedMap(obj.Object, "status")
+	if equality.Semantic.DeepEqual(current, status) {
+		return
+	}
+
+	updated := obj.DeepCopy()
+	if err := unstructured.SetNestedMap(updated.Object, status, "status"); err != nil {
+		return
+	}
+	_, err := c.dynamic.Resource(gvr).Namespace(obj.GetNamespace()).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	// Çakışmada bir sonraki senkronizasyonda yeniden denenir
+	if err != nil && !apierrors.IsConflict(err) {
+		log.Printf("%s cluster'ında %s durumu yazılamadı: %v", c.cluster, monitorRef(obj), err)
+	}
+}
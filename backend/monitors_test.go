@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newMonitor(namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "k8s-monitoring.io/v1alpha1",
+		"kind":       "Monitor",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       spec,
+	}}
+	if namespace == "" {
+		obj.SetKind("ClusterMonitor")
+	} else {
+		obj.SetNamespace(namespace)
+	}
+	obj.SetGeneration(1)
+	return obj
+}
+
+func newMonitorFakeClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		monitorGVR:        "MonitorList",
+		clusterMonitorGVR: "ClusterMonitorList",
+	}, objects...)
+}
+
+func TestMonitorControllerReconcile(t *testing.T) {
+	testDB := newTestDB(t)
+	ctx := context.Background()
+
+	monitor := newMonitor("shop", "checkout", map[string]interface{}{
+		"endpoint":        "https://checkout.example.com/healthz",
+		"intervalSeconds": int64(30),
+		"expectedStatus":  int64(204),
+		"owner":           "team-payments",
+		"auth": map[string]interface{}{
+			"username":    "probe",
+			"passwordRef": "secretRef: shop/checkout-probe#password",
+		},
+	})
+	client := newMonitorFakeClient(monitor)
+	controller := NewMonitorController(testDB, client, 0, "prod")
+
+	controller.Reconcile(ctx, monitorGVR, monitor)
+
+	var id int64
+	var endpoint, source, endpointSource, owner, password string
+	var interval, expected int
+	err := testDB.QueryRow(`
+		SELECT id, endpoint, source, endpoint_source, check_interval, expected_status, owner, check_password
+		FROM services WHERE monitor_ref = 'Monitor/shop/checkout' AND name = 'checkout' AND namespace = 'shop' AND cluster = 'prod'
+	`).Scan(&id, &endpoint, &source, &endpointSource, &interval, &expected, &owner, &password)
+	if err != nil {
+		t.Fatalf("servis kaydı bulunamadı: %v", err)
+	}
+	if endpoint != "https://checkout.example.com/healthz" || source != "monitor" || endpointSource != "monitor" {
+		t.Errorf("beklenmeyen servis: endpoint=%s source=%s endpoint_source=%s", endpoint, source, endpointSource)
+	}
+	if interval != 30 || expected != 204 || owner != "team-payments" || password != "secretRef: shop/checkout-probe#password" {
+		t.Errorf("beklenmeyen kontrol ayarları: interval=%d expected=%d owner=%s password=%s", interval, expected, owner, password)
+	}
+
+	// Kontrol sonucu durum alanına yazılmalı
+	testDB.Exec(`INSERT INTO uptime_checks (service_id, status, response_time, timestamp) VALUES (?, 'up', 120, ?)`, id, time.Now().Add(-time.Minute))
+	testDB.Exec(`INSERT INTO uptime_checks (service_id, status, response_time, error_message, timestamp) VALUES (?, 'down', 0, 'bağlantı reddedildi', ?)`, id, time.Now())
+	current, err := client.Resource(monitorGVR).Namespace("shop").Get(ctx, "checkout", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller.writeStatus(ctx, monitorGVR, current)
+
+	updated, err := client.Resource(monitorGVR).Namespace("shop").Get(ctx, "checkout", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, _, _ := unstructured.NestedString(updated.Object, "status", "state")
+	uptime, _, _ := unstructured.NestedString(updated.Object, "status", "uptime24h")
+	lastError, _, _ := unstructured.NestedString(updated.Object, "status", "lastResult", "error")
+	serviceID, _, _ := unstructured.NestedInt64(updated.Object, "status", "serviceId")
+	if state != "down" || uptime != "50.00" || lastError != "bağlantı reddedildi" || serviceID != id {
+		t.Errorf("beklenmeyen durum: %v", updated.Object["status"])
+	}
+
+	// Silinen CR'ın oluşturduğu servis arşivlenmeli
+	controller.release("Monitor/shop/checkout")
+	var archived bool
+	testDB.QueryRow("SELECT archived_at IS NOT NULL FROM services WHERE id = ?", id).Scan(&archived)
+	if !archived {
+		t.Error("silinen Monitor'ün servisi arşivlenmedi")
+	}
+}
+
+func TestMonitorControllerDiscoveredServiceAndValidation(t *testing.T) {
+	testDB := newTestDB(t)
+	ctx := context.Background()
+
+	// Keşfedilmiş servis: CR kontrol ayarlarını verir, silindiğinde servis yerinde kalır
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source) VALUES ('api', 'default', 'prod', 'service', 'discovery')`)
+	clusterMonitor := newMonitor("", "api-probe", map[string]interface{}{
+		"service":  "api",
+		"endpoint": "10.0.0.5:8080",
+	})
+	invalid := newMonitor("shop", "broken", map[string]interface{}{
+		"endpoint": "http://broken",
+		"auth":     map[string]interface{}{"passwordRef": "düz-metin"},
+	})
+	client := newMonitorFakeClient(clusterMonitor, invalid)
+	controller := NewMonitorController(testDB, client, 0, "prod")
+
+	controller.Reconcile(ctx, clusterMonitorGVR, clusterMonitor)
+	controller.Reconcile(ctx, monitorGVR, invalid)
+
+	var endpoint, source, ref string
+	testDB.QueryRow("SELECT endpoint, source, monitor_ref FROM services WHERE name = 'api'").Scan(&endpoint, &source, &ref)
+	if endpoint != "10.0.0.5:8080" || source != "discovery" || ref != "ClusterMonitor/api-probe" {
+		t.Errorf("beklenmeyen servis: endpoint=%s source=%s ref=%s", endpoint, source, ref)
+	}
+
+	var count int
+	testDB.QueryRow("SELECT COUNT(*) FROM services WHERE name = 'broken'").Scan(&count)
+	if count != 0 {
+		t.Error("geçersiz Monitor servis oluşturmamalı")
+	}
+	broken, _ := client.Resource(monitorGVR).Namespace("shop").Get(ctx, "broken", metav1.GetOptions{})
+	if state, _, _ := unstructured.NestedString(broken.Object, "status", "state"); state != "Invalid" {
+		t.Errorf("geçersiz Monitor durumu Invalid olmalı, alınan %q", state)
+	}
+
+	controller.release("ClusterMonitor/api-probe")
+	var archived bool
+	testDB.QueryRow("SELECT archived_at IS NOT NULL FROM services WHERE name = 'api'").Scan(&archived)
+	if archived {
+		t.Error("keşfedilmiş servis Monitor silindiğinde arşivlenmemeli")
+	}
+}
+
+func TestMonitorControllerRespectsAnnotationLock(t *testing.T) {
+	testDB := newTestDB(t)
+	ctx := context.Background()
+
+	// Annotation ile kilitli servis: CR ayarları yazılmaz, durum Invalid olur
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, source, endpoint, check_interval, annotation_lock)
+		VALUES ('api', 'shop', 'prod', 'service', 'discovery', 'http://api:8080/health', 30, 1)`)
+	monitor := newMonitor("shop", "api", map[string]interface{}{
+		"endpoint":        "http://override",
+		"intervalSeconds": int64(120),
+	})
+	client := newMonitorFakeClient(monitor)
+	controller := NewMonitorController(testDB, client, 0, "prod")
+	controller.Reconcile(ctx, monitorGVR, monitor)
+
+	var endpoint string
+	var interval int
+	var ref sql.NullString
+	testDB.QueryRow("SELECT endpoint, check_interval, monitor_ref FROM services WHERE name = 'api'").Scan(&endpoint, &interval, &ref)
+	if endpoint != "http://api:8080/health" || interval != 30 || ref.Valid {
+		t.Errorf("kilitli servis değiştirilmemeli: endpoint=%s interval=%d ref=%v", endpoint, interval, ref)
+	}
+	got, _ := client.Resource(monitorGVR).Namespace("shop").Get(ctx, "api", metav1.GetOptions{})
+	if state, _, _ := unstructured.NestedString(got.Object, "status", "state"); state != "Invalid" {
+		t.Errorf("kilitli servisi hedefleyen Monitor durumu Invalid olmalı, alınan %q", state)
+	}
+
+	// Kilit kalkınca CR uygulanır
+	testDB.Exec(`UPDATE services SET annotation_lock = 0 WHERE name = 'api'`)
+	controller.Reconcile(ctx, monitorGVR, monitor)
+	testDB.QueryRow("SELECT endpoint FROM services WHERE name = 'api'").Scan(&endpoint)
+	if endpoint != "http://override" {
+		t.Errorf("kilit kalkınca CR uygulanmalı, endpoint=%s", endpoint)
+	}
+}
+
+func TestParseMonitorSpecRestrictsSecretReferences(t *testing.T) {
+	spec := func(auth map[string]interface{}, extra ...string) map[string]interface{} {
+		s := map[string]interface{}{"endpoint": "https://attacker.example.com", "auth": auth}
+		if len(extra) == 2 {
+			s[extra[0]] = extra[1]
+		}
+		return s
+	}
+
+	valid := []*unstructured.Unstructured{
+		newMonitor("shop", "ok", spec(map[string]interface{}{"passwordRef": "secretRef: shop/probe#password"})),
+		newMonitor("shop", "header", spec(map[string]interface{}{
+			"headers": map[string]interface{}{"X-Api-Key": "secretRef: shop/probe#key", "X-Team": "payments"},
+		})),
+		newMonitor("", "cluster-ok", spec(map[string]interface{}{"passwordRef": "secretRef: billing/probe#password"}, "namespace", "billing")),
+		newMonitor("", "cluster-default", spec(map[string]interface{}{"passwordRef": "secretRef: default/probe#password"})),
+	}
+	for _, obj := range valid {
+		if _, err := parseMonitorSpec(obj); err != nil {
+			t.Errorf("%s kabul edilmeliydi: %v", obj.GetName(), err)
+		}
+	}
+
+	invalid := []*unstructured.Unstructured{
+		newMonitor("shop", "plain", spec(map[string]interface{}{"passwordRef": "düz-metin"})),
+		newMonitor("shop", "env", spec(map[string]interface{}{"passwordRef": "env:SECRETS_MASTER_KEY"})),
+		newMonitor("shop", "file", spec(map[string]interface{}{"passwordRef": "file:/var/run/secrets/kubernetes.io/serviceaccount/token"})),
+		newMonitor("shop", "other-ns", spec(map[string]interface{}{"passwordRef": "secretRef: kube-system/admin#token"})),
+		newMonitor("shop", "other-cluster", spec(map[string]interface{}{"passwordRef": "secretRef: prod/shop/probe#password"})),
+		newMonitor("shop", "header-env", spec(map[string]interface{}{"headers": map[string]interface{}{"Authorization": "env:API_TOKEN"}})),
+		newMonitor("shop", "header-other-ns", spec(map[string]interface{}{"headers": map[string]interface{}{"X-Api-Key": "secretRef: billing/api#key"}})),
+		newMonitor("", "cluster-other-ns", spec(map[string]interface{}{"passwordRef": "secretRef: kube-system/admin#token"}, "namespace", "billing")),
+	}
+	for _, obj := range invalid {
+		if _, err := parseMonitorSpec(obj); err == nil {
+			t.Errorf("%s reddedilmeliydi", obj.GetName())
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Gönderim durumları
+const (
+	DeliveryPending    = "pending"    // yeniden denenecek
+	DeliverySending    = "sending"    // yeniden deneme sürüyor
+	DeliveryDelivered  = "delivered"  // teslim edildi
+	DeliveryFailed     = "failed"     // deneme hakkı tükendi
+	DeliverySuperseded = "superseded" // aynı alarmın daha yeni bir olayı gönderildi
+)
+
+const (
+	// defaultDeliveryAttempts, bir olayın kanal başına en fazla gönderim denemesi
+	defaultDeliveryAttempts = 5
+	// deliveryBackoffBase ve deliveryBackoffMax, denemeler arasındaki üstel bekleme sınırları
+	deliveryBackoffBase = 30 * time.Second
+	deliveryBackoffMax  = time.Hour
+	// deliveryRetryInterval, zamanı gelen yeniden denemelerin kontrol aralığı
+	deliveryRetryInterval = 15 * time.Second
+)
+
+// RetryingNotifier, varsayılandan farklı deneme sayısı kullanan kanallar
+type RetryingNotifier interface {
+	MaxAttempts() int
+}
+
+// deliveryBackoff, n. başarısız denemeden sonra beklenecek süre (30 sn, 1 dk, 2 dk, ... en fazla 1 saat)
+func deliveryBackoff(attempt int) time.Duration {
+	backoff := deliveryBackoffBase
+	for i := 1; i < attempt && backoff < deliveryBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > deliveryBackoffMax {
+		backoff = deliveryBackoffMax
+	}
+	return backoff
+}
+
+// NotificationDelivery, gönderim kaydındaki bir satır
+type NotificationDelivery struct {
+	ID            int64      `json:"id"`
+	ChannelKey    string     `json:"channel_key"`
+	ChannelName   string     `json:"channel_name"`
+	AlertID       int64      `json:"alert_id"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// deliver, olayı gönderim kaydı oluşturarak kanala iletir. Aynı alarm için bekleyen eski
+// denemeler artık geçersiz olduğundan iptal edilir.
+func (d *NotificationDispatcher) deliver(ctx context.Context, channel NotificationChannel, notifier Notifier, event AlertEvent) error {
+	data, err := json.Marshal(event.Alert)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	d.db.Exec(`
+		UPDATE notification_deliveries SET status = ?, updated_at = ?
+		WHERE channel_key = ? AND alert_id = ? AND status = ?
+	`, DeliverySuperseded, now, channel.key(), event.Alert.ID, DeliveryPending)
+
+	result, err := d.db.Exec(`
+		INSERT INTO notification_deliveries (channel_key, channel_name, alert_id, event_type, alert, status, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`, channel.key(), channel.Name, event.Alert.ID, event.Type, string(data), DeliveryPending, now, now)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+	return d.attemptDelivery(ctx, id, 0, channel, notifier, event)
+}
+
+// attemptDelivery, kaydı yapılmış bir gönderimi dener ve sonucu kayda yazar. Başarısız
+// denemeler deneme hakkı bitene kadar üstel beklemeyle yeniden planlanır.
+func (d *NotificationDispatcher) attemptDelivery(ctx context.Context, id int64, previous int, channel NotificationChannel, notifier Notifier, event AlertEvent) error {
+	sendErr := d.notify(ctx, channel, notifier, event)
+	attempts := previous + 1
+	now := time.Now()
+
+	maxAttempts := defaultDeliveryAttempts
+	if r, ok := notifier.(RetryingNotifier); ok {
+		maxAttempts = r.MaxAttempts()
+	}
+
+	status, lastError := DeliveryDelivered, sql.NullString{}
+	var nextAttempt sql.NullTime
+	if sendErr != nil {
+		lastError = sql.NullString{String: sendErr.Error(), Valid: true}
+		if attempts < maxAttempts {
+			status = DeliveryPending
+			nextAttempt = sql.NullTime{Time: now.Add(deliveryBackoff(attempts)), Valid: true}
+		} else {
+			status = DeliveryFailed
+		}
+	}
+
+	_, err := d.db.Exec(`
+		UPDATE notification_deliveries SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, status, attempts, lastError, nextAttempt, now, id)
+	if err != nil {
+		log.Printf("Gönderim kaydı %d güncellenemedi: %v", id, err)
+	}
+	if sendErr != nil && status == DeliveryPending {
+		return fmt.Errorf("%v (deneme %d/%d, %s sonra yeniden denenecek)", sendErr, attempts, maxAttempts, deliveryBackoff(attempts))
+	}
+	return sendErr
+}
+
+// RetryDue, yeniden deneme zamanı gelmiş gönderimleri dener
+func (d *NotificationDispatcher) RetryDue(ctx context.Context, now time.Time) {
+	type due struct {
+		id         int64
+		channelKey string
+		event      AlertEvent
+		attempts   int
+	}
+
+	// Tek bağlantılı havuzda satırlar açıkken yazma yapılamaz, önce topla
+	rows, err := d.db.Query(`
+		SELECT id, channel_key, event_type, alert, attempts FROM notification_deliveries
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY id
+	`, DeliveryPending, now)
+	if err != nil {
+		log.Printf("Bekleyen gönderimler okunamadı: %v", err)
+		return
+	}
+	var pending []due
+	for rows.Next() {
+		var item due
+		var data string
+		if err := rows.Scan(&item.id, &item.channelKey, &item.event.Type, &data, &item.attempts); err != nil {
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), &item.event.Alert); err != nil {
+			continue
+		}
+		pending = append(pending, item)
+	}
+	rows.Close()
+	if len(pending) == 0 {
+		return
+	}
+
+	channels, err := d.channels()
+	if err != nil {
+		log.Printf("Bildirim kanalları okunamadı: %v", err)
+		return
+	}
+	byKey := make(map[string]NotificationChannel, len(channels))
+	for _, c := range channels {
+		byKey[c.key()] = c
+	}
+
+	for _, item := range pending {
+		// Olay akışı aynı alarmın daha yeni bir olayını gönderip bu kaydı geçersiz kılmış olabilir;
+		// kaydı sahiplenemeyen deneme atlanır
+		result, err := d.db.Exec(`UPDATE notification_deliveries SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+			DeliverySending, now, item.id, DeliveryPending)
+		if err != nil {
+			log.Printf("Gönderim kaydı %d güncellenemedi: %v", item.id, err)
+			continue
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+
+		channel, ok := byKey[item.channelKey]
+		var notifier Notifier
+		if ok {
+			notifier, err = channelTypes[channel.Type].build(channel.Config)
+		}
+		if !ok || err != nil {
+			reason := "kanal bulunamadı veya devre dışı"
+			if err != nil {
+				reason = err.Error()
+			}
+			d.db.Exec(`UPDATE notification_deliveries SET status = ?, last_error = ?, updated_at = ? WHERE id = ?`,
+				DeliveryFailed, reason, now, item.id)
+			continue
+		}
+		if err := d.attemptDelivery(ctx, item.id, item.attempts, channel, notifier, item.event); err != nil {
+			log.Printf("Alarm %d bildirimi %s kanalına yeniden gönderilemedi: %v", item.event.Alert.ID, channel.Name, err)
+		}
+	}
+}
+
+// runRetries, context iptal edilene kadar zamanı gelen gönderimleri yeniden dener
+func (d *NotificationDispatcher) runRetries(ctx context.Context) {
+	ticker := time.NewTicker(deliveryRetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.RetryDue(ctx, now)
+		}
+	}
+}
+
+// recoverDeliveries, önceki çalışmada yarım kalan yeniden denemeleri beklemeye alır ve
+// veritabanında bekleyen olaylar varsa Run döngüsünün onları göndermesini sağlar
+func (d *NotificationDispatcher) recoverDeliveries() {
+	now := time.Now()
+	d.db.Exec(`UPDATE notification_deliveries SET status = ?, next_attempt_at = ?, updated_at = ? WHERE status = ?`,
+		DeliveryPending, now, now, DeliverySending)
+
+	d.backlogMu.Lock()
+	defer d.backlogMu.Unlock()
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM notification_backlog`).Scan(&d.spilled); err != nil {
+		log.Printf("Bekleyen bildirim olayları okunamadı: %v", err)
+		return
+	}
+	if d.spilled > 0 {
+		log.Printf("Önceki çalışmadan kalan %d bildirim olayı gönderilecek", d.spilled)
+		select {
+		case d.backlogReady <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// spill, kuyruğa sığmayan olayı notification_backlog tablosuna yazar
+func (d *NotificationDispatcher) spill(event AlertEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`INSERT INTO notification_backlog (event, created_at) VALUES (?, ?)`, string(data), time.Now())
+	return err
+}
+
+// notificationBacklogBatch, DrainBacklog'un tek seferde okuduğu olay sayısı
+const notificationBacklogBatch = 100
+
+// DrainBacklog, kuyruktaki olayları ve ardından veritabanına yazılmış olayları geliş
+// sırasıyla gönderir. Tablo boşalınca Enqueue yeniden bellek kuyruğunu kullanır.
+func (d *NotificationDispatcher) DrainBacklog(ctx context.Context) {
+	// Tabloya yazılmaya başlamadan önce kuyruğa girmiş olaylar daha eskidir
+queued:
+	for {
+		select {
+		case event := <-d.events:
+			d.Dispatch(ctx, event)
+		default:
+			break queued
+		}
+	}
+
+	for ctx.Err() == nil {
+		// Sayım kilit altında yapılır; böylece Enqueue tabloya yazarken bekleyen olay kaçmaz
+		d.backlogMu.Lock()
+		err := d.db.QueryRow(`SELECT COUNT(*) FROM notification_backlog`).Scan(&d.spilled)
+		remaining := d.spilled
+		d.backlogMu.Unlock()
+		if err != nil {
+			log.Printf("Bekleyen bildirim olayları okunamadı: %v", err)
+			return
+		}
+		if remaining == 0 {
+			return
+		}
+
+		type spilledEvent struct {
+			id    int64
+			event AlertEvent
+		}
+		rows, err := d.db.Query(`SELECT id, event FROM notification_backlog ORDER BY id LIMIT ?`, notificationBacklogBatch)
+		if err != nil {
+			log.Printf("Bekleyen bildirim olayları okunamadı: %v", err)
+			return
+		}
+		var batch []spilledEvent
+		var invalid []int64
+		for rows.Next() {
+			var item spilledEvent
+			var data string
+			if err := rows.Scan(&item.id, &data); err != nil {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), &item.event); err != nil {
+				log.Printf("Bekleyen bildirim olayı %d çözülemedi: %v", item.id, err)
+				invalid = append(invalid, item.id)
+				continue
+			}
+			batch = append(batch, item)
+		}
+		rows.Close()
+
+		for _, id := range invalid {
+			d.db.Exec(`DELETE FROM notification_backlog WHERE id = ?`, id)
+		}
+		for _, item := range batch {
+			if ctx.Err() != nil {
+				return
+			}
+			d.Dispatch(ctx, item.event)
+			d.db.Exec(`DELETE FROM notification_backlog WHERE id = ?`, item.id)
+		}
+	}
+}
+
+// notificationDeliveriesHandler, gönderim kaydını listeler:
+//
+//	GET /api/v1/notification-deliveries?channel=3|env:slack&status=failed&alert_id=12&limit=100
+func notificationDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	var conditions []string
+	var args []interface{}
+	if channel := q.Get("channel"); channel != "" {
+		if _, err := strconv.ParseInt(channel, 10, 64); err == nil {
+			channel = "channel:" + channel
+		}
+		conditions = append(conditions, "channel_key = ?")
+		args = append(args, channel)
+	}
+	if status := q.Get("status"); status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, status)
+	}
+	if alertID := q.Get("alert_id"); alertID != "" {
+		conditions = append(conditions, "alert_id = ?")
+		args = append(args, alertID)
+	}
+	limit := 100
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+
+	query := `SELECT id, channel_key, channel_name, alert_id, event_type, status, attempts, last_error,
+		next_attempt_at, created_at, updated_at FROM notification_deliveries`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []NotificationDelivery{}
+	for rows.Next() {
+		var item NotificationDelivery
+		var lastError sql.NullString
+		var nextAttempt sql.NullTime
+		if err := rows.Scan(&item.ID, &item.ChannelKey, &item.ChannelName, &item.AlertID, &item.EventType, &item.Status,
+			&item.Attempts, &lastError, &nextAttempt, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			continue
+		}
+		item.LastError = lastError.String
+		if nextAttempt.Valid {
+			item.NextAttemptAt = &nextAttempt.Time
+		}
+		deliveries = append(deliveries, item)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": deliveries, "count": len(deliveries)})
+}
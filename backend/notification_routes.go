@@ -0,0 +1,416 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RouteMatch, bir yönlendirmenin eşleşme koşulları. Boş alanlar her değerle eşleşir;
+// dolu alanların hepsi sağlanmalıdır.
+type RouteMatch struct {
+	Clusters      []string `json:"clusters,omitempty"`       // regex
+	Namespaces    []string `json:"namespaces,omitempty"`     // regex
+	LabelSelector string   `json:"label_selector,omitempty"` // servis label'ları, ör. "team=payments,tier!=internal"
+	Severities    []string `json:"severities,omitempty"`     // critical, warning, info
+}
+
+// NotificationRoute, yönlendirme ağacındaki bir düğüm. Eşleşen alarm, eşleşen ilk alt
+// yönlendirmeye iner; alt yönlendirmede continue true ise sonraki kardeşler de denenir.
+// Hiçbir alt yönlendirme eşleşmezse düğümün kendi kanalları kullanılır. Kök düğüm
+// varsayılan yönlendirmedir ve koşulsuz eşleşir. Eskalasyon politikası tanımlanmamış
+// düğümler üst düğümün politikasını kullanır.
+type NotificationRoute struct {
+	Name             string              `json:"name,omitempty"`
+	Match            RouteMatch          `json:"match"`
+	Channels         []string            `json:"channels"` // kanal ID'si ("3") veya ortam değişkeni kanalı ("env:slack")
+	EscalationPolicy *int64              `json:"escalation_policy,omitempty"`
+	Continue         bool                `json:"continue,omitempty"`
+	Routes           []NotificationRoute `json:"routes,omitempty"`
+}
+
+// routeTarget, yönlendirmesi yapılacak alarmın özellikleri
+type routeTarget struct {
+	Cluster   string            `json:"cluster"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
+	Severity  string            `json:"severity"`
+}
+
+// compiledRoute, derlenmiş yönlendirme düğümü
+type compiledRoute struct {
+	name       string
+	clusters   []*regexp.Regexp
+	namespaces []*regexp.Regexp
+	selector   labels.Selector
+	severities map[string]bool
+	channels   []string
+	policy     int64 // eskalasyon politikası (0: yok)
+	cont       bool
+	routes     []*compiledRoute
+}
+
+// routeChannelKey, yönlendirmedeki kanal referansını kanal anahtarına çevirir
+func routeChannelKey(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if _, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return "channel:" + ref
+	}
+	return ref
+}
+
+// Compile, yönlendirme ağacını doğrular ve derler. path hata iletilerinde düğümü gösterir.
+func (r NotificationRoute) Compile(path string) (*compiledRoute, error) {
+	return r.compile(path, 0)
+}
+
+// compile, düğümü üst düğümden devralınan eskalasyon politikasıyla derler
+func (r NotificationRoute) compile(path string, policy int64) (*compiledRoute, error) {
+	c := &compiledRoute{name: r.Name, selector: labels.Everything(), severities: map[string]bool{}, cont: r.Continue, policy: policy}
+	if c.name == "" {
+		c.name = path
+	}
+	if r.EscalationPolicy != nil {
+		c.policy = *r.EscalationPolicy
+	}
+
+	var err error
+	if c.clusters, err = compileRegexps(path+".match.clusters", r.Match.Clusters); err != nil {
+		return nil, err
+	}
+	if c.namespaces, err = compileRegexps(path+".match.namespaces", r.Match.Namespaces); err != nil {
+		return nil, err
+	}
+	if r.Match.LabelSelector != "" {
+		if c.selector, err = labels.Parse(r.Match.LabelSelector); err != nil {
+			return nil, fmt.Errorf("%s.match.label_selector geçersiz: %v", path, err)
+		}
+	}
+	for _, s := range r.Match.Severities {
+		switch s {
+		case SeverityCritical, SeverityWarning, SeverityInfo:
+			c.severities[s] = true
+		default:
+			return nil, fmt.Errorf("%s.match.severities içinde geçersiz önem derecesi %q", path, s)
+		}
+	}
+	for _, ref := range r.Channels {
+		key := routeChannelKey(ref)
+		if !strings.HasPrefix(key, "channel:") && !strings.HasPrefix(key, "env:") {
+			return nil, fmt.Errorf("%s.channels içinde geçersiz kanal %q (kanal ID'si veya env:<tür> olmalı)", path, ref)
+		}
+		c.channels = append(c.channels, key)
+	}
+	for i, child := range r.Routes {
+		compiled, err := child.compile(fmt.Sprintf("%s.routes[%d]", path, i), c.policy)
+		if err != nil {
+			return nil, err
+		}
+		c.routes = append(c.routes, compiled)
+	}
+	return c, nil
+}
+
+// matches, alarmın düğümün koşullarını sağlayıp sağlamadığını döndürür
+func (c *compiledRoute) matches(t routeTarget) bool {
+	if len(c.clusters) > 0 && !matchAny(c.clusters, t.Cluster) {
+		return false
+	}
+	if len(c.namespaces) > 0 && !matchAny(c.namespaces, t.Namespace) {
+		return false
+	}
+	if len(c.severities) > 0 && !c.severities[t.Severity] {
+		return false
+	}
+	return c.selector.Matches(labels.Set(t.Labels))
+}
+
+// find, adı verilen düğümü döndürür
+func (c *compiledRoute) find(name string) *compiledRoute {
+	if c.name == name {
+		return c
+	}
+	for _, child := range c.routes {
+		if found := child.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// RouteResult, bir alarmın yönlendirme sonucu
+type RouteResult struct {
+	Routes   []string `json:"routes"`   // kanalları kullanılan düğümler
+	Channels []string `json:"channels"` // kanal anahtarları (tekrarsız, sırayla)
+	Policies []int64  `json:"policies"` // başlatılacak eskalasyon politikaları (tekrarsız)
+}
+
+// Route, alarmı ağaçta yürütür ve ulaşacağı kanalları döndürür. Kök düğüm eşleşme
+// koşullarından bağımsız olarak her alarmı kabul eder.
+func (c *compiledRoute) Route(t routeTarget) RouteResult {
+	result := RouteResult{Routes: []string{}, Channels: []string{}, Policies: []int64{}}
+	seen := map[string]bool{}
+	seenPolicies := map[int64]bool{}
+	var walk func(node *compiledRoute)
+	walk = func(node *compiledRoute) {
+		matched := false
+		for _, child := range node.routes {
+			if !child.matches(t) {
+				continue
+			}
+			matched = true
+			walk(child)
+			if !child.cont {
+				break
+			}
+		}
+		if matched {
+			return
+		}
+		result.Routes = append(result.Routes, node.name)
+		if node.policy != 0 && !seenPolicies[node.policy] {
+			seenPolicies[node.policy] = true
+			result.Policies = append(result.Policies, node.policy)
+		}
+		for _, key := range node.channels {
+			if !seen[key] {
+				seen[key] = true
+				result.Channels = append(result.Channels, key)
+			}
+		}
+	}
+	walk(c)
+	return result
+}
+
+// loadNotificationRoutes, kayıtlı yönlendirme ağacını okur; ağaç tanımlı değilse nil döner
+// (bu durumda alarmlar tüm etkin kanallara gönderilir)
+func loadNotificationRoutes(db *sql.DB) (*NotificationRoute, error) {
+	var data string
+	err := db.QueryRow("SELECT tree FROM notification_routes WHERE id = 1").Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var route NotificationRoute
+	if err := json.Unmarshal([]byte(data), &route); err != nil {
+		return nil, err
+	}
+	return &route, nil
+}
+
+// serviceRouteTarget, servis bilgilerinden (label'lar dahil) yönlendirme hedefini oluşturur
+func serviceRouteTarget(db *sql.DB, serviceID int, severity string) (routeTarget, error) {
+	t := routeTarget{Severity: severity, Labels: map[string]string{}}
+	var labelsJSON sql.NullString
+	err := db.QueryRow("SELECT cluster, namespace, labels FROM services WHERE id = ?", serviceID).
+		Scan(&t.Cluster, &t.Namespace, &labelsJSON)
+	if err != nil {
+		return t, err
+	}
+	if labelsJSON.Valid {
+		json.Unmarshal([]byte(labelsJSON.String), &t.Labels)
+	}
+	return t, nil
+}
+
+// routedChannels, alarmın yönlendirme ağacına göre ulaşacağı etkin kanalları ve başlatılacak
+// eskalasyon politikalarını döndürür. Ağaç tanımlı değilse tüm etkin kanallar döner.
+func (d *NotificationDispatcher) routedChannels(alert Alert) ([]NotificationChannel, []int64, error) {
+	channels, err := d.channels()
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := loadNotificationRoutes(d.db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("yönlendirme ağacı okunamadı: %v", err)
+	}
+	if tree == nil {
+		return channels, nil, nil
+	}
+	root, err := tree.Compile("route")
+	if err != nil {
+		return nil, nil, fmt.Errorf("yönlendirme ağacı geçersiz: %v", err)
+	}
+
+	target, err := serviceRouteTarget(d.db, alert.ServiceID, alert.Severity)
+	if err != nil {
+		// Servis silinmiş olabilir; alarmdaki bilgilerle devam et
+		target = routeTarget{Cluster: alert.Cluster, Namespace: alert.Namespace, Severity: alert.Severity}
+	}
+
+	byKey := make(map[string]NotificationChannel, len(channels))
+	for _, c := range channels {
+		byKey[c.key()] = c
+	}
+	result := root.Route(target)
+	var routed []NotificationChannel
+	for _, key := range result.Channels {
+		channel, ok := byKey[key]
+		if !ok {
+			log.Printf("Yönlendirmedeki %s kanalı bulunamadı veya devre dışı, atlandı", key)
+			continue
+		}
+		routed = append(routed, channel)
+	}
+	return routed, result.Policies, nil
+}
+
+// validateRouteReferences, ağaçtaki kanal ve eskalasyon politikası referanslarının var olduğunu doğrular
+func validateRouteReferences(db *sql.DB, route NotificationRoute, known map[string]bool) error {
+	for _, ref := range route.Channels {
+		if !known[routeChannelKey(ref)] {
+			return fmt.Errorf("kanal bulunamadı: %s", ref)
+		}
+	}
+	if route.EscalationPolicy != nil {
+		if _, err := getEscalationPolicy(db, *route.EscalationPolicy); err != nil {
+			return fmt.Errorf("eskalasyon politikası bulunamadı: %d", *route.EscalationPolicy)
+		}
+	}
+	for _, child := range route.Routes {
+		if err := validateRouteReferences(db, child, known); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationRoutesHandler, bildirim yönlendirme ağacını döndürür (GET), kaydeder (PUT)
+// ve siler (DELETE). Ağaç silindiğinde alarmlar tüm etkin kanallara gönderilir.
+func notificationRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		route, err := loadNotificationRoutes(db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"route": route, "configured": route != nil})
+
+	case "PUT":
+		var route NotificationRoute
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		if _, err := route.Compile("route"); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		known, err := knownChannelKeys(db)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		if err := validateRouteReferences(db, route, known); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		data, _ := json.Marshal(route)
+		_, err = db.Exec(`
+			INSERT INTO notification_routes (id, tree) VALUES (1, ?)
+			ON CONFLICT(id) DO UPDATE SET tree = excluded.tree, updated_at = CURRENT_TIMESTAMP
+		`, string(data))
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Yönlendirme ağacı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Yönlendirme ağacı güncellendi", "route": route})
+
+	case "DELETE":
+		if _, err := db.Exec("DELETE FROM notification_routes"); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Yönlendirme ağacı silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Yönlendirme ağacı silindi, alarmlar tüm etkin kanallara gönderilecek",
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// notificationRoutesPreviewHandler, bir servisin alarmlarının hangi kanallara ulaşacağını gösterir:
+//
+//	GET /api/v1/notification-routes/preview?service_id=7[&severity=critical]
+//
+// Önem derecesi verilmezse her önem derecesi için sonuç döner.
+func notificationRoutesPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	serviceID, err := strconv.Atoi(r.URL.Query().Get("service_id"))
+	if err != nil {
+		http.Error(w, `{"error":"Geçerli bir service_id gerekli"}`, http.StatusBadRequest)
+		return
+	}
+	severities := []string{SeverityCritical, SeverityWarning, SeverityInfo}
+	if s := r.URL.Query().Get("severity"); s != "" {
+		severities = []string{s}
+	}
+
+	dispatcher := notificationDispatcher
+	if dispatcher == nil {
+		dispatcher = &NotificationDispatcher{db: db}
+	}
+
+	var service map[string]interface{}
+	results := []map[string]interface{}{}
+	for _, severity := range severities {
+		target, err := serviceRouteTarget(db, serviceID, severity)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Servis bulunamadı"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Veritabanı hatası: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		service = map[string]interface{}{"id": serviceID, "cluster": target.Cluster, "namespace": target.Namespace, "labels": target.Labels}
+
+		routes := []string{}
+		if tree, err := loadNotificationRoutes(db); err == nil && tree != nil {
+			if root, err := tree.Compile("route"); err == nil {
+				routes = root.Route(target).Routes
+			}
+		}
+		channels, policies, err := dispatcher.routedChannels(Alert{ServiceID: serviceID, Cluster: target.Cluster, Namespace: target.Namespace, Severity: severity})
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusInternalServerError)
+			return
+		}
+		views := []NotificationChannel{}
+		for _, c := range channels {
+			views = append(views, c.View())
+		}
+		if policies == nil {
+			policies = []int64{}
+		}
+		results = append(results, map[string]interface{}{
+			"severity":            severity,
+			"routes":              routes,
+			"channels":            views,
+			"escalation_policies": policies,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"service": service, "results": results})
+}
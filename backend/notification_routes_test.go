@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationRouteTree(t *testing.T) {
+	tree := NotificationRoute{
+		Channels: []string{"1"},
+		Routes: []NotificationRoute{
+			{Name: "prod-critical", Match: RouteMatch{Clusters: []string{"prod-.*"}, Severities: []string{SeverityCritical}},
+				Channels: []string{"2"}, Continue: true},
+			{Name: "payments", Match: RouteMatch{LabelSelector: "team=payments"}, Channels: []string{"3", "2"},
+				Routes: []NotificationRoute{
+					{Name: "payments-db", Match: RouteMatch{Namespaces: []string{"db"}}, Channels: []string{"env:email"}},
+				}},
+			{Name: "shop", Match: RouteMatch{Namespaces: []string{"shop"}}, Channels: []string{"4"}},
+		},
+	}
+	root, err := tree.Compile("route")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		target   routeTarget
+		routes   []string
+		channels []string
+	}{
+		{"varsayılan", routeTarget{Cluster: "dev", Namespace: "misc", Severity: SeverityWarning},
+			[]string{"route"}, []string{"channel:1"}},
+		{"continue ile devam", routeTarget{Cluster: "prod-eu", Namespace: "shop", Severity: SeverityCritical},
+			[]string{"prod-critical", "shop"}, []string{"channel:2", "channel:4"}},
+		{"ilk eşleşmede dur", routeTarget{Cluster: "dev", Namespace: "shop", Labels: map[string]string{"team": "payments"}},
+			[]string{"payments"}, []string{"channel:3", "channel:2"}},
+		{"alt yönlendirme", routeTarget{Cluster: "dev", Namespace: "db", Labels: map[string]string{"team": "payments"}},
+			[]string{"payments-db"}, []string{"env:email"}},
+		{"tekrarsız kanallar", routeTarget{Cluster: "prod-us", Namespace: "api", Labels: map[string]string{"team": "payments"}, Severity: SeverityCritical},
+			[]string{"prod-critical", "payments"}, []string{"channel:2", "channel:3"}},
+	}
+	for _, c := range cases {
+		got := root.Route(c.target)
+		if !reflect.DeepEqual(got.Routes, c.routes) || !reflect.DeepEqual(got.Channels, c.channels) {
+			t.Errorf("%s: beklenen %v %v, alınan %v %v", c.name, c.routes, c.channels, got.Routes, got.Channels)
+		}
+	}
+
+	bad := NotificationRoute{Routes: []NotificationRoute{{Match: RouteMatch{Severities: []string{"fatal"}}}}}
+	if _, err := bad.Compile("route"); err == nil || !strings.Contains(err.Error(), "route.routes[0]") {
+		t.Errorf("geçersiz önem derecesi reddedilmeliydi: %v", err)
+	}
+}
+
+func TestDispatchFollowsRoutesAndPreview(t *testing.T) {
+	defaultServer, defaultRequests := newRecordingServer(t, func(string) string { return "ok" })
+	paymentsServer, paymentsRequests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "genel", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": defaultServer.URL}},
+		NotificationChannel{Name: "payments", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": paymentsServer.URL}},
+	)
+	dispatcher.db.Exec(`UPDATE services SET labels = '{"team":"payments"}' WHERE id = 7`)
+	dispatcher.db.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (8, 'cart', 'shop', 'prod', 'service')`)
+
+	body := `{"channels":["1"],"routes":[{"name":"payments","match":{"label_selector":"team=payments","severities":["critical"]},"channels":["2"]}]}`
+	rec := httptest.NewRecorder()
+	notificationRoutesHandler(rec, httptest.NewRequest("PUT", "/api/v1/notification-routes", strings.NewReader(body)))
+	if rec.Code != 200 {
+		t.Fatalf("yönlendirme ağacı kaydedilemedi: %d %s", rec.Code, rec.Body.String())
+	}
+
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", time.Now())
+	manager.Fire(statusAlertKey(8), 8, nil, SeverityCritical, "down", time.Now())
+	if got := len(paymentsRequests()); got != 1 {
+		t.Errorf("payments kanalına 1 bildirim bekleniyordu, alınan %d", got)
+	}
+	if got := len(defaultRequests()); got != 1 {
+		t.Errorf("varsayılan kanala 1 bildirim bekleniyordu, alınan %d", got)
+	}
+
+	rec = httptest.NewRecorder()
+	notificationRoutesPreviewHandler(rec, httptest.NewRequest("GET", "/api/v1/notification-routes/preview?service_id=7", nil))
+	var preview struct {
+		Results []struct {
+			Severity string                `json:"severity"`
+			Routes   []string              `json:"routes"`
+			Channels []NotificationChannel `json:"channels"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &preview); err != nil || len(preview.Results) != 3 {
+		t.Fatalf("beklenmeyen önizleme: %s", rec.Body.String())
+	}
+	for _, result := range preview.Results {
+		want := "genel"
+		if result.Severity == SeverityCritical {
+			want = "payments"
+		}
+		if len(result.Channels) != 1 || result.Channels[0].Name != want {
+			t.Errorf("%s için %s kanalı bekleniyordu: %+v", result.Severity, want, result)
+		}
+		if secret, _ := result.Channels[0].Config["webhook_url"].(string); secret != maskedSecretValue {
+			t.Errorf("önizlemede gizli ayar maskelenmedi: %v", secret)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	notificationRoutesHandler(rec, httptest.NewRequest("PUT", "/api/v1/notification-routes", strings.NewReader(`{"channels":["99"]}`)))
+	if rec.Code != 400 {
+		t.Errorf("olmayan kanal reddedilmeliydi: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,725 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bildirim kanalı türleri
+const (
+	ChannelTypeSlack = "slack"
+	ChannelTypeTeams = "teams"
+)
+
+// Bildirim kanalı kaynakları
+const (
+	ChannelSourceAPI = "api" // API ile oluşturulan, veritabanında saklanan kanal
+	ChannelSourceEnv = "env" // ALERT_NOTIFICATION_CHANNEL ile tanımlanan kanal
+)
+
+const (
+	// notificationTimeout, tek bir bildirim gönderiminin en uzun süresi
+	notificationTimeout = 10 * time.Second
+	// notificationQueueSize, gönderilmeyi bekleyen olay kuyruğunun boyutu
+	notificationQueueSize = 256
+)
+
+// notificationHTTPClient, bildirim kanallarına yapılan HTTP istekleri için ortak istemci
+var notificationHTTPClient = &http.Client{Timeout: notificationTimeout}
+
+// ChannelConfig, kanal türüne göre değişen ayarlar (ör. webhook_url, channel)
+type ChannelConfig map[string]interface{}
+
+// String, ayarı metin olarak döndürür
+func (c ChannelConfig) String(key string) string {
+	if v, ok := c[key].(string); ok {
+		return strings.TrimSpace(v)
+	}
+	return ""
+}
+
+// Secret, ayarı döndürür; değer env:, file: veya secretRef: referansıysa çözer
+func (c ChannelConfig) Secret(key string) (string, error) {
+	value := c.String(key)
+	if value == "" {
+		return "", nil
+	}
+	resolved, err := secretResolver.Resolve(value, defaultClusterName)
+	if err != nil {
+		return "", fmt.Errorf("%s çözülemedi: %v", key, err)
+	}
+	return resolved, nil
+}
+
+// Notifier, alarm olaylarını bir bildirim kanalına gönderir. ref, alarmın bu kanala ilk
+// gönderiminde dönen ileti referansıdır (ör. Slack ts); kapanış bildiriminde özgün iletiyi
+// güncellemek için kullanılır. Dönen referans bir sonraki olay için saklanır.
+type Notifier interface {
+	Notify(ctx context.Context, event AlertEvent, ref string) (string, error)
+}
+
+// AcknowledgingNotifier, onay olaylarını da karşı sisteme ileten kanallar (ör. PagerDuty,
+// Opsgenie). Diğer kanallara onay olayları gönderilmez.
+type AcknowledgingNotifier interface {
+	Acknowledges() bool
+}
+
+// channelType, bir kanal türünün gizli ayarlarını, doğrulamasını ve oluşturucusunu tanımlar
+type channelType struct {
+	secrets  []string
+	validate func(ChannelConfig) error
+	build    func(ChannelConfig) (Notifier, error)
+}
+
+// channelTypes, desteklenen bildirim kanalı türleri
+var channelTypes = map[string]channelType{
+	ChannelTypeSlack:     {secrets: []string{"webhook_url", "bot_token"}, validate: validateSlackConfig, build: newSlackNotifier},
+	ChannelTypeTeams:     {secrets: []string{"webhook_url"}, validate: validateTeamsConfig, build: newTeamsNotifier},
+	ChannelTypeEmail:     {secrets: []string{"username", "password"}, validate: validateEmailConfig, build: newEmailNotifier},
+	ChannelTypeWebhook:   {secrets: []string{"url", "secret", "headers"}, validate: validateWebhookConfig, build: newWebhookNotifier},
+	ChannelTypePagerDuty: {secrets: []string{"routing_key"}, validate: validatePagerDutyConfig, build: newPagerDutyNotifier},
+	ChannelTypeOpsgenie:  {secrets: []string{"api_key"}, validate: validateOpsgenieConfig, build: newOpsgenieNotifier},
+}
+
+// NotificationChannel, alarm bildirimlerinin gönderildiği kanal
+type NotificationChannel struct {
+	ID        int64         `json:"id"`
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Config    ChannelConfig `json:"config"`
+	Enabled   bool          `json:"enabled"`
+	Source    string        `json:"source"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// key, kanalın ileti referanslarında kullanılan anahtarı
+func (c NotificationChannel) key() string {
+	if c.Source == ChannelSourceEnv {
+		return "env:" + c.Type
+	}
+	return fmt.Sprintf("channel:%d", c.ID)
+}
+
+// View, kanalı gizli ayarları maskelenmiş olarak döndürür
+func (c NotificationChannel) View() NotificationChannel {
+	view := c
+	view.Config = ChannelConfig{}
+	secret := map[string]bool{}
+	for _, key := range channelTypes[c.Type].secrets {
+		secret[key] = true
+	}
+	for key, value := range c.Config {
+		if !secret[key] {
+			view.Config[key] = value
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			view.Config[key] = secretView(v)
+		case map[string]interface{}:
+			masked := map[string]interface{}{}
+			for name, item := range v {
+				s, _ := item.(string)
+				masked[name] = secretView(s)
+			}
+			view.Config[key] = masked
+		}
+	}
+	return view
+}
+
+// Validate, kanalın adını, türünü ve ayarlarını doğrular
+func (c *NotificationChannel) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("name alanı gerekli")
+	}
+	kind, ok := channelTypes[c.Type]
+	if !ok {
+		types := make([]string, 0, len(channelTypes))
+		for name := range channelTypes {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("type şunlardan biri olmalı: %s", strings.Join(types, ", "))
+	}
+	if c.Config == nil {
+		c.Config = ChannelConfig{}
+	}
+	for _, key := range kind.secrets {
+		if err := validateSecretValue(key, c.Config.String(key)); err != nil {
+			return err
+		}
+	}
+	return kind.validate(c.Config)
+}
+
+// validateWebhookURL, düz metin URL'lerin http(s) adresi olduğunu doğrular; referanslar gönderimde çözülür
+func validateWebhookURL(key, value string) error {
+	if value == "" || isSecretReference(value) {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s geçerli bir http(s) adresi olmalı", key)
+	}
+	return nil
+}
+
+// mergeSecrets, güncellemede maskeli olarak geri gönderilen gizli ayarları mevcut değerleriyle değiştirir
+func (c *NotificationChannel) mergeSecrets(existing ChannelConfig) {
+	for _, key := range channelTypes[c.Type].secrets {
+		switch v := c.Config[key].(type) {
+		case string:
+			if v == maskedSecretValue {
+				c.Config[key] = existing[key]
+			}
+		case map[string]interface{}:
+			old, _ := existing[key].(map[string]interface{})
+			for name, item := range v {
+				if item == maskedSecretValue {
+					v[name] = old[name]
+				}
+			}
+		}
+	}
+}
+
+// notificationChannelColumns, kanal sorgularında kullanılan kolonlar
+const notificationChannelColumns = `id, name, type, config, enabled, created_at, updated_at`
+
+// scanNotificationChannel, notificationChannelColumns sırasıyla okunan satırı çözerek kanala dönüştürür
+func scanNotificationChannel(scanner interface{ Scan(...interface{}) error }) (NotificationChannel, error) {
+	c := NotificationChannel{Source: ChannelSourceAPI}
+	var config string
+	if err := scanner.Scan(&c.ID, &c.Name, &c.Type, &config, &c.Enabled, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return c, err
+	}
+	plain, err := decryptSecret(config)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(plain), &c.Config); err != nil {
+		return c, fmt.Errorf("kanal %d ayarları okunamadı: %v", c.ID, err)
+	}
+	return c, nil
+}
+
+// getNotificationChannel, kanalı ID ile döndürür
+func getNotificationChannel(db *sql.DB, id int64) (NotificationChannel, error) {
+	return scanNotificationChannel(db.QueryRow(`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE id = ?`, id))
+}
+
+// listNotificationChannels, koşula uyan kanalları döndürür
+func listNotificationChannels(db *sql.DB, where string, args ...interface{}) ([]NotificationChannel, error) {
+	rows, err := db.Query(`SELECT `+notificationChannelColumns+` FROM notification_channels WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []NotificationChannel{}
+	for rows.Next() {
+		c, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, c)
+	}
+	return channels, rows.Err()
+}
+
+// saveNotificationChannel, kanalı ayarlarını şifreleyerek ekler (ID sıfırsa) veya günceller
+func saveNotificationChannel(db *sql.DB, c *NotificationChannel) error {
+	data, err := json.Marshal(c.Config)
+	if err != nil {
+		return err
+	}
+	config, err := encryptSecret(string(data))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	if c.ID == 0 {
+		result, err := db.Exec(`
+			INSERT INTO notification_channels (name, type, config, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, c.Name, c.Type, config, c.Enabled, now, now)
+		if err != nil {
+			return err
+		}
+		c.ID, _ = result.LastInsertId()
+		c.CreatedAt = now
+	} else {
+		_, err := db.Exec(`
+			UPDATE notification_channels SET name = ?, type = ?, config = ?, enabled = ?, updated_at = ? WHERE id = ?
+		`, c.Name, c.Type, config, c.Enabled, now, c.ID)
+		if err != nil {
+			return err
+		}
+	}
+	c.Source = ChannelSourceAPI
+	c.UpdatedAt = now
+	return nil
+}
+
+// envNotificationChannels, ALERT_NOTIFICATION_CHANNEL (virgülle ayrılmış) ile seçilen ve
+// ortam değişkenlerinden yapılandırılan kanalları döndürür
+func envNotificationChannels() []NotificationChannel {
+	var channels []NotificationChannel
+	for _, name := range strings.Split(os.Getenv("ALERT_NOTIFICATION_CHANNEL"), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		var config ChannelConfig
+		switch name {
+		case "":
+			continue
+		case ChannelTypeSlack:
+			config = ChannelConfig{"webhook_url": os.Getenv("SLACK_WEBHOOK_URL")}
+		case ChannelTypeTeams:
+			config = ChannelConfig{"webhook_url": os.Getenv("TEAMS_WEBHOOK_URL")}
+		case ChannelTypeEmail:
+			config = ChannelConfig{
+				"smtp_server": os.Getenv("EMAIL_SMTP_SERVER"),
+				"from":        os.Getenv("EMAIL_FROM"),
+				"to":          os.Getenv("EMAIL_TO"),
+				"username":    os.Getenv("EMAIL_SMTP_USERNAME"),
+				"password":    os.Getenv("EMAIL_SMTP_PASSWORD"),
+				"starttls":    os.Getenv("EMAIL_STARTTLS"),
+				"digest":      os.Getenv("EMAIL_DIGEST"),
+			}
+		case ChannelTypePagerDuty:
+			config = ChannelConfig{"routing_key": os.Getenv("PAGERDUTY_ROUTING_KEY")}
+		case ChannelTypeOpsgenie:
+			config = ChannelConfig{
+				"api_key": os.Getenv("OPSGENIE_API_KEY"),
+				"api_url": os.Getenv("OPSGENIE_API_URL"),
+				"team":    os.Getenv("OPSGENIE_TEAM"),
+			}
+		default:
+			log.Printf("ALERT_NOTIFICATION_CHANNEL: bilinmeyen kanal %q yok sayıldı", name)
+			continue
+		}
+
+		channel := NotificationChannel{Name: name + " (env)", Type: name, Config: config, Enabled: true, Source: ChannelSourceEnv}
+		if err := channel.Validate(); err != nil {
+			log.Printf("ALERT_NOTIFICATION_CHANNEL: %s kanalı yapılandırılamadı: %v", name, err)
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// alertNotification, kanal türünden bağımsız bildirim içeriği
+type alertNotification struct {
+	Resolved  bool
+	Title     string
+	Severity  string
+	Service   string
+	Namespace string
+	Cluster   string
+	Message   string
+	Duration  string
+	URL       string
+	OnCall    string // eskalasyon bildirimlerinde nöbetçiler
+}
+
+// alertUIURL, servisin arayüzdeki sayfasının adresi (ALERT_UI_URL ile değiştirilebilir)
+func alertUIURL(serviceID int) string {
+	base := strings.TrimRight(os.Getenv("ALERT_UI_URL"), "/")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return fmt.Sprintf("%s/services/%d", base, serviceID)
+}
+
+// humanDuration, süreyi "1 sa 5 dk" biçiminde yazar
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return fmt.Sprintf("%d sn", int(d.Seconds()))
+	}
+	var parts []string
+	if days := int(d.Hours()) / 24; days > 0 {
+		parts = append(parts, fmt.Sprintf("%d gün", days))
+	}
+	if hours := int(d.Hours()) % 24; hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d sa", hours))
+	}
+	if minutes := int(d.Minutes()) % 60; minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%d dk", minutes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// buildAlertNotification, olaydan bildirim içeriğini oluşturur
+func buildAlertNotification(event AlertEvent) alertNotification {
+	a := event.Alert
+	n := alertNotification{
+		Resolved:  event.Type == AlertEventResolved,
+		Severity:  a.Severity,
+		Service:   a.ServiceName,
+		Namespace: a.Namespace,
+		Cluster:   a.Cluster,
+		Message:   a.Message,
+		Duration:  humanDuration(a.Duration()),
+		URL:       alertUIURL(a.ServiceID),
+	}
+	if n.Service == "" {
+		n.Service = fmt.Sprintf("servis %d", a.ServiceID)
+	}
+	if n.Resolved {
+		n.Title = fmt.Sprintf("[ÇÖZÜLDÜ] %s", n.Service)
+	} else {
+		n.Title = fmt.Sprintf("[%s] %s", strings.ToUpper(a.Severity), n.Service)
+	}
+	if e := event.Escalation; e != nil {
+		n.Title = fmt.Sprintf("[ESKALASYON %d] %s", e.Level, n.Title)
+		n.OnCall = strings.Join(e.OnCallNames(), ", ")
+	}
+	return n
+}
+
+// postJSON, gövdeyi JSON olarak gönderir ve 2xx dışındaki yanıtları hata olarak döndürür
+func postJSON(ctx context.Context, method, target string, headers map[string]string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := notificationHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return data, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// NotificationDispatcher, alarm olaylarını sırayla etkin bildirim kanallarına gönderir
+type NotificationDispatcher struct {
+	db          *sql.DB
+	events      chan AlertEvent
+	envChannels []NotificationChannel
+
+	// backlogMu, spilled sayacını korur; spilled sıfırdan büyükken yeni olaylar sırayı
+	// korumak için kuyruğa değil notification_backlog tablosuna yazılır
+	backlogMu sync.Mutex
+	spilled   int
+	// backlogReady, tabloya olay yazıldığını Run döngüsüne bildirir
+	backlogReady chan struct{}
+}
+
+// NewNotificationDispatcher, ortam değişkenlerindeki kanalları da içeren yeni bir dağıtıcı oluşturur
+func NewNotificationDispatcher(db *sql.DB) *NotificationDispatcher {
+	return &NotificationDispatcher{
+		db:           db,
+		events:       make(chan AlertEvent, notificationQueueSize),
+		envChannels:  envNotificationChannels(),
+		backlogReady: make(chan struct{}, 1),
+	}
+}
+
+// notificationDispatcher, alarm yöneticisine abone olan genel dağıtıcı
+var notificationDispatcher *NotificationDispatcher
+
+// Enqueue, olayı gönderim kuyruğuna ekler. Kuyruk doluysa veya veritabanında bekleyen
+// olaylar varsa olay notification_backlog tablosuna yazılır ve sırası gelince gönderilir.
+func (d *NotificationDispatcher) Enqueue(event AlertEvent) {
+	d.backlogMu.Lock()
+	defer d.backlogMu.Unlock()
+	if d.spilled == 0 {
+		select {
+		case d.events <- event:
+			return
+		default:
+		}
+	}
+	if err := d.spill(event); err != nil {
+		log.Printf("Bildirim kuyruğu dolu ve olay kaydedilemedi, alarm %d (%s) olayı gönderilmedi: %v",
+			event.Alert.ID, event.Type, err)
+		return
+	}
+	d.spilled++
+	select {
+	case d.backlogReady <- struct{}{}:
+	default:
+	}
+}
+
+// Run, context iptal edilene kadar kuyruktaki ve veritabanında bekleyen olayları gönderir,
+// süresi dolan özetleri, sessize alma kurallarını ve eskalasyonları işler. Başarısız
+// gönderimler olay akışını bekletmemek için ayrı bir goroutine'de yeniden denenir.
+func (d *NotificationDispatcher) Run(ctx context.Context) {
+	d.recoverDeliveries()
+	go d.runRetries(ctx)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.events:
+			d.Dispatch(ctx, event)
+		case <-d.backlogReady:
+			d.DrainBacklog(ctx)
+		case now := <-ticker.C:
+			d.FlushDigests(ctx, now)
+			d.ExpireSilences(ctx, now)
+			d.Escalate(ctx, now)
+		}
+	}
+}
+
+// channels, etkin veritabanı kanalları ile ortam değişkeni kanallarını döndürür
+func (d *NotificationDispatcher) channels() ([]NotificationChannel, error) {
+	channels, err := listNotificationChannels(d.db, "enabled = 1")
+	if err != nil {
+		return nil, err
+	}
+	return append(channels, d.envChannels...), nil
+}
+
+// Dispatch, olayı yönlendirme ağacının seçtiği etkin kanallara gönderir. Sessize alınan
+// alarmların olayları gönderilmez ve eskalasyonları başlatılmaz.
+func (d *NotificationDispatcher) Dispatch(ctx context.Context, event AlertEvent) {
+	if d.silenced(event) {
+		return
+	}
+	channels, policies, err := d.routedChannels(event.Alert)
+	if err != nil {
+		log.Printf("Alarm %d için bildirim kanalları belirlenemedi: %v", event.Alert.ID, err)
+		return
+	}
+	switch event.Type {
+	case AlertEventFiring:
+		defer d.startEscalations(ctx, event.Alert, policies)
+	case AlertEventAcknowledged, AlertEventResolved:
+		d.stopEscalations(event.Alert.ID)
+	}
+	for _, channel := range channels {
+		if err := d.send(ctx, channel, event); err != nil {
+			log.Printf("Alarm %d bildirimi %s kanalına gönderilemedi: %v", event.Alert.ID, channel.Name, err)
+		}
+	}
+}
+
+// send, olayı tek bir kanala gönderir; özet modundaki olayları kuyruğa alır, diğerlerini
+// gönderim kaydıyla iletir
+func (d *NotificationDispatcher) send(ctx context.Context, channel NotificationChannel, event AlertEvent) error {
+	notifier, err := channelTypes[channel.Type].build(channel.Config)
+	if err != nil {
+		return err
+	}
+	if event.Type == AlertEventAcknowledged {
+		if ack, ok := notifier.(AcknowledgingNotifier); !ok || !ack.Acknowledges() {
+			return nil
+		}
+	}
+	if digest, ok := notifier.(DigestNotifier); ok && event.Escalation == nil && digest.Digests(event) {
+		return d.queueDigest(channel, event)
+	}
+	return d.deliver(ctx, channel, notifier, event)
+}
+
+// notify, olayı bildiriciyle gönderir ve dönen ileti referansını saklar
+func (d *NotificationDispatcher) notify(ctx context.Context, channel NotificationChannel, notifier Notifier, event AlertEvent) error {
+	var ref sql.NullString
+	d.db.QueryRow(`SELECT ref FROM notification_messages WHERE alert_id = ? AND channel_key = ?`,
+		event.Alert.ID, channel.key()).Scan(&ref)
+
+	sendCtx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+	newRef, err := notifier.Notify(sendCtx, event, ref.String)
+	if err != nil {
+		return err
+	}
+
+	if newRef != "" && newRef != ref.String {
+		_, err = d.db.Exec(`
+			INSERT INTO notification_messages (alert_id, channel_key, ref, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(alert_id, channel_key) DO UPDATE SET ref = excluded.ref
+		`, event.Alert.ID, channel.key(), newRef, time.Now())
+	}
+	return err
+}
+
+// testAlertEvent, kanal testi için örnek bir alarm olayı
+func testAlertEvent() AlertEvent {
+	now := time.Now()
+	return AlertEvent{Type: AlertEventFiring, Alert: Alert{
+		ServiceName: "test-servisi",
+		Namespace:   "default",
+		Cluster:     defaultClusterName,
+		DedupKey:    "test",
+		Severity:    SeverityInfo,
+		Status:      AlertStatusOpen,
+		Message:     "Bu bir test bildirimidir",
+		FirstSeen:   now,
+		LastSeen:    now,
+	}}
+}
+
+// notificationChannelsHandler, /api/v1/notification-channels altındaki endpoint'leri yönetir:
+//
+//	GET    /api/v1/notification-channels            kanallar (ortam değişkeni kanalları dahil)
+//	POST   /api/v1/notification-channels            kanal oluşturma
+//	GET    /api/v1/notification-channels/{id}       kanal detayı
+//	PUT    /api/v1/notification-channels/{id}       kanal güncelleme (maskeli gizli ayarlar korunur)
+//	DELETE /api/v1/notification-channels/{id}       kanal silme
+//	POST   /api/v1/notification-channels/{id}/test  test bildirimi gönderme
+func notificationChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notification-channels"), "/")
+	if path == "" {
+		switch r.Method {
+		case "GET":
+			channels, err := listNotificationChannels(db, "1 = 1")
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Bildirim kanalları alınamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			if notificationDispatcher != nil {
+				channels = append(channels, notificationDispatcher.envChannels...)
+			}
+			views := make([]NotificationChannel, 0, len(channels))
+			for _, c := range channels {
+				views = append(views, c.View())
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"channels": views, "count": len(views)})
+
+		case "POST":
+			channel := NotificationChannel{Enabled: true}
+			if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+				http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+				return
+			}
+			if err := channel.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			channel.ID = 0
+			if err := saveNotificationChannel(db, &channel); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Bildirim kanalı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Bildirim kanalı oluşturuldu",
+				"channel": channel.View(),
+			})
+
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	id, err := strconv.ParseInt(segments[0], 10, 64)
+	if err != nil || len(segments) > 2 || (len(segments) == 2 && segments[1] != "test") {
+		http.Error(w, `{"error":"Bulunamadı"}`, http.StatusNotFound)
+		return
+	}
+	channel, err := getNotificationChannel(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Bildirim kanalı bulunamadı"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Bildirim kanalı alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	if len(segments) == 2 {
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		notifier, err := channelTypes[channel.Type].build(channel.Config)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(r.Context(), notificationTimeout)
+			defer cancel()
+			_, err = notifier.Notify(ctx, testAlertEvent(), "")
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "Test bildirimi gönderilemedi: "+err.Error()), http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Test bildirimi gönderildi"})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(map[string]interface{}{"channel": channel.View()})
+
+	case "PUT":
+		existing := channel.Config
+		channel.Config = nil
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		if channel.Config == nil {
+			channel.Config = existing
+		} else {
+			channel.mergeSecrets(existing)
+		}
+		if err := channel.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		channel.ID = id
+		if err := saveNotificationChannel(db, &channel); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Bildirim kanalı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Bildirim kanalı güncellendi",
+			"channel": channel.View(),
+		})
+
+	case "DELETE":
+		if _, err := db.Exec("DELETE FROM notification_channels WHERE id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Bildirim kanalı silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		db.Exec("DELETE FROM notification_messages WHERE channel_key = ?", channel.key())
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Bildirim kanalı silindi",
+			"id":      id,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedRequest, sahte sunucuya gelen istek
+type recordedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// newRecordingServer, gelen JSON istekleri kaydeden ve respond ile yanıt veren yerel sunucu oluşturur
+func newRecordingServer(t *testing.T, respond func(path string) string) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		mu.Unlock()
+		io.WriteString(w, respond(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest{}, requests...)
+	}
+}
+
+// newNotificationTestSetup, test veritabanı, servis ve dağıtıcıya senkron bağlı alarm yöneticisi hazırlar
+func newNotificationTestSetup(t *testing.T, channels ...NotificationChannel) (*AlertManager, *NotificationDispatcher) {
+	t.Helper()
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (7, 'checkout', 'shop', 'prod', 'service')`)
+	for i := range channels {
+		if err := channels[i].Validate(); err != nil {
+			t.Fatalf("kanal geçersiz: %v", err)
+		}
+		if err := saveNotificationChannel(testDB, &channels[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Webhook şablon verisi servis ve son kontrol bilgilerini genel veritabanından okur
+	previous := db
+	db = testDB
+	t.Cleanup(func() { db = previous })
+
+	dispatcher := &NotificationDispatcher{db: testDB}
+	manager := NewAlertManager(testDB)
+	manager.Subscribe(func(event AlertEvent) { dispatcher.Dispatch(context.Background(), event) })
+	return manager, dispatcher
+}
+
+func TestSlackBotNotificationUpdatesThread(t *testing.T) {
+	server, requests := newRecordingServer(t, func(path string) string {
+		return `{"ok":true,"channel":"C123","ts":"1700000000.000100"}`
+	})
+	manager, _ := newNotificationTestSetup(t, NotificationChannel{
+		Name: "ops", Type: ChannelTypeSlack, Enabled: true,
+		Config: ChannelConfig{"bot_token": "xoxb-test", "channel": "#ops", "api_url": server.URL},
+	})
+
+	start := time.Now().Add(-5 * time.Minute)
+	if err := manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "bağlantı reddedildi", start); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Clear(statusAlertKey(7), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 3 {
+		t.Fatalf("3 Slack çağrısı bekleniyordu, alınan %d", len(got))
+	}
+	if got[0].Path != "/chat.postMessage" || got[0].Header.Get("Authorization") != "Bearer xoxb-test" || got[0].Body["channel"] != "#ops" {
+		t.Errorf("beklenmeyen ilk ileti: %s %v", got[0].Path, got[0].Body)
+	}
+	attachment := got[0].Body["attachments"].([]interface{})[0].(map[string]interface{})
+	fields, _ := json.Marshal(attachment["fields"])
+	for _, want := range []string{"checkout", "shop", "prod", "critical"} {
+		if !strings.Contains(string(fields), want) {
+			t.Errorf("ileti alanlarında %q yok: %s", want, fields)
+		}
+	}
+	if attachment["text"] != "bağlantı reddedildi" || attachment["title_link"] != "http://localhost:3000/services/7" {
+		t.Errorf("beklenmeyen ileti içeriği: %v", attachment)
+	}
+
+	if got[1].Path != "/chat.update" || got[1].Body["channel"] != "C123" || got[1].Body["ts"] != "1700000000.000100" {
+		t.Errorf("kapanışta özgün ileti güncellenmedi: %s %v", got[1].Path, got[1].Body)
+	}
+	if got[2].Path != "/chat.postMessage" || got[2].Body["thread_ts"] != "1700000000.000100" {
+		t.Errorf("kapanış yanıtı thread'e yazılmadı: %s %v", got[2].Path, got[2].Body)
+	}
+	if text, _ := got[1].Body["text"].(string); !strings.Contains(text, "ÇÖZÜLDÜ") {
+		t.Errorf("güncellenen ileti kapanışı göstermiyor: %q", text)
+	}
+}
+
+func TestSlackBotEscalationRepliesInThread(t *testing.T) {
+	var mu sync.Mutex
+	posts := 0
+	server, requests := newRecordingServer(t, func(path string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		posts++
+		return fmt.Sprintf(`{"ok":true,"channel":"C123","ts":"1700000000.00010%d"}`, posts)
+	})
+	manager, dispatcher := newNotificationTestSetup(t, NotificationChannel{
+		Name: "ops", Type: ChannelTypeSlack, Enabled: true,
+		Config: ChannelConfig{"bot_token": "xoxb-test", "channel": "#ops", "api_url": server.URL},
+	})
+	if err := manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "bağlantı reddedildi", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	alert, _ := manager.Get(mustOpenAlertID(t, dispatcher))
+
+	// Aynı kanala giden eskalasyon özgün iletinin thread'ine yazılır
+	channels, _ := listNotificationChannels(dispatcher.db, "1 = 1")
+	escalation := AlertEvent{Type: AlertEventFiring, Alert: alert, Escalation: &AlertEscalation{Policy: "ödeme", Level: 2}}
+	if err := dispatcher.send(context.Background(), channels[0], escalation); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Clear(statusAlertKey(7), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := requests()
+	if len(got) != 4 {
+		t.Fatalf("4 Slack çağrısı bekleniyordu, alınan %d", len(got))
+	}
+	if got[1].Path != "/chat.postMessage" || got[1].Body["thread_ts"] != "1700000000.000101" || got[1].Body["reply_broadcast"] != true {
+		t.Errorf("eskalasyon özgün iletinin thread'ine yazılmalıydı: %s %v", got[1].Path, got[1].Body)
+	}
+	if text, _ := got[1].Body["text"].(string); !strings.Contains(text, "ESKALASYON 2") {
+		t.Errorf("eskalasyon iletisi seviyeyi göstermiyor: %q", text)
+	}
+	if got[2].Path != "/chat.update" || got[2].Body["ts"] != "1700000000.000101" {
+		t.Errorf("kapanışta ilk ileti güncellenmeliydi: %s %v", got[2].Path, got[2].Body)
+	}
+	if got[3].Body["thread_ts"] != "1700000000.000101" {
+		t.Errorf("kapanış yanıtı ilk iletinin thread'ine yazılmalıydı: %v", got[3].Body)
+	}
+}
+
+func TestSlackWebhookAndTeamsNotifications(t *testing.T) {
+	slack, slackRequests := newRecordingServer(t, func(string) string { return "ok" })
+	teams, teamsRequests := newRecordingServer(t, func(string) string { return "1" })
+	manager, _ := newNotificationTestSetup(t,
+		NotificationChannel{Name: "slack", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": slack.URL + "/hook"}},
+		NotificationChannel{Name: "teams", Type: ChannelTypeTeams, Enabled: true, Config: ChannelConfig{"webhook_url": teams.URL}},
+		NotificationChannel{Name: "kapalı", Type: ChannelTypeTeams, Enabled: false, Config: ChannelConfig{"webhook_url": teams.URL}},
+	)
+
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityWarning, "yanıt yavaş", time.Now())
+	manager.Clear(statusAlertKey(7), time.Now())
+
+	if got := slackRequests(); len(got) != 2 || got[0].Path != "/hook" {
+		t.Fatalf("Slack webhook'una açılış ve kapanış gönderilmeliydi: %v", got)
+	}
+	got := teamsRequests()
+	if len(got) != 2 {
+		t.Fatalf("Teams'e 2 kart gönderilmeliydi (kapalı kanal hariç), alınan %d", len(got))
+	}
+	card, _ := json.Marshal(got[0].Body)
+	for _, want := range []string{"MessageCard", "checkout", "shop", "prod", "yanıt yavaş", "/services/7"} {
+		if !strings.Contains(string(card), want) {
+			t.Errorf("Teams kartında %q yok: %s", want, card)
+		}
+	}
+	if got[1].Body["themeColor"] != "2EB886" {
+		t.Errorf("kapanış kartı yeşil olmalı: %v", got[1].Body["themeColor"])
+	}
+}
+
+func TestNotificationChannelsHandlerMasksSecrets(t *testing.T) {
+	previous := db
+	db = newTestDB(t)
+	t.Cleanup(func() { db = previous })
+
+	body := `{"name":"ops","type":"slack","config":{"webhook_url":"https://hooks.slack.com/services/A/B/C"}}`
+	rec := httptest.NewRecorder()
+	notificationChannelsHandler(rec, httptest.NewRequest("POST", "/api/v1/notification-channels", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("kanal oluşturulamadı: %d %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "hooks.slack.com") {
+		t.Errorf("webhook adresi yanıtta maskelenmedi: %s", rec.Body)
+	}
+
+	// Maskeli değerle güncelleme mevcut adresi korumalı
+	rec = httptest.NewRecorder()
+	notificationChannelsHandler(rec, httptest.NewRequest("PUT", "/api/v1/notification-channels/1",
+		strings.NewReader(`{"name":"ops-2","config":{"webhook_url":"********"}}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("kanal güncellenemedi: %d %s", rec.Code, rec.Body)
+	}
+	channel, err := getNotificationChannel(db, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.Name != "ops-2" || channel.Config.String("webhook_url") != "https://hooks.slack.com/services/A/B/C" {
+		t.Errorf("beklenmeyen kanal: %+v", channel)
+	}
+
+	rec = httptest.NewRecorder()
+	notificationChannelsHandler(rec, httptest.NewRequest("POST", "/api/v1/notification-channels",
+		strings.NewReader(`{"name":"bozuk","type":"teams","config":{"webhook_url":"ftp://x"}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("geçersiz adres reddedilmeliydi: %d", rec.Code)
+	}
+}
+
+func TestEnqueueSpillsToBacklogWhenQueueIsFull(t *testing.T) {
+	server, requests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "ops", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": server.URL}},
+	)
+	dispatcher.events = make(chan AlertEvent, 1)
+	dispatcher.backlogReady = make(chan struct{}, 1)
+
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", time.Now())
+	alert, err := manager.Get(mustOpenAlertID(t, dispatcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := len(requests())
+
+	// Kuyruk dolunca olaylar düşürülmez, sırası korunarak tabloya yazılır
+	for _, eventType := range []string{AlertEventFiring, AlertEventFiring, AlertEventResolved} {
+		dispatcher.Enqueue(AlertEvent{Type: eventType, Alert: alert})
+	}
+	var backlog int
+	dispatcher.db.QueryRow(`SELECT COUNT(*) FROM notification_backlog`).Scan(&backlog)
+	if backlog != 2 || len(dispatcher.events) != 1 || len(dispatcher.backlogReady) != 1 {
+		t.Fatalf("2 olay tabloya yazılmalıydı: tablo %d, kuyruk %d", backlog, len(dispatcher.events))
+	}
+
+	dispatcher.DrainBacklog(context.Background())
+	if got := len(requests()) - sent; got != 3 {
+		t.Fatalf("bekleyen 3 olay gönderilmeliydi, gönderilen %d", got)
+	}
+	dispatcher.db.QueryRow(`SELECT COUNT(*) FROM notification_backlog`).Scan(&backlog)
+	if backlog != 0 {
+		t.Errorf("gönderilen olaylar tablodan silinmeliydi: %d", backlog)
+	}
+	var last string
+	dispatcher.db.QueryRow(`SELECT event_type FROM notification_deliveries ORDER BY id DESC LIMIT 1`).Scan(&last)
+	if last != AlertEventResolved {
+		t.Errorf("olaylar geliş sırasıyla gönderilmeliydi, son olay %s", last)
+	}
+
+	// Tablo boşalınca olaylar yeniden bellek kuyruğuna alınır
+	dispatcher.Enqueue(AlertEvent{Type: AlertEventResolved, Alert: alert})
+	if len(dispatcher.events) != 1 {
+		t.Errorf("olay bellek kuyruğuna alınmalıydı")
+	}
+}
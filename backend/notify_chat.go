@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultSlackAPIURL, bot token ile kullanılan Slack Web API adresi
+const defaultSlackAPIURL = "https://slack.com/api"
+
+// Bildirimlerde kullanılan renkler (açık alarm önem derecesine göre, kapanan alarm yeşil)
+var notificationColors = map[string]string{
+	SeverityCritical: "#D00000",
+	SeverityWarning:  "#F2A900",
+	SeverityInfo:     "#439FE0",
+	"resolved":       "#2EB886",
+}
+
+// notificationColor, bildirim içeriğinin rengini döndürür
+func notificationColor(n alertNotification) string {
+	if n.Resolved {
+		return notificationColors["resolved"]
+	}
+	if color, ok := notificationColors[n.Severity]; ok {
+		return color
+	}
+	return notificationColors[SeverityWarning]
+}
+
+// validateSlackConfig, Slack kanalı ayarlarını doğrular: incoming webhook (webhook_url) veya
+// iletileri güncelleyebilmek için bot token ile Web API (bot_token + channel)
+func validateSlackConfig(c ChannelConfig) error {
+	webhook, token, channel := c.String("webhook_url"), c.String("bot_token"), c.String("channel")
+	switch {
+	case token != "" && channel == "":
+		return fmt.Errorf("bot_token ile birlikte channel gerekli")
+	case token == "" && webhook == "":
+		return fmt.Errorf("webhook_url veya bot_token ve channel gerekli")
+	}
+	if err := validateWebhookURL("webhook_url", webhook); err != nil {
+		return err
+	}
+	return validateWebhookURL("api_url", c.String("api_url"))
+}
+
+// slackNotifier, Slack'e alarm bildirimi gönderir. Bot token ile gönderilen iletiler
+// alarm kapandığında güncellenir ve iletinin thread'ine kapanış yanıtı yazılır; aynı alarmın
+// eskalasyon ve hatırlatma bildirimleri de bu thread'e yazılır. Incoming webhook iletileri
+// değiştirilemediğinden kapanış yeni ileti olarak gönderilir.
+type slackNotifier struct {
+	webhookURL string
+	botToken   string
+	channel    string
+	apiURL     string
+}
+
+// newSlackNotifier, ayarlardaki referansları çözerek Slack bildiricisi oluşturur
+func newSlackNotifier(c ChannelConfig) (Notifier, error) {
+	n := &slackNotifier{channel: c.String("channel"), apiURL: strings.TrimRight(c.String("api_url"), "/")}
+	if n.apiURL == "" {
+		n.apiURL = defaultSlackAPIURL
+	}
+	var err error
+	if n.webhookURL, err = c.Secret("webhook_url"); err != nil {
+		return nil, err
+	}
+	if n.botToken, err = c.Secret("bot_token"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// slackAttachment, Slack iletisindeki renkli alan bloğu
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []slackField `json:"fields"`
+	Footer    string       `json:"footer"`
+	Ts        int64        `json:"ts"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+// slackMessage, bildirim içeriğinden Slack ileti gövdesini oluşturur
+func slackMessage(n alertNotification) map[string]interface{} {
+	icon := ":red_circle:"
+	durationTitle := "Süre"
+	if n.Resolved {
+		icon = ":white_check_mark:"
+		durationTitle = "Toplam süre"
+	}
+	fields := []slackField{
+		{Title: "Servis", Value: n.Service, Short: true},
+		{Title: "Namespace", Value: n.Namespace, Short: true},
+		{Title: "Cluster", Value: n.Cluster, Short: true},
+		{Title: "Önem", Value: n.Severity, Short: true},
+		{Title: durationTitle, Value: n.Duration, Short: true},
+	}
+	if n.OnCall != "" {
+		fields = append(fields, slackField{Title: "Nöbetçi", Value: n.OnCall, Short: true})
+	}
+	return map[string]interface{}{
+		"text": fmt.Sprintf("%s *%s*", icon, n.Title),
+		"attachments": []slackAttachment{{
+			Color:     notificationColor(n),
+			Title:     n.Title,
+			TitleLink: n.URL,
+			Text:      n.Message,
+			Fields:    fields,
+			Footer:    "k8s-monitoring",
+			Ts:        time.Now().Unix(),
+		}},
+	}
+}
+
+// Notify, olayı Slack'e gönderir. Bot token kullanılıyorsa referans alarmın ilk iletisinin
+// "kanal:ts" değeridir ve sonraki bildirimler bu referansı değiştirmez.
+func (s *slackNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	n := buildAlertNotification(event)
+	message := slackMessage(n)
+
+	if s.botToken == "" {
+		_, err := postJSON(ctx, "POST", s.webhookURL, nil, message)
+		return "", err
+	}
+
+	channel, ts, threaded := strings.Cut(ref, ":")
+	if n.Resolved && threaded {
+		// Özgün iletiyi kapandı olarak güncelle ve thread'ine kapanış yanıtı yaz
+		message["channel"], message["ts"] = channel, ts
+		if _, err := s.call(ctx, "chat.update", message); err != nil {
+			return ref, err
+		}
+		reply := map[string]interface{}{
+			"channel":   channel,
+			"thread_ts": ts,
+			"text":      fmt.Sprintf(":white_check_mark: Alarm çözüldü, toplam süre %s", n.Duration),
+		}
+		_, err := s.call(ctx, "chat.postMessage", reply)
+		return ref, err
+	}
+	if threaded {
+		// Eskalasyon veya hatırlatma: özgün iletinin thread'ine yaz ve kanalda da göster;
+		// kapanışta güncellenecek ileti ilk ileti olarak kalır
+		message["channel"], message["thread_ts"], message["reply_broadcast"] = channel, ts, true
+		_, err := s.call(ctx, "chat.postMessage", message)
+		return ref, err
+	}
+
+	message["channel"] = s.channel
+	resp, err := s.call(ctx, "chat.postMessage", message)
+	if err != nil {
+		return ref, err
+	}
+	if n.Resolved {
+		return ref, nil
+	}
+	return resp.Channel + ":" + resp.Ts, nil
+}
+
+// slackAPIResponse, Slack Web API yanıtı
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel string `json:"channel"`
+	Ts      string `json:"ts"`
+}
+
+// call, Slack Web API metodunu çağırır; HTTP 200 ile dönen "ok": false yanıtları da hata sayılır
+func (s *slackNotifier) call(ctx context.Context, method string, payload interface{}) (slackAPIResponse, error) {
+	var resp slackAPIResponse
+	data, err := postJSON(ctx, "POST", s.apiURL+"/"+method, map[string]string{"Authorization": "Bearer " + s.botToken}, payload)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, fmt.Errorf("Slack yanıtı ayrıştırılamadı: %v", err)
+	}
+	if !resp.OK {
+		return resp, fmt.Errorf("Slack %s hatası: %s", method, resp.Error)
+	}
+	return resp, nil
+}
+
+// validateTeamsConfig, Teams connector ayarlarını doğrular
+func validateTeamsConfig(c ChannelConfig) error {
+	if c.String("webhook_url") == "" {
+		return fmt.Errorf("webhook_url gerekli")
+	}
+	return validateWebhookURL("webhook_url", c.String("webhook_url"))
+}
+
+// teamsNotifier, Microsoft Teams connector'üne MessageCard gönderir. Connector iletileri
+// sonradan güncellenemediğinden kapanış ayrı bir kart olarak gönderilir.
+type teamsNotifier struct {
+	webhookURL string
+}
+
+// newTeamsNotifier, ayarlardaki referansı çözerek Teams bildiricisi oluşturur
+func newTeamsNotifier(c ChannelConfig) (Notifier, error) {
+	webhookURL, err := c.Secret("webhook_url")
+	if err != nil {
+		return nil, err
+	}
+	return &teamsNotifier{webhookURL: webhookURL}, nil
+}
+
+// teamsCard, bildirim içeriğinden MessageCard gövdesini oluşturur
+func teamsCard(n alertNotification) map[string]interface{} {
+	durationName := "Süre"
+	if n.Resolved {
+		durationName = "Toplam süre"
+	}
+	fact := func(name, value string) map[string]string {
+		return map[string]string{"name": name, "value": value}
+	}
+	facts := []map[string]string{
+		fact("Servis", n.Service),
+		fact("Namespace", n.Namespace),
+		fact("Cluster", n.Cluster),
+		fact("Önem", n.Severity),
+		fact(durationName, n.Duration),
+	}
+	if n.OnCall != "" {
+		facts = append(facts, fact("Nöbetçi", n.OnCall))
+	}
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"themeColor": strings.TrimPrefix(notificationColor(n), "#"),
+		"summary":    n.Title,
+		"sections": []map[string]interface{}{{
+			"activityTitle":    n.Title,
+			"activitySubtitle": n.Message,
+			"facts":            facts,
+			"markdown":         true,
+		}},
+		"potentialAction": []map[string]interface{}{{
+			"@type":   "OpenUri",
+			"name":    "Arayüzde aç",
+			"targets": []map[string]string{{"os": "default", "uri": n.URL}},
+		}},
+	}
+}
+
+// Notify, olayı Teams'e gönderir
+func (t *teamsNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	_, err := postJSON(ctx, "POST", t.webhookURL, nil, teamsCard(buildAlertNotification(event)))
+	return "", err
+}
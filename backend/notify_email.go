@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChannelTypeEmail, SMTP üzerinden e-posta gönderen kanal türü
+const ChannelTypeEmail = "email"
+
+// STARTTLS kullanım biçimleri
+const (
+	StartTLSRequired      = "required"      // sunucu STARTTLS desteklemiyorsa gönderim yapılmaz
+	StartTLSOpportunistic = "opportunistic" // sunucu destekliyorsa kullanılır
+	StartTLSDisabled      = "disabled"
+)
+
+// digestIntervals, özet modlarının toplama süreleri
+var digestIntervals = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+}
+
+// DigestNotifier, kritik olmayan olayları biriktirip toplu gönderebilen kanallar
+type DigestNotifier interface {
+	Notifier
+	// DigestInterval, olayların biriktirileceği süre (0 ise özet kapalı)
+	DigestInterval() time.Duration
+	// Digests, olayın anında gönderilmek yerine özete eklenip eklenmeyeceğini döndürür
+	Digests(event AlertEvent) bool
+	// SendDigest, biriken olayları tek ileti olarak gönderir
+	SendDigest(ctx context.Context, events []AlertEvent) error
+}
+
+// validateEmailConfig, SMTP kanalı ayarlarını doğrular
+func validateEmailConfig(c ChannelConfig) error {
+	server := c.String("smtp_server")
+	if server == "" {
+		return fmt.Errorf("smtp_server gerekli (ör. smtp.example.com:587)")
+	}
+	if _, port, err := net.SplitHostPort(server); err != nil || port == "" {
+		return fmt.Errorf("smtp_server host:port biçiminde olmalı")
+	}
+	if _, err := mail.ParseAddress(c.String("from")); err != nil {
+		return fmt.Errorf("from geçerli bir e-posta adresi olmalı")
+	}
+	to := emailRecipients(c.String("to"))
+	if len(to) == 0 {
+		return fmt.Errorf("to alanında en az bir alıcı gerekli")
+	}
+	for _, addr := range to {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("geçersiz alıcı adresi: %s", addr)
+		}
+	}
+	switch c.String("starttls") {
+	case "", StartTLSRequired, StartTLSOpportunistic, StartTLSDisabled:
+	default:
+		return fmt.Errorf("starttls required, opportunistic veya disabled olmalı")
+	}
+	if digest := c.String("digest"); digest != "" {
+		if _, ok := digestIntervals[digest]; !ok {
+			return fmt.Errorf("digest hourly veya daily olmalı")
+		}
+	}
+	if c.String("password") != "" && c.String("username") == "" {
+		return fmt.Errorf("password ile birlikte username gerekli")
+	}
+	return nil
+}
+
+// emailRecipients, virgülle ayrılmış alıcı listesini ayrıştırır
+func emailRecipients(value string) []string {
+	var recipients []string
+	for _, addr := range strings.Split(value, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	return recipients
+}
+
+// emailNotifier, alarm bildirimlerini SMTP ile HTML ve düz metin e-posta olarak gönderir
+type emailNotifier struct {
+	server     string
+	username   string
+	password   string
+	from       string
+	to         []string
+	startTLS   string
+	skipVerify bool
+	digest     time.Duration
+}
+
+// newEmailNotifier, ayarlardaki referansları çözerek e-posta bildiricisi oluşturur
+func newEmailNotifier(c ChannelConfig) (Notifier, error) {
+	n := &emailNotifier{
+		server:   c.String("smtp_server"),
+		from:     c.String("from"),
+		to:       emailRecipients(c.String("to")),
+		startTLS: c.String("starttls"),
+		digest:   digestIntervals[c.String("digest")],
+	}
+	if n.startTLS == "" {
+		n.startTLS = StartTLSRequired
+	}
+	n.skipVerify, _ = strconv.ParseBool(fmt.Sprint(c["tls_skip_verify"]))
+
+	var err error
+	if n.username, err = c.Secret("username"); err != nil {
+		return nil, err
+	}
+	if n.password, err = c.Secret("password"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// DigestInterval, özet modunun toplama süresini döndürür
+func (e *emailNotifier) DigestInterval() time.Duration {
+	return e.digest
+}
+
+// Digests, özet modunda kritik olmayan alarmların olaylarını biriktirir; kritik alarmlar hemen gönderilir
+func (e *emailNotifier) Digests(event AlertEvent) bool {
+	return e.digest > 0 && event.Alert.Severity != SeverityCritical
+}
+
+// emailAlertTemplate, tek alarm bildiriminin HTML gövdesi
+var emailAlertTemplate = template.Must(template.New("alert").Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif">
+<h2 style="color:{{.Color}}">{{.N.Title}}</h2>
+<p>{{.N.Message}}</p>
+<table cellpadding="4" style="border-collapse:collapse">
+<tr><th align="left">Servis</th><td>{{.N.Service}}</td></tr>
+<tr><th align="left">Namespace</th><td>{{.N.Namespace}}</td></tr>
+<tr><th align="left">Cluster</th><td>{{.N.Cluster}}</td></tr>
+<tr><th align="left">Önem</th><td>{{.N.Severity}}</td></tr>
+<tr><th align="left">{{if .N.Resolved}}Toplam süre{{else}}Süre{{end}}</th><td>{{.N.Duration}}</td></tr>
+{{if .N.OnCall}}<tr><th align="left">Nöbetçi</th><td>{{.N.OnCall}}</td></tr>
+{{end}}</table>
+<p><a href="{{.N.URL}}">Arayüzde aç</a></p>
+</body></html>`))
+
+// emailDigestTemplate, özet e-postasının HTML gövdesi
+var emailDigestTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif">
+<h2>Alarm özeti ({{len .}} olay)</h2>
+<table cellpadding="4" border="1" style="border-collapse:collapse">
+<tr><th>Durum</th><th>Servis</th><th>Namespace</th><th>Cluster</th><th>Önem</th><th>Mesaj</th><th>Süre</th></tr>
+{{range .}}<tr><td>{{if .Resolved}}çözüldü{{else}}açık{{end}}</td><td><a href="{{.URL}}">{{.Service}}</a></td><td>{{.Namespace}}</td><td>{{.Cluster}}</td><td>{{.Severity}}</td><td>{{.Message}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>
+</body></html>`))
+
+// emailAlertText, tek alarm bildiriminin düz metin gövdesi
+func emailAlertText(n alertNotification) string {
+	duration := "Süre"
+	if n.Resolved {
+		duration = "Toplam süre"
+	}
+	onCall := ""
+	if n.OnCall != "" {
+		onCall = fmt.Sprintf("Nöbetçi: %s\n", n.OnCall)
+	}
+	return fmt.Sprintf("%s\n\n%s\n\nServis: %s\nNamespace: %s\nCluster: %s\nÖnem: %s\n%s: %s\n%s\n%s\n",
+		n.Title, n.Message, n.Service, n.Namespace, n.Cluster, n.Severity, duration, n.Duration, onCall, n.URL)
+}
+
+// Notify, olayı e-posta olarak gönderir
+func (e *emailNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	n := buildAlertNotification(event)
+	var html bytes.Buffer
+	if err := emailAlertTemplate.Execute(&html, map[string]interface{}{"N": n, "Color": notificationColor(n)}); err != nil {
+		return "", err
+	}
+
+	subject := n.Title
+	if n.Namespace != "" {
+		subject = fmt.Sprintf("%s (%s/%s)", n.Title, n.Namespace, n.Cluster)
+	}
+	return "", e.send(ctx, subject, emailAlertText(n), html.String())
+}
+
+// SendDigest, biriken olayları tek bir özet e-postası olarak gönderir
+func (e *emailNotifier) SendDigest(ctx context.Context, events []AlertEvent) error {
+	notifications := make([]alertNotification, 0, len(events))
+	var text strings.Builder
+	fmt.Fprintf(&text, "Alarm özeti (%d olay)\n\n", len(events))
+	for _, event := range events {
+		n := buildAlertNotification(event)
+		notifications = append(notifications, n)
+		fmt.Fprintf(&text, "- %s %s/%s [%s]: %s (%s)\n  %s\n", n.Title, n.Namespace, n.Cluster, n.Severity, n.Message, n.Duration, n.URL)
+	}
+
+	var html bytes.Buffer
+	if err := emailDigestTemplate.Execute(&html, notifications); err != nil {
+		return err
+	}
+	return e.send(ctx, fmt.Sprintf("Alarm özeti: %d olay", len(events)), text.String(), html.String())
+}
+
+// buildEmailMessage, düz metin ve HTML gövdeli multipart/alternative iletiyi oluşturur
+func buildEmailMessage(from string, to []string, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		qp.Close()
+	}
+	writer.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send, iletiyi SMTP sunucusu üzerinden gönderir. STARTTLS ayara göre zorunlu, fırsatçı
+// veya kapalıdır; kullanıcı adı verilmişse PLAIN kimlik doğrulaması yapılır.
+func (e *emailNotifier) send(ctx context.Context, subject, text, html string) error {
+	msg, err := buildEmailMessage(e.from, e.to, subject, text, html)
+	if err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(e.server)
+
+	dialer := net.Dialer{Timeout: notificationTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", e.server)
+	if err != nil {
+		return fmt.Errorf("SMTP sunucusuna bağlanılamadı: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP oturumu başlatılamadı: %v", err)
+	}
+	defer client.Close()
+
+	if e.startTLS != StartTLSDisabled {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host, InsecureSkipVerify: e.skipVerify}); err != nil {
+				return fmt.Errorf("STARTTLS başarısız: %v", err)
+			}
+		} else if e.startTLS == StartTLSRequired {
+			return fmt.Errorf("SMTP sunucusu STARTTLS desteklemiyor")
+		}
+	}
+
+	if e.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.username, e.password, host)); err != nil {
+			return fmt.Errorf("SMTP kimlik doğrulaması başarısız: %v", err)
+		}
+	}
+
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, addr := range e.to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("alıcı reddedildi (%s): %v", addr, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// queueDigest, olayı kanalın özet kuyruğuna ekler
+func (d *NotificationDispatcher) queueDigest(channel NotificationChannel, event AlertEvent) error {
+	data, err := json.Marshal(event.Alert)
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`
+		INSERT INTO notification_digest (channel_key, event_type, alert, created_at) VALUES (?, ?, ?, ?)
+	`, channel.key(), event.Type, string(data), time.Now())
+	return err
+}
+
+// FlushDigests, en eski bekleyen olayı özet süresini dolduran kanalların özetlerini gönderir
+func (d *NotificationDispatcher) FlushDigests(ctx context.Context, now time.Time) {
+	channels, err := d.channels()
+	if err != nil {
+		log.Printf("Bildirim kanalları okunamadı: %v", err)
+		return
+	}
+
+	for _, channel := range channels {
+		notifier, err := channelTypes[channel.Type].build(channel.Config)
+		if err != nil {
+			continue
+		}
+		digest, ok := notifier.(DigestNotifier)
+		if !ok || digest.DigestInterval() == 0 {
+			continue
+		}
+
+		var oldest sql.NullTime
+		d.db.QueryRow(`SELECT created_at FROM notification_digest WHERE channel_key = ? ORDER BY id LIMIT 1`, channel.key()).Scan(&oldest)
+		if !oldest.Valid || now.Sub(oldest.Time) < digest.DigestInterval() {
+			continue
+		}
+
+		ids, events, err := d.pendingDigest(channel.key())
+		if err != nil || len(events) == 0 {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, notificationTimeout)
+		err = digest.SendDigest(sendCtx, events)
+		cancel()
+		if err != nil {
+			log.Printf("%s kanalının özeti gönderilemedi: %v", channel.Name, err)
+			continue
+		}
+		for _, id := range ids {
+			d.db.Exec(`DELETE FROM notification_digest WHERE id = ?`, id)
+		}
+		log.Printf("%s kanalına %d olaylık özet gönderildi", channel.Name, len(events))
+	}
+}
+
+// pendingDigest, kanalın özet kuyruğundaki olayları eskiden yeniye döndürür
+func (d *NotificationDispatcher) pendingDigest(channelKey string) ([]int64, []AlertEvent, error) {
+	rows, err := d.db.Query(`SELECT id, event_type, alert FROM notification_digest WHERE channel_key = ? ORDER BY id`, channelKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var events []AlertEvent
+	for rows.Next() {
+		var id int64
+		var event AlertEvent
+		var data string
+		if err := rows.Scan(&id, &event.Type, &data); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &event.Alert); err != nil {
+			log.Printf("Özet kaydı %d okunamadı: %v", id, err)
+		}
+		ids = append(ids, id)
+		events = append(events, event)
+	}
+	return ids, events, rows.Err()
+}
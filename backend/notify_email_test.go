@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sinkMessage, yerel SMTP sunucusunun aldığı ileti
+type sinkMessage struct {
+	TLS  bool
+	Auth string // AUTH PLAIN ile gelen "kullanıcı:şifre"
+	From string
+	To   []string
+	Data string
+}
+
+// smtpSink, STARTTLS ve AUTH PLAIN destekleyen, iletileri bellekte tutan yerel SMTP sunucusu
+type smtpSink struct {
+	addr     string
+	tls      *tls.Config
+	noTLS    bool // STARTTLS desteklemeyen sunucuyu taklit eder
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+// newSMTPSink, kendinden imzalı sertifikayla yerel bir SMTP sunucusu başlatır
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sink := &smtpSink{
+		addr: listener.Addr().String(),
+		tls:  &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+// serve, tek bir SMTP oturumunu yürütür
+func (s *smtpSink) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	var msg sinkMessage
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 sink ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			if msg.TLS || s.noTLS {
+				reply("250-sink\r\n250 AUTH PLAIN")
+			} else {
+				reply("250-sink\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 hazır")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, reader, msg.TLS = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			parts := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+			fields := strings.Split(string(decoded), "\x00")
+			msg.Auth = fields[1] + ":" + fields[2]
+			reply("235 tamam")
+		case "MAIL":
+			msg.From = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 tamam")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 tamam")
+		case "DATA":
+			reply("354 devam")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 kuyruğa alındı")
+		case "QUIT":
+			reply("221 güle güle")
+			return
+		default:
+			reply("250 tamam")
+		}
+	}
+}
+
+// received, alınan iletileri döndürür
+func (s *smtpSink) received() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage{}, s.messages...)
+}
+
+// messageParts, iletinin konusunu ve içerik türüne göre gövde parçalarını döndürür
+func messageParts(t *testing.T, data string) (string, map[string]string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ileti ayrıştırılamadı: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	reader := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		var body strings.Builder
+		buf := make([]byte, 4096)
+		for {
+			n, err := part.Read(buf)
+			body.Write(buf[:n])
+			if err != nil {
+				break
+			}
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[mediaType] = body.String()
+	}
+	return subject, parts
+}
+
+func emailChannel(sink *smtpSink, digest string) NotificationChannel {
+	return NotificationChannel{Name: "e-posta", Type: ChannelTypeEmail, Enabled: true, Config: ChannelConfig{
+		"smtp_server":     sink.addr,
+		"from":            "alerts@example.com",
+		"to":              "ops@example.com, oncall@example.com",
+		"username":        "alerts",
+		"password":        "gizli",
+		"tls_skip_verify": true,
+		"digest":          digest,
+	}}
+}
+
+func TestEmailNotificationUsesStartTLSAndAuth(t *testing.T) {
+	sink := newSMTPSink(t)
+	manager, _ := newNotificationTestSetup(t, emailChannel(sink, ""))
+
+	if err := manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "bağlantı reddedildi", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := sink.received()
+	if len(messages) != 1 {
+		t.Fatalf("1 e-posta bekleniyordu, alınan %d", len(messages))
+	}
+	msg := messages[0]
+	if !msg.TLS || msg.Auth != "alerts:gizli" {
+		t.Errorf("STARTTLS ve kimlik doğrulaması kullanılmadı: tls=%v auth=%q", msg.TLS, msg.Auth)
+	}
+	if msg.From != "alerts@example.com" || len(msg.To) != 2 {
+		t.Errorf("beklenmeyen zarf: from=%s to=%v", msg.From, msg.To)
+	}
+
+	subject, parts := messageParts(t, msg.Data)
+	if !strings.Contains(subject, "checkout") || !strings.Contains(subject, "shop/prod") {
+		t.Errorf("beklenmeyen konu: %q", subject)
+	}
+	for _, mediaType := range []string{"text/plain", "text/html"} {
+		body := parts[mediaType]
+		for _, want := range []string{"checkout", "shop", "prod", "bağlantı reddedildi", "/services/7"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s gövdesinde %q yok:\n%s", mediaType, want, body)
+			}
+		}
+	}
+}
+
+func TestEmailStartTLSModes(t *testing.T) {
+	sink := newSMTPSink(t)
+	sink.noTLS = true
+
+	config := emailChannel(sink, "").Config
+	required, err := newEmailNotifier(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := required.Notify(context.Background(), testAlertEvent(), ""); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("STARTTLS zorunluyken desteklemeyen sunucuya gönderilmemeli: %v", err)
+	}
+
+	config["starttls"] = StartTLSOpportunistic
+	opportunistic, _ := newEmailNotifier(config)
+	if _, err := opportunistic.Notify(context.Background(), testAlertEvent(), ""); err != nil {
+		t.Fatalf("fırsatçı STARTTLS ile gönderim başarısız: %v", err)
+	}
+	if messages := sink.received(); len(messages) != 1 || messages[0].TLS {
+		t.Errorf("ileti TLS olmadan teslim edilmeliydi: %+v", messages)
+	}
+}
+
+func TestEmailDigestBatchesNonCriticalAlerts(t *testing.T) {
+	sink := newSMTPSink(t)
+	manager, dispatcher := newNotificationTestSetup(t, emailChannel(sink, "hourly"))
+	dispatcher.db.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (8, 'cart', 'shop', 'prod', 'service')`)
+
+	now := time.Now()
+	manager.Fire(ruleAlertKey(1, 7), 7, nil, SeverityWarning, "p95 yüksek", now)
+	manager.Fire(ruleAlertKey(1, 8), 8, nil, SeverityInfo, "uptime düşük", now)
+	if got := len(sink.received()); got != 0 {
+		t.Fatalf("kritik olmayan alarmlar hemen gönderilmemeli, alınan %d", got)
+	}
+
+	// Kritik alarm özet modunda da hemen gönderilir
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "servis down", now)
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("kritik alarm hemen gönderilmeliydi, alınan %d", got)
+	}
+
+	// Süre dolmadan özet gönderilmez
+	dispatcher.FlushDigests(context.Background(), now.Add(30*time.Minute))
+	if got := len(sink.received()); got != 1 {
+		t.Fatalf("özet süresi dolmadan gönderildi")
+	}
+
+	dispatcher.FlushDigests(context.Background(), now.Add(time.Hour+time.Minute))
+	messages := sink.received()
+	if len(messages) != 2 {
+		t.Fatalf("özet e-postası bekleniyordu, toplam %d", len(messages))
+	}
+	subject, parts := messageParts(t, messages[1].Data)
+	if !strings.Contains(subject, "2 olay") {
+		t.Errorf("beklenmeyen özet konusu: %q", subject)
+	}
+	for _, want := range []string{"checkout", "cart", "p95 yüksek", "uptime düşük"} {
+		if !strings.Contains(parts["text/html"], want) || !strings.Contains(parts["text/plain"], want) {
+			t.Errorf("özette %q yok", want)
+		}
+	}
+
+	var pending int
+	dispatcher.db.QueryRow("SELECT COUNT(*) FROM notification_digest").Scan(&pending)
+	if pending != 0 {
+		t.Errorf("gönderilen özet olayları kuyruktan silinmedi: %d", pending)
+	}
+}
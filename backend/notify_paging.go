@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Olay yönetimi (paging) kanal türleri
+const (
+	ChannelTypePagerDuty = "pagerduty"
+	ChannelTypeOpsgenie  = "opsgenie"
+)
+
+// Varsayılan API adresleri (Opsgenie AB hesapları için https://api.eu.opsgenie.com)
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com"
+	defaultOpsgenieURL  = "https://api.opsgenie.com"
+)
+
+// incidentDedupKey, olayın karşı sistemdeki tekilleştirme anahtarı. Alarmın anahtarı servis
+// ve kural başına sabit olduğundan ("status:7", "rule:3:7") tekrarlayan hatalar aynı olayı
+// günceller, kapanış da doğru olayı çözer.
+func incidentDedupKey(a Alert) string {
+	return "monitoring/" + a.DedupKey
+}
+
+// pagerDutySeverities, alarm önem derecelerinin PagerDuty karşılıkları
+var pagerDutySeverities = map[string]string{
+	SeverityCritical: "critical",
+	SeverityWarning:  "warning",
+	SeverityInfo:     "info",
+}
+
+// opsgeniePriorities, alarm önem derecelerinin Opsgenie öncelikleri
+var opsgeniePriorities = map[string]string{
+	SeverityCritical: "P1",
+	SeverityWarning:  "P3",
+	SeverityInfo:     "P5",
+}
+
+// validatePagerDutyConfig, PagerDuty Events API v2 ayarlarını doğrular
+func validatePagerDutyConfig(c ChannelConfig) error {
+	if c.String("routing_key") == "" {
+		return fmt.Errorf("routing_key gerekli")
+	}
+	if err := validateSecretValue("routing_key", c.String("routing_key")); err != nil {
+		return err
+	}
+	return validateWebhookURL("api_url", c.String("api_url"))
+}
+
+// pagerDutyNotifier, PagerDuty Events API v2 ile olay açar, onaylar ve çözer
+type pagerDutyNotifier struct {
+	routingKey string
+	apiURL     string
+}
+
+// newPagerDutyNotifier, ayarlardaki referansları çözerek PagerDuty bildiricisi oluşturur
+func newPagerDutyNotifier(c ChannelConfig) (Notifier, error) {
+	routingKey, err := c.Secret("routing_key")
+	if err != nil {
+		return nil, err
+	}
+	apiURL := strings.TrimRight(c.String("api_url"), "/")
+	if apiURL == "" {
+		apiURL = defaultPagerDutyURL
+	}
+	return &pagerDutyNotifier{routingKey: routingKey, apiURL: apiURL}, nil
+}
+
+// Acknowledges, onay olaylarının PagerDuty'ye iletildiğini belirtir
+func (p *pagerDutyNotifier) Acknowledges() bool {
+	return true
+}
+
+// Notify, olayı PagerDuty'de trigger, acknowledge veya resolve eylemi olarak gönderir
+func (p *pagerDutyNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	a := event.Alert
+	payload := map[string]interface{}{
+		"routing_key": p.routingKey,
+		"dedup_key":   incidentDedupKey(a),
+	}
+	switch event.Type {
+	case AlertEventResolved:
+		payload["event_action"] = "resolve"
+	case AlertEventAcknowledged:
+		payload["event_action"] = "acknowledge"
+	default:
+		n := buildAlertNotification(event)
+		severity, ok := pagerDutySeverities[a.Severity]
+		if !ok {
+			severity = "warning"
+		}
+		payload["event_action"] = "trigger"
+		payload["payload"] = map[string]interface{}{
+			"summary":   fmt.Sprintf("%s: %s", n.Title, a.Message),
+			"source":    fmt.Sprintf("%s/%s/%s", a.Cluster, a.Namespace, n.Service),
+			"severity":  severity,
+			"timestamp": a.FirstSeen,
+			"component": n.Service,
+			"group":     a.Namespace,
+			"class":     a.DedupKey,
+			"custom_details": map[string]interface{}{
+				"cluster":     a.Cluster,
+				"namespace":   a.Namespace,
+				"service":     n.Service,
+				"message":     a.Message,
+				"occurrences": a.Occurrences,
+			},
+		}
+		payload["links"] = []map[string]string{{"href": n.URL, "text": "Arayüzde aç"}}
+	}
+
+	data, err := postJSON(ctx, "POST", p.apiURL+"/v2/enqueue", nil, payload)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Status   string `json:"status"`
+		Message  string `json:"message"`
+		DedupKey string `json:"dedup_key"`
+	}
+	if err := json.Unmarshal(data, &resp); err == nil && resp.Status != "" && resp.Status != "success" {
+		return "", fmt.Errorf("PagerDuty hatası: %s", resp.Message)
+	}
+	return resp.DedupKey, nil
+}
+
+// validateOpsgenieConfig, Opsgenie Alert API ayarlarını doğrular
+func validateOpsgenieConfig(c ChannelConfig) error {
+	if c.String("api_key") == "" {
+		return fmt.Errorf("api_key gerekli")
+	}
+	if err := validateSecretValue("api_key", c.String("api_key")); err != nil {
+		return err
+	}
+	return validateWebhookURL("api_url", c.String("api_url"))
+}
+
+// opsgenieNotifier, Opsgenie Alert API ile alarm açar, onaylar ve kapatır. Alarmlar
+// incidentDedupKey ile alias olarak açıldığından sonraki işlemler alias üzerinden yapılır.
+type opsgenieNotifier struct {
+	apiKey string
+	apiURL string
+	team   string
+}
+
+// newOpsgenieNotifier, ayarlardaki referansları çözerek Opsgenie bildiricisi oluşturur
+func newOpsgenieNotifier(c ChannelConfig) (Notifier, error) {
+	apiKey, err := c.Secret("api_key")
+	if err != nil {
+		return nil, err
+	}
+	apiURL := strings.TrimRight(c.String("api_url"), "/")
+	if apiURL == "" {
+		apiURL = defaultOpsgenieURL
+	}
+	return &opsgenieNotifier{apiKey: apiKey, apiURL: apiURL, team: c.String("team")}, nil
+}
+
+// Acknowledges, onay olaylarının Opsgenie'ye iletildiğini belirtir
+func (o *opsgenieNotifier) Acknowledges() bool {
+	return true
+}
+
+// Notify, olayı Opsgenie'ye gönderir
+func (o *opsgenieNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	a := event.Alert
+	alias := incidentDedupKey(a)
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey}
+	action := func(name string) string {
+		return fmt.Sprintf("%s/v2/alerts/%s/%s?identifierType=alias", o.apiURL, url.PathEscape(alias), name)
+	}
+
+	var err error
+	switch event.Type {
+	case AlertEventResolved:
+		_, err = postJSON(ctx, "POST", action("close"), headers, map[string]string{
+			"source": "monitoring",
+			"note":   fmt.Sprintf("Alarm çözüldü (%s)", humanDuration(a.Duration())),
+		})
+	case AlertEventAcknowledged:
+		payload := map[string]string{"source": "monitoring", "note": "Alarm onaylandı"}
+		if a.AcknowledgedBy != "" {
+			payload["user"] = a.AcknowledgedBy
+		}
+		_, err = postJSON(ctx, "POST", action("acknowledge"), headers, payload)
+	default:
+		n := buildAlertNotification(event)
+		priority, ok := opsgeniePriorities[a.Severity]
+		if !ok {
+			priority = "P3"
+		}
+		message := fmt.Sprintf("%s: %s", n.Title, a.Message)
+		if len([]rune(message)) > 130 {
+			message = string([]rune(message)[:127]) + "..."
+		}
+		payload := map[string]interface{}{
+			"message":     message,
+			"alias":       alias,
+			"description": fmt.Sprintf("%s\n\n%s", a.Message, n.URL),
+			"priority":    priority,
+			"source":      "monitoring",
+			"entity":      n.Service,
+			"tags":        []string{"cluster:" + a.Cluster, "namespace:" + a.Namespace, "severity:" + a.Severity},
+			"details": map[string]string{
+				"cluster":   a.Cluster,
+				"namespace": a.Namespace,
+				"service":   n.Service,
+				"url":       n.URL,
+			},
+		}
+		if o.team != "" {
+			payload["responders"] = []map[string]string{{"name": o.team, "type": "team"}}
+		}
+		_, err = postJSON(ctx, "POST", o.apiURL+"/v2/alerts", headers, payload)
+	}
+	if err != nil {
+		return "", err
+	}
+	return alias, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPagerDutyAndOpsgenieIncidentLifecycle(t *testing.T) {
+	pagerDuty, pagerDutyRequests := newRecordingServer(t, func(string) string {
+		return `{"status":"success","message":"Event processed","dedup_key":"monitoring/status:7"}`
+	})
+	opsgenie, opsgenieRequests := newRecordingServer(t, func(string) string {
+		return `{"result":"Request will be processed","requestId":"r1"}`
+	})
+	slack, slackRequests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "pd", Type: ChannelTypePagerDuty, Enabled: true,
+			Config: ChannelConfig{"routing_key": "R0UT1NG", "api_url": pagerDuty.URL}},
+		NotificationChannel{Name: "og", Type: ChannelTypeOpsgenie, Enabled: true,
+			Config: ChannelConfig{"api_key": "genie", "api_url": opsgenie.URL, "team": "platform"}},
+		NotificationChannel{Name: "slack", Type: ChannelTypeSlack, Enabled: true,
+			Config: ChannelConfig{"webhook_url": slack.URL}},
+	)
+
+	start := time.Now().Add(-10 * time.Minute)
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "bağlantı reddedildi", start)
+	// Tekrarlayan hata yeni olay açmamalı
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "bağlantı reddedildi", start.Add(time.Minute))
+
+	alert, err := manager.Get(mustOpenAlertID(t, dispatcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Dispatch(context.Background(), AlertEvent{Type: AlertEventAcknowledged, Alert: alert})
+	manager.Clear(statusAlertKey(7), time.Now())
+
+	pd := pagerDutyRequests()
+	if len(pd) != 3 {
+		t.Fatalf("PagerDuty'ye 3 olay bekleniyordu, alınan %d", len(pd))
+	}
+	for i, action := range []string{"trigger", "acknowledge", "resolve"} {
+		if pd[i].Path != "/v2/enqueue" || pd[i].Body["event_action"] != action ||
+			pd[i].Body["dedup_key"] != "monitoring/status:7" || pd[i].Body["routing_key"] != "R0UT1NG" {
+			t.Errorf("PagerDuty olayı %d beklenmeyen: %s %v", i, pd[i].Path, pd[i].Body)
+		}
+	}
+	payload, _ := pd[0].Body["payload"].(map[string]interface{})
+	if payload["severity"] != "critical" || payload["source"] != "prod/shop/checkout" ||
+		!strings.Contains(payload["summary"].(string), "bağlantı reddedildi") {
+		t.Errorf("beklenmeyen trigger içeriği: %v", payload)
+	}
+
+	og := opsgenieRequests()
+	if len(og) != 3 {
+		t.Fatalf("Opsgenie'ye 3 istek bekleniyordu, alınan %d", len(og))
+	}
+	if og[0].Path != "/v2/alerts" || og[0].Body["alias"] != "monitoring/status:7" || og[0].Body["priority"] != "P1" ||
+		og[0].Header.Get("Authorization") != "GenieKey genie" {
+		t.Errorf("beklenmeyen Opsgenie alarmı: %s %v", og[0].Path, og[0].Body)
+	}
+	if responders, _ := og[0].Body["responders"].([]interface{}); len(responders) != 1 {
+		t.Errorf("ekip sorumlu olarak eklenmedi: %v", og[0].Body)
+	}
+	if og[1].Path != "/v2/alerts/monitoring/status:7/acknowledge" {
+		t.Errorf("onay alias üzerinden yapılmadı: %s", og[1].Path)
+	}
+	if !strings.HasSuffix(og[2].Path, "/close") {
+		t.Errorf("kapanış alias üzerinden yapılmadı: %s", og[2].Path)
+	}
+
+	// Onay olayları yalnızca onayı destekleyen kanallara gider
+	if got := slackRequests(); len(got) != 2 {
+		t.Errorf("Slack'e yalnızca açılış ve kapanış gönderilmeliydi, alınan %d", len(got))
+	}
+
+	// Aynı servis için yeniden açılan alarm aynı anahtarı kullanır
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "yine down", time.Now())
+	if pd = pagerDutyRequests(); len(pd) != 4 || pd[3].Body["dedup_key"] != "monitoring/status:7" {
+		t.Errorf("yeniden açılışta anahtar değişti: %v", pd[len(pd)-1].Body)
+	}
+}
+
+func TestPagerDutyRejectedEventFails(t *testing.T) {
+	server, _ := newRecordingServer(t, func(string) string {
+		return `{"status":"invalid event","message":"Event object is invalid"}`
+	})
+	notifier, err := newPagerDutyNotifier(ChannelConfig{"routing_key": "x", "api_url": server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notifier.Notify(context.Background(), testAlertEvent(), ""); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("reddedilen olay hata döndürmeliydi: %v", err)
+	}
+}
+
+func TestOpsgenieAcknowledgeAndCloseThroughAlertManager(t *testing.T) {
+	type call struct {
+		path, query string
+		body        map[string]interface{}
+	}
+	var mu sync.Mutex
+	var calls []call
+	failAcknowledge := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		calls = append(calls, call{r.URL.Path, r.URL.RawQuery, body})
+		fail := failAcknowledge && strings.HasSuffix(r.URL.Path, "/acknowledge")
+		mu.Unlock()
+		if fail {
+			http.Error(w, `{"message":"geçici hata"}`, http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"result":"Request will be processed","requestId":"r1"}`)
+	}))
+	t.Cleanup(server.Close)
+	recorded := func() []call {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]call{}, calls...)
+	}
+
+	manager, dispatcher := newNotificationTestSetup(t, NotificationChannel{Name: "og", Type: ChannelTypeOpsgenie, Enabled: true,
+		Config: ChannelConfig{"api_key": "genie", "api_url": server.URL}})
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityWarning, "yavaş yanıt", time.Now().Add(-3*time.Minute))
+	id := mustOpenAlertID(t, dispatcher)
+
+	// Onaylayan kişi Opsgenie'ye kullanıcı olarak iletilir
+	if _, err := manager.Acknowledge(id, "ayse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Acknowledge(id, "mehmet"); err == nil {
+		t.Errorf("ikinci onay reddedilmeliydi")
+	}
+	// Elle kapatma alarmı Opsgenie'de de kapatır
+	if _, err := manager.Resolve(id); err != nil {
+		t.Fatal(err)
+	}
+
+	got := recorded()
+	if len(got) != 3 {
+		t.Fatalf("Opsgenie'ye 3 istek bekleniyordu, alınan %d: %+v", len(got), got)
+	}
+	if got[0].path != "/v2/alerts" || got[0].body["priority"] != "P3" {
+		t.Errorf("beklenmeyen açılış: %s %v", got[0].path, got[0].body)
+	}
+	if got[1].path != "/v2/alerts/monitoring/status:7/acknowledge" || got[1].query != "identifierType=alias" ||
+		got[1].body["user"] != "ayse" || got[1].body["source"] != "monitoring" {
+		t.Errorf("beklenmeyen onay: %s?%s %v", got[1].path, got[1].query, got[1].body)
+	}
+	if got[2].path != "/v2/alerts/monitoring/status:7/close" || got[2].query != "identifierType=alias" ||
+		!strings.HasPrefix(got[2].body["note"].(string), "Alarm çözüldü") {
+		t.Errorf("beklenmeyen kapanış: %s?%s %v", got[2].path, got[2].query, got[2].body)
+	}
+
+	// Gönderilemeyen onay yeniden denenmek üzere bekler; ardından gelen kapanış onu geçersiz kılar
+	mu.Lock()
+	failAcknowledge = true
+	mu.Unlock()
+	manager.Clear(statusAlertKey(7), time.Now())
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", time.Now())
+	id = mustOpenAlertID(t, dispatcher)
+	manager.Acknowledge(id, "ayse")
+	manager.Resolve(id)
+
+	statuses := map[string]string{}
+	deliveries, _ := dispatcher.db.Query(`SELECT event_type, status FROM notification_deliveries WHERE alert_id = ?`, id)
+	for deliveries.Next() {
+		var eventType, status string
+		deliveries.Scan(&eventType, &status)
+		statuses[eventType] = status
+	}
+	deliveries.Close()
+	want := map[string]string{
+		AlertEventFiring:       DeliveryDelivered,
+		AlertEventAcknowledged: DeliverySuperseded,
+		AlertEventResolved:     DeliveryDelivered,
+	}
+	for eventType, status := range want {
+		if statuses[eventType] != status {
+			t.Errorf("%s gönderimi %q olmalıydı, alınan %q", eventType, status, statuses[eventType])
+		}
+	}
+	if got = recorded(); !strings.HasSuffix(got[len(got)-1].path, "/close") {
+		t.Errorf("son istek kapanış olmalıydı: %s", got[len(got)-1].path)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ChannelTypeWebhook, yapılandırılabilir HTTP isteği gönderen genel kanal türü
+const ChannelTypeWebhook = "webhook"
+
+// İmzalı webhook isteklerinde kullanılan başlıklar. İmza, "zaman damgası.gövde"
+// üzerinden HMAC-SHA256 ile hesaplanır: sha256=<hex>
+const (
+	WebhookTimestampHeader = "X-Monitoring-Timestamp"
+	WebhookSignatureHeader = "X-Monitoring-Signature"
+)
+
+// maxWebhookRetries, webhook kanalında ayarlanabilecek en fazla yeniden deneme sayısı
+const maxWebhookRetries = 10
+
+// webhookTemplateFuncs, gövde şablonlarında kullanılabilen fonksiyonlar
+var webhookTemplateFuncs = template.FuncMap{
+	// json, değeri JSON olarak yazar (metinleri tırnak ve kaçış karakterleriyle)
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// validateWebhookConfig, webhook kanalı ayarlarını doğrular
+func validateWebhookConfig(c ChannelConfig) error {
+	if c.String("url") == "" {
+		return fmt.Errorf("url gerekli")
+	}
+	if err := validateWebhookURL("url", c.String("url")); err != nil {
+		return err
+	}
+	switch strings.ToUpper(c.String("method")) {
+	case "", "POST", "PUT", "PATCH":
+	default:
+		return fmt.Errorf("method POST, PUT veya PATCH olmalı")
+	}
+	if headers, ok := c["headers"]; ok && headers != nil {
+		values, ok := headers.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("headers ad-değer nesnesi olmalı")
+		}
+		for name, value := range values {
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("headers.%s metin olmalı", name)
+			}
+			if err := validateSecretValue("headers."+name, s); err != nil {
+				return err
+			}
+		}
+	}
+	if body := c.String("body_template"); body != "" {
+		if _, err := template.New("body").Funcs(webhookTemplateFuncs).Parse(body); err != nil {
+			return fmt.Errorf("body_template ayrıştırılamadı: %v", err)
+		}
+	}
+	if retries, ok := c["max_retries"]; ok && retries != nil {
+		n, ok := retries.(float64)
+		if !ok || n < 0 || n > maxWebhookRetries || n != float64(int(n)) {
+			return fmt.Errorf("max_retries 0 ile %d arasında bir tam sayı olmalı", maxWebhookRetries)
+		}
+	}
+	return nil
+}
+
+// webhookNotifier, alarm olaylarını yapılandırılan adrese şablonlu gövdeyle gönderir
+type webhookNotifier struct {
+	url         string
+	method      string
+	headers     map[string]string
+	body        *template.Template
+	secret      string
+	maxAttempts int
+}
+
+// newWebhookNotifier, ayarlardaki referansları çözerek webhook bildiricisi oluşturur
+func newWebhookNotifier(c ChannelConfig) (Notifier, error) {
+	n := &webhookNotifier{
+		method:      strings.ToUpper(c.String("method")),
+		headers:     map[string]string{},
+		maxAttempts: defaultDeliveryAttempts,
+	}
+	if n.method == "" {
+		n.method = "POST"
+	}
+	if retries, ok := c["max_retries"].(float64); ok {
+		n.maxAttempts = int(retries) + 1
+	}
+
+	var err error
+	if n.url, err = c.Secret("url"); err != nil {
+		return nil, err
+	}
+	if n.secret, err = c.Secret("secret"); err != nil {
+		return nil, err
+	}
+	if headers, ok := c["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			s, _ := value.(string)
+			if n.headers[name], err = secretResolver.Resolve(s, defaultClusterName); err != nil {
+				return nil, fmt.Errorf("headers.%s çözülemedi: %v", name, err)
+			}
+		}
+	}
+	if body := c.String("body_template"); body != "" {
+		if n.body, err = template.New("body").Funcs(webhookTemplateFuncs).Parse(body); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// Acknowledges, onay olaylarının da webhook'a gönderildiğini belirtir (şablonda .Event ile ayrılır)
+func (n *webhookNotifier) Acknowledges() bool {
+	return true
+}
+
+// MaxAttempts, ilk gönderim dahil toplam deneme sayısı
+func (n *webhookNotifier) MaxAttempts() int {
+	return n.maxAttempts
+}
+
+// WebhookService, şablonlara verilen servis alanları
+type WebhookService struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Cluster   string `json:"cluster"`
+	Endpoint  string `json:"endpoint"`
+	Type      string `json:"type"`
+	Owner     string `json:"owner,omitempty"`
+	URL       string `json:"url"`
+}
+
+// WebhookCheck, şablonlara verilen son kontrol sonucu
+type WebhookCheck struct {
+	Status       string    `json:"status"`
+	ResponseTime int64     `json:"response_time"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// WebhookPayload, gövde şablonunun verisi; şablon verilmemişse JSON olarak gönderilir
+type WebhookPayload struct {
+	Event      string           `json:"event"`
+	Alert      Alert            `json:"alert"`
+	Service    WebhookService   `json:"service"`
+	LastCheck  *WebhookCheck    `json:"last_check,omitempty"`
+	Escalation *AlertEscalation `json:"escalation,omitempty"`
+	SentAt     time.Time        `json:"sent_at"`
+}
+
+// webhookPayload, olay için servis ve son kontrol bilgilerini okuyarak şablon verisini oluşturur
+func webhookPayload(event AlertEvent) WebhookPayload {
+	a := event.Alert
+	payload := WebhookPayload{
+		Event: event.Type,
+		Alert: a,
+		Service: WebhookService{
+			ID: a.ServiceID, Name: a.ServiceName, Namespace: a.Namespace, Cluster: a.Cluster,
+			URL: alertUIURL(a.ServiceID),
+		},
+		Escalation: event.Escalation,
+		SentAt:     time.Now(),
+	}
+	if db == nil || a.ServiceID == 0 {
+		return payload
+	}
+
+	var endpoint, serviceType, owner sql.NullString
+	db.QueryRow(`SELECT endpoint, type, owner FROM services WHERE id = ?`, a.ServiceID).Scan(&endpoint, &serviceType, &owner)
+	payload.Service.Endpoint, payload.Service.Type, payload.Service.Owner = endpoint.String, serviceType.String, owner.String
+
+	var check WebhookCheck
+	var responseTime sql.NullInt64
+	var errorMessage sql.NullString
+	err := db.QueryRow(`
+		SELECT status, response_time, error_message, timestamp FROM uptime_checks
+		WHERE service_id = ? ORDER BY timestamp DESC LIMIT 1
+	`, a.ServiceID).Scan(&check.Status, &responseTime, &errorMessage, &check.Timestamp)
+	if err == nil {
+		check.ResponseTime, check.ErrorMessage = responseTime.Int64, errorMessage.String
+		payload.LastCheck = &check
+	}
+	return payload
+}
+
+// signWebhook, zaman damgası ve gövde için HMAC-SHA256 imzasını hesaplar
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify, olayı webhook adresine gönderir
+func (n *webhookNotifier) Notify(ctx context.Context, event AlertEvent, ref string) (string, error) {
+	payload := webhookPayload(event)
+
+	var body []byte
+	if n.body != nil {
+		var buf bytes.Buffer
+		if err := n.body.Execute(&buf, payload); err != nil {
+			return "", fmt.Errorf("gövde şablonu çalıştırılamadı: %v", err)
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return "", err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, n.method, n.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range n.headers {
+		req.Header.Set(name, value)
+	}
+	if n.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, signWebhook(n.secret, timestamp, body))
+	}
+
+	resp, err := notificationHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return "", nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookRendersTemplateAndSignsBody(t *testing.T) {
+	var mu sync.Mutex
+	var body string
+	var header http.Header
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		body, header, method = string(data), r.Header.Clone(), r.Method
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	manager, _ := newNotificationTestSetup(t, NotificationChannel{
+		Name: "hook", Type: ChannelTypeWebhook, Enabled: true,
+		Config: ChannelConfig{
+			"url":     server.URL,
+			"method":  "put",
+			"secret":  "imza-anahtari",
+			"headers": map[string]interface{}{"Authorization": "Bearer abc"},
+			"body_template": `{"text":{{json .Alert.Message}},"service":"{{.Service.Name}}","endpoint":"{{.Service.Endpoint}}",` +
+				`"last":"{{with .LastCheck}}{{.Status}}/{{.ResponseTime}}{{end}}","event":"{{.Event}}"}`,
+		},
+	})
+	db.Exec(`UPDATE services SET endpoint = 'http://checkout:8080' WHERE id = 7`)
+	db.Exec(`INSERT INTO uptime_checks (service_id, status, response_time, timestamp) VALUES (7, 'down', 1500, ?)`, time.Now())
+
+	if err := manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, `bağlantı "reddedildi"`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := `{"text":"bağlantı \"reddedildi\"","service":"checkout","endpoint":"http://checkout:8080","last":"down/1500","event":"firing"}`
+	if body != want {
+		t.Errorf("beklenmeyen gövde:\n%s\nbeklenen:\n%s", body, want)
+	}
+	if method != "PUT" || header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("yöntem veya başlık uygulanmadı: %s %v", method, header)
+	}
+	timestamp := header.Get(WebhookTimestampHeader)
+	if timestamp == "" || header.Get(WebhookSignatureHeader) != signWebhook("imza-anahtari", timestamp, []byte(body)) {
+		t.Errorf("imza doğrulanamadı: %s=%s %s=%s", WebhookTimestampHeader, timestamp,
+			WebhookSignatureHeader, header.Get(WebhookSignatureHeader))
+	}
+}
+
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	failUntil := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failUntil) {
+			http.Error(w, "geçici hata", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	manager, dispatcher := newNotificationTestSetup(t, NotificationChannel{
+		Name: "hook", Type: ChannelTypeWebhook, Enabled: true,
+		Config: ChannelConfig{"url": server.URL, "max_retries": float64(2)},
+	})
+
+	delivery := func() (string, int, string) {
+		var status, lastError string
+		var attempts int
+		dispatcher.db.QueryRow(`SELECT status, attempts, COALESCE(last_error, '') FROM notification_deliveries ORDER BY id DESC LIMIT 1`).
+			Scan(&status, &attempts, &lastError)
+		return status, attempts, lastError
+	}
+
+	start := time.Now()
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "servis down", start)
+	if status, attempts, lastError := delivery(); status != DeliveryPending || attempts != 1 || !strings.Contains(lastError, "503") {
+		t.Fatalf("ilk başarısız deneme bekliyor olmalı: %s %d %q", status, attempts, lastError)
+	}
+
+	// Bekleme süresi dolmadan yeniden denenmez
+	dispatcher.RetryDue(context.Background(), start.Add(10*time.Second))
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("bekleme süresi dolmadan yeniden denendi: %d çağrı", got)
+	}
+
+	dispatcher.RetryDue(context.Background(), start.Add(deliveryBackoff(1)+time.Second))
+	if status, attempts, _ := delivery(); status != DeliveryPending || attempts != 2 {
+		t.Fatalf("ikinci deneme de başarısız olup beklemeli: %s %d", status, attempts)
+	}
+	dispatcher.RetryDue(context.Background(), start.Add(deliveryBackoff(1)+deliveryBackoff(2)+time.Second))
+	if status, attempts, _ := delivery(); status != DeliveryDelivered || attempts != 3 {
+		t.Fatalf("üçüncü denemede teslim edilmeliydi: %s %d", status, attempts)
+	}
+
+	// Deneme hakkı bitince kayıt başarısız olarak kapanır
+	atomic.StoreInt32(&failUntil, 100)
+	manager.Resolve(mustOpenAlertID(t, dispatcher))
+	for i := 1; i <= 3; i++ {
+		dispatcher.RetryDue(context.Background(), time.Now().Add(2*deliveryBackoffMax))
+	}
+	if status, attempts, _ := delivery(); status != DeliveryFailed || attempts != 3 {
+		t.Fatalf("deneme hakkı bitince başarısız olmalı: %s %d", status, attempts)
+	}
+
+	rec := httptest.NewRecorder()
+	notificationDeliveriesHandler(rec, httptest.NewRequest("GET", "/api/v1/notification-deliveries?status=failed", nil))
+	if !strings.Contains(rec.Body.String(), `"count":1`) || !strings.Contains(rec.Body.String(), `"event_type":"resolved"`) {
+		t.Errorf("gönderim kaydı listelenmedi: %s", rec.Body.String())
+	}
+}
+
+// mustOpenAlertID, test veritabanındaki açık alarmın kimliğini döndürür
+func mustOpenAlertID(t *testing.T, dispatcher *NotificationDispatcher) int64 {
+	t.Helper()
+	var id int64
+	if err := dispatcher.db.QueryRow(`SELECT id FROM alerts WHERE status = ?`, AlertStatusOpen).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
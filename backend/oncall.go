@@ -0,0 +1,453 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Nöbet rotasyon türleri
+const (
+	RotationDaily  = "daily"
+	RotationWeekly = "weekly"
+)
+
+// rotationDays, rotasyon türüne göre bir nöbetin gün sayısı
+var rotationDays = map[string]int{
+	RotationDaily:  1,
+	RotationWeekly: 7,
+}
+
+// OnCallSchedule, katılımcıların sırayla nöbet tuttuğu rotasyon. StartAt ilk devir anıdır;
+// devirler, TimeZone'daki duvar saatine göre her gün veya her hafta aynı saatte yapılır.
+type OnCallSchedule struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Rotation     string    `json:"rotation"`
+	Participants []string  `json:"participants"`
+	StartAt      time.Time `json:"start_at"`
+	TimeZone     string    `json:"timezone"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OnCallOverride, belirli bir aralık için rotasyonun yerine geçen nöbetçi
+type OnCallOverride struct {
+	ID         int64     `json:"id"`
+	ScheduleID int64     `json:"schedule_id"`
+	Person     string    `json:"person"`
+	StartAt    time.Time `json:"start_at"`
+	EndAt      time.Time `json:"end_at"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OnCall, bir rotasyonda belirli bir andaki nöbetçi
+type OnCall struct {
+	ScheduleID int64     `json:"schedule_id"`
+	Schedule   string    `json:"schedule"`
+	Person     string    `json:"person"`
+	Override   bool      `json:"override"`
+	ShiftStart time.Time `json:"shift_start"`
+	ShiftEnd   time.Time `json:"shift_end"`
+}
+
+// Validate, rotasyonu doğrular ve katılımcı adlarını temizler
+func (s *OnCallSchedule) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return fmt.Errorf("name gerekli")
+	}
+	if _, ok := rotationDays[s.Rotation]; !ok {
+		return fmt.Errorf("rotation daily veya weekly olmalı")
+	}
+	var participants []string
+	for _, p := range s.Participants {
+		if p = strings.TrimSpace(p); p != "" {
+			participants = append(participants, p)
+		}
+	}
+	if len(participants) == 0 {
+		return fmt.Errorf("en az bir katılımcı gerekli")
+	}
+	s.Participants = participants
+	if s.StartAt.IsZero() {
+		return fmt.Errorf("start_at gerekli")
+	}
+	if s.TimeZone == "" {
+		s.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("geçersiz timezone %q", s.TimeZone)
+	}
+	return nil
+}
+
+// shift, t anını içeren nöbetin sırasını ve sınırlarını döndürür. Devirler AddDate ile
+// hesaplandığından yaz saati geçişlerinde de duvar saati korunur.
+func (s OnCallSchedule) shift(t time.Time) (int, time.Time, time.Time) {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start := s.StartAt.In(loc)
+	days := rotationDays[s.Rotation]
+	if days == 0 {
+		days = 1
+	}
+
+	n := int(t.Sub(start) / (time.Duration(days) * 24 * time.Hour))
+	for start.AddDate(0, 0, n*days).After(t) {
+		n--
+	}
+	for !start.AddDate(0, 0, (n+1)*days).After(t) {
+		n++
+	}
+	return n, start.AddDate(0, 0, n*days), start.AddDate(0, 0, (n+1)*days)
+}
+
+// At, t anındaki nöbetçiyi döndürür; t'yi kapsayan en son eklenen değişiklik rotasyonun önüne geçer
+func (s OnCallSchedule) At(t time.Time, overrides []OnCallOverride) OnCall {
+	n, shiftStart, shiftEnd := s.shift(t)
+	index := n % len(s.Participants)
+	if index < 0 {
+		index += len(s.Participants)
+	}
+	onCall := OnCall{ScheduleID: s.ID, Schedule: s.Name, Person: s.Participants[index], ShiftStart: shiftStart, ShiftEnd: shiftEnd}
+
+	for i := len(overrides) - 1; i >= 0; i-- {
+		o := overrides[i]
+		if !o.StartAt.After(t) && o.EndAt.After(t) {
+			onCall.Person, onCall.Override = o.Person, true
+			onCall.ShiftStart, onCall.ShiftEnd = o.StartAt, o.EndAt
+			break
+		}
+	}
+	return onCall
+}
+
+// onCallScheduleColumns, oncall_schedules sorgularında kullanılan kolonlar
+const onCallScheduleColumns = `id, name, rotation, participants, start_at, timezone, created_at, updated_at`
+
+// scanOnCallSchedule, onCallScheduleColumns sırasıyla okunan satırı OnCallSchedule'a dönüştürür
+func scanOnCallSchedule(scanner interface{ Scan(...interface{}) error }) (OnCallSchedule, error) {
+	var s OnCallSchedule
+	var participants string
+	if err := scanner.Scan(&s.ID, &s.Name, &s.Rotation, &participants, &s.StartAt, &s.TimeZone, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal([]byte(participants), &s.Participants)
+}
+
+// getOnCallSchedule, rotasyonu ID ile döndürür
+func getOnCallSchedule(db *sql.DB, id int64) (OnCallSchedule, error) {
+	return scanOnCallSchedule(db.QueryRow(`SELECT `+onCallScheduleColumns+` FROM oncall_schedules WHERE id = ?`, id))
+}
+
+// listOnCallSchedules, tüm rotasyonları döndürür
+func listOnCallSchedules(db *sql.DB) ([]OnCallSchedule, error) {
+	rows, err := db.Query(`SELECT ` + onCallScheduleColumns + ` FROM oncall_schedules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []OnCallSchedule{}
+	for rows.Next() {
+		s, err := scanOnCallSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// saveOnCallSchedule, rotasyonu ekler (ID 0 ise) veya günceller
+func saveOnCallSchedule(db *sql.DB, s *OnCallSchedule) error {
+	participants, err := json.Marshal(s.Participants)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if s.ID == 0 {
+		result, err := db.Exec(`
+			INSERT INTO oncall_schedules (name, rotation, participants, start_at, timezone, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, s.Name, s.Rotation, string(participants), s.StartAt.UTC(), s.TimeZone, now, now)
+		if err != nil {
+			return err
+		}
+		s.ID, _ = result.LastInsertId()
+		s.CreatedAt = now
+	} else {
+		_, err := db.Exec(`
+			UPDATE oncall_schedules SET name = ?, rotation = ?, participants = ?, start_at = ?, timezone = ?, updated_at = ?
+			WHERE id = ?
+		`, s.Name, s.Rotation, string(participants), s.StartAt.UTC(), s.TimeZone, now, s.ID)
+		if err != nil {
+			return err
+		}
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// listOnCallOverrides, rotasyonun değişikliklerini ekleniş sırasıyla döndürür. from sıfır
+// değilse yalnızca o andan sonra bitenler döner.
+func listOnCallOverrides(db *sql.DB, scheduleID int64, from time.Time) ([]OnCallOverride, error) {
+	query := `SELECT id, schedule_id, person, start_at, end_at, comment, created_at FROM oncall_overrides WHERE schedule_id = ?`
+	args := []interface{}{scheduleID}
+	if !from.IsZero() {
+		query += " AND end_at > ?"
+		args = append(args, from.UTC())
+	}
+	rows, err := db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []OnCallOverride{}
+	for rows.Next() {
+		var o OnCallOverride
+		var comment sql.NullString
+		if err := rows.Scan(&o.ID, &o.ScheduleID, &o.Person, &o.StartAt, &o.EndAt, &comment, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		o.Comment = comment.String
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// onCallFor, verilen rotasyonlardaki t anındaki nöbetçileri döndürür
+func onCallFor(db *sql.DB, scheduleIDs []int64, t time.Time) ([]OnCall, error) {
+	result := []OnCall{}
+	for _, id := range scheduleIDs {
+		schedule, err := getOnCallSchedule(db, id)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		overrides, err := listOnCallOverrides(db, id, t)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, schedule.At(t, overrides))
+	}
+	return result, nil
+}
+
+// onCallSchedulesHandler, /api/v1/oncall-schedules altındaki endpoint'leri yönetir:
+//
+//	GET    /api/v1/oncall-schedules                        rotasyonlar ve şu anki nöbetçiler
+//	POST   /api/v1/oncall-schedules                        rotasyon oluşturma
+//	GET    /api/v1/oncall-schedules/{id}                   rotasyon detayı
+//	PUT    /api/v1/oncall-schedules/{id}                   rotasyon güncelleme
+//	DELETE /api/v1/oncall-schedules/{id}                   rotasyon silme
+//	GET    /api/v1/oncall-schedules/{id}/overrides         güncel ve gelecek değişiklikler
+//	POST   /api/v1/oncall-schedules/{id}/overrides         değişiklik ekleme
+//	DELETE /api/v1/oncall-schedules/{id}/overrides/{oid}   değişiklik silme
+func onCallSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/oncall-schedules"), "/")
+	if path == "" {
+		switch r.Method {
+		case "GET":
+			schedules, err := listOnCallSchedules(db)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Nöbet rotasyonları alınamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			now := time.Now()
+			result := []map[string]interface{}{}
+			for _, s := range schedules {
+				overrides, _ := listOnCallOverrides(db, s.ID, now)
+				result = append(result, map[string]interface{}{"schedule": s, "on_call": s.At(now, overrides)})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"schedules": result, "count": len(result)})
+
+		case "POST":
+			var schedule OnCallSchedule
+			if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+				http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+				return
+			}
+			if err := schedule.Validate(); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			schedule.ID = 0
+			if err := saveOnCallSchedule(db, &schedule); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Nöbet rotasyonu kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message":  "Nöbet rotasyonu oluşturuldu",
+				"schedule": schedule,
+			})
+
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	id, err := strconv.ParseInt(segments[0], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Geçersiz rotasyon ID"}`, http.StatusBadRequest)
+		return
+	}
+	schedule, err := getOnCallSchedule(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Nöbet rotasyonu bulunamadı"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Nöbet rotasyonu alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	if len(segments) > 1 && segments[1] == "overrides" {
+		onCallOverridesHandler(w, r, schedule, segments[2:])
+		return
+	}
+	if len(segments) > 1 {
+		http.Error(w, `{"error":"Bulunamadı"}`, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		now := time.Now()
+		overrides, _ := listOnCallOverrides(db, id, now)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"schedule":  schedule,
+			"on_call":   schedule.At(now, overrides),
+			"overrides": overrides,
+		})
+
+	case "PUT":
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		if err := schedule.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		schedule.ID = id
+		if err := saveOnCallSchedule(db, &schedule); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet rotasyonu kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "Nöbet rotasyonu güncellendi",
+			"schedule": schedule,
+		})
+
+	case "DELETE":
+		if policy, ok := escalationPolicyUsingSchedule(db, id); ok {
+			http.Error(w, fmt.Sprintf(`{"error":"Rotasyon %q eskalasyon politikasında kullanılıyor"}`, policy), http.StatusConflict)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM oncall_overrides WHERE schedule_id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet değişiklikleri silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.Exec("DELETE FROM oncall_schedules WHERE id = ?", id); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet rotasyonu silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Nöbet rotasyonu silindi",
+			"id":      id,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// onCallOverridesHandler, rotasyonun nöbet değişikliklerini yönetir
+func onCallOverridesHandler(w http.ResponseWriter, r *http.Request, schedule OnCallSchedule, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == "GET":
+		from := time.Now()
+		if r.URL.Query().Get("all") == "true" {
+			from = time.Time{}
+		}
+		overrides, err := listOnCallOverrides(db, schedule.ID, from)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet değişiklikleri alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"overrides": overrides, "count": len(overrides)})
+
+	case len(segments) == 0 && r.Method == "POST":
+		var o OnCallOverride
+		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+			http.Error(w, `{"error":"İstek gövdesi ayrıştırılamadı"}`, http.StatusBadRequest)
+			return
+		}
+		o.Person = strings.TrimSpace(o.Person)
+		switch {
+		case o.Person == "":
+			http.Error(w, `{"error":"person gerekli"}`, http.StatusBadRequest)
+			return
+		case o.StartAt.IsZero() || !o.EndAt.After(o.StartAt):
+			http.Error(w, `{"error":"start_at ve ondan sonra gelen end_at gerekli"}`, http.StatusBadRequest)
+			return
+		}
+
+		o.ScheduleID, o.CreatedAt = schedule.ID, time.Now()
+		result, err := db.Exec(`
+			INSERT INTO oncall_overrides (schedule_id, person, start_at, end_at, comment, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, o.ScheduleID, o.Person, o.StartAt.UTC(), o.EndAt.UTC(), o.Comment, o.CreatedAt)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet değişikliği kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		o.ID, _ = result.LastInsertId()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":  "Nöbet değişikliği eklendi",
+			"override": o,
+		})
+
+	case len(segments) == 1 && r.Method == "DELETE":
+		overrideID, err := strconv.ParseInt(segments[0], 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Geçersiz değişiklik ID"}`, http.StatusBadRequest)
+			return
+		}
+		result, err := db.Exec("DELETE FROM oncall_overrides WHERE id = ? AND schedule_id = ?", overrideID, schedule.ID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Nöbet değişikliği silinemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			http.Error(w, `{"error":"Nöbet değişikliği bulunamadı"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Nöbet değişikliği silindi",
+			"id":      overrideID,
+		})
+
+	case len(segments) <= 1:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+
+	default:
+		http.Error(w, `{"error":"Bulunamadı"}`, http.StatusNotFound)
+	}
+}
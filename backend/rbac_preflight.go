@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// monitorClusterRoleName, üretilen ClusterRole'ün adı
+const monitorClusterRoleName = "k8s-monitoring"
+
+// RequiredPermission, izleyicinin ihtiyaç duyduğu tek bir Kubernetes yetkisi
+type RequiredPermission struct {
+	Verb     string `json:"verb"`
+	Group    string `json:"group"`
+	Resource string `json:"resource,omitempty"`
+	Path     string `json:"path,omitempty"` // kaynak dışı URL'ler için (ör. /readyz)
+}
+
+// FeaturePermissions, bir özelliğin çalışması için gereken yetkileri gruplar
+type FeaturePermissions struct {
+	Feature     string
+	Description string
+	Permissions []RequiredPermission
+}
+
+// requiredPermissions, izleyicinin özellik bazında ihtiyaç duyduğu tüm yetkiler
+var requiredPermissions = []FeaturePermissions{
+	{
+		Feature:     "discovery",
+		Description: "Servis keşfi",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Resource: "namespaces"},
+			{Verb: "list", Resource: "services"},
+			{Verb: "watch", Resource: "services"},
+			{Verb: "list", Group: "discovery.k8s.io", Resource: "endpointslices"},
+			{Verb: "watch", Group: "discovery.k8s.io", Resource: "endpointslices"},
+		},
+	},
+	{
+		Feature:     "ingress_endpoints",
+		Description: "Ingress kurallarından endpoint türetme",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Group: "networking.k8s.io", Resource: "ingresses"},
+			{Verb: "watch", Group: "networking.k8s.io", Resource: "ingresses"},
+		},
+	},
+	{
+		Feature:     "route_endpoints",
+		Description: "OpenShift Route'larından endpoint türetme (yalnızca OpenShift)",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Group: "route.openshift.io", Resource: "routes"},
+			{Verb: "watch", Group: "route.openshift.io", Resource: "routes"},
+		},
+	},
+	{
+		Feature:     "events",
+		Description: "Kubernetes olaylarının kesintilerle ilişkilendirilmesi",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Resource: "events"},
+			{Verb: "watch", Resource: "events"},
+			{Verb: "get", Resource: "pods"},
+		},
+	},
+	{
+		Feature:     "rollouts",
+		Description: "Deployment rollout'larının kontrol geçmişiyle ilişkilendirilmesi",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Group: "apps", Resource: "deployments"},
+			{Verb: "watch", Group: "apps", Resource: "deployments"},
+			{Verb: "list", Group: "apps", Resource: "replicasets"},
+			{Verb: "watch", Group: "apps", Resource: "replicasets"},
+		},
+	},
+	{
+		Feature:     "scale_to_zero",
+		Description: "0 replikaya ölçeklenen iş yüklerinin servisleri duraklatması",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Group: "apps", Resource: "deployments"},
+			{Verb: "watch", Group: "apps", Resource: "deployments"},
+			{Verb: "list", Group: "apps", Resource: "statefulsets"},
+			{Verb: "watch", Group: "apps", Resource: "statefulsets"},
+		},
+	},
+	{
+		Feature:     "cluster_health",
+		Description: "API sunucusu sağlık izleme",
+		Permissions: []RequiredPermission{
+			{Verb: "get", Path: "/readyz"},
+			{Verb: "get", Path: "/livez"},
+			{Verb: "get", Path: "/version"},
+			{Verb: "list", Resource: "nodes"},
+		},
+	},
+	{
+		Feature:     "secret_refs",
+		Description: "secretRef ile verilen kimlik bilgilerinin okunması",
+		Permissions: []RequiredPermission{
+			{Verb: "get", Resource: "secrets"},
+		},
+	},
+	{
+		Feature:     "monitor_crds",
+		Description: "Monitor/ClusterMonitor kaynaklarının uygulanması ve durumlarının yazılması",
+		Permissions: []RequiredPermission{
+			{Verb: "list", Group: "k8s-monitoring.io", Resource: "monitors"},
+			{Verb: "watch", Group: "k8s-monitoring.io", Resource: "monitors"},
+			{Verb: "update", Group: "k8s-monitoring.io", Resource: "monitors/status"},
+			{Verb: "list", Group: "k8s-monitoring.io", Resource: "clustermonitors"},
+			{Verb: "watch", Group: "k8s-monitoring.io", Resource: "clustermonitors"},
+			{Verb: "update", Group: "k8s-monitoring.io", Resource: "clustermonitors/status"},
+		},
+	},
+}
+
+// PermissionCheck, tek bir yetki kontrolünün sonucu
+type PermissionCheck struct {
+	RequiredPermission
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// FeatureReport, bir özelliğin yetki durumunu gösterir
+type FeatureReport struct {
+	Feature     string            `json:"feature"`
+	Description string            `json:"description"`
+	Works       bool              `json:"works"`
+	Permissions []PermissionCheck `json:"permissions"`
+}
+
+// PermissionReport, bir cluster için yetki matrisini temsil eder
+type PermissionReport struct {
+	AllFeaturesWork bool                `json:"all_features_work"`
+	Features        []FeatureReport     `json:"features"`
+	RulesReview     *RulesReviewSummary `json:"rules_review,omitempty"`
+	CheckedAt       time.Time           `json:"checked_at"`
+}
+
+// RulesReviewSummary, SelfSubjectRulesReview sonucunun özeti
+type RulesReviewSummary struct {
+	Namespace     string                            `json:"namespace"`
+	Incomplete    bool                              `json:"incomplete"`
+	EvaluationErr string                            `json:"evaluation_error,omitempty"`
+	ResourceRules []authorizationv1.ResourceRule    `json:"resource_rules"`
+	NonResource   []authorizationv1.NonResourceRule `json:"non_resource_rules"`
+}
+
+// RunPermissionPreflight, gereken her yetkiyi SelfSubjectAccessReview ile kontrol eder
+// ve verilen namespace için SelfSubjectRulesReview sonucunu ekler
+func RunPermissionPreflight(ctx context.Context, client kubernetes.Interface, namespace string) PermissionReport {
+	report := PermissionReport{
+		AllFeaturesWork: true,
+		Features:        []FeatureReport{},
+		CheckedAt:       time.Now(),
+	}
+
+	for _, feature := range requiredPermissions {
+		featureReport := FeatureReport{
+			Feature:     feature.Feature,
+			Description: feature.Description,
+			Works:       true,
+			Permissions: []PermissionCheck{},
+		}
+
+		for _, perm := range feature.Permissions {
+			check := checkPermission(ctx, client, perm)
+			if !check.Allowed {
+				featureReport.Works = false
+			}
+			featureReport.Permissions = append(featureReport.Permissions, check)
+		}
+
+		if !featureReport.Works {
+			report.AllFeaturesWork = false
+		}
+		report.Features = append(report.Features, featureReport)
+	}
+
+	if namespace == "" {
+		namespace = "default"
+	}
+	review, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}, metav1.CreateOptions{})
+	if err == nil {
+		report.RulesReview = &RulesReviewSummary{
+			Namespace:     namespace,
+			Incomplete:    review.Status.Incomplete,
+			EvaluationErr: review.Status.EvaluationError,
+			ResourceRules: review.Status.ResourceRules,
+			NonResource:   review.Status.NonResourceRules,
+		}
+	}
+
+	return report
+}
+
+// checkPermission, tek bir yetkiyi cluster genelinde SelfSubjectAccessReview ile kontrol eder
+func checkPermission(ctx context.Context, client kubernetes.Interface, perm RequiredPermission) PermissionCheck {
+	check := PermissionCheck{RequiredPermission: perm}
+
+	review := &authorizationv1.SelfSubjectAccessReview{}
+	if perm.Path != "" {
+		review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: perm.Path,
+			Verb: perm.Verb,
+		}
+	} else {
+		// Alt kaynaklar (ör. monitors/status) ayrı alanda gönderilir
+		resource, subresource, _ := strings.Cut(perm.Resource, "/")
+		review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Verb:        perm.Verb,
+			Group:       perm.Group,
+			Resource:    resource,
+			Subresource: subresource,
+		}
+	}
+
+	result, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		check.Reason = fmt.Sprintf("Yetki kontrolü yapılamadı: %v", err)
+		return check
+	}
+
+	check.Allowed = result.Status.Allowed
+	check.Reason = result.Status.Reason
+	if result.Status.Denied && check.Reason == "" {
+		check.Reason = "Açıkça reddedildi"
+	}
+	return check
+}
+
+// preflightCluster, kayıtlı bir cluster için yetki kontrolünü çalıştırır
+// ve çalışmayacak özellikleri loglar
+func preflightCluster(clusterID int) (*PermissionReport, error) {
+	record, err := loadCluster(db, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("cluster bilgileri alınamadı: %v", err)
+	}
+	client, err := newClusterClient(record)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report := RunPermissionPreflight(ctx, client, "")
+	for _, feature := range report.Features {
+		if !feature.Works {
+			log.Printf("%s cluster'ında yetki eksik, %s çalışmayacak", record.Name, feature.Description)
+		}
+	}
+	return &report, nil
+}
+
+// minimalClusterRole, tüm özellikler için gereken en küçük ClusterRole'ü üretir
+func minimalClusterRole() rbacv1.ClusterRole {
+	resourceVerbs := map[string]map[string]map[string]bool{} // grup -> kaynak -> fiiller
+	pathVerbs := map[string]map[string]bool{}                // path -> fiiller
+
+	for _, feature := range requiredPermissions {
+		for _, perm := range feature.Permissions {
+			if perm.Path != "" {
+				if pathVerbs[perm.Path] == nil {
+					pathVerbs[perm.Path] = map[string]bool{}
+				}
+				pathVerbs[perm.Path][perm.Verb] = true
+				continue
+			}
+			if resourceVerbs[perm.Group] == nil {
+				resourceVerbs[perm.Group] = map[string]map[string]bool{}
+			}
+			if resourceVerbs[perm.Group][perm.Resource] == nil {
+				resourceVerbs[perm.Group][perm.Resource] = map[string]bool{}
+			}
+			resourceVerbs[perm.Group][perm.Resource][perm.Verb] = true
+		}
+	}
+
+	role := rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+		ObjectMeta: metav1.ObjectMeta{Name: monitorClusterRoleName},
+	}
+
+	for _, group := range sortedKeys(resourceVerbs) {
+		for _, resource := range sortedKeys(resourceVerbs[group]) {
+			role.Rules = append(role.Rules, rbacv1.PolicyRule{
+				APIGroups: []string{group},
+				Resources: []string{resource},
+				Verbs:     sortedKeys(resourceVerbs[group][resource]),
+			})
+		}
+	}
+	for _, path := range sortedKeys(pathVerbs) {
+		role.Rules = append(role.Rules, rbacv1.PolicyRule{
+			NonResourceURLs: []string{path},
+			Verbs:           sortedKeys(pathVerbs[path]),
+		})
+	}
+
+	return role
+}
+
+// sortedKeys, map anahtarlarını sıralı döndürür
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// clusterPermissionsHandler, cluster için yetki matrisini döndürür.
+// ?format=yaml ile gereken en küçük ClusterRole YAML olarak döner.
+func clusterPermissionsHandler(w http.ResponseWriter, r *http.Request, clusterID int) {
+	if r.URL.Query().Get("format") == "yaml" {
+		data, err := yaml.Marshal(minimalClusterRole())
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"ClusterRole oluşturulamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	record, err := loadCluster(db, clusterID)
+	if err != nil {
+		http.Error(w, `{"error":"Cluster bulunamadı"}`, http.StatusNotFound)
+		return
+	}
+	client, err := newClusterClient(record)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	report := RunPermissionPreflight(ctx, client, r.URL.Query().Get("namespace"))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cluster":     record.Name,
+		"permissions": report,
+	})
+}
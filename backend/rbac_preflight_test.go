@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// preflightClient, verilen kaynakları reddeden, route'lar için hata dönen sahte bir istemci üretir
+func preflightClient(denied ...string) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		if attrs := review.Spec.ResourceAttributes; attrs != nil {
+			if attrs.Resource == "routes" {
+				return true, nil, errors.New("the server could not find the requested resource")
+			}
+			resource := attrs.Resource
+			if attrs.Subresource != "" {
+				resource += "/" + attrs.Subresource
+			}
+			for _, d := range denied {
+				if d == resource {
+					review.Status = authorizationv1.SubjectAccessReviewStatus{Denied: true}
+					return true, review, nil
+				}
+			}
+		}
+		review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "selfsubjectrulesreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectRulesReview)
+		review.Status = authorizationv1.SubjectRulesReviewStatus{
+			Incomplete:    true,
+			ResourceRules: []authorizationv1.ResourceRule{{Verbs: []string{"list"}, Resources: []string{"services"}}},
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func TestRunPermissionPreflightReportsMissingPermissions(t *testing.T) {
+	report := RunPermissionPreflight(context.Background(), preflightClient("statefulsets", "monitors/status"), "")
+
+	if report.AllFeaturesWork {
+		t.Fatalf("eksik yetkiler varken tüm özellikler çalışır görünmemeli")
+	}
+	if len(report.Features) != len(requiredPermissions) {
+		t.Fatalf("her özellik raporlanmalıydı: %d", len(report.Features))
+	}
+
+	broken := map[string]bool{}
+	for _, feature := range report.Features {
+		if !feature.Works {
+			broken[feature.Feature] = true
+		}
+		for _, check := range feature.Permissions {
+			switch {
+			case check.Resource == "statefulsets" || check.Resource == "monitors/status":
+				if check.Allowed || check.Reason != "Açıkça reddedildi" {
+					t.Errorf("%s reddedilmiş görünmeliydi: %+v", check.Resource, check)
+				}
+			case check.Resource == "routes":
+				if check.Allowed || !strings.Contains(check.Reason, "Yetki kontrolü yapılamadı") {
+					t.Errorf("kontrol edilemeyen yetki izinli sayılmamalı: %+v", check)
+				}
+			case !check.Allowed:
+				t.Errorf("beklenmeyen reddedilen yetki: %+v", check)
+			}
+		}
+	}
+	// Ortak yetkiler (deployments) izinli olsa da eksik tek yetki özelliği bozar
+	want := map[string]bool{"scale_to_zero": true, "monitor_crds": true, "route_endpoints": true}
+	if len(broken) != len(want) {
+		t.Errorf("beklenen bozuk özellikler %v, alınan %v", want, broken)
+	}
+	for feature := range want {
+		if !broken[feature] {
+			t.Errorf("%s çalışmaz olarak işaretlenmeliydi", feature)
+		}
+	}
+
+	if report.RulesReview == nil || report.RulesReview.Namespace != "default" || !report.RulesReview.Incomplete ||
+		len(report.RulesReview.ResourceRules) != 1 {
+		t.Errorf("kural özeti varsayılan namespace için eklenmeliydi: %+v", report.RulesReview)
+	}
+}
+
+func TestRunPermissionPreflightAllowedEverything(t *testing.T) {
+	report := RunPermissionPreflight(context.Background(), preflightClient(), "shop")
+	for _, feature := range report.Features {
+		if feature.Feature != "route_endpoints" && !feature.Works {
+			t.Errorf("%s çalışır görünmeliydi", feature.Feature)
+		}
+	}
+	if report.RulesReview == nil || report.RulesReview.Namespace != "shop" {
+		t.Errorf("kural özeti istenen namespace için olmalıydı: %+v", report.RulesReview)
+	}
+}
+
+func TestMinimalClusterRoleCoversEveryPermission(t *testing.T) {
+	role := minimalClusterRole()
+	allows := func(perm RequiredPermission) bool {
+		for _, rule := range role.Rules {
+			matches := false
+			if perm.Path != "" {
+				matches = len(rule.NonResourceURLs) == 1 && rule.NonResourceURLs[0] == perm.Path
+			} else {
+				matches = len(rule.APIGroups) == 1 && rule.APIGroups[0] == perm.Group &&
+					len(rule.Resources) == 1 && rule.Resources[0] == perm.Resource
+			}
+			if !matches {
+				continue
+			}
+			for _, verb := range rule.Verbs {
+				if verb == perm.Verb {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, feature := range requiredPermissions {
+		for _, perm := range feature.Permissions {
+			if !allows(perm) {
+				t.Errorf("%s için gereken %+v ClusterRole'de yok", feature.Feature, perm)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	clusterPermissionsHandler(rec, httptest.NewRequest("GET", "/api/v1/clusters/1/permissions?format=yaml", nil), 1)
+	if rec.Header().Get("Content-Type") != "application/yaml" || !strings.Contains(rec.Body.String(), "name: "+monitorClusterRoleName) {
+		t.Errorf("ClusterRole YAML olarak dönmeliydi: %s", rec.Body)
+	}
+}
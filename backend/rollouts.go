@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Rollout durumları
+const (
+	RolloutProgressing = "progressing"
+	RolloutComplete    = "complete"
+	RolloutFailed      = "failed"
+	RolloutPaused      = "paused"     // Deployment duraklatıldı (kubectl rollout pause)
+	RolloutSuperseded  = "superseded" // bitmeden yeni bir revizyon başladı
+	RolloutDeleted     = "deleted"    // Deployment rollout sırasında silindi
+)
+
+// defaultRolloutGracePeriod, rollout bittikten sonra alarmların bastırılmaya devam ettiği süre
+const defaultRolloutGracePeriod = 2 * time.Minute
+
+// defaultProgressDeadline, progressDeadlineSeconds verilmemiş Deployment'lar için Kubernetes varsayılanı
+const defaultProgressDeadline = 600 * time.Second
+
+// RolloutMarker, bir Deployment rollout'unun etkilenen servis için başlangıç/bitiş işareti
+type RolloutMarker struct {
+	ID         int64      `json:"id"`
+	ServiceID  int64      `json:"service_id,omitempty"`
+	Cluster    string     `json:"cluster"`
+	Namespace  string     `json:"namespace"`
+	Deployment string     `json:"deployment"`
+	Revision   string     `json:"revision"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	// DeadlineAt, açık işaretin alarm bastırması için geçerli sayıldığı son an
+	// (başlangıç + progressDeadlineSeconds); kapanmayan işaretler süresiz bastırmaz
+	DeadlineAt *time.Time `json:"deadline_at,omitempty"`
+}
+
+// rolloutSuppressionEnabled, rollout sırasında down alarmlarının bastırılıp bastırılmayacağını döndürür
+func rolloutSuppressionEnabled() bool {
+	return os.Getenv("ROLLOUT_SUPPRESS_ALERTS") == "true"
+}
+
+// rolloutGracePeriod, ROLLOUT_GRACE_PERIOD ortam değişkeninden bastırma süresini okur (ör. 90s, 5m)
+func rolloutGracePeriod() time.Duration {
+	if v := os.Getenv("ROLLOUT_GRACE_PERIOD"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+		log.Printf("Geçersiz ROLLOUT_GRACE_PERIOD değeri %q, varsayılan kullanılıyor", v)
+	}
+	return defaultRolloutGracePeriod
+}
+
+// deploymentRolloutState, Deployment'ın rollout durumunu ve revizyonunu döndürür
+func deploymentRolloutState(d *appsv1.Deployment) (string, string, string) {
+	revision := d.Annotations["deployment.kubernetes.io/revision"]
+
+	for _, cond := range d.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+			return RolloutFailed, revision, cond.Message
+		}
+	}
+
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	// Yalnızca ölçekleme (replika sayısı değişimi) rollout sayılmaz; eski revizyonun
+	// pod'ları varken veya yeni revizyon henüz tüm replikalara ulaşmamışken rollout sürer.
+	// Controller yeni spec'i henüz işlemediyse durum alanları ve revizyon eski spec'e aittir;
+	// revizyon boş döner ve işlendiğinde açık işarete yazılır.
+	switch {
+	case d.Spec.Paused:
+		return RolloutPaused, revision, "Deployment duraklatıldı"
+	case d.Status.ObservedGeneration < d.Generation:
+		return RolloutProgressing, "", "Yeni spec henüz işlenmedi"
+	case d.Status.UpdatedReplicas < desired || d.Status.Replicas > d.Status.UpdatedReplicas:
+		return RolloutProgressing, revision, fmt.Sprintf("%d/%d replika güncellendi", d.Status.UpdatedReplicas, desired)
+	}
+	return RolloutComplete, revision, ""
+}
+
+// RolloutWatcher, bir cluster'daki Deployment ve ReplicaSet rollout'larını izler
+// ve etkilenen servisler için başlangıç/bitiş işaretleri kaydeder
+type RolloutWatcher struct {
+	db          *sql.DB
+	client      kubernetes.Interface
+	cluster     string
+	deployments appslisters.DeploymentLister
+}
+
+// NewRolloutWatcher, yeni bir rollout izleyici oluşturur
+func NewRolloutWatcher(db *sql.DB, client kubernetes.Interface, cluster string) *RolloutWatcher {
+	return &RolloutWatcher{
+		db:      db,
+		client:  client,
+		cluster: cluster,
+	}
+}
+
+// Run, context iptal edilene kadar rollout'ları izler
+func (w *RolloutWatcher) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactory(w.client, discoveryResyncPeriod)
+	deploymentInformer := factory.Apps().V1().Deployments().Informer()
+	replicaSetInformer := factory.Apps().V1().ReplicaSets().Informer()
+	w.deployments = factory.Apps().V1().Deployments().Lister()
+
+	_, err := deploymentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if d, ok := obj.(*appsv1.Deployment); ok {
+				w.handleDeployment(d)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if d, ok := newObj.(*appsv1.Deployment); ok {
+				w.handleDeployment(d)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if d, ok := obj.(*appsv1.Deployment); ok {
+				w.closeMarkers(d.Namespace, d.Name, RolloutDeleted, "Deployment silindi")
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("deployment handler'ı kaydedilemedi: %v", err)
+	}
+
+	// Yeni ReplicaSet'ler rollout başlangıcını Deployment durumundan önce gösterebilir;
+	// sahibi olan Deployment yeniden değerlendirilir
+	_, err = replicaSetInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if rs, ok := obj.(*appsv1.ReplicaSet); ok {
+				w.handleReplicaSet(rs)
+			}
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			if rs, ok := newObj.(*appsv1.ReplicaSet); ok {
+				w.handleReplicaSet(rs)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("replicaset handler'ı kaydedilemedi: %v", err)
+	}
+
+	// 0 replikaya ölçeklenen iş yüklerinin tespiti Deployment'larla birlikte StatefulSet'leri de
+	// aynı önbellekten okur; StatefulSet yetkisi yoksa yalnızca Deployment'lar kullanılır
+	synced := []cache.InformerSynced{deploymentInformer.HasSynced, replicaSetInformer.HasSynced}
+	var statefulSets appslisters.StatefulSetLister
+	if _, err := w.client.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{Limit: 1}); err == nil {
+		synced = append(synced, factory.Apps().V1().StatefulSets().Informer().HasSynced)
+		statefulSets = factory.Apps().V1().StatefulSets().Lister()
+	} else {
+		log.Printf("%s cluster'ında StatefulSet'ler okunamadı, 0 replika tespiti yalnızca Deployment'larla yapılacak: %v", w.cluster, err)
+	}
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("%s cluster'ı için rollout önbelleği senkronize edilemedi", w.cluster)
+	}
+	if err := w.closeMissing(); err != nil {
+		log.Printf("%s cluster'ında silinen Deployment'ların rollout işaretleri kapatılamadı: %v", w.cluster, err)
+	}
+	workloadScale.Register(w.cluster, w.deployments, statefulSets)
+	log.Printf("%s cluster'ı için rollout izleyicisi başlatıldı", w.cluster)
+
+	<-ctx.Done()
+	workloadScale.Unregister(w.cluster)
+	factory.Shutdown()
+	return nil
+}
+
+// closeMissing, uygulama kapalıyken silinen Deployment'ların açık kalan işaretlerini kapatır
+func (w *RolloutWatcher) closeMissing() error {
+	type deployment struct{ namespace, name string }
+
+	// Tek bağlantılı havuzda satırlar açıkken güncelleme yapılamaz, önce topla
+	rows, err := w.db.Query(`
+		SELECT DISTINCT namespace, deployment FROM rollout_markers
+		WHERE cluster = ? AND ended_at IS NULL
+	`, w.cluster)
+	if err != nil {
+		return err
+	}
+	var open []deployment
+	for rows.Next() {
+		var d deployment
+		if err := rows.Scan(&d.namespace, &d.name); err != nil {
+			rows.Close()
+			return err
+		}
+		open = append(open, d)
+	}
+	rows.Close()
+
+	for _, d := range open {
+		if _, err := w.deployments.Deployments(d.namespace).Get(d.name); apierrors.IsNotFound(err) {
+			w.closeMarkers(d.namespace, d.name, RolloutDeleted, "Deployment silindi")
+		}
+	}
+	return nil
+}
+
+// handleReplicaSet, ReplicaSet'in sahibi olan Deployment'ı yeniden değerlendirir
+func (w *RolloutWatcher) handleReplicaSet(rs *appsv1.ReplicaSet) {
+	owner := metav1.GetControllerOf(rs)
+	if owner == nil || owner.Kind != "Deployment" || w.deployments == nil {
+		return
+	}
+	if d, err := w.deployments.Deployments(rs.Namespace).Get(owner.Name); err == nil {
+		w.handleDeployment(d)
+	}
+}
+
+// handleDeployment, Deployment durumuna göre rollout işaretlerini açar veya kapatır
+func (w *RolloutWatcher) handleDeployment(d *appsv1.Deployment) {
+	state, revision, message := deploymentRolloutState(d)
+
+	var openRevision sql.NullString
+	err := w.db.QueryRow(`
+		SELECT revision FROM rollout_markers
+		WHERE cluster = ? AND namespace = ? AND deployment = ? AND ended_at IS NULL
+		LIMIT 1
+	`, w.cluster, d.Namespace, d.Name).Scan(&openRevision)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("%s/%s rollout işaretleri okunamadı: %v", d.Namespace, d.Name, err)
+		return
+	}
+	open := err == nil
+
+	switch {
+	case state == RolloutProgressing && open && (openRevision.String == revision || revision == ""):
+		// Rollout sürüyor, işaret zaten açık
+	case state == RolloutProgressing && open && openRevision.String == "":
+		// İşaret spec işlenmeden açılmıştı; aynı rollout'un revizyonu artık biliniyor
+		if _, err := w.db.Exec(`
+			UPDATE rollout_markers SET revision = ?, message = ?
+			WHERE cluster = ? AND namespace = ? AND deployment = ? AND ended_at IS NULL
+		`, revision, message, w.cluster, d.Namespace, d.Name); err != nil {
+			log.Printf("%s/%s rollout işareti güncellenemedi: %v", d.Namespace, d.Name, err)
+		}
+	case state == RolloutProgressing:
+		if open {
+			w.closeMarkers(d.Namespace, d.Name, RolloutSuperseded, "")
+		}
+		w.openMarkers(d, revision, message)
+	case open:
+		w.closeMarkers(d.Namespace, d.Name, state, message)
+	}
+}
+
+// openMarkers, Deployment'ın pod'larını seçen her servis için rollout başlangıç işareti ekler
+func (w *RolloutWatcher) openMarkers(d *appsv1.Deployment, revision, message string) {
+	serviceIDs, err := servicesSelectingPods(w.db, w.cluster, d.Namespace, d.Spec.Template.Labels)
+	if err != nil {
+		log.Printf("%s/%s rollout'undan etkilenen servisler bulunamadı: %v", d.Namespace, d.Name, err)
+		return
+	}
+	// Servis eşleşmese de rollout durumunun izlenebilmesi için servissiz işaret tutulur
+	if len(serviceIDs) == 0 {
+		serviceIDs = []int64{0}
+	}
+
+	now := time.Now()
+	deadline := defaultProgressDeadline
+	if d.Spec.ProgressDeadlineSeconds != nil {
+		deadline = time.Duration(*d.Spec.ProgressDeadlineSeconds) * time.Second
+	}
+	for _, id := range serviceIDs {
+		serviceID := sql.NullInt64{Int64: id, Valid: id > 0}
+		_, err := w.db.Exec(`
+			INSERT INTO rollout_markers (service_id, cluster, namespace, deployment, revision, status, message, started_at, deadline_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, serviceID, w.cluster, d.Namespace, d.Name, revision, RolloutProgressing, message, now, now.Add(deadline))
+		if err != nil {
+			log.Printf("%s/%s rollout işareti kaydedilemedi: %v", d.Namespace, d.Name, err)
+			return
+		}
+	}
+	log.Printf("%s cluster'ında %s/%s rollout'u başladı (revizyon %s, %d servis)", w.cluster, d.Namespace, d.Name, revision, len(serviceIDs))
+}
+
+// closeMarkers, Deployment'ın açık rollout işaretlerini verilen durumla kapatır
+func (w *RolloutWatcher) closeMarkers(namespace, name, status, message string) {
+	result, err := w.db.Exec(`
+		UPDATE rollout_markers SET ended_at = ?, status = ?, message = COALESCE(NULLIF(?, ''), message)
+		WHERE cluster = ? AND namespace = ? AND deployment = ? AND ended_at IS NULL
+	`, time.Now(), status, message, w.cluster, namespace, name)
+	if err != nil {
+		log.Printf("%s/%s rollout işareti kapatılamadı: %v", namespace, name, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		log.Printf("%s cluster'ında %s/%s rollout'u bitti: %s", w.cluster, namespace, name, status)
+	}
+}
+
+// servicesSelectingPods, seçicisi verilen pod label'larıyla eşleşen servislerin ID'lerini döndürür
+func servicesSelectingPods(db *sql.DB, cluster, namespace string, podLabels map[string]string) ([]int64, error) {
+	rows, err := db.Query(`
+		SELECT id, selector FROM services
+		WHERE cluster = ? AND namespace = ? AND selector IS NOT NULL AND archived_at IS NULL
+	`, cluster, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		selector := map[string]string{}
+		if err := json.Unmarshal([]byte(data), &selector); err != nil || len(selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(selector).Matches(labels.Set(podLabels)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// rolloutMarkers, servisin verilen aralıkla kesişen rollout işaretlerini döndürür
+func rolloutMarkers(db *sql.DB, serviceID int, start, end time.Time) ([]RolloutMarker, error) {
+	rows, err := db.Query(`
+		SELECT id, service_id, cluster, namespace, deployment, revision, status, message, started_at, ended_at, deadline_at
+		FROM rollout_markers
+		WHERE service_id = ? AND started_at <= ? AND (ended_at IS NULL OR ended_at >= ?)
+		ORDER BY started_at ASC
+	`, serviceID, end, start)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	markers := []RolloutMarker{}
+	for rows.Next() {
+		var m RolloutMarker
+		var message sql.NullString
+		var endedAt, deadlineAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.ServiceID, &m.Cluster, &m.Namespace, &m.Deployment, &m.Revision,
+			&m.Status, &message, &m.StartedAt, &endedAt, &deadlineAt); err != nil {
+			return nil, err
+		}
+		m.Message = message.String
+		if endedAt.Valid {
+			m.EndedAt = &endedAt.Time
+		}
+		if deadlineAt.Valid {
+			m.DeadlineAt = &deadlineAt.Time
+		}
+		markers = append(markers, m)
+	}
+	return markers, rows.Err()
+}
+
+// suppressedByRollout, servis için verilen anda down alarmlarının rollout nedeniyle
+// bastırılıp bastırılmayacağını döndürür. Rollout bittikten sonra da grace period boyunca bastırılır.
+// Kapanmamış işaretler (ör. izleyici silinmeyi kaçırdıysa) en fazla progressDeadlineSeconds
+// artı grace period boyunca bastırır.
+func suppressedByRollout(db *sql.DB, serviceID int, at time.Time) (bool, *RolloutMarker) {
+	if !rolloutSuppressionEnabled() {
+		return false, nil
+	}
+	grace := rolloutGracePeriod()
+	markers, err := rolloutMarkers(db, serviceID, at.Add(-grace), at)
+	if err != nil {
+		return false, nil
+	}
+	for i := len(markers) - 1; i >= 0; i-- {
+		m := markers[i]
+		if m.EndedAt == nil {
+			deadline := m.StartedAt.Add(defaultProgressDeadline)
+			if m.DeadlineAt != nil {
+				deadline = *m.DeadlineAt
+			}
+			if at.After(deadline.Add(grace)) {
+				continue
+			}
+		}
+		return true, &m
+	}
+	return false, nil
+}
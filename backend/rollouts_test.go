@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestRolloutMarkersClosedForDeletedDeployments(t *testing.T) {
+	testDB := newTestDB(t)
+	t.Setenv("ROLLOUT_SUPPRESS_ALERTS", "true")
+	t.Setenv("ROLLOUT_GRACE_PERIOD", "2m")
+
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, selector) VALUES (1, 'api', 'shop', 'prod', 'service', '{"app":"api"}')`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, selector) VALUES (2, 'web', 'shop', 'prod', 'service', '{"app":"web"}')`)
+
+	deadline := int32(300)
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{
+				ProgressDeadlineSeconds: &deadline,
+				Template:                corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+			},
+		}
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	w := NewRolloutWatcher(testDB, nil, "prod")
+	w.deployments = appslisters.NewDeploymentLister(indexer)
+
+	api, web := deployment("api"), deployment("web")
+	indexer.Add(web)
+	w.openMarkers(api, "2", "")
+	w.openMarkers(web, "5", "")
+
+	// Uygulama kapalıyken silinen Deployment'ın işareti kapanır, var olanınki açık kalır
+	if err := w.closeMissing(); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	testDB.QueryRow(`SELECT status FROM rollout_markers WHERE deployment = 'api'`).Scan(&status)
+	if status != RolloutDeleted {
+		t.Errorf("silinen Deployment'ın işareti kapanmalıydı: %s", status)
+	}
+	var open int
+	testDB.QueryRow(`SELECT COUNT(*) FROM rollout_markers WHERE deployment = 'web' AND ended_at IS NULL`).Scan(&open)
+	if open != 1 {
+		t.Errorf("var olan Deployment'ın işareti açık kalmalıydı")
+	}
+
+	// Kapanmayan işaret progressDeadlineSeconds + grace period sonrasında bastırmaz
+	now := time.Now()
+	if suppressed, _ := suppressedByRollout(testDB, 2, now.Add(4*time.Minute)); !suppressed {
+		t.Errorf("rollout sürerken bastırılmalıydı")
+	}
+	if suppressed, _ := suppressedByRollout(testDB, 2, now.Add(6*time.Minute)); !suppressed {
+		t.Errorf("grace period içinde bastırılmalıydı")
+	}
+	if suppressed, _ := suppressedByRollout(testDB, 2, now.Add(8*time.Minute)); suppressed {
+		t.Errorf("süresi aşan açık işaret bastırmamalı")
+	}
+
+	// Informer silme olayı da işareti kapatır
+	w.closeMarkers(web.Namespace, web.Name, RolloutDeleted, "Deployment silindi")
+	if suppressed, _ := suppressedByRollout(testDB, 2, time.Now().Add(3*time.Minute)); suppressed {
+		t.Errorf("kapanan işaret grace period sonrasında bastırmamalı")
+	}
+}
+
+// rolloutDeployment, verilen durum alanlarıyla test Deployment'ı üretir
+func rolloutDeployment(replicas, updated, total int32, generation, observed int64, revision string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "api",
+			Namespace:   "shop",
+			Generation:  generation,
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": revision},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "api"}}},
+		},
+		Status: appsv1.DeploymentStatus{ObservedGeneration: observed, UpdatedReplicas: updated, Replicas: total},
+	}
+}
+
+func TestDeploymentRolloutState(t *testing.T) {
+	paused := rolloutDeployment(3, 1, 4, 2, 2, "3")
+	paused.Spec.Paused = true
+	failed := rolloutDeployment(3, 1, 4, 2, 2, "3")
+	failed.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse,
+		Reason: "ProgressDeadlineExceeded", Message: "ReplicaSet api-7d9 has timed out progressing.",
+	}}
+	noReplicas := rolloutDeployment(0, 0, 0, 1, 1, "1")
+	noReplicas.Spec.Replicas = nil
+
+	cases := []struct {
+		name     string
+		d        *appsv1.Deployment
+		state    string
+		revision string
+		message  string
+	}{
+		{"tamamlandı", rolloutDeployment(3, 3, 3, 2, 2, "3"), RolloutComplete, "3", ""},
+		{"yeni replikalar bekleniyor", rolloutDeployment(3, 1, 3, 2, 2, "3"), RolloutProgressing, "3", "1/3 replika güncellendi"},
+		{"eski pod'lar kapanıyor", rolloutDeployment(3, 3, 4, 2, 2, "3"), RolloutProgressing, "3", "3/3 replika güncellendi"},
+		{"spec işlenmedi", rolloutDeployment(3, 3, 3, 3, 2, "3"), RolloutProgressing, "", "Yeni spec henüz işlenmedi"},
+		{"duraklatıldı", paused, RolloutPaused, "3", "Deployment duraklatıldı"},
+		{"süre aşıldı", failed, RolloutFailed, "3", "ReplicaSet api-7d9 has timed out progressing."},
+		{"replika varsayılanı 1", noReplicas, RolloutProgressing, "1", "0/1 replika güncellendi"},
+	}
+	for _, c := range cases {
+		state, revision, message := deploymentRolloutState(c.d)
+		if state != c.state || revision != c.revision || message != c.message {
+			t.Errorf("%s: beklenen %s %q %q, alınan %s %q %q", c.name, c.state, c.revision, c.message, state, revision, message)
+		}
+	}
+}
+
+func TestHandleDeploymentOpensAndClosesMarkers(t *testing.T) {
+	testDB := newTestDB(t)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, selector) VALUES (1, 'api', 'shop', 'prod', 'service', '{"app":"api"}')`)
+	w := NewRolloutWatcher(testDB, nil, "prod")
+
+	type marker struct {
+		Revision, Status string
+		Open             bool
+	}
+	markers := func() []marker {
+		rows, err := testDB.Query(`SELECT revision, status, ended_at IS NULL FROM rollout_markers WHERE service_id = 1 ORDER BY id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var result []marker
+		for rows.Next() {
+			var m marker
+			rows.Scan(&m.Revision, &m.Status, &m.Open)
+			result = append(result, m)
+		}
+		return result
+	}
+
+	paused := rolloutDeployment(3, 1, 4, 4, 4, "4")
+	paused.Spec.Paused = true
+
+	steps := []struct {
+		name string
+		d    *appsv1.Deployment
+		want []marker
+	}{
+		{"tamamlanmış Deployment işaret açmaz", rolloutDeployment(3, 3, 3, 1, 1, "2"), nil},
+		{"spec işlenmeden rollout başlar", rolloutDeployment(3, 3, 3, 2, 1, "2"),
+			[]marker{{"", RolloutProgressing, true}}},
+		{"revizyon öğrenilince aynı işarete yazılır", rolloutDeployment(3, 1, 4, 2, 2, "3"),
+			[]marker{{"3", RolloutProgressing, true}}},
+		{"rollout biter", rolloutDeployment(3, 3, 3, 2, 2, "3"),
+			[]marker{{"3", RolloutComplete, false}}},
+		{"yalnızca ölçekleme rollout değildir", rolloutDeployment(5, 5, 5, 3, 3, "3"),
+			[]marker{{"3", RolloutComplete, false}}},
+		{"yeni rollout", rolloutDeployment(5, 2, 6, 4, 4, "4"),
+			[]marker{{"3", RolloutComplete, false}, {"4", RolloutProgressing, true}}},
+		{"bitmeden yeni revizyon", rolloutDeployment(5, 1, 7, 5, 5, "5"),
+			[]marker{{"3", RolloutComplete, false}, {"4", RolloutSuperseded, false}, {"5", RolloutProgressing, true}}},
+		{"duraklatma işareti kapatır", paused,
+			[]marker{{"3", RolloutComplete, false}, {"4", RolloutSuperseded, false}, {"5", RolloutPaused, false}}},
+	}
+	for _, step := range steps {
+		w.handleDeployment(step.d)
+		if got := markers(); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: beklenen %+v, alınan %+v", step.name, step.want, got)
+		}
+	}
+}
+
+func TestServiceDeleteRemovesRolloutMarkers(t *testing.T) {
+	testDB := newTestDB(t)
+	previousDB, previousMonitor := db, uptimeMonitor
+	db = testDB
+	// Bekleyen bir yeniden yükleme zamanlayıcısı, silme sonrası yeniden yüklemenin çalışmasını engeller
+	uptimeMonitor = &UptimeMonitor{reloadTimer: time.AfterFunc(time.Hour, func() {})}
+	t.Cleanup(func() {
+		uptimeMonitor.reloadTimer.Stop()
+		db, uptimeMonitor = previousDB, previousMonitor
+	})
+
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, selector) VALUES (1, 'api', 'shop', 'prod', 'service', '{"app":"api"}')`)
+	testDB.Exec(`INSERT INTO services (id, name, namespace, cluster, type, selector) VALUES (2, 'web', 'shop', 'prod', 'service', '{"app":"web"}')`)
+	w := NewRolloutWatcher(testDB, nil, "prod")
+	w.handleDeployment(rolloutDeployment(3, 1, 3, 2, 2, "3"))
+	testDB.Exec(`INSERT INTO rollout_markers (service_id, cluster, namespace, deployment, revision, status, started_at)
+		VALUES (2, 'prod', 'shop', 'web', '7', 'complete', CURRENT_TIMESTAMP)`)
+
+	rec := httptest.NewRecorder()
+	servicesHandler(rec, httptest.NewRequest("DELETE", "/api/v1/services/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("servis silinemedi: %d %s", rec.Code, rec.Body)
+	}
+
+	var deleted, kept int
+	testDB.QueryRow(`SELECT COUNT(*) FROM rollout_markers WHERE service_id = 1`).Scan(&deleted)
+	testDB.QueryRow(`SELECT COUNT(*) FROM rollout_markers WHERE service_id = 2`).Scan(&kept)
+	if deleted != 0 || kept != 1 {
+		t.Errorf("yalnızca silinen servisin rollout işaretleri silinmeliydi: %d %d", deleted, kept)
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Secret referans türleri
+const (
+	SecretRefEnv        = "env"       // env:DEGISKEN_ADI
+	SecretRefFile       = "file"      // file:/run/secrets/parola
+	SecretRefKubernetes = "secretRef" // secretRef: ns/ad#anahtar veya secretRef: cluster/ns/ad#anahtar
+)
+
+// maskedSecretValue, API yanıtlarında düz metin sırların yerine gösterilir
+const maskedSecretValue = "********"
+
+// secretRefFailureTTL, çözülemeyen referansların yeniden denenmeden önce önbellekte kalma süresi
+const secretRefFailureTTL = 30 * time.Second
+
+// SecretReference, bir sırrın değeri yerine nereden okunacağını tanımlar
+type SecretReference struct {
+	Kind      string
+	Name      string // ortam değişkeni adı, dosya yolu veya Secret adı
+	Cluster   string // yalnızca secretRef; boşsa varsayılan cluster kullanılır
+	Namespace string
+	Key       string
+}
+
+// String, referansı saklandığı biçimde döndürür
+func (r SecretReference) String() string {
+	switch r.Kind {
+	case SecretRefKubernetes:
+		path := r.Namespace + "/" + r.Name + "#" + r.Key
+		if r.Cluster != "" {
+			path = r.Cluster + "/" + path
+		}
+		return SecretRefKubernetes + ": " + path
+	default:
+		return r.Kind + ":" + r.Name
+	}
+}
+
+// SecretRefError, bir referansın çözülemediğini belirtir. Hedef kesintisinden
+// ayırt edilebilmesi için kontrol sonuçlarında yapılandırma hatası olarak raporlanır.
+type SecretRefError struct {
+	Ref string
+	Err error
+}
+
+func (e *SecretRefError) Error() string {
+	return fmt.Sprintf("%s referansı çözülemedi: %v", e.Ref, e.Err)
+}
+
+func (e *SecretRefError) Unwrap() error {
+	return e.Err
+}
+
+// parseSecretReference, değerin bir referans olup olmadığını kontrol eder ve ayrıştırır.
+// Referans olmayan değerler için ok false döner.
+func parseSecretReference(value string) (ref SecretReference, ok bool, err error) {
+	value = strings.TrimSpace(value)
+
+	switch {
+	case strings.HasPrefix(value, SecretRefEnv+":"):
+		ref = SecretReference{Kind: SecretRefEnv, Name: strings.TrimSpace(strings.TrimPrefix(value, SecretRefEnv+":"))}
+	case strings.HasPrefix(value, SecretRefFile+":"):
+		ref = SecretReference{Kind: SecretRefFile, Name: strings.TrimSpace(strings.TrimPrefix(value, SecretRefFile+":"))}
+	case strings.HasPrefix(value, SecretRefKubernetes+":"):
+		ref = SecretReference{Kind: SecretRefKubernetes}
+		path := strings.TrimSpace(strings.TrimPrefix(value, SecretRefKubernetes+":"))
+
+		hash := strings.LastIndex(path, "#")
+		if hash < 0 {
+			return ref, true, fmt.Errorf("secretRef biçimi ns/ad#anahtar olmalı: %q", value)
+		}
+		ref.Key = path[hash+1:]
+
+		parts := strings.Split(path[:hash], "/")
+		switch len(parts) {
+		case 2:
+			ref.Namespace, ref.Name = parts[0], parts[1]
+		case 3:
+			ref.Cluster, ref.Namespace, ref.Name = parts[0], parts[1], parts[2]
+		default:
+			return ref, true, fmt.Errorf("secretRef biçimi ns/ad#anahtar olmalı: %q", value)
+		}
+		if ref.Namespace == "" || ref.Key == "" {
+			return ref, true, fmt.Errorf("secretRef biçimi ns/ad#anahtar olmalı: %q", value)
+		}
+	default:
+		return ref, false, nil
+	}
+
+	if ref.Name == "" {
+		return ref, true, fmt.Errorf("referans hedefi boş: %q", value)
+	}
+	return ref, true, nil
+}
+
+// isSecretReference, değerin bir referans olup olmadığını döndürür
+func isSecretReference(value string) bool {
+	_, ok, _ := parseSecretReference(value)
+	return ok
+}
+
+// secretCacheEntry, çözülmüş bir referansın önbellek kaydı
+type secretCacheEntry struct {
+	value     string
+	err       error
+	expiresAt time.Time
+}
+
+// SecretResolver, referansları çözer ve sonuçları belirli bir süre önbellekte tutar.
+// Süre dolduğunda değer kaynaktan yeniden okunur, böylece döndürülen sırlar kendiliğinden yenilenir.
+//
+// Referanslar API istemcilerinin seçtiği adreslere gönderildiğinden env: yalnızca envPrefix ile
+// başlayan değişkenleri, file: yalnızca dir içindeki dosyaları okuyabilir. Ayar boşsa tür kapalıdır.
+type SecretResolver struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	envPrefix string
+	dir       string
+	entries   map[string]secretCacheEntry
+}
+
+// NewSecretResolver, yeni bir referans çözücü oluşturur
+func NewSecretResolver(ttl time.Duration, envPrefix, dir string) *SecretResolver {
+	return &SecretResolver{
+		ttl:       ttl,
+		envPrefix: envPrefix,
+		dir:       dir,
+		entries:   make(map[string]secretCacheEntry),
+	}
+}
+
+// secretRefCacheTTL, çözülen referansların önbellekte kalma süresi
+func secretRefCacheTTL() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("SECRET_REF_CACHE_TTL")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
+
+// reservedSecretEnvPrefix, env: referansıyla hiçbir zaman okunamayan değişkenler (ana anahtarlar)
+const reservedSecretEnvPrefix = "SECRETS_"
+
+// secretRefEnvPrefix, env: referanslarının okuyabileceği ortam değişkenlerinin ön eki
+func secretRefEnvPrefix() string {
+	return strings.TrimSpace(os.Getenv("SECRET_REF_ENV_PREFIX"))
+}
+
+// secretRefDir, file: referanslarının okuyabileceği dizin
+func secretRefDir() string {
+	return strings.TrimSpace(os.Getenv("SECRET_REF_DIR"))
+}
+
+// secretResolver, uygulama genelinde kullanılan referans çözücü
+var secretResolver = NewSecretResolver(secretRefCacheTTL(), secretRefEnvPrefix(), secretRefDir())
+
+// Check, referansın bu çözücünün okumasına izin verilen bir kaynağı gösterip göstermediğini kontrol eder
+func (s *SecretResolver) Check(ref SecretReference) error {
+	switch ref.Kind {
+	case SecretRefEnv:
+		if s.envPrefix == "" {
+			return fmt.Errorf("env: referansları kapalı (SECRET_REF_ENV_PREFIX tanımlı değil)")
+		}
+		if strings.HasPrefix(ref.Name, reservedSecretEnvPrefix) || !strings.HasPrefix(ref.Name, s.envPrefix) {
+			return fmt.Errorf("%s ortam değişkeni okunamaz, yalnızca %s ile başlayan değişkenlere izin verilir", ref.Name, s.envPrefix)
+		}
+	case SecretRefFile:
+		_, err := s.secretFilePath(ref.Name)
+		return err
+	}
+	return nil
+}
+
+// secretFilePath, file: referansının yolunu sembolik bağlantılar ve ".." çözülmüş olarak döndürür.
+// Göreli yollar sır dizinine göre çözülür; dizin dışına çıkan yollar reddedilir.
+func (s *SecretResolver) secretFilePath(name string) (string, error) {
+	if s.dir == "" {
+		return "", fmt.Errorf("file: referansları kapalı (SECRET_REF_DIR tanımlı değil)")
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err == nil {
+		dir, err = filepath.EvalSymlinks(dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("sır dizini okunamadı: %v", err)
+	}
+
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Clean(name))
+	if err != nil {
+		return "", fmt.Errorf("dosya okunamadı: %v", err)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s sır dizini (%s) dışında", name, s.dir)
+	}
+	return path, nil
+}
+
+// Resolve, değer bir referanssa çözer; değilse aynen döndürür.
+// defaultCluster, cluster belirtilmemiş secretRef'lerin okunacağı cluster'dır.
+func (s *SecretResolver) Resolve(value, defaultCluster string) (string, error) {
+	ref, ok, err := parseSecretReference(value)
+	if !ok {
+		return value, nil
+	}
+	if err != nil {
+		return "", &SecretRefError{Ref: value, Err: err}
+	}
+	if ref.Kind == SecretRefKubernetes && ref.Cluster == "" {
+		ref.Cluster = defaultCluster
+	}
+
+	if err := s.Check(ref); err != nil {
+		return "", &SecretRefError{Ref: ref.String(), Err: err}
+	}
+
+	key := ref.String()
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, cached := s.entries[key]
+	s.mu.Unlock()
+	if cached && now.Before(entry.expiresAt) {
+		return entry.value, entry.err
+	}
+
+	resolved, err := s.fetch(ref)
+	entry = secretCacheEntry{value: resolved, expiresAt: now.Add(s.ttl)}
+	if err != nil {
+		entry = secretCacheEntry{err: &SecretRefError{Ref: key, Err: err}, expiresAt: now.Add(secretRefFailureTTL)}
+	}
+
+	s.mu.Lock()
+	s.entries[key] = entry
+	s.mu.Unlock()
+	return entry.value, entry.err
+}
+
+// Invalidate, önbelleği temizler; referanslar bir sonraki kullanımda yeniden okunur
+func (s *SecretResolver) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = make(map[string]secretCacheEntry)
+}
+
+// fetch, referansın değerini kaynağından okur
+func (s *SecretResolver) fetch(ref SecretReference) (string, error) {
+	switch ref.Kind {
+	case SecretRefEnv:
+		value, ok := os.LookupEnv(ref.Name)
+		if !ok {
+			return "", fmt.Errorf("%s ortam değişkeni tanımlı değil", ref.Name)
+		}
+		return value, nil
+
+	case SecretRefFile:
+		path, err := s.secretFilePath(ref.Name)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("dosya okunamadı: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	case SecretRefKubernetes:
+		client, _, err := clusterManager.Client(ref.Cluster)
+		if err != nil {
+			return "", err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		secret, err := client.CoreV1().Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("Secret okunamadı: %v", err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("Secret içinde %q anahtarı yok", ref.Key)
+		}
+		return string(value), nil
+	}
+	return "", fmt.Errorf("bilinmeyen referans türü: %s", ref.Kind)
+}
+
+// validateSecretValue, kaydedilmeden önce değerin geçerli bir referans veya düz değer olduğunu
+// ve referansın okunmasına izin verilen bir kaynağı gösterdiğini doğrular
+func validateSecretValue(field, value string) error {
+	ref, ok, err := parseSecretReference(value)
+	if !ok {
+		return nil
+	}
+	if err == nil {
+		err = secretResolver.Check(ref)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", field, err)
+	}
+	return nil
+}
+
+// secretView, bir sırrı API'de gösterilecek biçime çevirir: referanslar olduğu gibi,
+// düz metin değerler maskelenmiş olarak döner
+func secretView(value string) string {
+	if value == "" || isSecretReference(value) {
+		return value
+	}
+	return maskedSecretValue
+}
+
+// secretRefTransport, istek başına token referansını çözerek Authorization başlığını ekler.
+// Referans çözücü önbellek kullandığından token kaynağında yenilendiğinde istemci de yenisini kullanır.
+type secretRefTransport struct {
+	ref     string
+	cluster string
+	next    http.RoundTripper
+}
+
+func (t *secretRefTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := secretResolver.Resolve(t.ref, t.cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
+
+// CheckAuth, bir servisin uptime kontrolünde kullanılacak kimlik bilgileri.
+// Her değer düz metin veya referans (env:, file:, secretRef:) olabilir.
+type CheckAuth struct {
+	Username string
+	Password string
+	Headers  map[string]string
+}
+
+// CheckAuthInput, servis ekleme ve güncelleme isteklerindeki kimlik bilgisi alanları.
+// Gönderilmeyen (nil) alanlar değiştirilmez.
+type CheckAuthInput struct {
+	Username *string            `json:"check_username"`
+	Password *string            `json:"check_password"`
+	Headers  *map[string]string `json:"check_headers"`
+}
+
+// Validate, gönderilen değerlerdeki referansların biçimini doğrular
+func (in CheckAuthInput) Validate() error {
+	if in.Username != nil {
+		if err := validateSecretValue("check_username", *in.Username); err != nil {
+			return err
+		}
+	}
+	if in.Password != nil {
+		if err := validateSecretValue("check_password", *in.Password); err != nil {
+			return err
+		}
+	}
+	if in.Headers != nil {
+		for name, value := range *in.Headers {
+			if err := validateSecretValue("check_headers."+name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveCheckAuth, gönderilen kimlik bilgisi alanlarını şifreleyerek servise yazar
+func saveCheckAuth(db *sql.DB, serviceID int64, in CheckAuthInput) error {
+	if in.Username != nil {
+		if _, err := db.Exec("UPDATE services SET check_username = ? WHERE id = ?", *in.Username, serviceID); err != nil {
+			return err
+		}
+	}
+	if in.Password != nil {
+		password, err := encryptSecret(*in.Password)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec("UPDATE services SET check_password = ? WHERE id = ?", password, serviceID); err != nil {
+			return err
+		}
+	}
+	if in.Headers != nil {
+		data, err := json.Marshal(*in.Headers)
+		if err != nil {
+			return err
+		}
+		headers, err := encryptSecret(string(data))
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec("UPDATE services SET check_headers = ? WHERE id = ?", headers, serviceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeCheckAuth, veritabanındaki (şifreli) kolon değerlerinden CheckAuth oluşturur
+func decodeCheckAuth(username, password, headers sql.NullString) (CheckAuth, error) {
+	auth := CheckAuth{Username: username.String, Headers: map[string]string{}}
+
+	var err error
+	if auth.Password, err = decryptSecret(password.String); err != nil {
+		return auth, err
+	}
+
+	rawHeaders, err := decryptSecret(headers.String)
+	if err != nil {
+		return auth, err
+	}
+	if rawHeaders != "" {
+		if err := json.Unmarshal([]byte(rawHeaders), &auth.Headers); err != nil {
+			return auth, fmt.Errorf("check_headers ayrıştırılamadı: %v", err)
+		}
+	}
+	return auth, nil
+}
+
+// View, kimlik bilgilerini sırları açığa çıkarmadan API yanıtına ekler
+func (a CheckAuth) View(out map[string]interface{}) {
+	headers := make(map[string]string, len(a.Headers))
+	for name, value := range a.Headers {
+		headers[name] = secretView(value)
+	}
+
+	out["check_username"] = a.Username
+	out["check_password_set"] = a.Password != ""
+	if isSecretReference(a.Password) {
+		out["check_password_ref"] = a.Password
+	}
+	out["check_headers"] = headers
+}
+
+// Resolve, referansları çözerek kontrolde kullanılacak gerçek değerleri döndürür
+func (a CheckAuth) Resolve(cluster string) (CheckAuth, error) {
+	resolved := CheckAuth{Headers: make(map[string]string, len(a.Headers))}
+
+	var err error
+	if resolved.Username, err = secretResolver.Resolve(a.Username, cluster); err != nil {
+		return resolved, err
+	}
+	if resolved.Password, err = secretResolver.Resolve(a.Password, cluster); err != nil {
+		return resolved, err
+	}
+	for name, value := range a.Headers {
+		if resolved.Headers[name], err = secretResolver.Resolve(value, cluster); err != nil {
+			return resolved, err
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretResolverRestrictsEnvReferences(t *testing.T) {
+	t.Setenv("MONITOR_SECRET_API_TOKEN", "api-token")
+	t.Setenv("SECRETS_MASTER_KEY", "ana-anahtar")
+	t.Setenv("HOME_TOKEN", "başka")
+
+	resolver := NewSecretResolver(time.Minute, "MONITOR_SECRET_", "")
+	if got, err := resolver.Resolve("env:MONITOR_SECRET_API_TOKEN", ""); err != nil || got != "api-token" {
+		t.Errorf("ön ekli değişken okunmalıydı: %q %v", got, err)
+	}
+	for _, ref := range []string{"env:HOME_TOKEN", "env:SECRETS_MASTER_KEY"} {
+		var refErr *SecretRefError
+		if got, err := resolver.Resolve(ref, ""); !errors.As(err, &refErr) || got != "" {
+			t.Errorf("%s reddedilmeliydi: %q %v", ref, got, err)
+		}
+	}
+
+	// SECRETS_ ile başlayan değişkenler ön ek onlara izin verse de okunamaz
+	resolver = NewSecretResolver(time.Minute, "SECRETS_", "")
+	if _, err := resolver.Resolve("env:SECRETS_MASTER_KEY", ""); err == nil {
+		t.Errorf("ana anahtar okunmamalıydı")
+	}
+
+	// Ön ek tanımlı değilse env: referansları kapalıdır
+	resolver = NewSecretResolver(time.Minute, "", "")
+	if _, err := resolver.Resolve("env:MONITOR_SECRET_API_TOKEN", ""); err == nil || !strings.Contains(err.Error(), "SECRET_REF_ENV_PREFIX") {
+		t.Errorf("env: referansları kapalı olmalıydı: %v", err)
+	}
+}
+
+func TestSecretResolverRestrictsFileReferences(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "secrets")
+	os.Mkdir(dir, 0o700)
+	os.WriteFile(filepath.Join(dir, "db-password"), []byte("parola\n"), 0o600)
+	os.WriteFile(filepath.Join(root, "outside"), []byte("dışarıdaki"), 0o600)
+	if err := os.Symlink(filepath.Join(root, "outside"), filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	os.Symlink(filepath.Join(dir, "db-password"), filepath.Join(root, "inside-link"))
+
+	resolver := NewSecretResolver(time.Minute, "", dir)
+	for _, ref := range []string{"file:" + filepath.Join(dir, "db-password"), "file:db-password", "file:" + filepath.Join(root, "inside-link")} {
+		if got, err := resolver.Resolve(ref, ""); err != nil || got != "parola" {
+			t.Errorf("%s dizin içindeki dosyayı okumalıydı: %q %v", ref, got, err)
+		}
+	}
+	for _, ref := range []string{
+		"file:" + filepath.Join(root, "outside"),
+		"file:" + dir + "/../outside",
+		"file:../outside",
+		"file:escape",
+		"file:/etc/passwd",
+		"file:" + dir,
+	} {
+		if got, err := resolver.Resolve(ref, ""); err == nil || got != "" {
+			t.Errorf("%s reddedilmeliydi: %q", ref, got)
+		}
+	}
+
+	// Dizin tanımlı değilse file: referansları kapalıdır
+	resolver = NewSecretResolver(time.Minute, "", "")
+	if _, err := resolver.Resolve("file:"+filepath.Join(dir, "db-password"), ""); err == nil || !strings.Contains(err.Error(), "SECRET_REF_DIR") {
+		t.Errorf("file: referansları kapalı olmalıydı: %v", err)
+	}
+}
+
+func TestValidateSecretValueRejectsDisallowedReferences(t *testing.T) {
+	previous := secretResolver
+	secretResolver = NewSecretResolver(time.Minute, "MONITOR_SECRET_", t.TempDir())
+	t.Cleanup(func() { secretResolver = previous })
+
+	for _, value := range []string{"düz-değer", "", "env:MONITOR_SECRET_TOKEN", "secretRef: shop/api#token"} {
+		if err := validateSecretValue("check_password", value); err != nil {
+			t.Errorf("%q kabul edilmeliydi: %v", value, err)
+		}
+	}
+	for _, value := range []string{"env:SECRETS_MASTER_KEY", "env:PATH", "file:/etc/shadow", "secretRef: eksik"} {
+		if err := validateSecretValue("check_password", value); err == nil || !strings.HasPrefix(err.Error(), "check_password: ") {
+			t.Errorf("%q reddedilmeliydi: %v", value, err)
+		}
+	}
+
+	pass := "env:SECRETS_MASTER_KEY"
+	if err := (CheckAuthInput{Password: &pass}).Validate(); err == nil {
+		t.Errorf("servis kimlik bilgisi ana anahtarı göstermemeli")
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// encryptedSecretPrefix, şifrelenmiş değerleri düz metinden ayırır.
+// Biçim: enc:v1:<anahtar-id>:<sarılmış-veri-anahtarı>:<şifreli-metin>
+const encryptedSecretPrefix = "enc:v1:"
+
+// SecretKeyring, ana anahtarları tutar. Yeni değerler her zaman güncel anahtarla
+// sarılır; eski anahtarlar yalnızca rotasyon sırasında çözmek için kullanılır.
+type SecretKeyring struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+// secretKeyring, uygulama genelinde kullanılan anahtar halkası.
+// nil ise ana anahtar yapılandırılmamıştır ve sırlar düz metin saklanır.
+var secretKeyring *SecretKeyring
+
+// NewSecretKeyring, güncel ve (varsa) eski ana anahtarlardan bir anahtar halkası oluşturur
+func NewSecretKeyring(current []byte, previous ...[]byte) (*SecretKeyring, error) {
+	if len(current) != 32 {
+		return nil, fmt.Errorf("ana anahtar 32 bayt olmalı, %d bayt verildi", len(current))
+	}
+
+	k := &SecretKeyring{
+		currentID: secretKeyID(current),
+		keys:      map[string][]byte{},
+	}
+	k.keys[k.currentID] = current
+
+	for _, key := range previous {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("eski ana anahtar 32 bayt olmalı, %d bayt verildi", len(key))
+		}
+		k.keys[secretKeyID(key)] = key
+	}
+	return k, nil
+}
+
+// secretKeyID, anahtarın kendisini açığa çıkarmadan kısa bir kimlik üretir
+func secretKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// parseMasterKey, base64 veya hex kodlu 32 baytlık ana anahtarı çözer
+func parseMasterKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, fmt.Errorf("ana anahtar base64 veya hex kodlu 32 bayt olmalı")
+}
+
+// readMasterKey, anahtarı önce <name>_FILE ile verilen dosyadan, yoksa <name> ortam değişkeninden okur
+func readMasterKey(name string) ([]byte, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s dosyası okunamadı: %v", path, err)
+		}
+		return parseMasterKey(string(data))
+	}
+	if value := os.Getenv(name); value != "" {
+		return parseMasterKey(value)
+	}
+	return nil, nil
+}
+
+// LoadSecretKeyring, ana anahtarları ortamdan yükler.
+// SECRETS_MASTER_KEY(_FILE) güncel anahtar, SECRETS_PREVIOUS_MASTER_KEY(_FILE) rotasyondan önceki anahtardır.
+// Hiç anahtar verilmemişse nil döner.
+func LoadSecretKeyring() (*SecretKeyring, error) {
+	current, err := readMasterKey("SECRETS_MASTER_KEY")
+	if err != nil {
+		return nil, err
+	}
+	previous, err := readMasterKey("SECRETS_PREVIOUS_MASTER_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		if previous != nil {
+			return nil, fmt.Errorf("SECRETS_PREVIOUS_MASTER_KEY yalnızca SECRETS_MASTER_KEY ile birlikte kullanılabilir")
+		}
+		return nil, nil
+	}
+	if previous == nil {
+		return NewSecretKeyring(current)
+	}
+	return NewSecretKeyring(current, previous)
+}
+
+// Encrypt, değeri rastgele bir veri anahtarıyla şifreler ve veri anahtarını ana anahtarla sarar
+func (k *SecretKeyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("veri anahtarı üretilemedi: %v", err)
+	}
+
+	ciphertext, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := sealAESGCM(k.keys[k.currentID], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedSecretPrefix + k.currentID + ":" +
+		base64.StdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt, şifrelenmiş değeri çözer
+func (k *SecretKeyring) Decrypt(value string) (string, error) {
+	keyID, wrappedKey, ciphertext, err := splitEncryptedSecret(value)
+	if err != nil {
+		return "", err
+	}
+
+	masterKey, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%s kimlikli ana anahtar bulunamadı", keyID)
+	}
+
+	dataKey, err := openAESGCM(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("veri anahtarı çözülemedi: %v", err)
+	}
+	plaintext, err := openAESGCM(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("sır çözülemedi: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap, veri anahtarını güncel ana anahtarla yeniden sarar; şifreli metin değişmez.
+// Değer zaten güncel anahtarla sarılmışsa aynen döner.
+func (k *SecretKeyring) Rewrap(value string) (string, error) {
+	keyID, wrappedKey, ciphertext, err := splitEncryptedSecret(value)
+	if err != nil {
+		return "", err
+	}
+	if keyID == k.currentID {
+		return value, nil
+	}
+
+	masterKey, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%s kimlikli ana anahtar bulunamadı", keyID)
+	}
+	dataKey, err := openAESGCM(masterKey, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("veri anahtarı çözülemedi: %v", err)
+	}
+	rewrapped, err := sealAESGCM(k.keys[k.currentID], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedSecretPrefix + k.currentID + ":" +
+		base64.StdEncoding.EncodeToString(rewrapped) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// splitEncryptedSecret, şifreli değeri anahtar kimliği, sarılmış anahtar ve şifreli metne ayırır
+func splitEncryptedSecret(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, encryptedSecretPrefix), ":")
+	if !isEncryptedSecret(value) || len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("geçersiz şifreli değer biçimi")
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("sarılmış anahtar çözülemedi: %v", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("şifreli metin çözülemedi: %v", err)
+	}
+	return parts[0], wrappedKey, ciphertext, nil
+}
+
+// sealAESGCM, veriyi AES-256-GCM ile şifreler; nonce çıktının başına eklenir
+func sealAESGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("nonce üretilemedi: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAESGCM, sealAESGCM ile şifrelenmiş veriyi çözer
+func openAESGCM(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("şifreli veri çok kısa")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// isEncryptedSecret, değerin şifrelenmiş biçimde saklanıp saklanmadığını döndürür
+func isEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+// encryptSecret, ana anahtar yapılandırılmışsa değeri veritabanına yazmadan önce şifreler
+func encryptSecret(value string) (string, error) {
+	if value == "" || secretKeyring == nil || isEncryptedSecret(value) {
+		return value, nil
+	}
+	return secretKeyring.Encrypt(value)
+}
+
+// decryptSecret, veritabanından okunan değeri çözer. Düz metin değerler aynen döner.
+func decryptSecret(value string) (string, error) {
+	if !isEncryptedSecret(value) {
+		return value, nil
+	}
+	if secretKeyring == nil {
+		return "", fmt.Errorf("şifreli sır çözülemedi: SECRETS_MASTER_KEY yapılandırılmamış")
+	}
+	return secretKeyring.Decrypt(value)
+}
+
+// secretColumn, veritabanında sır içeren bir kolonu tanımlar
+type secretColumn struct {
+	Table  string
+	Column string
+	Where  string // ek filtre (ör. settings tablosundaki anahtar)
+}
+
+// secretColumns, şifrelenmesi gereken tüm kolonlar
+var secretColumns = []secretColumn{
+	{Table: "clusters", Column: "token"},
+	{Table: "clusters", Column: "ca_cert"},
+	{Table: "clusters", Column: "kubeconfig"},
+	{Table: "settings", Column: "value", Where: "key = 'k8s_api_token'"},
+	{Table: "services", Column: "check_password"},
+	{Table: "services", Column: "check_headers"},
+	{Table: "notification_channels", Column: "config"},
+}
+
+// migrateSecrets, düz metin sırları şifreler ve eski anahtarla sarılmış değerleri
+// güncel ana anahtarla yeniden sarar. Değiştirilen satır sayısını döndürür.
+func migrateSecrets(db *sql.DB, keyring *SecretKeyring) (int, error) {
+	if keyring == nil {
+		return 0, fmt.Errorf("SECRETS_MASTER_KEY veya SECRETS_MASTER_KEY_FILE tanımlı değil")
+	}
+
+	changed := 0
+	for _, col := range secretColumns {
+		query := fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s IS NOT NULL AND %s != ''", col.Column, col.Table, col.Column, col.Column)
+		if col.Where != "" {
+			query += " AND " + col.Where
+		}
+
+		// Tek bağlantılı havuzda satırlar açıkken güncelleme yapılamaz, önce topla
+		rows, err := db.Query(query)
+		if err != nil {
+			return changed, fmt.Errorf("%s.%s okunamadı: %v", col.Table, col.Column, err)
+		}
+		values := map[int64]string{}
+		for rows.Next() {
+			var rowID int64
+			var value string
+			if err := rows.Scan(&rowID, &value); err != nil {
+				rows.Close()
+				return changed, err
+			}
+			values[rowID] = value
+		}
+		rows.Close()
+
+		for rowID, value := range values {
+			var updated string
+			if isEncryptedSecret(value) {
+				updated, err = keyring.Rewrap(value)
+			} else {
+				updated, err = keyring.Encrypt(value)
+			}
+			if err != nil {
+				return changed, fmt.Errorf("%s.%s (satır %d) işlenemedi: %v", col.Table, col.Column, rowID, err)
+			}
+			if updated == value {
+				continue
+			}
+
+			update := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", col.Table, col.Column)
+			if _, err := db.Exec(update, updated, rowID); err != nil {
+				return changed, fmt.Errorf("%s.%s (satır %d) güncellenemedi: %v", col.Table, col.Column, rowID, err)
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// runSecretsCommand, "secrets migrate" komutunu çalıştırır: düz metin sırları şifreler ve
+// SECRETS_PREVIOUS_MASTER_KEY ile sarılmış değerleri SECRETS_MASTER_KEY ile yeniden sarar
+func runSecretsCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 || (args[0] != "migrate" && args[0] != "rotate") {
+		return fmt.Errorf("kullanım: secrets migrate|rotate")
+	}
+
+	changed, err := migrateSecrets(db, secretKeyring)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d sır güncel ana anahtar (%s) ile şifrelendi", changed, secretKeyring.currentID)
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testMasterKey, verilen baytla doldurulmuş 32 baytlık ana anahtar üretir
+func testMasterKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestSecretKeyringEncryptDecryptRoundTrip(t *testing.T) {
+	keyring, err := NewSecretKeyring(testMasterKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plaintext := range []string{"s3cr3t-token", "", "çok gizli: değer:with:colons"} {
+		encrypted, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isEncryptedSecret(encrypted) || (plaintext != "" && strings.Contains(encrypted, plaintext)) {
+			t.Errorf("değer şifrelenmemiş görünüyor: %s", encrypted)
+		}
+		decrypted, err := keyring.Decrypt(encrypted)
+		if err != nil || decrypted != plaintext {
+			t.Errorf("beklenen %q, alınan %q (%v)", plaintext, decrypted, err)
+		}
+	}
+
+	// Aynı değer her seferinde farklı veri anahtarı ve nonce ile şifrelenir
+	a, _ := keyring.Encrypt("aynı")
+	b, _ := keyring.Encrypt("aynı")
+	if a == b {
+		t.Errorf("aynı değer için aynı şifreli metin üretildi")
+	}
+
+	if _, err := NewSecretKeyring([]byte("kısa")); err == nil {
+		t.Errorf("32 bayttan kısa anahtar reddedilmeliydi")
+	}
+}
+
+func TestSecretKeyringRotation(t *testing.T) {
+	oldKeyring, _ := NewSecretKeyring(testMasterKey(1))
+	encrypted, err := oldKeyring.Encrypt("eski-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotasyondan sonra eski anahtarla sarılmış değer eski anahtar halkada kaldıkça çözülür
+	rotated, err := NewSecretKeyring(testMasterKey(2), testMasterKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rotated.Decrypt(encrypted); err != nil || got != "eski-token" {
+		t.Fatalf("eski anahtarla çözülemedi: %q %v", got, err)
+	}
+
+	rewrapped, err := rotated.Rewrap(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrapped == encrypted || !strings.HasPrefix(rewrapped, encryptedSecretPrefix+secretKeyID(testMasterKey(2))+":") {
+		t.Errorf("değer güncel anahtarla yeniden sarılmadı: %s", rewrapped)
+	}
+	if again, _ := rotated.Rewrap(rewrapped); again != rewrapped {
+		t.Errorf("güncel anahtarla sarılmış değer değişmemeliydi")
+	}
+
+	// Eski anahtar kaldırıldıktan sonra yeniden sarılmış değer yine çözülür
+	current, _ := NewSecretKeyring(testMasterKey(2))
+	if got, err := current.Decrypt(rewrapped); err != nil || got != "eski-token" {
+		t.Errorf("yeniden sarılmış değer çözülemedi: %q %v", got, err)
+	}
+	if _, err := current.Decrypt(encrypted); err == nil {
+		t.Errorf("eski anahtar olmadan eski değer çözülmemeliydi")
+	}
+}
+
+func TestSecretKeyringWrongKeyFails(t *testing.T) {
+	keyring, _ := NewSecretKeyring(testMasterKey(1))
+	encrypted, _ := keyring.Encrypt("token")
+
+	// Farklı anahtar aynı kimliği taşısa bile GCM doğrulaması çöp değer yerine hata döndürür
+	wrong, _ := NewSecretKeyring(testMasterKey(9))
+	wrong.keys[keyring.currentID] = testMasterKey(9)
+	if got, err := wrong.Decrypt(encrypted); err == nil {
+		t.Errorf("yanlış anahtarla hata bekleniyordu, alınan %q", got)
+	}
+
+	other, _ := NewSecretKeyring(testMasterKey(3))
+	if _, err := other.Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), "bulunamadı") {
+		t.Errorf("bilinmeyen anahtar kimliği için hata bekleniyordu: %v", err)
+	}
+
+	// Bozulmuş şifreli metin de reddedilir
+	parts := strings.Split(encrypted, ":")
+	parts[len(parts)-1] = "AAAA" + parts[len(parts)-1][4:]
+	if _, err := keyring.Decrypt(strings.Join(parts, ":")); err == nil {
+		t.Errorf("bozulmuş şifreli metin reddedilmeliydi")
+	}
+	if _, err := keyring.Decrypt("enc:v1:eksik"); err == nil {
+		t.Errorf("geçersiz biçim reddedilmeliydi")
+	}
+}
+
+func TestMigrateSecretsEncryptsPlaintextAndRewraps(t *testing.T) {
+	testDB := newTestDB(t)
+	oldKeyring, _ := NewSecretKeyring(testMasterKey(1))
+	oldToken, _ := oldKeyring.Encrypt("eski-token")
+
+	testDB.Exec(`INSERT INTO clusters (name, api_url, auth_type, token, ca_cert) VALUES ('prod', 'https://prod', 'token', 'duz-token', 'ca-pem')`)
+	testDB.Exec(`INSERT INTO clusters (name, api_url, auth_type, token) VALUES ('stage', 'https://stage', 'token', ?)`, oldToken)
+	testDB.Exec(`INSERT INTO settings (key, value) VALUES ('k8s_api_token', 'ayar-token'), ('theme', 'dark')`)
+	testDB.Exec(`INSERT INTO services (name, namespace, cluster, type, check_password) VALUES ('api', 'shop', 'prod', 'service', 'parola')`)
+
+	if _, err := migrateSecrets(testDB, nil); err == nil {
+		t.Errorf("anahtar olmadan taşıma reddedilmeliydi")
+	}
+
+	keyring, _ := NewSecretKeyring(testMasterKey(2), testMasterKey(1))
+	changed, err := migrateSecrets(testDB, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 5 {
+		t.Errorf("5 değer güncellenmeliydi, güncellenen %d", changed)
+	}
+
+	// Hiçbir sır kolonunda düz metin veya eski anahtarla sarılmış değer kalmamalı
+	prefix := encryptedSecretPrefix + keyring.currentID + ":"
+	for _, col := range secretColumns {
+		query := "SELECT " + col.Column + " FROM " + col.Table + " WHERE " + col.Column + " IS NOT NULL AND " + col.Column + " != ''"
+		if col.Where != "" {
+			query += " AND " + col.Where
+		}
+		rows, err := testDB.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var value string
+			rows.Scan(&value)
+			if !strings.HasPrefix(value, prefix) {
+				t.Errorf("%s.%s güncel anahtarla şifrelenmemiş: %s", col.Table, col.Column, value)
+			}
+		}
+		rows.Close()
+	}
+
+	var token, theme string
+	testDB.QueryRow(`SELECT token FROM clusters WHERE name = 'stage'`).Scan(&token)
+	if got, err := keyring.Decrypt(token); err != nil || got != "eski-token" {
+		t.Errorf("yeniden sarılan değer çözülemedi: %q %v", got, err)
+	}
+	testDB.QueryRow(`SELECT value FROM settings WHERE key = 'theme'`).Scan(&theme)
+	if theme != "dark" {
+		t.Errorf("sır olmayan ayar değiştirilmemeliydi: %s", theme)
+	}
+
+	// İkinci çalıştırma hiçbir şeyi değiştirmez
+	if changed, err := migrateSecrets(testDB, keyring); err != nil || changed != 0 {
+		t.Errorf("tekrar çalıştırmada değişiklik beklenmiyordu: %d %v", changed, err)
+	}
+}
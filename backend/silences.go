@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sessize alma kuralı durumları (başlangıç ve bitiş zamanlarından hesaplanır)
+const (
+	SilencePending = "pending" // henüz başlamadı
+	SilenceActive  = "active"
+	SilenceExpired = "expired"
+)
+
+// SilenceMatchers, sessize alma kuralının eşleştiricileri. Boş alanlar her değerle eşleşir;
+// dolu alanların hepsi sağlanmalıdır. En az bir alan dolu olmalıdır.
+type SilenceMatchers struct {
+	Clusters      []string `json:"clusters,omitempty"`       // regex
+	Namespaces    []string `json:"namespaces,omitempty"`     // regex
+	Services      []int    `json:"services,omitempty"`       // servis ID'leri
+	LabelSelector string   `json:"label_selector,omitempty"` // servis label'ları, ör. "team=payments"
+}
+
+// empty, hiçbir eşleştiricinin tanımlanmadığını döndürür
+func (m SilenceMatchers) empty() bool {
+	return len(m.Clusters) == 0 && len(m.Namespaces) == 0 && len(m.Services) == 0 && strings.TrimSpace(m.LabelSelector) == ""
+}
+
+// Silence, eşleşen alarmların bildirimlerini belirli bir süre için bastıran kural. Sessize
+// alınan alarmlar yine kaydedilir; kural süresi dolduğunda hâlâ açıksa bildirilir.
+type Silence struct {
+	ID        int64           `json:"id"`
+	Matchers  SilenceMatchers `json:"matchers"`
+	StartsAt  time.Time       `json:"starts_at"`
+	EndsAt    time.Time       `json:"ends_at"`
+	Comment   string          `json:"comment"`
+	CreatedBy string          `json:"created_by,omitempty"`
+	Status    string          `json:"status"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Validate, kuralı doğrular; başlangıç verilmemişse şimdiki zamanı kullanır
+func (s *Silence) Validate(now time.Time) error {
+	s.Comment = strings.TrimSpace(s.Comment)
+	s.CreatedBy = strings.TrimSpace(s.CreatedBy)
+	if s.Matchers.empty() {
+		return fmt.Errorf("en az bir eşleştirici gerekli (clusters, namespaces, services veya label_selector)")
+	}
+	if s.Comment == "" {
+		return fmt.Errorf("comment gerekli")
+	}
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	if s.EndsAt.IsZero() {
+		return fmt.Errorf("ends_at gerekli")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("ends_at starts_at'ten sonra olmalı")
+	}
+	if !s.EndsAt.After(now) {
+		return fmt.Errorf("ends_at gelecekte olmalı")
+	}
+	_, err := s.compile()
+	return err
+}
+
+// status, kuralın verilen andaki durumunu döndürür
+func (s Silence) status(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilencePending
+	case now.Before(s.EndsAt):
+		return SilenceActive
+	default:
+		return SilenceExpired
+	}
+}
+
+// compiledSilence, eşleştiricileri derlenmiş sessize alma kuralı
+type compiledSilence struct {
+	Silence
+	match    *compiledRoute
+	services map[int]bool
+}
+
+// compile, eşleştiricileri yönlendirme koşullarıyla aynı kurallarla derler
+func (s Silence) compile() (*compiledSilence, error) {
+	route := NotificationRoute{Match: RouteMatch{
+		Clusters:      s.Matchers.Clusters,
+		Namespaces:    s.Matchers.Namespaces,
+		LabelSelector: s.Matchers.LabelSelector,
+	}}
+	match, err := route.Compile("matchers")
+	if err != nil {
+		return nil, err
+	}
+	c := &compiledSilence{Silence: s, match: match, services: map[int]bool{}}
+	for _, id := range s.Matchers.Services {
+		c.services[id] = true
+	}
+	return c, nil
+}
+
+// matches, alarmın kurala uyup uymadığını döndürür
+func (c *compiledSilence) matches(serviceID int, t routeTarget) bool {
+	if len(c.services) > 0 && !c.services[serviceID] {
+		return false
+	}
+	return c.match.matches(t)
+}
+
+// silenceColumns, silences sorgularında kullanılan kolonlar
+const silenceColumns = `id, matchers, starts_at, ends_at, comment, created_by, created_at, updated_at`
+
+// scanSilence, silenceColumns sırasıyla okunan satırı Silence'a dönüştürür
+func scanSilence(scanner interface{ Scan(...interface{}) error }) (Silence, error) {
+	var s Silence
+	var matchers string
+	var createdBy sql.NullString
+	if err := scanner.Scan(&s.ID, &matchers, &s.StartsAt, &s.EndsAt, &s.Comment, &createdBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return s, err
+	}
+	s.CreatedBy = createdBy.String
+	s.Status = s.status(time.Now())
+	return s, json.Unmarshal([]byte(matchers), &s.Matchers)
+}
+
+// getSilence, kuralı ID ile döndürür
+func getSilence(db *sql.DB, id int64) (Silence, error) {
+	return scanSilence(db.QueryRow(`SELECT `+silenceColumns+` FROM silences WHERE id = ?`, id))
+}
+
+// listSilences, koşula uyan kuralları bitiş zamanına göre döndürür
+func listSilences(db *sql.DB, where string, args ...interface{}) ([]Silence, error) {
+	rows, err := db.Query(`SELECT `+silenceColumns+` FROM silences WHERE `+where+` ORDER BY ends_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	silences := []Silence{}
+	for rows.Next() {
+		s, err := scanSilence(rows)
+		if err != nil {
+			return nil, err
+		}
+		silences = append(silences, s)
+	}
+	return silences, rows.Err()
+}
+
+// saveSilence, kuralı ekler (ID 0 ise) veya günceller
+func saveSilence(db *sql.DB, s *Silence) error {
+	matchers, err := json.Marshal(s.Matchers)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.StartsAt, s.EndsAt = s.StartsAt.UTC(), s.EndsAt.UTC()
+	if s.ID == 0 {
+		result, err := db.Exec(`
+			INSERT INTO silences (matchers, starts_at, ends_at, comment, created_by, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, string(matchers), s.StartsAt, s.EndsAt, s.Comment, s.CreatedBy, now, now)
+		if err != nil {
+			return err
+		}
+		s.ID, _ = result.LastInsertId()
+		s.CreatedAt = now
+	} else {
+		_, err := db.Exec(`UPDATE silences SET matchers = ?, starts_at = ?, ends_at = ?, comment = ?, created_by = ?, updated_at = ? WHERE id = ?`,
+			string(matchers), s.StartsAt, s.EndsAt, s.Comment, s.CreatedBy, now, s.ID)
+		if err != nil {
+			return err
+		}
+	}
+	s.UpdatedAt = now
+	s.Status = s.status(now)
+	return nil
+}
+
+// matchingSilence, alarma verilen anda uyan etkin kuralı döndürür (yoksa nil)
+func matchingSilence(db *sql.DB, alert Alert, now time.Time) (*Silence, error) {
+	active, err := listSilences(db, "starts_at <= ? AND ends_at > ?", now.UTC(), now.UTC())
+	if err != nil || len(active) == 0 {
+		return nil, err
+	}
+	target, err := serviceRouteTarget(db, alert.ServiceID, alert.Severity)
+	if err != nil {
+		// Servis silinmiş olabilir; alarmdaki bilgilerle devam et
+		target = routeTarget{Cluster: alert.Cluster, Namespace: alert.Namespace, Severity: alert.Severity}
+	}
+	for _, s := range active {
+		c, err := s.compile()
+		if err != nil {
+			log.Printf("Sessize alma kuralı %d derlenemedi: %v", s.ID, err)
+			continue
+		}
+		if c.matches(alert.ServiceID, target) {
+			return &c.Silence, nil
+		}
+	}
+	return nil, nil
+}
+
+// silenced, olayın sessize alma nedeniyle bildirilmeyeceğini döndürür. Açılan alarm etkin bir
+// kurala uyuyorsa kural alarma işlenir; böyle alarmların onay ve kapanışları da bildirilmez.
+func (d *NotificationDispatcher) silenced(event AlertEvent) bool {
+	if event.Type != AlertEventFiring {
+		return event.Alert.SilencedBy != nil
+	}
+	silence, err := matchingSilence(d.db, event.Alert, time.Now())
+	if err != nil {
+		log.Printf("Sessize alma kuralları okunamadı: %v", err)
+		return false
+	}
+	if silence == nil {
+		return false
+	}
+	if _, err := d.db.Exec(`UPDATE alerts SET silenced_by = ? WHERE id = ?`, silence.ID, event.Alert.ID); err != nil {
+		log.Printf("Alarm %d sessize alınamadı: %v", event.Alert.ID, err)
+		return false
+	}
+	log.Printf("ALARM sessize alındı: %d (%s) kural %d, %s tarihine kadar", event.Alert.ID, event.Alert.DedupKey,
+		silence.ID, silence.EndsAt.Format(time.RFC3339))
+	return true
+}
+
+// suppressedUntil, açık alarmın eskalasyonunun ertelenmesi veya etkin bir sessize alma
+// kuralı nedeniyle beklemesi gereken zamanı döndürür (beklemesi gerekmiyorsa sıfır)
+func (d *NotificationDispatcher) suppressedUntil(alert Alert, now time.Time) time.Time {
+	var until time.Time
+	if alert.SnoozedUntil != nil && alert.SnoozedUntil.After(now) {
+		until = *alert.SnoozedUntil
+	}
+	silence, err := matchingSilence(d.db, alert, now)
+	if err != nil {
+		log.Printf("Sessize alma kuralları okunamadı: %v", err)
+	} else if silence != nil && silence.EndsAt.After(until) {
+		until = silence.EndsAt
+	}
+	return until
+}
+
+// ExpireSilences, artık hiçbir etkin sessize alma kuralına uymayan (kuralı dolmuş, sonlandırılmış
+// veya güncellenmiş) açık alarmları ve ertelemesi dolan alarmları bildirir. Başka bir etkin
+// kurala uyan alarmlar o kurala aktarılır.
+func (d *NotificationDispatcher) ExpireSilences(ctx context.Context, now time.Time) {
+	silenced, err := listAlerts(d.db, `a.status = ? AND a.silenced_by IS NOT NULL`, AlertStatusOpen)
+	if err != nil {
+		log.Printf("Sessize alınmış alarmlar okunamadı: %v", err)
+		return
+	}
+	for _, alert := range silenced {
+		silence, err := matchingSilence(d.db, alert, now)
+		if err != nil {
+			log.Printf("Sessize alma kuralları okunamadı: %v", err)
+			return
+		}
+		if silence != nil {
+			if silence.ID != *alert.SilencedBy {
+				d.db.Exec(`UPDATE alerts SET silenced_by = ? WHERE id = ?`, silence.ID, alert.ID)
+			}
+			continue
+		}
+		if _, err := d.db.Exec(`UPDATE alerts SET silenced_by = NULL WHERE id = ?`, alert.ID); err != nil {
+			log.Printf("Alarm %d sessize alma kaydı temizlenemedi: %v", alert.ID, err)
+			continue
+		}
+		// Sessizken onaylanan alarm zaten ilgilenilen bir alarmdır; açılış bildirimi ve eskalasyon gönderilmez
+		if alert.AcknowledgedAt != nil {
+			log.Printf("ALARM sessize alma süresi doldu, %s tarafından onaylandığı için bildirilmiyor: %d (%s)",
+				alert.AcknowledgedBy, alert.ID, alert.DedupKey)
+			continue
+		}
+		log.Printf("ALARM sessize alma süresi doldu, bildiriliyor: %d (%s)", alert.ID, alert.DedupKey)
+		alert.SilencedBy = nil
+		d.Dispatch(ctx, AlertEvent{Type: AlertEventFiring, Alert: alert})
+	}
+
+	snoozed, err := listAlerts(d.db, `a.snoozed_until IS NOT NULL AND a.snoozed_until <= ?`, now.UTC())
+	if err != nil {
+		log.Printf("Ertelenmiş alarmlar okunamadı: %v", err)
+		return
+	}
+	for _, alert := range snoozed {
+		if _, err := d.db.Exec(`UPDATE alerts SET snoozed_until = NULL, snoozed_by = NULL WHERE id = ?`, alert.ID); err != nil {
+			log.Printf("Alarm %d ertelemesi temizlenemedi: %v", alert.ID, err)
+			continue
+		}
+		if alert.Status != AlertStatusOpen || alert.AcknowledgedAt != nil || alert.SilencedBy != nil {
+			continue
+		}
+		log.Printf("ALARM ertelemesi doldu, hatırlatılıyor: %d (%s)", alert.ID, alert.DedupKey)
+		alert.SnoozedUntil, alert.SnoozedBy = nil, ""
+		d.Dispatch(ctx, AlertEvent{Type: AlertEventFiring, Alert: alert})
+	}
+}
+
+// silencesHandler, /api/v1/silences altındaki endpoint'leri yönetir:
+//
+//	GET    /api/v1/silences                  etkin ve bekleyen kurallar (?include_expired=true ile tümü)
+//	POST   /api/v1/silences                  kural oluşturma
+//	GET    /api/v1/silences/{id}             kural detayı ve şu an sessize aldığı açık alarmlar
+//	PUT    /api/v1/silences/{id}             kural güncelleme
+//	DELETE /api/v1/silences/{id}             kuralı hemen sona erdirme (kayıt geçmiş için tutulur)
+func silencesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	decode := func(silence *Silence) error {
+		if err := json.NewDecoder(r.Body).Decode(silence); err != nil {
+			return fmt.Errorf("İstek gövdesi ayrıştırılamadı")
+		}
+		return silence.Validate(time.Now())
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/silences"), "/")
+	if path == "" {
+		switch r.Method {
+		case "GET":
+			where, args := "ends_at > ?", []interface{}{time.Now().UTC()}
+			if r.URL.Query().Get("include_expired") == "true" {
+				where, args = "1 = 1", nil
+			}
+			silences, err := listSilences(db, where, args...)
+			if err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Sessize alma kuralları alınamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"silences": silences, "count": len(silences)})
+
+		case "POST":
+			var silence Silence
+			if err := decode(&silence); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+				return
+			}
+			silence.ID = 0
+			if err := saveSilence(db, &silence); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Sessize alma kuralı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Sessize alma kuralı %d oluşturuldu: %s (%s)", silence.ID, silence.Comment, silence.CreatedBy)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Sessize alma kuralı oluşturuldu",
+				"silence": silence,
+			})
+
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Geçersiz kural ID"}`, http.StatusBadRequest)
+		return
+	}
+	silence, err := getSilence(db, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Sessize alma kuralı bulunamadı"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":"Sessize alma kuralı alınamadı: %v"}`, err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		alerts, err := listAlerts(db, "a.status = ? AND a.silenced_by = ?", AlertStatusOpen, id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Alarmlar alınamadı: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"silence": silence, "alerts": alerts})
+
+	case "PUT":
+		if silence.Status == SilenceExpired {
+			http.Error(w, `{"error":"Süresi dolmuş kural güncellenemez"}`, http.StatusConflict)
+			return
+		}
+		if err := decode(&silence); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		silence.ID = id
+		if err := saveSilence(db, &silence); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":"Sessize alma kuralı kaydedilemedi: %v"}`, err), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Sessize alma kuralı güncellendi",
+			"silence": silence,
+		})
+
+	case "DELETE":
+		// Bastırılan alarmlar bir sonraki dakikalık kontrolde bildirilir
+		if silence.Status != SilenceExpired {
+			now := time.Now().UTC()
+			startsAt := silence.StartsAt.UTC()
+			if silence.Status == SilencePending {
+				startsAt = now
+			}
+			if _, err := db.Exec(`UPDATE silences SET starts_at = ?, ends_at = ?, updated_at = ? WHERE id = ?`, startsAt, now, now, id); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error":"Sessize alma kuralı sonlandırılamadı: %v"}`, err), http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Sessize alma kuralı %d sonlandırıldı", id)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Sessize alma kuralı sonlandırıldı",
+			"id":      id,
+		})
+
+	default:
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
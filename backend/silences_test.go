@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSilencedAlertIsRecordedButNotNotified(t *testing.T) {
+	server, requests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "ops", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": server.URL}},
+	)
+	dispatcher.db.Exec(`INSERT INTO services (id, name, namespace, cluster, type) VALUES (8, 'billing', 'finance', 'prod', 'service')`)
+
+	rec := httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("POST", "/api/v1/silences", strings.NewReader(`{"comment":"bakım"}`)))
+	if rec.Code != 400 {
+		t.Errorf("eşleştiricisiz kural reddedilmeliydi: %d", rec.Code)
+	}
+
+	endsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("POST", "/api/v1/silences", strings.NewReader(
+		`{"matchers":{"clusters":["^prod$"],"namespaces":["^shop$"]},"ends_at":"`+endsAt+`","comment":"planlı bakım","created_by":"ayse"}`)))
+	if rec.Code != 201 {
+		t.Fatalf("kural oluşturulamadı: %d %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Silence Silence `json:"silence"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if created.Silence.Status != SilenceActive {
+		t.Errorf("kural etkin olmalıydı: %s", created.Silence.Status)
+	}
+
+	start := time.Now()
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", start)
+	manager.Fire(statusAlertKey(8), 8, nil, SeverityCritical, "down", start)
+	if got := len(requests()); got != 1 {
+		t.Fatalf("yalnızca sessize alınmayan alarm bildirilmeliydi, alınan %d", got)
+	}
+	alerts, _ := manager.List("a.service_id = 7")
+	if len(alerts) != 1 || alerts[0].SilencedBy == nil || *alerts[0].SilencedBy != created.Silence.ID {
+		t.Fatalf("sessize alınan alarm kaydedilmeliydi: %+v", alerts)
+	}
+
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/v1/silences/%d", created.Silence.ID), nil))
+	if !strings.Contains(rec.Body.String(), `"dedup_key":"status:7"`) {
+		t.Errorf("kural detayında sessize alınan alarm yok: %s", rec.Body.String())
+	}
+
+	// Kural sürerken alarm bildirilmez, sonlandırılınca bildirilir
+	dispatcher.ExpireSilences(context.Background(), time.Now())
+	if got := len(requests()); got != 1 {
+		t.Fatalf("kural sürerken bildirim gönderilmemeliydi, alınan %d", got)
+	}
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/silences/%d", created.Silence.ID), nil))
+	if rec.Code != 200 {
+		t.Fatalf("kural sonlandırılamadı: %d %s", rec.Code, rec.Body.String())
+	}
+	dispatcher.ExpireSilences(context.Background(), time.Now().Add(time.Second))
+	if got := len(requests()); got != 2 {
+		t.Fatalf("kural bitince açık alarm bildirilmeliydi, alınan %d", got)
+	}
+	if alerts, _ := manager.List("a.service_id = 7"); alerts[0].SilencedBy != nil {
+		t.Errorf("sessize alma kaydı temizlenmeliydi")
+	}
+
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("GET", "/api/v1/silences", nil))
+	if !strings.Contains(rec.Body.String(), `"count":0`) {
+		t.Errorf("süresi dolan kural varsayılan listede olmamalı: %s", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("GET", "/api/v1/silences?include_expired=true", nil))
+	if !strings.Contains(rec.Body.String(), `"status":"expired"`) {
+		t.Errorf("süresi dolan kural geçmişte görünmeli: %s", rec.Body.String())
+	}
+}
+
+func TestSilenceExpiryDoesNotNotifyAcknowledgedAlert(t *testing.T) {
+	server, requests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "ops", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": server.URL}},
+	)
+
+	rec := httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("POST", "/api/v1/silences", strings.NewReader(
+		`{"matchers":{"namespaces":["^shop$"]},"ends_at":"`+time.Now().Add(time.Hour).UTC().Format(time.RFC3339)+`","comment":"bakım"}`)))
+	if rec.Code != 201 {
+		t.Fatalf("kural oluşturulamadı: %d %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Silence Silence `json:"silence"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", time.Now())
+	if _, err := manager.Acknowledge(mustOpenAlertID(t, dispatcher), "ayse"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sessizken onaylanan alarm kural bitince yeniden bildirilmez
+	rec = httptest.NewRecorder()
+	silencesHandler(rec, httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/silences/%d", created.Silence.ID), nil))
+	dispatcher.ExpireSilences(context.Background(), time.Now().Add(time.Second))
+	if got := len(requests()); got != 0 {
+		t.Errorf("onaylanmış alarm bildirilmemeliydi, alınan %d", got)
+	}
+	alerts, _ := manager.List("a.service_id = 7")
+	if len(alerts) != 1 || alerts[0].SilencedBy != nil {
+		t.Errorf("sessize alma kaydı yine de temizlenmeliydi: %+v", alerts)
+	}
+	var escalations int
+	dispatcher.db.QueryRow(`SELECT COUNT(*) FROM alert_escalations WHERE status = ?`, EscalationActive).Scan(&escalations)
+	if escalations != 0 {
+		t.Errorf("onaylanmış alarm için eskalasyon başlatılmamalıydı")
+	}
+}
+
+func TestSnoozePostponesEscalationAndReminds(t *testing.T) {
+	first, firstRequests := newRecordingServer(t, func(string) string { return "ok" })
+	second, secondRequests := newRecordingServer(t, func(string) string { return "ok" })
+	manager, dispatcher := newNotificationTestSetup(t,
+		NotificationChannel{Name: "ops", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": first.URL}},
+		NotificationChannel{Name: "yonetici", Type: ChannelTypeSlack, Enabled: true, Config: ChannelConfig{"webhook_url": second.URL}},
+	)
+	previous := alertManager
+	alertManager = manager
+	t.Cleanup(func() { alertManager = previous })
+
+	policy := EscalationPolicy{Name: "kritik", Levels: []EscalationLevel{{AfterMinutes: 15, Channels: []string{"2"}}}}
+	if err := saveEscalationPolicy(db, &policy); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	notificationRoutesHandler(rec, httptest.NewRequest("PUT", "/api/v1/notification-routes",
+		strings.NewReader(fmt.Sprintf(`{"channels":["1"],"escalation_policy":%d}`, policy.ID))))
+	if rec.Code != 200 {
+		t.Fatalf("yönlendirme kaydedilemedi: %d %s", rec.Code, rec.Body.String())
+	}
+
+	start := time.Now()
+	manager.Fire(statusAlertKey(7), 7, nil, SeverityCritical, "down", start)
+	if got := len(firstRequests()); got != 1 {
+		t.Fatalf("alarm bildirilmeliydi, alınan %d", got)
+	}
+	alertID := mustOpenAlertID(t, dispatcher)
+
+	rec = httptest.NewRecorder()
+	alertsHandler(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v1/alerts/%d/snooze", alertID), strings.NewReader(`{"by":"ayse"}`)))
+	if rec.Code != 400 {
+		t.Errorf("until veya duration olmadan erteleme reddedilmeliydi: %d", rec.Code)
+	}
+	until := start.Add(30 * time.Minute).UTC().Format(time.RFC3339)
+	rec = httptest.NewRecorder()
+	alertsHandler(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v1/alerts/%d/snooze", alertID),
+		strings.NewReader(`{"until":"`+until+`","by":"ayse"}`)))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"snoozed_by":"ayse"`) {
+		t.Fatalf("alarm ertelenemedi: %d %s", rec.Code, rec.Body.String())
+	}
+
+	dispatcher.Escalate(context.Background(), start.Add(16*time.Minute))
+	if got := len(secondRequests()); got != 0 {
+		t.Fatalf("ertelenen alarm eskale edilmemeliydi, alınan %d", got)
+	}
+
+	later := start.Add(31 * time.Minute)
+	dispatcher.ExpireSilences(context.Background(), later)
+	if got := len(firstRequests()); got != 2 {
+		t.Errorf("erteleme bitince hatırlatma gönderilmeliydi, alınan %d", got)
+	}
+	if alert, _ := manager.Get(alertID); alert.SnoozedUntil != nil {
+		t.Errorf("erteleme temizlenmeliydi: %v", alert.SnoozedUntil)
+	}
+	dispatcher.Escalate(context.Background(), later)
+	if got := len(secondRequests()); got != 1 {
+		t.Errorf("erteleme bitince eskalasyon sürmeliydi, alınan %d", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -36,6 +37,7 @@ type UptimeCheckConfig struct {
 	CheckType     UptimeCheckType
 	CheckInterval int // saniye cinsinden
 	Timeout       time.Duration
+	Selector      map[string]string // servisin pod seçicisi (ölçek 0 kontrolü için)
 
 	// HTTP kontrolü için ek alanlar
 	ExpectedStatusCode int
@@ -57,14 +59,23 @@ type UptimeCheckResult struct {
 	ResponseTime int64 // milisaniye
 	ErrorMessage string
 	Timestamp    time.Time
+
+	// CertExpiresAt, TLS bağlantısında görülen sunucu sertifikasının son kullanma tarihi
+	CertExpiresAt *time.Time
 }
 
+// uptimeReloadDelay, art arda gelen yeniden yükleme isteklerinin birleştirildiği süre
+const uptimeReloadDelay = 10 * time.Second
+
 // UptimeMonitor, tüm izleme işlemlerini yönetir
 type UptimeMonitor struct {
 	db          *sql.DB
 	configs     []UptimeCheckConfig
 	cancelFuncs []context.CancelFunc
 	configMutex sync.RWMutex
+
+	reloadMutex sync.Mutex
+	reloadTimer *time.Timer
 }
 
 // NewUptimeMonitor, yeni bir izleme örneği oluşturur
@@ -80,9 +91,12 @@ func NewUptimeMonitor(db *sql.DB) *UptimeMonitor {
 func (m *UptimeMonitor) loadServiceConfigs() error {
 	rows, err := m.db.Query(`
 		SELECT id, name, namespace, cluster, endpoint, 
-		       COALESCE(check_interval, 60) as check_interval 
+		       COALESCE(check_interval, 60) as check_interval,
+		       check_username, check_password, check_headers,
+		       COALESCE(expected_status, 0), COALESCE(check_type, ''), selector
 		FROM services 
-		WHERE endpoint IS NOT NULL AND endpoint != ''
+		WHERE endpoint IS NOT NULL AND endpoint != '' AND archived_at IS NULL
+		  AND COALESCE(monitoring_enabled, 1) = 1
 	`)
 	if err != nil {
 		return fmt.Errorf("servis yapılandırmaları yüklenemedi: %v", err)
@@ -96,6 +110,9 @@ func (m *UptimeMonitor) loadServiceConfigs() error {
 	for rows.Next() {
 		var config UptimeCheckConfig
 		var endpoint string
+		var username, password, headers sql.NullString
+		var checkType string
+		var selector sql.NullString
 
 		err := rows.Scan(
 			&config.ServiceID,
@@ -104,12 +121,24 @@ func (m *UptimeMonitor) loadServiceConfigs() error {
 			&config.Cluster,
 			&endpoint,
 			&config.CheckInterval,
+			&username,
+			&password,
+			&headers,
+			&config.ExpectedStatusCode,
+			&checkType,
+			&selector,
 		)
 		if err != nil {
 			log.Printf("Servis yapılandırması okunurken hata: %v", err)
 			continue
 		}
 
+		// Referanslar (env:, file:, secretRef:) her kontrolde çözülür
+		auth, err := decodeCheckAuth(username, password, headers)
+		if err != nil {
+			log.Printf("Servis %d kimlik bilgileri okunamadı: %v", config.ServiceID, err)
+		}
+
 		// Endpoint türünü belirle
 		if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
 			config.CheckType = CheckTypeHTTP
@@ -118,11 +147,20 @@ func (m *UptimeMonitor) loadServiceConfigs() error {
 		} else {
 			config.CheckType = CheckTypeDNS
 		}
+		// Açıkça belirtilen kontrol türü (arayüz veya k8s-monitoring/check-type) önceliklidir
+		if checkType != "" {
+			config.CheckType = UptimeCheckType(checkType)
+		}
 
 		config.Endpoint = endpoint
+		if selector.Valid && selector.String != "" {
+			json.Unmarshal([]byte(selector.String), &config.Selector)
+		}
 		config.Timeout = 10 * time.Second
 		config.SSLWarningDays = 30 // Varsayılan olarak 30 gün
-		config.Headers = make(map[string]string)
+		config.Username = auth.Username
+		config.Password = auth.Password
+		config.Headers = auth.Headers
 
 		// HTTPS için SSL kontrolünü varsayılan olarak etkinleştir
 		if strings.HasPrefix(endpoint, "https://") {
@@ -139,10 +177,10 @@ func (m *UptimeMonitor) loadServiceConfigs() error {
 func (m *UptimeMonitor) saveCheckResult(result UptimeCheckResult) error {
 	_, err := m.db.Exec(`
 		INSERT INTO uptime_checks 
-		(service_id, status, response_time, error_message, timestamp)
-		VALUES (?, ?, ?, ?, ?)
+		(service_id, status, response_time, error_message, timestamp, cert_expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`, result.ServiceID, result.Status, result.ResponseTime,
-		result.ErrorMessage, result.Timestamp)
+		result.ErrorMessage, result.Timestamp, result.CertExpiresAt)
 
 	return err
 }
@@ -188,6 +226,11 @@ func (m *UptimeMonitor) performHTTPCheck(config UptimeCheckConfig) UptimeCheckRe
 	}
 	defer resp.Body.Close()
 
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiresAt := resp.TLS.PeerCertificates[0].NotAfter
+		result.CertExpiresAt = &expiresAt
+	}
+
 	// Yanıt süresi hesaplama
 	duration := time.Since(start)
 	result.ResponseTime = duration.Milliseconds()
@@ -317,6 +360,7 @@ func (m *UptimeMonitor) performCertificateCheck(config UptimeCheckConfig) Uptime
 	// İlk (sunucu) sertifikayı kontrol et
 	cert := certs[0]
 	now := time.Now()
+	result.CertExpiresAt = &cert.NotAfter
 
 	// Son kullanma tarihini kontrol et
 	if now.After(cert.NotAfter) {
@@ -376,6 +420,38 @@ func verifyHostname(hostname string, cert *x509.Certificate) bool {
 	return false
 }
 
+// performCheck, kimlik bilgisi referanslarını çözer ve kontrol türüne göre ilgili kontrolü çalıştırır.
+// Referans çözülemezse hedef denenmez ve sonuç "config_error" olarak döner.
+func (m *UptimeMonitor) performCheck(config UptimeCheckConfig) (UptimeCheckResult, bool) {
+	auth, err := CheckAuth{Username: config.Username, Password: config.Password, Headers: config.Headers}.Resolve(config.Cluster)
+	if err != nil {
+		return UptimeCheckResult{
+			ServiceID:    config.ServiceID,
+			Status:       "config_error",
+			ErrorMessage: err.Error(),
+			Timestamp:    time.Now(),
+		}, true
+	}
+	config.Username = auth.Username
+	config.Password = auth.Password
+	config.Headers = auth.Headers
+
+	// Kontrol türüne göre ilgili metodu çağır
+	switch config.CheckType {
+	case CheckTypeHTTP:
+		return m.performHTTPCheck(config), true
+	case CheckTypeTCP:
+		return m.performTCPCheck(config), true
+	case CheckTypeDNS:
+		return m.performDNSCheck(config), true
+	case CheckTypeCertificate:
+		return m.performCertificateCheck(config), true
+	default:
+		log.Printf("Desteklenmeyen kontrol türü: %v", config.CheckType)
+		return UptimeCheckResult{}, false
+	}
+}
+
 // startServiceMonitoring, belirli bir servis için izleme başlatır
 func (m *UptimeMonitor) startServiceMonitoring(config UptimeCheckConfig) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -390,31 +466,48 @@ func (m *UptimeMonitor) startServiceMonitoring(config UptimeCheckConfig) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				var result UptimeCheckResult
-
-				// Kontrol türüne göre ilgili metodu çağır
-				switch config.CheckType {
-				case CheckTypeHTTP:
-					result = m.performHTTPCheck(config)
-				case CheckTypeTCP:
-					result = m.performTCPCheck(config)
-				case CheckTypeDNS:
-					result = m.performDNSCheck(config)
-				case CheckTypeCertificate:
-					result = m.performCertificateCheck(config)
-				default:
-					log.Printf("Desteklenmeyen kontrol türü: %v", config.CheckType)
+				// İş yükleri bilerek 0 replikaya ölçeklendiyse hedef denenmez;
+				// ölçek büyütüldüğünde kontroller kendiliğinden devam eder
+				if paused, reason := workloadScale.Paused(config.Cluster, config.Namespace, config.Selector); paused {
+					result := UptimeCheckResult{
+						ServiceID:    config.ServiceID,
+						Status:       CheckStatusPaused,
+						ErrorMessage: reason,
+						Timestamp:    time.Now(),
+					}
+					if err := m.saveCheckResult(result); err != nil {
+						log.Printf("Kontrol sonucu kaydedilemedi: %v", err)
+					}
+					if alertManager != nil {
+						alertManager.ProcessCheckResult(result)
+					}
+					continue
+				}
+
+				result, ok := m.performCheck(config)
+				if !ok {
 					continue
 				}
 
+				// Cluster erişilemiyorsa servis kesintisini cluster kesintisinden ayır
+				if result.Status == "down" && clusterHealth.Unreachable(config.Cluster) {
+					result.Status = "cluster_unreachable"
+					result.ErrorMessage = fmt.Sprintf("%s cluster'ına erişilemiyor: %s", config.Cluster, result.ErrorMessage)
+				}
+
 				// Sonucu kaydet
 				err := m.saveCheckResult(result)
 				if err != nil {
 					log.Printf("Kontrol sonucu kaydedilemedi: %v", err)
 				}
 
+				// Alarmları aç, güncelle veya kapat (rollout bastırması dahil)
+				if alertManager != nil {
+					alertManager.ProcessCheckResult(result)
+				}
+
 				// Hata durumunda log at
-				if result.Status == "down" {
+				if result.Status == "down" || result.Status == "cluster_unreachable" || result.Status == "config_error" {
 					log.Printf("Servis %d durumu: %s - %s",
 						result.ServiceID, result.Status, result.ErrorMessage)
 				}
@@ -432,10 +525,17 @@ func (m *UptimeMonitor) StartUptimeMonitoring() error {
 	}
 
 	// Her bir servis için izlemeyi başlat
+	monitored := make(map[int]bool, len(m.configs))
 	for _, config := range m.configs {
+		monitored[config.ServiceID] = true
 		m.startServiceMonitoring(config)
 	}
 
+	// İzlemeden çıkan servislerin açık durum alarmlarını kapat
+	if alertManager != nil {
+		alertManager.ClearUnmonitored(monitored, time.Now())
+	}
+
 	log.Printf("%d servis için uptime izlemesi başlatıldı", len(m.configs))
 	return nil
 }
@@ -452,14 +552,40 @@ func (m *UptimeMonitor) StopUptimeMonitoring() {
 	log.Println("Uptime izlemesi durduruldu")
 }
 
+// RequestReload, servis yapılandırmalarının kısa bir gecikmeyle yeniden yüklenmesini ister.
+// Gecikme süresince gelen diğer istekler aynı yeniden yüklemeye katılır.
+func (m *UptimeMonitor) RequestReload() {
+	m.reloadMutex.Lock()
+	defer m.reloadMutex.Unlock()
+
+	if m.reloadTimer != nil {
+		return
+	}
+	m.reloadTimer = time.AfterFunc(uptimeReloadDelay, func() {
+		m.reloadMutex.Lock()
+		m.reloadTimer = nil
+		m.reloadMutex.Unlock()
+
+		if err := m.Reload(); err != nil {
+			log.Printf("Uptime izlemesi yeniden yüklenemedi: %v", err)
+		}
+	})
+}
+
+// Reload, tüm izlemeleri durdurur ve güncel servis yapılandırmalarıyla yeniden başlatır
+func (m *UptimeMonitor) Reload() error {
+	m.StopUptimeMonitoring()
+	return m.StartUptimeMonitoring()
+}
+
 // CalculateUptimePercentage, belirli bir servis için uptime yüzdesini hesaplar.
-// Bu hesaplama, uptime_checks tablosundaki tüm kayıtlar üzerinden yapılır.
+// Bu hesaplama, uptime_checks tablosundaki duraklatılmış (paused) olmayan tüm kayıtlar üzerinden yapılır.
 func (m *UptimeMonitor) CalculateUptimePercentage(serviceID int) (float64, error) {
 	var totalChecks int
 	var upChecks int
 
 	// Toplam kontrol sayısını al
-	err := m.db.QueryRow(`SELECT COUNT(*) FROM uptime_checks WHERE service_id = ?`, serviceID).Scan(&totalChecks)
+	err := m.db.QueryRow(`SELECT COUNT(*) FROM uptime_checks WHERE service_id = ? AND status != ?`, serviceID, CheckStatusPaused).Scan(&totalChecks)
 	if err != nil {
 		return 0, fmt.Errorf("kontrol sayısı alınamadı: %v", err)
 	}
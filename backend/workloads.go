@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+)
+
+// CheckStatusPaused, servisin arkasındaki iş yükleri bilerek 0 replikaya ölçeklendiğinde
+// kaydedilen durum. SLA hesaplarına ve alarmlara dahil edilmez.
+const CheckStatusPaused = "paused"
+
+// scaledWorkload, bir Deployment veya StatefulSet'in pod şablonu ve istenen replika sayısı
+type scaledWorkload struct {
+	Kind     string
+	Name     string
+	Labels   map[string]string
+	Replicas int32
+}
+
+// WorkloadScaleCache, servis seçicilerinin arkasındaki iş yüklerinin replika sayılarını
+// cluster'ların informer önbelleklerinden okur; kontroller API çağrısı yapmaz.
+// Türler ayrı kaydedilir, okunamayan tür (ör. StatefulSet yetkisi yok) diğerini etkilemez.
+type WorkloadScaleCache struct {
+	mu           sync.RWMutex
+	deployments  map[string]appslisters.DeploymentLister
+	statefulSets map[string]appslisters.StatefulSetLister
+}
+
+// NewWorkloadScaleCache, yeni bir iş yükü önbelleği oluşturur
+func NewWorkloadScaleCache() *WorkloadScaleCache {
+	return &WorkloadScaleCache{
+		deployments:  make(map[string]appslisters.DeploymentLister),
+		statefulSets: make(map[string]appslisters.StatefulSetLister),
+	}
+}
+
+// workloadScale, uptime kontrollerinde kullanılan genel önbellek
+var workloadScale = NewWorkloadScaleCache()
+
+// Register, cluster'ın senkronize olmuş lister'larını kaydeder; nil lister o türün okunamadığını belirtir
+func (c *WorkloadScaleCache) Register(cluster string, deployments appslisters.DeploymentLister, statefulSets appslisters.StatefulSetLister) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.deployments, cluster)
+	delete(c.statefulSets, cluster)
+	if deployments != nil {
+		c.deployments[cluster] = deployments
+	}
+	if statefulSets != nil {
+		c.statefulSets[cluster] = statefulSets
+	}
+}
+
+// Unregister, durdurulan cluster'ın lister'larını kaldırır
+func (c *WorkloadScaleCache) Unregister(cluster string) {
+	c.Register(cluster, nil, nil)
+}
+
+// list, namespace'teki okunabilen Deployment ve StatefulSet'leri döndürür.
+// Cluster için hiçbir tür kayıtlı değilse hata döner.
+func (c *WorkloadScaleCache) list(cluster, namespace string) ([]scaledWorkload, error) {
+	c.mu.RLock()
+	deployments, hasDeployments := c.deployments[cluster]
+	statefulSets, hasStatefulSets := c.statefulSets[cluster]
+	c.mu.RUnlock()
+	if !hasDeployments && !hasStatefulSets {
+		return nil, fmt.Errorf("%s cluster'ı için iş yükü önbelleği yok", cluster)
+	}
+
+	var workloads []scaledWorkload
+	if hasDeployments {
+		list, err := deployments.Deployments(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("deployment'lar listelenemedi: %v", err)
+		}
+		for _, d := range list {
+			replicas := int32(1)
+			if d.Spec.Replicas != nil {
+				replicas = *d.Spec.Replicas
+			}
+			workloads = append(workloads, scaledWorkload{Kind: "Deployment", Name: d.Name, Labels: d.Spec.Template.Labels, Replicas: replicas})
+		}
+	}
+	if hasStatefulSets {
+		list, err := statefulSets.StatefulSets(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("statefulset'ler listelenemedi: %v", err)
+		}
+		for _, s := range list {
+			replicas := int32(1)
+			if s.Spec.Replicas != nil {
+				replicas = *s.Spec.Replicas
+			}
+			workloads = append(workloads, scaledWorkload{Kind: "StatefulSet", Name: s.Name, Labels: s.Spec.Template.Labels, Replicas: replicas})
+		}
+	}
+	return workloads, nil
+}
+
+// scaledToZero, seçiciyle eşleşen iş yüklerinin tamamı 0 replikaya ölçeklendiyse true ve
+// açıklama döndürür. Eşleşen iş yükü yoksa veya biri çalışıyorsa false döner.
+func scaledToZero(workloads []scaledWorkload, selector map[string]string) (bool, string) {
+	if len(selector) == 0 {
+		return false, ""
+	}
+	matcher := labels.SelectorFromSet(selector)
+
+	var names []string
+	for _, w := range workloads {
+		if !matcher.Matches(labels.Set(w.Labels)) {
+			continue
+		}
+		if w.Replicas > 0 {
+			return false, ""
+		}
+		names = append(names, w.Kind+"/"+w.Name)
+	}
+	if len(names) == 0 {
+		return false, ""
+	}
+	return true, fmt.Sprintf("%s 0 replikaya ölçeklendi, kontrol duraklatıldı", strings.Join(names, ", "))
+}
+
+// Paused, servisin arkasındaki iş yüklerinin bilerek durdurulup durdurulmadığını döndürür.
+// Cluster'ın iş yükleri okunamıyorsa servis duraklatılmış sayılmaz ve normal kontrol yapılır.
+func (c *WorkloadScaleCache) Paused(cluster, namespace string, selector map[string]string) (bool, string) {
+	if len(selector) == 0 {
+		return false, ""
+	}
+	workloads, err := c.list(cluster, namespace)
+	if err != nil {
+		return false, ""
+	}
+	return scaledToZero(workloads, selector)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestScaledToZero(t *testing.T) {
+	workloads := []scaledWorkload{
+		{Kind: "Deployment", Name: "api", Labels: map[string]string{"app": "api", "tier": "web"}, Replicas: 0},
+		{Kind: "Deployment", Name: "api-canary", Labels: map[string]string{"app": "api", "track": "canary"}, Replicas: 0},
+		{Kind: "StatefulSet", Name: "db", Labels: map[string]string{"app": "db"}, Replicas: 0},
+		{Kind: "Deployment", Name: "web", Labels: map[string]string{"app": "web"}, Replicas: 2},
+		{Kind: "StatefulSet", Name: "web-cache", Labels: map[string]string{"app": "web"}, Replicas: 0},
+	}
+
+	cases := []struct {
+		name     string
+		selector map[string]string
+		paused   bool
+		reason   string
+	}{
+		{"tüm eşleşenler 0", map[string]string{"app": "api"}, true, "Deployment/api, Deployment/api-canary 0 replikaya ölçeklendi"},
+		{"statefulset", map[string]string{"app": "db"}, true, "StatefulSet/db 0 replikaya ölçeklendi"},
+		{"biri çalışıyor", map[string]string{"app": "web"}, false, ""},
+		{"eşleşen yok", map[string]string{"app": "worker"}, false, ""},
+		{"seçici yok", nil, false, ""},
+	}
+	for _, c := range cases {
+		paused, reason := scaledToZero(workloads, c.selector)
+		if paused != c.paused || !strings.HasPrefix(reason, c.reason) {
+			t.Errorf("%s: beklenen %v %q, alınan %v %q", c.name, c.paused, c.reason, paused, reason)
+		}
+	}
+}
+
+func testDeployment(namespace, name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+		},
+	}
+}
+
+func testStatefulSet(namespace, name string, replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+		},
+	}
+}
+
+func TestWorkloadScaleCacheReadsRegisteredListers(t *testing.T) {
+	deployments := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	statefulSets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	deployments.Add(testDeployment("shop", "api", 0))
+	deployments.Add(testDeployment("shop", "web", 3))
+	deployments.Add(testDeployment("billing", "api", 2))
+	statefulSets.Add(testStatefulSet("shop", "db", 0))
+
+	c := NewWorkloadScaleCache()
+	if paused, _ := c.Paused("prod", "shop", map[string]string{"app": "api"}); paused {
+		t.Errorf("kayıtlı olmayan cluster'da servis duraklatılmamalı")
+	}
+
+	c.Register("prod", appslisters.NewDeploymentLister(deployments), appslisters.NewStatefulSetLister(statefulSets))
+	if paused, reason := c.Paused("prod", "shop", map[string]string{"app": "api"}); !paused || !strings.Contains(reason, "Deployment/api") {
+		t.Errorf("0 replikalı Deployment servisi duraklatmalıydı: %q", reason)
+	}
+	if paused, _ := c.Paused("prod", "billing", map[string]string{"app": "api"}); paused {
+		t.Errorf("başka namespace'teki iş yükleri karışmamalı")
+	}
+	if paused, reason := c.Paused("prod", "shop", map[string]string{"app": "db"}); !paused || !strings.Contains(reason, "StatefulSet/db") {
+		t.Errorf("0 replikalı StatefulSet servisi duraklatmalıydı: %q", reason)
+	}
+
+	// StatefulSet'ler okunamıyorsa Deployment'lar yine kullanılır
+	c.Register("prod", appslisters.NewDeploymentLister(deployments), nil)
+	if paused, _ := c.Paused("prod", "shop", map[string]string{"app": "api"}); !paused {
+		t.Errorf("StatefulSet olmadan da Deployment'lar değerlendirilmeliydi")
+	}
+	if paused, _ := c.Paused("prod", "shop", map[string]string{"app": "db"}); paused {
+		t.Errorf("okunamayan StatefulSet servisi duraklatmamalı")
+	}
+
+	c.Unregister("prod")
+	if paused, _ := c.Paused("prod", "shop", map[string]string{"app": "api"}); paused {
+		t.Errorf("durdurulan cluster'ın önbelleği kullanılmamalı")
+	}
+}
+
+func TestRolloutWatcherRegistersWorkloadsWithoutStatefulSets(t *testing.T) {
+	client := fake.NewSimpleClientset(testDeployment("shop", "api", 0), testStatefulSet("shop", "db", 0))
+	client.PrependReactor("list", "statefulsets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "statefulsets"}, "", nil)
+	})
+
+	const cluster = "workloads-test"
+	w := NewRolloutWatcher(newTestDB(t), client, cluster)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	eventually(t, "Deployment önbelleğinin kaydedilmesi", func() bool {
+		paused, _ := workloadScale.Paused(cluster, "shop", map[string]string{"app": "api"})
+		return paused
+	})
+	if paused, _ := workloadScale.Paused(cluster, "shop", map[string]string{"app": "db"}); paused {
+		t.Errorf("yetki olmayan StatefulSet'ler değerlendirilmemeli")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("rollout izleyicisi hatayla durdu: %v", err)
+	}
+	if paused, _ := workloadScale.Paused(cluster, "shop", map[string]string{"app": "api"}); paused {
+		t.Errorf("durdurulan izleyicinin önbelleği kaldırılmalıydı")
+	}
+}
@@ -123,18 +123,28 @@ const ChartDetailModal: React.FC<ChartDetailModalProps> = ({ serviceId, onClose
       const historyData = response.data.history || [];
       setHistory(historyData);
 
-      // Metrikleri hesapla
-      if (historyData.length > 0) {
-        const totalResponseTime = historyData.reduce((sum: number, record: UptimeRecord) => sum + record.responseTime, 0);
-        const avgResponseTime = Math.round(totalResponseTime / historyData.length);
-        const successfulRequests = historyData.filter((record: UptimeRecord) => record.status === 'up').length;
-        const uptimePercentage = Math.round((successfulRequests / historyData.length) * 100);
-
+      // Metrikleri hesapla; duraklatılmış (paused) kontroller SLA hesabına girmez
+      const summary = response.data.summary;
+      if (summary) {
         setMetrics({
-          averageResponseTime: avgResponseTime,
-          uptimePercentage: uptimePercentage,
-          successfulRequests: successfulRequests
+          averageResponseTime: Math.round(summary.avg_response_time),
+          uptimePercentage: Math.round(summary.uptime_percentage),
+          successfulRequests: summary.up_checks
         });
+      } else {
+        const counted = historyData.filter((record: UptimeRecord) => record.status !== 'paused');
+        if (counted.length > 0) {
+          const totalResponseTime = counted.reduce((sum: number, record: UptimeRecord) => sum + record.responseTime, 0);
+          const avgResponseTime = Math.round(totalResponseTime / counted.length);
+          const successfulRequests = counted.filter((record: UptimeRecord) => record.status === 'up').length;
+          const uptimePercentage = Math.round((successfulRequests / counted.length) * 100);
+
+          setMetrics({
+            averageResponseTime: avgResponseTime,
+            uptimePercentage: uptimePercentage,
+            successfulRequests: successfulRequests
+          });
+        }
       }
 
       setError("");
@@ -34,6 +34,8 @@ const getCardBgColor = (status?: string) => {
       return "bg-red-50";
     case "warning":
       return "bg-yellow-50";
+    case "paused":
+      return "bg-slate-100";
     default:
       return "bg-gray-50";
   }
@@ -56,6 +58,8 @@ export const ServiceCard: React.FC<ServiceCardProps> = ({ service, onEdit, onDel
             ? 'bg-slate-50 dark:bg-slate-800 border-l-4 border-emerald-500'
             : service.status === 'down'
             ? 'bg-slate-50 dark:bg-slate-800 border-l-4 border-red-500'
+            : service.status === 'paused'
+            ? 'bg-slate-100 dark:bg-slate-800 border-l-4 border-slate-400'
             : 'bg-slate-50 dark:bg-slate-800 border-l-4 border-amber-500'
         }
       `}
@@ -68,6 +72,8 @@ export const ServiceCard: React.FC<ServiceCardProps> = ({ service, onEdit, onDel
               ? 'bg-emerald-100 text-emerald-700 dark:bg-emerald-900/30 dark:text-emerald-300'
               : service.status === 'down'
               ? 'bg-red-100 text-red-700 dark:bg-red-900/30 dark:text-red-300'
+              : service.status === 'paused'
+              ? 'bg-slate-200 text-slate-600 dark:bg-slate-700 dark:text-slate-300'
               : 'bg-amber-100 text-amber-700 dark:bg-amber-900/30 dark:text-amber-300'
           }
         `}>
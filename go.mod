@@ -4,4 +4,4 @@ go 1.24.0
 
 require github.com/mattn/go-sqlite3 v1.14.24
 
-require github.com/gorilla/mux v1.8.1 // indirect
+require github.com/gorilla/mux v1.8.1